@@ -0,0 +1,92 @@
+package pricing
+
+import (
+	"context"
+	"sort"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// DenomQuote is a bid expressed in one denom of the registry.
+type DenomQuote struct {
+	Denom     string
+	Display   string // Human-readable name of Denom, e.g. USDC
+	Price     string // Rate per block in Denom, formatted at Precision with the denom's rounding; empty when Err is set
+	Precision int
+	Err       error // Conversion failure, e.g. the denom's price oracle is unavailable
+}
+
+// AllDenomsBid is a bid priced once and expressed in every denom of the registry.
+type AllDenomsBid struct {
+	Result *BidResult   // The bid in the order's denom
+	Quotes []DenomQuote // One per registered denom, sorted by denom
+}
+
+// Quote returns the quote in denom, if it is registered.
+func (b *AllDenomsBid) Quote(denom string) (DenomQuote, bool) {
+	for _, quote := range b.Quotes {
+		if quote.Denom == denom {
+			return quote, true
+		}
+	}
+	return DenomQuote{}, false
+}
+
+// PriceAllDenoms prices a request once and converts the bid into every denom of the registry, so front-ends
+// can show equivalent prices and the provider can bid in whichever denom an order uses. The quote in the
+// order's denom is the bid itself; the others convert its cost-based rate, since the order's max price and
+// shading only apply in its own denom. Like CalculatePrice it returns ctx.Err() if ctx is done first.
+func PriceAllDenoms(ctx context.Context, request Request) (*AllDenomsBid, error) {
+	result, err := calculateBidContext(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return allDenomsBid(request, result)
+}
+
+// PriceAllDenoms is the package level PriceAllDenoms with the engine's AKT price.
+func (e *PricingEngine) PriceAllDenoms(ctx context.Context, request Request) (*AllDenomsBid, error) {
+	result, err := e.CalculateBid(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return allDenomsBid(request, result)
+}
+
+// allDenomsBid converts a priced bid into every denom of the configured registry.
+func allDenomsBid(request Request, result *BidResult) (*AllDenomsBid, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, withReason(ErrConfig, err)
+	}
+
+	precision := request.PricePrecision
+	if precision == 0 {
+		precision = DefaultPricePrecision
+	}
+	bid := &AllDenomsBid{Result: result}
+	for denom, info := range config.Denoms {
+		quote := DenomQuote{Denom: denom, Display: info.Display, Precision: config.Denoms.Precision(denom, precision)}
+		switch {
+		case denom == result.Denom:
+			quote.Price, quote.Precision = result.Price, result.Precision
+		case result.RatePerBlockUsd.IsNil():
+			// Special pricing bids have no rates to convert
+			continue
+		default:
+			quote.Price, quote.Err = convertBid(config.Denoms, denom, result, quote.Precision)
+		}
+		bid.Quotes = append(bid.Quotes, quote)
+	}
+	sort.Slice(bid.Quotes, func(i, j int) bool { return bid.Quotes[i].Denom < bid.Quotes[j].Denom })
+	return bid, nil
+}
+
+// convertBid formats the cost-based rates of result in denom, without a max price to check against.
+func convertBid(denoms DenomRegistry, denom string, result *BidResult, precision int) (string, error) {
+	rate, err := denoms.RatePerBlock(denom, result.RatePerBlockUakt, result.RatePerBlockUsd)
+	if err != nil {
+		return "", err
+	}
+	return denoms.FormatRate(denom, rate, precision, sdkmath.LegacyDec{})
+}
@@ -0,0 +1,168 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// DefaultAuditLogMaxBytes is the size at which the audit log is rotated when AUDIT_LOG_MAX_BYTES is unset.
+const DefaultAuditLogMaxBytes = 100 << 20
+
+// AuditRecord is one pricing decision in the audit log, with everything needed to reproduce it.
+type AuditRecord struct {
+	Time       time.Time `json:"time"`
+	Version    string    `json:"version"`     // Build version of the pricing script, see BuildVersion
+	ConfigHash string    `json:"config_hash"` // Hash of the pricing environment and configuration file, see ConfigHash
+
+	// Input
+	Owner            string            `json:"owner"`
+	OrderID          string            `json:"order_id,omitempty"`
+	GroupSpec        *dtypes.GroupSpec `json:"group_spec"`
+	PricePrecision   int               `json:"price_precision"`
+	Deposit          string            `json:"deposit,omitempty"`
+	ExpectedDuration string            `json:"expected_duration,omitempty"`
+
+	// Oracle
+	USDPerAKT      float64 `json:"akt_price_usd,omitempty"`
+	AKTPriceSource string  `json:"akt_price_source,omitempty"`
+
+	// Output
+	Price            string       `json:"price,omitempty"`
+	Denom            string       `json:"denom,omitempty"`
+	CostPrice        string       `json:"cost_price,omitempty"`
+	TotalCostUsd     string       `json:"total_cost_usd,omitempty"`
+	RatePerBlockUakt string       `json:"rate_per_block_uakt,omitempty"`
+	Provider         string       `json:"provider,omitempty"`
+	Profile          string       `json:"profile,omitempty"`
+	Region           string       `json:"region,omitempty"`
+	Adjustments      []Adjustment `json:"adjustments,omitempty"`
+	Reason           string       `json:"reason,omitempty"`
+	ReasonCode       string       `json:"reason_code,omitempty"`
+	Failed           bool         `json:"failed,omitempty"`
+}
+
+// NewAuditRecord builds the audit record of a pricing outcome. result may be nil for rejected requests.
+func NewAuditRecord(request Request, result *BidResult, bidErr error, now time.Time) AuditRecord {
+	record := AuditRecord{
+		Time:           now.UTC(),
+		Version:        BuildVersion(),
+		Owner:          request.Owner,
+		OrderID:        request.OrderID,
+		GroupSpec:      request.GSpec,
+		PricePrecision: request.PricePrecision,
+		USDPerAKT:      request.USDPerAKT,
+	}
+	if request.Deposit != nil {
+		record.Deposit = request.Deposit.String()
+	}
+	if request.ExpectedDuration > 0 {
+		record.ExpectedDuration = request.ExpectedDuration.String()
+	}
+
+	if bidErr != nil {
+		var rejected bool
+		record.Reason = bidErr.Error()
+		record.ReasonCode, rejected = ErrorReason(bidErr)
+		record.Failed = !rejected
+	}
+	if result != nil {
+		record.ConfigHash = result.ConfigHash
+		record.USDPerAKT = result.USDPerAKT
+		record.AKTPriceSource = result.AKTPriceSource
+		record.Price = result.Price
+		record.Denom = result.Denom
+		record.CostPrice = result.CostPrice
+		record.Provider = result.Provider
+		record.Profile = result.Profile
+		record.Region = result.Region
+		record.Adjustments = result.Adjustments
+		if !result.TotalCostUsd.IsNil() {
+			record.TotalCostUsd = result.TotalCostUsd.String()
+		}
+		if !result.RatePerBlockUakt.IsNil() {
+			record.RatePerBlockUakt = result.RatePerBlockUakt.String()
+		}
+	}
+	if record.ConfigHash == "" {
+		record.ConfigHash = ConfigHash()
+	}
+	return record
+}
+
+// auditBid appends the outcome to the audit log named by AUDIT_LOG, if set. Failures are logged and never
+// affect the bid.
+func auditBid(request Request, result *BidResult, bidErr error) {
+	path := os.Getenv("AUDIT_LOG")
+	if path == "" {
+		return
+	}
+
+	line, err := json.Marshal(NewAuditRecord(request, result, bidErr, time.Now()))
+	if err != nil {
+		log.Printf("Error encoding audit record: %v", err)
+		return
+	}
+	if err := appendAuditLog(path, append(line, '\n'), auditLogMaxBytes(), time.Now()); err != nil {
+		log.Printf("Error writing audit log: %v", err)
+	}
+}
+
+// auditLogMaxBytes reads AUDIT_LOG_MAX_BYTES; zero or less disables rotation by size.
+func auditLogMaxBytes() int64 {
+	val := os.Getenv("AUDIT_LOG_MAX_BYTES")
+	if val == "" {
+		return DefaultAuditLogMaxBytes
+	}
+	maxBytes, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		log.Printf("Invalid AUDIT_LOG_MAX_BYTES %q, using %d", val, DefaultAuditLogMaxBytes)
+		return DefaultAuditLogMaxBytes
+	}
+	return maxBytes
+}
+
+// appendAuditLog appends line to the audit log, first rotating it if it was started on an earlier UTC day or
+// the line would take it past maxBytes. Each line is a single append so concurrent bid script runs don't
+// interleave records.
+func appendAuditLog(path string, line []byte, maxBytes int64, now time.Time) error {
+	unlock := lockCache(path)
+	defer unlock()
+
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+		nextDay := info.ModTime().UTC().Format("2006-01-02") != now.UTC().Format("2006-01-02")
+		if nextDay || (maxBytes > 0 && info.Size()+int64(len(line)) > maxBytes) {
+			if err := rotateAuditLog(path, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// rotateAuditLog renames the audit log to <path>.<UTC timestamp> and makes it read-only. A log another
+// process already rotated is left alone.
+func rotateAuditLog(path string, now time.Time) error {
+	rotated := fmt.Sprintf("%s.%s", path, now.UTC().Format("20060102T150405.000000000"))
+	if err := os.Rename(path, rotated); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return os.Chmod(rotated, 0440)
+}
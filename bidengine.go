@@ -0,0 +1,29 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// CalculatePrice prices a request as a coin in the order's denom, the form the provider's bid engine expects.
+// It returns ctx.Err() if ctx is done first, cancelling the pricing, which records the cancellation in the
+// bid history.
+func CalculatePrice(ctx context.Context, request Request) (sdk.DecCoin, error) {
+	result, err := calculateBidContext(ctx, request)
+	if err != nil {
+		return sdk.DecCoin{}, err
+	}
+	return bidCoin(result)
+}
+
+// bidCoin converts the formatted price of a bid result into a coin.
+func bidCoin(result *BidResult) (sdk.DecCoin, error) {
+	amount, err := sdkmath.LegacyNewDecFromStr(result.Price)
+	if err != nil {
+		return sdk.DecCoin{}, fmt.Errorf("invalid bid price %q: %w", result.Price, err)
+	}
+	return sdk.DecCoin{Denom: result.Denom, Amount: amount}, nil
+}
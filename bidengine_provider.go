@@ -0,0 +1,39 @@
+//go:build provider
+
+package pricing
+
+import (
+	"context"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/akash-network/provider/bidengine"
+)
+
+// BidPricingStrategy implements the provider's bidengine.BidPricingStrategy with this pricing engine, so a
+// custom provider build can link the package instead of running it as a bid script. Configuration is read
+// from the environment on every call, as in the bid script, and concurrent bids share the AKT price of one
+// PricingEngine.
+type BidPricingStrategy struct {
+	engine *PricingEngine
+}
+
+var _ bidengine.BidPricingStrategy = BidPricingStrategy{}
+
+// NewBidPricingStrategy returns the provider bid pricing strategy.
+func NewBidPricingStrategy() BidPricingStrategy {
+	return BidPricingStrategy{engine: NewPricingEngine()}
+}
+
+// CalculatePrice prices the order of a provider bid request.
+func (s BidPricingStrategy) CalculatePrice(ctx context.Context, req bidengine.Request) (sdk.DecCoin, error) {
+	engine := s.engine
+	if engine == nil {
+		engine = NewPricingEngine()
+	}
+	return engine.CalculatePrice(ctx, Request{
+		Owner:          req.Owner,
+		GSpec:          req.GSpec,
+		PricePrecision: req.PricePrecision,
+	})
+}
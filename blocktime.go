@@ -0,0 +1,225 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// DefaultBlockTimeSampleSize is the number of blocks the average block time is measured over.
+const DefaultBlockTimeSampleSize = 1000
+
+// Bounds of an average block time, measured or configured, and of PRICE_TARGET_DAYS_PER_MONTH.
+const (
+	minBlockTimeSeconds = 1
+	maxBlockTimeSeconds = 60
+	minDaysPerMonth     = 28
+	maxDaysPerMonth     = 31
+)
+
+// BlockSchedule is the length of a month and of a block that monthly USD costs are spread over to get a
+// per-block rate. Each BidResult carries the schedule it was priced with, so the USD to uakt conversion can
+// be audited.
+type BlockSchedule struct {
+	DaysPerMonth     float64           // PRICE_TARGET_DAYS_PER_MONTH
+	BlockTimeSeconds float64           // Configured, measured or derived from BLOCKS_PER_MONTH
+	BlocksPerMonth   sdkmath.LegacyDec // Blocks a monthly cost is divided by
+}
+
+// BlockScheduleFromEnv returns the schedule of PRICE_TARGET_DAYS_PER_MONTH and either BLOCKS_PER_MONTH, which
+// fixes the blocks of a month outright, or the average block time of GetAverageBlockTime.
+func BlockScheduleFromEnv() (BlockSchedule, error) {
+	days, err := DaysPerMonthFromEnv()
+	if err != nil {
+		return BlockSchedule{}, err
+	}
+	if _, err := configuredBlockTime(days); err != nil {
+		return BlockSchedule{}, err
+	}
+	if val := strings.TrimSpace(os.Getenv("BLOCKS_PER_MONTH")); val != "" {
+		blocks, _ := strconv.ParseFloat(val, 64) // Checked by configuredBlockTime
+		return BlockSchedule{DaysPerMonth: days, BlockTimeSeconds: days * secondsPerDay / blocks, BlocksPerMonth: decFromFloat(blocks)}, nil
+	}
+	blockTime := GetAverageBlockTime()
+	return BlockSchedule{DaysPerMonth: days, BlockTimeSeconds: blockTime, BlocksPerMonth: blocksPerMonthFor(days, blockTime)}, nil
+}
+
+// DaysPerMonthFromEnv returns PRICE_TARGET_DAYS_PER_MONTH, the days of the month that monthly targets are
+// priced over, defaulting to DaysPerMonth.
+func DaysPerMonthFromEnv() (float64, error) {
+	val := strings.TrimSpace(os.Getenv("PRICE_TARGET_DAYS_PER_MONTH"))
+	if val == "" {
+		return DaysPerMonth, nil
+	}
+	days, err := strconv.ParseFloat(val, 64)
+	if err != nil || days < minDaysPerMonth || days > maxDaysPerMonth {
+		return 0, fmt.Errorf("invalid PRICE_TARGET_DAYS_PER_MONTH %q: must be between %d and %d", val, minDaysPerMonth, maxDaysPerMonth)
+	}
+	return days, nil
+}
+
+// daysPerMonth returns PRICE_TARGET_DAYS_PER_MONTH. Invalid values fall back to the default here, since
+// bids reject them through BlockScheduleFromEnv.
+func daysPerMonth() float64 {
+	days, err := DaysPerMonthFromEnv()
+	if err != nil {
+		return DaysPerMonth
+	}
+	return days
+}
+
+// configuredBlockTime returns the block time fixed by BLOCK_TIME_SECONDS, or implied by BLOCKS_PER_MONTH over
+// days, and 0 when neither is set. Setting both is an error, since they could disagree.
+func configuredBlockTime(days float64) (float64, error) {
+	secondsVal := strings.TrimSpace(os.Getenv("BLOCK_TIME_SECONDS"))
+	blocksVal := strings.TrimSpace(os.Getenv("BLOCKS_PER_MONTH"))
+	switch {
+	case secondsVal != "" && blocksVal != "":
+		return 0, fmt.Errorf("BLOCK_TIME_SECONDS and BLOCKS_PER_MONTH both set the block rate, set only one")
+	case secondsVal != "":
+		blockTime, err := strconv.ParseFloat(secondsVal, 64)
+		if err != nil || blockTime < minBlockTimeSeconds || blockTime > maxBlockTimeSeconds {
+			return 0, fmt.Errorf("invalid BLOCK_TIME_SECONDS %q: must be between %d and %d", secondsVal, minBlockTimeSeconds, maxBlockTimeSeconds)
+		}
+		return blockTime, nil
+	case blocksVal != "":
+		blocks, err := strconv.ParseFloat(blocksVal, 64)
+		if err != nil || blocks <= 0 || checkDecFloat(blocks) != nil {
+			return 0, fmt.Errorf("invalid BLOCKS_PER_MONTH %q: must be a positive number", blocksVal)
+		}
+		blockTime := days * secondsPerDay / blocks
+		if blockTime < minBlockTimeSeconds || blockTime > maxBlockTimeSeconds {
+			return 0, fmt.Errorf("invalid BLOCKS_PER_MONTH %q: implies a block time of %.3fs, outside %d to %d seconds", blocksVal, blockTime, minBlockTimeSeconds, maxBlockTimeSeconds)
+		}
+		return blockTime, nil
+	}
+	return 0, nil
+}
+
+// blockTimeCacheFile caches the measured average block time.
+const blockTimeCacheFile = "/tmp/blocktime.cache"
+
+// GetAverageBlockTime returns the average block time in seconds: BLOCK_TIME_SECONDS or the block time
+// implied by BLOCKS_PER_MONTH when set, else measured from the RPC endpoint in BLOCK_TIME_RPC. The
+// measurement is cached for 60 minutes; AverageBlockTimeSeconds is used when nothing is configured or the
+// measurement fails.
+func GetAverageBlockTime() float64 {
+	if blockTime, err := configuredBlockTime(daysPerMonth()); err == nil && blockTime > 0 {
+		return blockTime
+	}
+
+	rpcURL := strings.TrimRight(os.Getenv("BLOCK_TIME_RPC"), "/")
+	if rpcURL == "" {
+		return AverageBlockTimeSeconds
+	}
+
+	if blockTime, err := readCachedPrice(blockTimeCacheFile); err == nil {
+		return blockTime
+	}
+
+	sampleSize := blockTimeSampleSize()
+	blockTime, err := MeasureAverageBlockTime(rpcURL, sampleSize)
+	if err != nil {
+		log.Printf("Error measuring block time, using %.3fs: %v", AverageBlockTimeSeconds, err)
+		return AverageBlockTimeSeconds
+	}
+
+	if err := cachePrice(blockTimeCacheFile, blockTime); err != nil {
+		log.Printf("Error caching block time: %v", err)
+	}
+	return blockTime
+}
+
+// BlockTimeSampleSizeFromEnv returns BLOCK_TIME_SAMPLE_SIZE, defaulting to DefaultBlockTimeSampleSize.
+func BlockTimeSampleSizeFromEnv() (int64, error) {
+	size, ok, err := LookupEnvInt("BLOCK_TIME_SAMPLE_SIZE")
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return DefaultBlockTimeSampleSize, nil
+	}
+	if size <= 0 {
+		return 0, fmt.Errorf("invalid BLOCK_TIME_SAMPLE_SIZE %d: must be a positive integer", size)
+	}
+	return size, nil
+}
+
+// blockTimeSampleSize returns BLOCK_TIME_SAMPLE_SIZE, warning and using the default when it is invalid.
+func blockTimeSampleSize() int64 {
+	size, err := BlockTimeSampleSizeFromEnv()
+	if err != nil {
+		warnEnvProblem(err)
+		return DefaultBlockTimeSampleSize
+	}
+	return size
+}
+
+// MeasureAverageBlockTime queries a Tendermint RPC endpoint for the latest block and the block sampleSize
+// heights earlier, and returns the average time between them in seconds.
+func MeasureAverageBlockTime(rpcURL string, sampleSize int64) (float64, error) {
+	if sampleSize <= 0 {
+		return 0, fmt.Errorf("invalid block time sample size: %d", sampleSize)
+	}
+
+	latestHeight, latestTime, err := fetchBlockHeader(rpcURL + "/block")
+	if err != nil {
+		return 0, err
+	}
+	if latestHeight <= sampleSize {
+		return 0, fmt.Errorf("chain height %d is below sample size %d", latestHeight, sampleSize)
+	}
+
+	startHeight, startTime, err := fetchBlockHeader(fmt.Sprintf("%s/block?height=%d", rpcURL, latestHeight-sampleSize))
+	if err != nil {
+		return 0, err
+	}
+
+	blockTime := latestTime.Sub(startTime).Seconds() / float64(latestHeight-startHeight)
+	// Reject measurements that are clearly wrong rather than skewing every bid
+	if blockTime < minBlockTimeSeconds || blockTime > maxBlockTimeSeconds {
+		return 0, fmt.Errorf("measured block time %.3fs is out of range", blockTime)
+	}
+	return blockTime, nil
+}
+
+// fetchBlockHeader returns the height and time of the block at the given RPC URL.
+func fetchBlockHeader(blockURL string) (int64, time.Time, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(blockURL)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, time.Time{}, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, blockURL)
+	}
+
+	var data struct {
+		Result struct {
+			Block struct {
+				Header struct {
+					Height string    `json:"height"`
+					Time   time.Time `json:"time"`
+				} `json:"header"`
+			} `json:"block"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return 0, time.Time{}, fmt.Errorf("error decoding block from %s: %w", blockURL, err)
+	}
+
+	header := data.Result.Block.Header
+	height, err := strconv.ParseInt(header.Height, 10, 64)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("invalid block height %q from %s", header.Height, blockURL)
+	}
+	return height, header.Time, nil
+}
@@ -1,39 +1,193 @@
 package pricing
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// GetAKTPrice fetches the current price of AKT from the APIs, caching it.
+// Sources of the AKT price a bid used, recorded in BidResult.AKTPriceSource.
+const (
+	AKTPriceSourceOracle = "oracle" // Fetched from the price APIs
+	AKTPriceSourceCache  = "cache"  // Read from the price cache or a PricingEngine's shared copy
+	AKTPriceSourcePin    = "pin"    // Fixed by AKT_PRICE_PIN or Request.USDPerAKT
+	AKTPriceSourceStale  = "stale"  // An expired cached price within AKT_PRICE_MAX_STALENESS, the oracles failing
+	AKTPriceSourceOrder  = "order"  // The price of an earlier bid of the order, repeated within BID_HISTORY_PIN_WINDOW
+)
+
+// staleAKTPrices counts the AKT price lookups of this process answered with a stale price.
+var staleAKTPrices int64
+
+// GetAKTPrice fetches the current price of AKT from the APIs, caching it. A price pinned with AKT_PRICE_PIN
+// is returned without querying the oracle.
 func GetAKTPrice() (float64, error) {
-	cacheFile := "/tmp/aktprice.cache"
+	price, _, err := getAKTPrice(context.Background())
+	return price, err
+}
+
+// getAKTPrice is GetAKTPrice, also returning the source of the price. The oracles are not queried, and a
+// stale price is not used, once ctx is done.
+func getAKTPrice(ctx context.Context) (float64, string, error) {
+	if pin, ok, err := pinnedAKTPrice(); ok || err != nil {
+		return pin, AKTPriceSourcePin, err
+	}
+
+	cacheFile := AKTPriceCacheFile
+	price, err := readCachedPrice(cacheFile)
+	if err == nil {
+		return price, AKTPriceSourceCache, nil
+	}
+
+	unlock := lockCache(cacheFile)
+	defer unlock()
+	// Another bid may have refreshed the cache while this one waited for the lock
+	if price, err := readCachedPrice(cacheFile); err == nil {
+		return price, AKTPriceSourceCache, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, "", err
+	}
+	price, err = fetchPriceFromAPI(ctx)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, "", ctxErr
+		}
+		if stale, ok := staleAKTPrice(cacheFile); ok {
+			log.Printf("WARNING: AKT price oracles failed (%v), using stale cached price %g", err, stale)
+			atomic.AddInt64(&staleAKTPrices, 1)
+			return stale, AKTPriceSourceStale, nil
+		}
+		return 0, "", err
+	}
+
+	if err := cachePrice(cacheFile, price); err != nil {
+		return 0, "", err
+	}
+
+	return price, AKTPriceSourceOracle, nil
+}
+
+// AKTPriceMaxStaleness reads AKT_PRICE_MAX_STALENESS (a Go duration or days such as "1d"), the oldest
+// cached AKT price bids may use while every oracle fails. Zero, the default, disables the grace window.
+func AKTPriceMaxStaleness() (time.Duration, error) {
+	val := strings.TrimSpace(os.Getenv("AKT_PRICE_MAX_STALENESS"))
+	if val == "" {
+		return 0, nil
+	}
+	staleness, err := parseDays(val)
+	if err != nil || staleness < 0 {
+		return 0, fmt.Errorf("invalid AKT_PRICE_MAX_STALENESS %q", val)
+	}
+	return staleness, nil
+}
+
+// staleAKTPrice returns the cached AKT price if it is within the max staleness, regardless of the cache TTL.
+func staleAKTPrice(cacheFile string) (float64, bool) {
+	maxStaleness, err := AKTPriceMaxStaleness()
+	if err != nil {
+		log.Printf("Stale AKT price disabled: %v", err)
+		return 0, false
+	}
+	if maxStaleness == 0 {
+		return 0, false
+	}
+	info, err := os.Stat(cacheFile)
+	if err != nil || time.Since(info.ModTime()) > maxStaleness {
+		return 0, false
+	}
+	price, err := readStalePrice(cacheFile)
+	if err != nil || price <= 0 {
+		return 0, false
+	}
+	return price, true
+}
+
+// WriteAKTPriceMetrics writes the age of the cached AKT price and the number of lookups answered with a stale
+// one in the Prometheus text exposition format. The age is omitted when nothing is cached.
+func WriteAKTPriceMetrics(w io.Writer) error {
+	ew := &errWriter{w: w}
+	if info, err := os.Stat(AKTPriceCacheFile); err == nil {
+		ew.printf("# HELP akash_pricing_akt_price_age_seconds Age of the cached AKT price.\n")
+		ew.printf("# TYPE akash_pricing_akt_price_age_seconds gauge\n")
+		ew.printf("akash_pricing_akt_price_age_seconds %d\n", int64(time.Since(info.ModTime()).Seconds()))
+	}
+	ew.printf("# HELP akash_pricing_akt_price_stale_total AKT price lookups answered with a stale cached price while the oracles failed.\n")
+	ew.printf("# TYPE akash_pricing_akt_price_stale_total counter\n")
+	ew.printf("akash_pricing_akt_price_stale_total %d\n", atomic.LoadInt64(&staleAKTPrices))
+	return ew.err
+}
+
+// pinnedAKTPrice returns the USD per AKT fixed by AKT_PRICE_PIN, for providers pegging their prices daily
+// or reproducible runs. ok is false if it is unset.
+func pinnedAKTPrice() (price float64, ok bool, err error) {
+	val := os.Getenv("AKT_PRICE_PIN")
+	if val == "" {
+		return 0, false, nil
+	}
+	price, err = strconv.ParseFloat(val, 64)
+	if err != nil || price <= 0 || checkDecFloat(price) != nil {
+		return 0, false, fmt.Errorf("invalid AKT_PRICE_PIN %q: must be a positive number", val)
+	}
+	return price, true, nil
+}
+
+// GetCoinGeckoPrice fetches the USD price of a CoinGecko asset, caching it like the AKT price.
+func GetCoinGeckoPrice(id string) (float64, error) {
+	if id == AKTCoinGeckoID {
+		return GetAKTPrice()
+	}
+
+	cacheFile := coinGeckoCacheFile(id)
 	price, err := readCachedPrice(cacheFile)
 	if err == nil {
 		return price, nil
 	}
 
-	price, err = fetchPriceFromAPI()
+	unlock := lockCache(cacheFile)
+	defer unlock()
+	// Another bid may have refreshed the cache while this one waited for the lock
+	if price, err := readCachedPrice(cacheFile); err == nil {
+		return price, nil
+	}
+
+	resp, err := http.Get("https://api.coingecko.com/api/v3/simple/price?ids=" + url.QueryEscape(id) + "&vs_currencies=usd")
 	if err != nil {
 		return 0, err
 	}
+	defer resp.Body.Close()
 
-	if err := cachePrice(cacheFile, price); err != nil {
+	var data map[string]map[string]float64
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
 		return 0, err
 	}
+	price = data[id]["usd"]
+	if price <= 0 {
+		return 0, fmt.Errorf("no USD price for %s", id)
+	}
 
+	if err := cachePrice(cacheFile, price); err != nil {
+		return 0, err
+	}
 	return price, nil
 }
 
 // readCachedPrice reads the AKT price from the cache file.
 func readCachedPrice(cacheFile string) (float64, error) {
 	fileInfo, err := os.Stat(cacheFile)
-	if os.IsNotExist(err) || time.Since(fileInfo.ModTime()) > 60*time.Minute {
+	if err != nil || time.Since(fileInfo.ModTime()) > PriceCacheTTL {
 		return 0, fmt.Errorf("cache file does not exist or is expired")
 	}
 
@@ -46,29 +200,46 @@ func readCachedPrice(cacheFile string) (float64, error) {
 	if err != nil {
 		return 0, err
 	}
+	if price <= 0 {
+		return 0, fmt.Errorf("cached price %g is not positive", price)
+	}
 
 	return price, nil
 }
 
-// fetchPriceFromAPI tries to fetch the AKT price from primary and fallback APIs.
-func fetchPriceFromAPI() (float64, error) {
+// fetchPriceFromAPI tries to fetch the AKT price from primary and fallback APIs. The fallback is not tried
+// once ctx is done.
+func fetchPriceFromAPI(ctx context.Context) (float64, error) {
 	// Primary: DIA Data API (same as bash script)
 	primaryURL := "https://api.diadata.org/v1/assetQuotation/Osmosis/ibc-C2CFB1C37C146CF95B0784FD518F8030FEFC76C5800105B1742FB65FFE65F873"
 	// Fallback: CoinGecko API
 	fallbackURL := "https://api.coingecko.com/api/v3/simple/price?ids=akash-network&vs_currencies=usd"
 
-	price, err := fetchPriceFromURL(primaryURL)
+	price, err := fetchPriceFromURL(ctx, primaryURL)
 	if err != nil {
+		if ctx.Err() != nil {
+			return 0, err
+		}
 		fmt.Println("Primary API failed, trying fallback")
-		return fetchPriceFromURL(fallbackURL)
+		price, fallbackErr := fetchPriceFromURL(ctx, fallbackURL)
+		notifyEvent(WebhookEvent{
+			Type:    EventOracleFailover,
+			Message: "AKT price oracle failed over to CoinGecko",
+			Fields:  map[string]string{"primary_error": err.Error(), "fallback_ok": strconv.FormatBool(fallbackErr == nil)},
+		})
+		return price, fallbackErr
 	}
 
 	return price, nil
 }
 
 // fetchPriceFromURL fetches the AKT price from a given URL.
-func fetchPriceFromURL(url string) (float64, error) {
-	resp, err := http.Get(url)
+func fetchPriceFromURL(ctx context.Context, url string) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return 0, err
 	}
@@ -79,32 +250,72 @@ func fetchPriceFromURL(url string) (float64, error) {
 		return 0, err
 	}
 
-	return extractPrice(data), nil
+	return extractPrice(data)
 }
 
-// extractPrice extracts the AKT price from the API response.
-func extractPrice(data interface{}) float64 {
-	switch v := data.(type) {
-	case map[string]interface{}:
-		// Try DIA Data API format (capital P "Price")
-		if price, ok := v["Price"].(float64); ok {
-			return price
-		}
-		// Try lowercase "price" for other APIs
-		if price, ok := v["price"].(float64); ok {
-			return price
+// extractPrice extracts the AKT price from the API response. Responses without a recognised positive price
+// are errors, so they fail over to the next oracle instead of being cached.
+func extractPrice(data interface{}) (float64, error) {
+	price, ok := 0.0, false
+	if v, isMap := data.(map[string]interface{}); isMap {
+		// Try DIA Data API format (capital P "Price"), then lowercase "price" for other APIs
+		price, ok = v["Price"].(float64)
+		if !ok {
+			price, ok = v["price"].(float64)
 		}
 		// Try CoinGecko format
-		if nested, ok := v["akash-network"].(map[string]interface{}); ok {
-			if price, ok := nested["usd"].(float64); ok {
-				return price
-			}
+		if nested, isNested := v["akash-network"].(map[string]interface{}); !ok && isNested {
+			price, ok = nested["usd"].(float64)
 		}
 	}
-	return 0
+	if !ok {
+		return 0, fmt.Errorf("no AKT price in the oracle response")
+	}
+	if price <= 0 || checkDecFloat(price) != nil {
+		return 0, fmt.Errorf("invalid AKT price %g in the oracle response", price)
+	}
+	return price, nil
 }
 
-// cachePrice writes the AKT price to the cache file.
+// cachePrice writes the AKT price to the cache file. Non-positive prices are never cached.
 func cachePrice(cacheFile string, price float64) error {
-	return ioutil.WriteFile(cacheFile, []byte(fmt.Sprintf("%f", price)), 0644)
+	if price <= 0 {
+		return fmt.Errorf("refusing to cache non-positive price %g", price)
+	}
+	return writeFileAtomic(cacheFile, []byte(fmt.Sprintf("%f", price)))
+}
+
+// cacheLocks serializes refreshes of each cache file within the process, so concurrent bids that find a
+// cache expired fetch once while the others wait and read the refreshed value.
+var cacheLocks sync.Map // Cache file path -> *sync.Mutex
+
+// lockCache locks the refresh of a cache file and returns the unlock function.
+func lockCache(cacheFile string) func() {
+	lock, _ := cacheLocks.LoadOrStore(cacheFile, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// writeFileAtomic replaces a file through a rename, so concurrent readers in this or other processes see
+// either the old or the new content and never a partial write.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
 }
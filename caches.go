@@ -0,0 +1,232 @@
+package pricing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache files shared by bid script runs.
+const (
+	AKTPriceCacheFile = "/tmp/aktprice.cache"
+	PriceCacheTTL     = 60 * time.Minute // Lifetime of cached prices, FX rates and the block time
+
+	coinGeckoCachePattern = "/tmp/*-price.cache"
+	fxCachePattern        = "/tmp/fx-*-usd.cache"
+)
+
+// CacheStatus describes one cache file.
+type CacheStatus struct {
+	Name    string
+	Path    string
+	Exists  bool
+	Age     time.Duration
+	Expired bool   // Older than its TTL, the next bid refreshes it
+	Value   string // The cached price, rate or whitelist size
+}
+
+// CacheRefresh is the outcome of force-refreshing one cache. Err is nil if it succeeded.
+type CacheRefresh struct {
+	Name string
+	Err  error
+}
+
+// coinGeckoCacheFile returns the cache file of a CoinGecko asset price.
+func coinGeckoCacheFile(id string) string {
+	return strings.Replace(coinGeckoCachePattern, "*", url.PathEscape(id), 1)
+}
+
+// fxCacheFile returns the cache file of a currency's USD rate.
+func fxCacheFile(currency string) string {
+	return strings.Replace(fxCachePattern, "*", currency, 1)
+}
+
+// CacheStatuses returns the AKT price, whitelist, block time and GPU inventory caches, followed by any cached
+// FX rates and CoinGecko prices.
+func CacheStatuses() []CacheStatus {
+	statuses := []CacheStatus{
+		priceCacheStatus("AKT price", AKTPriceCacheFile, "USD"),
+		whitelistCacheStatus(configuredWhitelistCacheFile()),
+		priceCacheStatus("block time", blockTimeCacheFile, "s"),
+		gpuInventoryCacheStatus(GPUInventoryCacheFile),
+	}
+	fxFiles, _ := filepath.Glob(fxCachePattern)
+	for _, file := range fxFiles {
+		currency := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "fx-"), "-usd.cache")
+		statuses = append(statuses, priceCacheStatus(currency+"/USD rate", file, "USD"))
+	}
+	priceFiles, _ := filepath.Glob(coinGeckoCachePattern)
+	for _, file := range priceFiles {
+		id := strings.TrimSuffix(filepath.Base(file), "-price.cache")
+		statuses = append(statuses, priceCacheStatus(id+" price", file, "USD"))
+	}
+	return statuses
+}
+
+// priceCacheStatus describes a cache file holding a single number.
+func priceCacheStatus(name, path, unit string) CacheStatus {
+	status := CacheStatus{Name: name, Path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return status
+	}
+	status.Exists = true
+	status.Age = time.Since(info.ModTime())
+	status.Expired = status.Age > PriceCacheTTL
+	if value, err := readStalePrice(path); err == nil {
+		status.Value = strconv.FormatFloat(value, 'f', -1, 64) + " " + unit
+	} else {
+		status.Value = "unreadable: " + err.Error()
+	}
+	return status
+}
+
+// gpuInventoryCacheStatus describes the cached GPU inventory discovered from Kubernetes.
+func gpuInventoryCacheStatus(path string) CacheStatus {
+	status := CacheStatus{Name: "GPU inventory", Path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return status
+	}
+	status.Exists = true
+	status.Age = time.Since(info.ModTime())
+	ttl := DefaultGPUInventoryTTL
+	if val, err := time.ParseDuration(os.Getenv("GPU_INVENTORY_TTL")); err == nil && val > 0 {
+		ttl = val
+	}
+	status.Expired = status.Age > ttl
+	var inventory GPUInventory
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &inventory)
+	}
+	if err != nil {
+		status.Value = "unreadable: " + err.Error()
+	} else {
+		status.Value = inventory.String()
+	}
+	return status
+}
+
+// whitelistCacheStatus describes the cached whitelist with its entry and line counts.
+func whitelistCacheStatus(path string) CacheStatus {
+	status := CacheStatus{Name: "whitelist", Path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return status
+	}
+	status.Exists = true
+	status.Age = time.Since(info.ModTime())
+	status.Expired = status.Age > DefaultWhitelistTTL
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		status.Value = "unreadable: " + err.Error()
+		return status
+	}
+	lines := bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		lines++
+	}
+	entries, err := parseWhitelist(data, readWhitelistMeta(path).Format)
+	if err != nil {
+		status.Value = fmt.Sprintf("%d lines, unparsable: %v", lines, err)
+		return status
+	}
+	status.Value = fmt.Sprintf("%d entries, %d lines", len(entries), lines)
+	return status
+}
+
+// ClearCaches removes every cache file, including the whitelist's metadata, and returns the removed paths.
+// The next bid fetches everything again.
+func ClearCaches() ([]string, error) {
+	// DefaultWhitelistFile itself is the whitelist cache of earlier versions, before each URL had its own
+	paths := []string{AKTPriceCacheFile, DefaultWhitelistFile, whitelistMetaFile(DefaultWhitelistFile), blockTimeCacheFile, GPUInventoryCacheFile}
+	for _, pattern := range []string{DefaultWhitelistFile + "-*", fxCachePattern, coinGeckoCachePattern} {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, files...)
+	}
+
+	var removed []string
+	for _, path := range paths {
+		unlock := lockCache(path)
+		err := os.Remove(path)
+		unlock()
+		if err == nil {
+			removed = append(removed, path)
+		} else if !os.IsNotExist(err) {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// RefreshCaches fetches the AKT price, the WHITELIST_URL whitelist, the BLOCK_TIME_RPC block time, the
+// Kubernetes GPU inventory and the target currency's USD rate again regardless of their age. Sources that are not configured are skipped, as
+// are local whitelists, which are never cached.
+func RefreshCaches() []CacheRefresh {
+	refreshes := []CacheRefresh{{"AKT price", refreshPriceCache(AKTPriceCacheFile, func() (float64, error) { return fetchPriceFromAPI(context.Background()) })}}
+
+	whitelistURL := strings.Trim(os.Getenv("WHITELIST_URL"), "\"")
+	if _, local := localWhitelistPath(whitelistURL); whitelistURL != "" && !local {
+		whitelistFile := whitelistCacheFile(whitelistURL)
+		unlock := lockCache(whitelistFile)
+		err := fetchWhitelist(context.Background(), whitelistURL, whitelistFile)
+		unlock()
+		refreshes = append(refreshes, CacheRefresh{"whitelist", err})
+	}
+
+	if rpcURL := strings.TrimRight(os.Getenv("BLOCK_TIME_RPC"), "/"); rpcURL != "" {
+		sampleSize := blockTimeSampleSize()
+		err := refreshPriceCache(blockTimeCacheFile, func() (float64, error) {
+			return MeasureAverageBlockTime(rpcURL, sampleSize)
+		})
+		refreshes = append(refreshes, CacheRefresh{"block time", err})
+	}
+
+	if inventory, err := NewGPUInventoryProviderFromEnv(); err != nil {
+		refreshes = append(refreshes, CacheRefresh{"GPU inventory", err})
+	} else if k8s, ok := inventory.(*KubernetesGPUInventory); ok {
+		unlock := lockCache(k8s.CacheFile)
+		_, err := k8s.refresh()
+		unlock()
+		refreshes = append(refreshes, CacheRefresh{"GPU inventory", err})
+	}
+
+	priceTargets, err := LoadPriceTargets()
+	if err != nil {
+		return append(refreshes, CacheRefresh{"target currency", err})
+	}
+	if currency := strings.ToUpper(strings.TrimSpace(priceTargets.Currency)); currency != "" && currency != "USD" {
+		err := refreshPriceCache(fxCacheFile(currency), func() (float64, error) {
+			return fetchFXRate(currency)
+		})
+		refreshes = append(refreshes, CacheRefresh{currency + "/USD rate", err})
+	}
+	return refreshes
+}
+
+// refreshPriceCache fetches a value and caches it, keeping the cached value if the fetch fails.
+func refreshPriceCache(cacheFile string, fetch func() (float64, error)) error {
+	unlock := lockCache(cacheFile)
+	defer unlock()
+	value, err := fetch()
+	if err != nil {
+		return err
+	}
+	if value <= 0 {
+		return fmt.Errorf("source returned %v", value)
+	}
+	return cachePrice(cacheFile, value)
+}
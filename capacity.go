@@ -0,0 +1,232 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// Capacity describes what the provider can provision. Unset fields do not restrict requests, so an empty
+// capacity accepts everything.
+type Capacity struct {
+	StorageClasses []string         `json:"storage_classes,omitempty"` // Persistent and RAM storage classes offered, e.g. beta2; ephemeral storage is always offered
+	LeasedIPs      *bool            `json:"leased_ips,omitempty"`      // Whether leased IPv4 endpoints are offered
+	IPv6           *bool            `json:"ipv6,omitempty"`            // Whether leased IPv6 endpoints are offered
+	CPUArchs       []string         `json:"cpu_archs,omitempty"`       // CPU architectures of the nodes, amd64 or arm64
+	MaxReplica     *ReplicaCapacity `json:"max_replica,omitempty"`     // Resources of the largest node a replica must fit on
+}
+
+// ReplicaCapacity limits the resources of one replica. Zero fields are not limited.
+type ReplicaCapacity struct {
+	CPU      float64 `json:"cpu,omitempty"`       // Cores
+	MemoryGB float64 `json:"memory_gb,omitempty"` // Gigabytes of PRICE_TARGET_SIZE_UNIT
+	GPUs     int64   `json:"gpus,omitempty"`
+}
+
+// CapacityProvider reports what the provider can currently provision.
+type CapacityProvider interface {
+	Capacity() (*Capacity, error)
+}
+
+// StaticCapacity is the capacity section of the configuration file.
+type StaticCapacity struct {
+	capacity *Capacity
+}
+
+// Capacity returns the configured capacity.
+func (s StaticCapacity) Capacity() (*Capacity, error) {
+	return s.capacity, nil
+}
+
+// FileCapacityProvider reads the capacity from a JSON file in the format of the configuration file's capacity
+// section, typically written by a sidecar watching the cluster.
+type FileCapacityProvider struct {
+	Path string
+}
+
+// Capacity reads the capacity file.
+func (p *FileCapacityProvider) Capacity() (*Capacity, error) {
+	data, err := ioutil.ReadFile(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	return parseCapacity(data, p.Path)
+}
+
+// HTTPCapacityProvider fetches the capacity as JSON from an inventory service on every bid.
+type HTTPCapacityProvider struct {
+	URL    string
+	Client *http.Client
+}
+
+// Capacity fetches the capacity document.
+func (p *HTTPCapacityProvider) Capacity() (*Capacity, error) {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	resp, err := client.Get(p.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP request error: %s", resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	return parseCapacity(data, p.URL)
+}
+
+// parseCapacity decodes and validates a capacity document read from source.
+func parseCapacity(data []byte, source string) (*Capacity, error) {
+	var capacity Capacity
+	if err := json.Unmarshal(data, &capacity); err != nil {
+		return nil, fmt.Errorf("invalid capacity %s: %w", source, err)
+	}
+	if err := capacity.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid capacity %s: %w", source, err)
+	}
+	return &capacity, nil
+}
+
+// NewCapacityProviderFromEnv returns the live capacity source configured by CAPACITY_URL or CAPACITY_FILE,
+// else the capacity section of the configuration file, or nil if neither is set.
+func NewCapacityProviderFromEnv(config *Config) CapacityProvider {
+	if capacityURL := os.Getenv("CAPACITY_URL"); capacityURL != "" {
+		return &HTTPCapacityProvider{URL: capacityURL}
+	}
+	if capacityFile := os.Getenv("CAPACITY_FILE"); capacityFile != "" {
+		return &FileCapacityProvider{Path: capacityFile}
+	}
+	if config != nil && config.Capacity != nil {
+		return StaticCapacity{config.Capacity}
+	}
+	return nil
+}
+
+// Validate checks the CPU architectures are known, storage classes are named and the replica limits are not
+// negative.
+func (c *Capacity) Validate() error {
+	for _, arch := range c.CPUArchs {
+		if arch != CPUArchAMD64 && arch != CPUArchARM64 {
+			return fmt.Errorf("unknown CPU architecture %q, expected %s or %s", arch, CPUArchAMD64, CPUArchARM64)
+		}
+	}
+	for _, class := range c.StorageClasses {
+		if strings.TrimSpace(class) == "" {
+			return fmt.Errorf("empty storage class")
+		}
+	}
+	if m := c.MaxReplica; m != nil {
+		if err := checkDecFloat(m.CPU); err != nil || m.CPU < 0 {
+			return fmt.Errorf("invalid max_replica cpu %g", m.CPU)
+		}
+		if err := checkDecFloat(m.MemoryGB); err != nil || m.MemoryGB < 0 {
+			return fmt.Errorf("invalid max_replica memory_gb %g", m.MemoryGB)
+		}
+		if m.GPUs < 0 {
+			return fmt.Errorf("invalid max_replica gpus %d", m.GPUs)
+		}
+	}
+	return nil
+}
+
+// Check rejects requests the provider cannot provision with ErrUnsatisfiable, naming every shortfall.
+func (c *Capacity) Check(gSpec *dtypes.GroupSpec, requests ResourceRequests) error {
+	var problems []string
+
+	if c.StorageClasses != nil {
+		offered := make(map[string]bool, len(c.StorageClasses))
+		for _, class := range c.StorageClasses {
+			offered[class] = true
+		}
+		var missing []string
+		for class, size := range requests.StorageRequested {
+			if class == "default" || class == "ephemeral" || offered[class] || size.IsZero() {
+				continue
+			}
+			missing = append(missing, class)
+		}
+		sort.Strings(missing)
+		for _, class := range missing {
+			problems = append(problems, fmt.Sprintf("storage class %s is not offered", class))
+		}
+	}
+
+	ipv4 := requests.IPsRequested - requests.IPv6Requested
+	if c.LeasedIPs != nil && !*c.LeasedIPs && ipv4 > 0 {
+		problems = append(problems, "leased IPs are not offered")
+	}
+	if c.IPv6 != nil && !*c.IPv6 && requests.IPv6Requested > 0 {
+		problems = append(problems, "leased IPv6 is not offered")
+	}
+
+	if c.CPUArchs != nil {
+		offered := make(map[string]bool, len(c.CPUArchs))
+		for _, arch := range c.CPUArchs {
+			offered[arch] = true
+		}
+		missingArchs := make(map[string]bool)
+		for kind := range requests.CPUKindRequested {
+			if kind.Arch != "" && !offered[kind.Arch] {
+				missingArchs[kind.Arch] = true
+			}
+		}
+		missing := make([]string, 0, len(missingArchs))
+		for arch := range missingArchs {
+			missing = append(missing, arch)
+		}
+		sort.Strings(missing)
+		for _, arch := range missing {
+			problems = append(problems, fmt.Sprintf("CPU architecture %s is not offered", arch))
+		}
+	}
+
+	if c.MaxReplica != nil {
+		problems = append(problems, c.MaxReplica.problems(gSpec)...)
+	}
+
+	if len(problems) > 0 {
+		return withReason(ErrUnsatisfiable, fmt.Errorf("%s", strings.Join(problems, "; ")))
+	}
+	return nil
+}
+
+// problems lists the resource units whose replicas exceed the limits.
+func (m *ReplicaCapacity) problems(gSpec *dtypes.GroupSpec) []string {
+	var problems []string
+	for _, resourceUnit := range gSpec.Resources {
+		id := resourceUnit.Resources.ID
+		if cpu := resourceUnit.Resources.CPU; m.CPU > 0 && cpu != nil {
+			cores := sdkmath.LegacyNewDecFromInt(cpu.Units.Val).QuoInt64(1000)
+			if cores.GT(decFromFloat(m.CPU)) {
+				problems = append(problems, fmt.Sprintf("resource %d needs %s CPU cores per replica, at most %g fit on a node", id, FormatDec(cores, 3), m.CPU))
+			}
+		}
+		if memory := resourceUnit.Resources.Memory; m.MemoryGB > 0 && memory != nil {
+			unit := sizeUnit()
+			gb := sdkmath.LegacyNewDecFromInt(memory.Quantity.Val).Quo(bytesPerUnit(unit))
+			if gb.GT(decFromFloat(m.MemoryGB)) {
+				problems = append(problems, fmt.Sprintf("resource %d needs %s %s of memory per replica, at most %g fit on a node", id, FormatDec(gb, 2), unit, m.MemoryGB))
+			}
+		}
+		if gpu := resourceUnit.Resources.GPU; m.GPUs > 0 && gpu != nil {
+			if units := gpu.Units.Val; units.GT(sdkmath.NewInt(m.GPUs)) {
+				problems = append(problems, fmt.Sprintf("resource %d needs %s GPUs per replica, at most %d fit on a node", id, units, m.GPUs))
+			}
+		}
+	}
+	return problems
+}
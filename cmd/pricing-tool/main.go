@@ -0,0 +1,1217 @@
+// Command pricing-tool is the Akash provider bid price script and its operator tooling.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	pricing "github.com/akash-network/pricing-script"
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// usage describes the available subcommands.
+const usage = `Usage: pricing-tool [--config-dir <dir>] [command] [flags]
+
+Without a command, pricing-tool runs as the provider bid script: it reads the deployment order JSON from
+stdin and prints the bid price per block to stdout. Set DEBUG_BID_SCRIPT=1 to log the pricing to stderr.
+It exits with status 3 when it declines to bid because the order's max price is too low, and 1 when the
+order is rejected for any other reason or pricing fails.
+  [--output plain|json]                       Bid script response format (default BID_SCRIPT_OUTPUT or plain)
+With BID_SCRIPT_COMPAT=legacy, stdin may hold only the resources array and AKASH_OWNER, PRICE, PRICE_DENOM,
+PRICE_PRECISION and AKASH_ORDER_ID supply the rest of the order, as with the original bash script.
+
+--config-dir (default PRICING_CONFIG_DIR) reads the configuration from a directory with one file per key, as
+mounted from a Kubernetes ConfigMap or Secret; serve, socket, consume and export reload it on change.
+
+Commands:
+  price --sdl <file>|--groupspec <file>       Preview the bid for a deployment without placing it
+  simulate --orders <file>                    Replay historical orders and report win rates and revenue
+  export [--listen :9102] [--interval 1m]     Serve per-unit bid prices as Prometheus gauges on /metrics
+  serve [--listen :8080] [--metrics]          Run the pricing daemon with /price, /healthz and /readyz
+        [--workers 64] [--queue 256]          Bound concurrent pricing, answering 429 when the queue is full
+  consume                                     Price orders from NATS_SUBJECT and publish the results
+  socket --path <file> [--mode 0660]          Serve JSON-RPC pricing on a Unix domain socket
+  validate                                    Check the pricing configuration and data sources
+  costs                                       Print the cost floors of the cost model next to the price targets
+  policy export|import|diff                   Export, import or compare complete pricing policies as YAML
+  tune --sdl <file>|--groupspec <file>        Adjust price targets at a prompt while repricing sample groups
+  cache show|clear|refresh                    Inspect, wipe or force-refresh the price and whitelist caches
+  feedback record --order <id> --won|--lost   Record whether the bid for an order won the lease
+  feedback report [--bands 10,50,100]         Print win rates per GPU model and monthly USD band
+  feedback revenue [--akt-price <usd>]        Estimate the monthly revenue of the won leases at current prices
+  fixture --sdl <file> --name <name>          Convert an SDL file into testdata fixtures
+  version                                     Print the build version and the configuration hash
+`
+
+// exitDeclined is the bid script's exit status when the order's max price is below the required rate, so
+// the provider can tell an underpriced order from a bad request or an outage.
+const exitDeclined = 3
+
+func main() {
+	if err := pricing.ResolveEnvSecrets(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := loadConfigDir(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	stopTracing := startTracing()
+	if len(os.Args) < 2 || (strings.HasPrefix(os.Args[1], "-") && !isHelpFlag(os.Args[1])) {
+		err := runBidScript(os.Args[1:])
+		stopTracing()
+		if errors.Is(err, pricing.ErrRateTooLow) {
+			fmt.Fprintf(os.Stderr, "Declined: %v\n", err)
+			os.Exit(exitDeclined)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if longRunningCommands[os.Args[1]] {
+		// Malformed settings are reported before the first order, and refuse the start with CONFIG_STRICT
+		if err := pricing.CheckStartupConfig(); err != nil {
+			stopTracing()
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "price":
+		err = runPrice(os.Args[2:])
+	case "simulate":
+		err = runSimulate(os.Args[2:])
+	case "export":
+		err = runExport(os.Args[2:])
+	case "serve":
+		err = runServe(os.Args[2:])
+	case "consume":
+		err = runConsume()
+	case "socket":
+		err = runSocket(os.Args[2:])
+	case "validate":
+		err = runValidate()
+	case "costs":
+		err = runCosts()
+	case "cache":
+		err = runCache(os.Args[2:])
+	case "policy":
+		err = runPolicy(os.Args[2:])
+	case "tune":
+		err = runTune(os.Args[2:])
+	case "feedback":
+		err = runFeedback(os.Args[2:])
+	case "fixture":
+		err = runFixture(os.Args[2:])
+	case "version":
+		runVersion()
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	stopTracing()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// activeConfigDir is the configuration directory applied by loadConfigDir, if any.
+var activeConfigDir string
+
+// longRunningCommands keep watching the configuration directory for changes.
+var longRunningCommands = map[string]bool{"serve": true, "socket": true, "consume": true, "export": true}
+
+// loadConfigDir applies the configuration directory named by a leading --config-dir flag, or by
+// PRICING_CONFIG_DIR, and removes the flag from os.Args. Long-running commands reload it on change.
+func loadConfigDir() error {
+	dir := os.Getenv("PRICING_CONFIG_DIR")
+	if len(os.Args) > 1 && strings.HasPrefix(os.Args[1], "--config-dir=") {
+		dir = strings.TrimPrefix(os.Args[1], "--config-dir=")
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	} else if len(os.Args) > 1 && os.Args[1] == "--config-dir" {
+		if len(os.Args) < 3 {
+			return fmt.Errorf("--config-dir requires a directory")
+		}
+		dir = os.Args[2]
+		os.Args = append(os.Args[:1], os.Args[3:]...)
+	}
+	if dir == "" {
+		return nil
+	}
+
+	configDir := pricing.NewConfigDir(dir)
+	if _, err := configDir.Load(); err != nil {
+		return err
+	}
+	activeConfigDir = dir
+	if len(os.Args) > 1 && longRunningCommands[os.Args[1]] {
+		go configDir.Watch(context.Background(), pricing.DefaultConfigDirInterval)
+	}
+	return nil
+}
+
+// startTracing exports bid pipeline spans when an OTLP endpoint is configured and returns a function
+// flushing them. Tracing errors are reported on stderr and never stop pricing.
+func startTracing() func() {
+	shutdown, err := pricing.InitTracing(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown(ctx)
+	}
+}
+
+// isHelpFlag reports whether arg asks for the usage text rather than a bid script flag.
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// runBidScript prices the deployment order on stdin and writes only the response to stdout, as the provider
+// expects. Pricing output goes to stderr when DEBUG_BID_SCRIPT is set and is discarded otherwise.
+func runBidScript(args []string) error {
+	fs := flag.NewFlagSet("pricing-tool", flag.ExitOnError)
+	output := fs.String("output", os.Getenv("BID_SCRIPT_OUTPUT"), "response format: plain or json")
+	fs.Parse(args)
+
+	format, err := pricing.ParseOutputFormat(*output)
+	if err != nil {
+		return err
+	}
+
+	stdout := os.Stdout
+	restore, err := quietPricingOutput()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	request, result, err := pricing.Request{}, (*pricing.BidResult)(nil), checkBidScriptConfig()
+	if err == nil {
+		request, result, err = priceOrder(os.Stdin)
+	}
+	if writeErr := pricing.WriteBidResponse(stdout, format, pricing.NewBidResponse(request, result, err)); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// checkBidScriptConfig refuses to price an order with invalid configuration when CONFIG_STRICT is set.
+// Otherwise the checks are left to the validate command rather than run for every order.
+func checkBidScriptConfig() error {
+	strict, err := pricing.ConfigStrict()
+	if err != nil || !strict {
+		return err
+	}
+	return pricing.CheckStartupConfig()
+}
+
+// quietPricingOutput redirects the pricing output of stdout and the log to stderr when DEBUG_BID_SCRIPT is
+// set and discards it otherwise. The returned function restores stdout.
+func quietPricingOutput() (func(), error) {
+	stdout := os.Stdout
+	if os.Getenv("DEBUG_BID_SCRIPT") != "" {
+		os.Stdout = os.Stderr
+		log.SetOutput(os.Stderr)
+		log.SetPrefix("DEBUG: ")
+		return func() { os.Stdout = stdout }, nil
+	}
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	os.Stdout = devNull
+	log.SetOutput(ioutil.Discard)
+	return func() {
+		os.Stdout = stdout
+		devNull.Close()
+	}, nil
+}
+
+// priceOrder decodes and prices a deployment order. The request is returned even when pricing fails so the
+// response can report the order's denom and precision.
+func priceOrder(r io.Reader) (pricing.Request, *pricing.BidResult, error) {
+	legacy, err := pricing.LegacyEnvMode()
+	if err != nil {
+		return pricing.Request{}, nil, err
+	}
+	if legacy {
+		request, err := pricing.LegacyEnvRequest(r)
+		if err != nil {
+			return request, nil, err
+		}
+		result, err := pricing.CalculateBid(request)
+		return request, result, err
+	}
+
+	order, err := pricing.DecodeDeploymentOrder(r)
+	if err != nil {
+		return pricing.Request{}, nil, err
+	}
+
+	// The provider always passes the tenant; fall back to a placeholder so manual runs still price
+	owner := os.Getenv("AKASH_OWNER")
+	if owner == "" {
+		owner = "unknown"
+	}
+	request, err := order.Request(owner)
+	if err != nil {
+		return pricing.Request{PricePrecision: order.PricePrecision}, nil, err
+	}
+
+	result, err := pricing.CalculateBid(request)
+	return request, result, err
+}
+
+// runPrice prices every group of an SDL or GroupSpec file and prints the breakdown.
+func runPrice(args []string) error {
+	fs := flag.NewFlagSet("price", flag.ExitOnError)
+	sdlPath := fs.String("sdl", "", "SDL file to price")
+	groupSpecPath := fs.String("groupspec", "", "GroupSpec JSON file (object or array) to price")
+	aktPrice := fs.Float64("akt-price", 0, "AKT price in USD to use instead of the oracle")
+	owner := fs.String("owner", os.Getenv("AKASH_OWNER"), "deployment owner address")
+	provider := fs.String("provider", "", "provider address whose configuration namespace to price with (default PROVIDER_ADDRESS)")
+	precision := fs.Int("precision", 6, "decimal places of the bid")
+	explain := fs.String("explain", "", "print an explanation of each bid as text, markdown or html instead of the breakdown")
+	fs.Parse(args)
+
+	specs, err := readGroupSpecs(*sdlPath, *groupSpecPath)
+	if err != nil {
+		return err
+	}
+
+	if *owner == "" {
+		*owner = "akash1dryrun"
+	}
+
+	bid, err := pricing.PreviewGroups(context.Background(), pricing.Request{
+		Owner:          *owner,
+		Provider:       *provider,
+		PricePrecision: *precision,
+		USDPerAKT:      *aktPrice,
+	}, specs)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range bid.Groups {
+		fmt.Printf("\n=== Group %s ===\n", group.Name)
+		if group.Err != nil {
+			fmt.Printf("Rejected: %v\n", group.Err)
+			continue
+		}
+		if *explain != "" {
+			explanation, err := pricing.ExplainAs(group.Result, *explain)
+			if err != nil {
+				return err
+			}
+			fmt.Print(explanation)
+			continue
+		}
+		printBreakdown(group.Result)
+	}
+	if len(bid.Groups) > 1 {
+		fmt.Printf("\n=== Deployment ===\n")
+		fmt.Printf("Total monthly cost: %s\n", pricing.FormatMoney(bid.TotalCostUsd, pricing.DefaultPriceTargetCurrency))
+		fmt.Printf("Total rate per block: %suakt\n", pricing.FormatDec(bid.TotalRatePerBlockUakt, *precision))
+	}
+	return nil
+}
+
+// readGroupSpecs reads the groups of exactly one of an SDL or GroupSpec file.
+func readGroupSpecs(sdlPath, groupSpecPath string) ([]*dtypes.GroupSpec, error) {
+	switch {
+	case sdlPath != "" && groupSpecPath != "":
+		return nil, fmt.Errorf("--sdl and --groupspec are mutually exclusive")
+	case sdlPath != "":
+		return pricing.ReadSDLGroupSpecs(sdlPath)
+	case groupSpecPath != "":
+		return pricing.ReadGroupSpecFile(groupSpecPath)
+	}
+	return nil, fmt.Errorf("one of --sdl or --groupspec is required")
+}
+
+// runSimulate replays historical orders through the current configuration and reports how many would
+// have been won and the revenue they would have brought.
+func runSimulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	ordersPath := fs.String("orders", "", "JSONL file of historical orders")
+	historyPath := fs.String("akt-price-history", "", "CSV file of time,usd AKT prices to price each order at")
+	aktPrice := fs.Float64("akt-price", 0, "AKT price in USD for every order when there is no price history")
+	verbose := fs.Bool("verbose", false, "print the bid for every order")
+	fs.Parse(args)
+
+	if *ordersPath == "" {
+		return fmt.Errorf("--orders is required")
+	}
+	orders, err := pricing.ReadSimulatedOrders(*ordersPath)
+	if err != nil {
+		return err
+	}
+	var history pricing.AKTPriceHistory
+	if *historyPath != "" {
+		if history, err = pricing.ReadAKTPriceHistory(*historyPath); err != nil {
+			return err
+		}
+	}
+
+	restore, err := quietPricingOutput()
+	if err != nil {
+		return err
+	}
+	report, err := pricing.Simulate(orders, history, *aktPrice)
+	restore()
+	if err != nil {
+		return err
+	}
+
+	if *verbose {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ORDER\tTIME\tAKT USD\tBID\tWINNING\tOUTCOME\tUSD/MONTH")
+		for i, bid := range report.Bids {
+			orderID := bid.Order.OrderID
+			if orderID == "" {
+				orderID = fmt.Sprintf("#%d", i+1)
+			}
+			price, outcome, revenue := "-", "", "-"
+			switch {
+			case bid.Err != nil:
+				code, _ := pricing.ErrorReason(bid.Err)
+				outcome = "rejected: " + code
+			case bid.WouldWin == nil:
+				outcome = "no winning price"
+			case *bid.WouldWin:
+				outcome = "win"
+			default:
+				outcome = "lose"
+			}
+			if bid.Result != nil {
+				price = bid.Result.Price + bid.Result.Denom
+				revenue = pricing.FormatDec(bid.RevenueUsd, 2)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%g\t%s\t%s\t%s\t%s\n", orderID, bid.Order.Time.Format(time.RFC3339), bid.USDPerAKT, price, bid.Order.WinningPrice, outcome, revenue)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("Replayed %d orders: %d priced, %d rejected\n", len(report.Bids), report.Priced, len(report.Bids)-report.Priced)
+	codes := make([]string, 0, len(report.Rejected))
+	for code := range report.Rejected {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Printf("  %s: %d\n", code, report.Rejected[code])
+	}
+	if report.Compared > 0 {
+		fmt.Printf("Win threshold: %d of %d bids at or below the winning price (%.1f%%)\n", report.Wins, report.Compared, report.WinRate()*100)
+	}
+	fmt.Printf("Historically won: %d orders\n", report.HistoricalWins)
+	fmt.Printf("Monthly revenue (USD):\n")
+	fmt.Printf("  All bids:          %s\n", pricing.FormatDec(report.BidRevenueUsd, 2))
+	fmt.Printf("  Bids that win:     %s\n", pricing.FormatDec(report.WonRevenueUsd, 2))
+	fmt.Printf("  Historically won:  %s\n", pricing.FormatDec(report.HistoricalRevenue, 2))
+	return nil
+}
+
+// runExport serves the per-unit price preview on /metrics, refreshing it every interval until interrupted.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	listen := fs.String("listen", ":9102", "address to serve /metrics on")
+	interval := fs.Duration("interval", pricing.DefaultExporterInterval, "how often to recompute the prices")
+	aktPrice := fs.Float64("akt-price", 0, "AKT price in USD to use instead of the oracle")
+	fs.Parse(args)
+
+	exporter := pricing.NewPriceExporter(*interval, *aktPrice)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter)
+	mux.HandleFunc("/revenue", exporter.ServeRevenue)
+	server := &http.Server{Addr: *listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go exporter.Run(ctx)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Serving price preview on %s/metrics every %s", *listen, *interval)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// runServe runs the pricing daemon until interrupted. Pricing output is discarded unless DEBUG_BID_SCRIPT
+// is set, as for the bid script.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", ":8080", "address to serve the pricing endpoints on")
+	metrics := fs.Bool("metrics", false, "also serve the price preview exporter on /metrics and /revenue")
+	interval := fs.Duration("interval", pricing.DefaultExporterInterval, "how often --metrics recomputes the prices")
+	limits, err := pricing.ServerLimitsFromEnv()
+	if err != nil {
+		return err
+	}
+	workers := fs.Int("workers", limits.Workers, "requests priced concurrently, SERVE_WORKERS by default")
+	queueLength := fs.Int("queue", limits.QueueLength, "requests waiting for a worker before new ones get 429, SERVE_QUEUE_LENGTH by default")
+	requestTimeout := fs.Duration("request-timeout", limits.RequestTimeout, "longest a request may wait and be priced, 0 for no limit, SERVE_REQUEST_TIMEOUT by default")
+	fs.Parse(args)
+
+	pricingServer := pricing.NewPricingServer()
+	pricingServer.Pool = pricing.NewWorkerPool(*workers, *queueLength)
+	pricingServer.RequestTimeout = *requestTimeout
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *metrics {
+		exporter := pricing.NewPriceExporter(*interval, 0)
+		pricingServer.Handle("/metrics", exporter)
+		pricingServer.Handle("/revenue", http.HandlerFunc(exporter.ServeRevenue))
+		go exporter.Run(ctx)
+	}
+	listener, err := pricing.OrderEventListenerFromEnv(pricingServer.Engine())
+	if err != nil {
+		return err
+	}
+	if listener != nil {
+		go func() {
+			if err := listener.Run(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "Order event listener stopped: %v\n", err)
+			}
+		}()
+	}
+	go func() {
+		// /readyz fails until the caches are warm, so the first bids after a restart skip the cold path
+		if err := pricingServer.Engine().Start(ctx); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Warm-up: %v\n", err)
+		}
+	}()
+	server := &http.Server{Addr: *listen, Handler: pricingServer, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving pricing on %s\n", *listen)
+	restore, err := quietPricingOutput()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// runConsume prices orders delivered over NATS until interrupted.
+func runConsume() error {
+	consumer, err := pricing.NATSConsumerFromEnv()
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Pricing orders from NATS subject %s\n", consumer.Subject)
+	restore, err := quietPricingOutput()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	return consumer.Run(ctx)
+}
+
+// runSocket serves JSON-RPC pricing on a Unix domain socket until interrupted.
+func runSocket(args []string) error {
+	fs := flag.NewFlagSet("socket", flag.ExitOnError)
+	path := fs.String("path", "", "path of the Unix domain socket")
+	mode := fs.String("mode", "0660", "octal permissions of the socket file")
+	group := fs.String("group", "", "group owning the socket file (default the process group)")
+	timeout := fs.Duration("timeout", pricing.DefaultSocketTimeout, "deadline of pricing one request")
+	fs.Parse(args)
+
+	if *path == "" {
+		return fmt.Errorf("--path is required")
+	}
+	perm, err := strconv.ParseUint(*mode, 8, 32)
+	if err != nil || perm > 0777 {
+		return fmt.Errorf("invalid --mode %q: must be octal permissions such as 0660", *mode)
+	}
+	server := pricing.NewSocketServer(*path)
+	server.Mode, server.Group, server.Timeout = os.FileMode(perm), *group, *timeout
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Serving pricing on %s\n", *path)
+	restore, err := quietPricingOutput()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	return server.Serve(ctx)
+}
+
+// printBreakdown prints a bid result.
+func printBreakdown(result *pricing.BidResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if result.Profile != "" {
+		fmt.Fprintf(w, "Profile:\t%s\n", result.Profile)
+	}
+	if result.Region != "" {
+		fmt.Fprintf(w, "Region:\t%s\n", result.Region)
+	}
+	if resources := result.Resources; !resources.CPURequested.IsNil() {
+		fmt.Fprintf(w, "CPU:\t%s cores\n", pricing.FormatDec(resources.CPURequested, 3))
+		for _, kind := range resources.CPUKinds() {
+			fmt.Fprintf(w, "CPU (%s):\t%s cores\n", kind, pricing.FormatDec(resources.CPUKindRequested[kind], 3))
+		}
+		fmt.Fprintf(w, "Memory:\t%s\n", pricing.FormatSize(resources.MemoryRequested, resources.SizeUnit))
+		classes := make([]string, 0, len(resources.StorageRequested))
+		for class := range resources.StorageRequested {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			fmt.Fprintf(w, "Storage (%s):\t%s\n", class, pricing.FormatSize(resources.StorageRequested[class], resources.SizeUnit))
+		}
+		if resources.GPUsRequested > 0 {
+			fmt.Fprintf(w, "GPUs:\t%d\n", resources.GPUsRequested)
+		}
+		fmt.Fprintf(w, "Endpoints:\t%d shared HTTP, %d random port, %d leased IP\n",
+			resources.EndpointsRequested, resources.RandomPortsRequested, resources.IPsRequested)
+		if resources.IPv6Requested > 0 {
+			fmt.Fprintf(w, "Leased IPv6:\t%d\n", resources.IPv6Requested)
+		}
+		if !resources.EgressGBRequested.IsNil() {
+			fmt.Fprintf(w, "Egress:\t%s GB/month\n", pricing.FormatDec(resources.EgressGBRequested, 3))
+		}
+	}
+	if !result.TotalCostUsd.IsNil() {
+		fmt.Fprintf(w, "AKT price:\t%g USD (%s)\n", result.USDPerAKT, result.AKTPriceSource)
+		fmt.Fprintf(w, "Monthly cost:\t%s USD\n", pricing.FormatDec(result.TotalCostUsd, 2))
+		fmt.Fprintf(w, "Rate per block:\t%s uakt (%s USD)\n",
+			pricing.FormatDec(result.RatePerBlockUakt, result.Precision), pricing.FormatDec(result.RatePerBlockUsd, 8))
+		if schedule := result.Schedule; !schedule.BlocksPerMonth.IsNil() {
+			fmt.Fprintf(w, "Blocks per month:\t%s (%.3fs blocks over %g days)\n",
+				pricing.FormatDec(schedule.BlocksPerMonth, 1), schedule.BlockTimeSeconds, schedule.DaysPerMonth)
+		}
+	}
+	if result.CostPrice != "" && result.CostPrice != result.Price {
+		fmt.Fprintf(w, "Cost-based bid:\t%s %s\n", result.CostPrice, result.Denom)
+	}
+	fmt.Fprintf(w, "Bid:\t%s %s\n", result.Price, result.Denom)
+	for _, adjustment := range result.Adjustments {
+		fmt.Fprintf(w, "Adjustment:\t%s: %s\n", adjustment.Name, adjustment.Detail)
+	}
+	fmt.Fprintf(w, "Version:\t%s (config %s)\n", result.Version, result.ConfigHash)
+	w.Flush()
+}
+
+// runVersion prints the build version and the hash of the current configuration, to correlate bid changes
+// with binary and configuration rollouts.
+func runVersion() {
+	fmt.Printf("Version: %s\n", pricing.BuildVersion())
+	fmt.Printf("Go:      %s\n", runtime.Version())
+	fmt.Printf("Config:  %s\n", pricing.ConfigHash())
+}
+
+// runValidate runs every configuration check and fails if any of them fails.
+func runValidate() error {
+	failed := 0
+	for _, check := range pricing.Validate() {
+		if check.Err != nil {
+			failed++
+			fmt.Printf("❌ %s: %v\n", check.Name, check.Err)
+		} else {
+			fmt.Printf("✅ %s\n", check.Name)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// runCosts prints the monthly cost of each node of the cost model and the resulting per-unit cost floors
+// next to the base price targets.
+func runCosts() error {
+	config, err := pricing.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if config.CostModel == nil {
+		return fmt.Errorf("no cost_model in the PRICING_CONFIG file")
+	}
+	targets, err := pricing.LoadPriceTargets()
+	if err != nil {
+		return err
+	}
+	usdPerUnit, err := pricing.GetUSDPerUnit(targets.Currency)
+	if err != nil {
+		return fmt.Errorf("error getting %s/USD rate: %w", targets.Currency, err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tCOUNT\tUSD/MONTH EACH")
+	for _, node := range config.CostModel.Nodes {
+		count := node.Count
+		if count == 0 {
+			count = 1
+		}
+		fmt.Fprintf(w, "%s\t%d\t%.2f\n", node.Name, count, node.MonthlyUsd())
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "UNIT\tCOST USD/MONTH\tTARGET USD/MONTH\tMARGIN")
+	below := 0
+	for _, c := range config.CostModel.Floors(targets).Compare(targets, usdPerUnit) {
+		margin := fmt.Sprintf("%+.1f%%", (c.TargetUsd/c.FloorUsd-1)*100)
+		if c.BelowCost() {
+			below++
+			margin += " below cost"
+		}
+		fmt.Fprintf(w, "%s\t%.4f\t%.4f\t%s\n", c.Unit, c.FloorUsd, c.TargetUsd, margin)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if below > 0 {
+		return fmt.Errorf("%d target(s) below cost", below)
+	}
+	return nil
+}
+
+// runCache handles the cache subcommands.
+func runCache(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("cache requires a subcommand: show, clear or refresh")
+	}
+
+	switch args[0] {
+	case "show":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CACHE\tVALUE\tAGE\tPATH")
+		for _, status := range pricing.CacheStatuses() {
+			value, age := "-", "not cached"
+			if status.Exists {
+				value, age = status.Value, status.Age.Round(time.Second).String()
+				if status.Expired {
+					age += " (expired)"
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Name, value, age, status.Path)
+		}
+		return w.Flush()
+
+	case "clear":
+		removed, err := pricing.ClearCaches()
+		for _, path := range removed {
+			fmt.Printf("Removed %s\n", path)
+		}
+		if err == nil && len(removed) == 0 {
+			fmt.Println("No cache files to remove")
+		}
+		return err
+
+	case "refresh":
+		restore, err := quietPricingOutput()
+		if err != nil {
+			return err
+		}
+		refreshes := pricing.RefreshCaches()
+		restore()
+
+		failed := 0
+		for _, refresh := range refreshes {
+			if refresh.Err != nil {
+				failed++
+				fmt.Printf("❌ %s: %v\n", refresh.Name, refresh.Err)
+			} else {
+				fmt.Printf("✅ %s refreshed\n", refresh.Name)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d cache(s) failed to refresh", failed)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown cache subcommand %q", args[0])
+	}
+}
+
+// runPolicy exports the current pricing policy, writes a policy file as a configuration directory, or
+// lists the settings that differ between two policies.
+func runPolicy(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("policy requires a subcommand: export, import or diff")
+	}
+
+	switch args[0] {
+	case "export":
+		fs := flag.NewFlagSet("policy export", flag.ExitOnError)
+		out := fs.String("out", "", "file to write the policy to (default stdout)")
+		fs.Parse(args[1:])
+
+		data, err := pricing.ExportPolicy()
+		if err != nil {
+			return err
+		}
+		if *out == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		return ioutil.WriteFile(*out, data, 0644)
+
+	case "import":
+		fs := flag.NewFlagSet("policy import", flag.ExitOnError)
+		file := fs.String("file", "", "policy file to import")
+		configDir := fs.String("config-dir", "", "configuration directory to write the policy to")
+		fs.Parse(args[1:])
+
+		if *file == "" || *configDir == "" {
+			return fmt.Errorf("--file and --config-dir are required")
+		}
+		policy, err := readPolicy(*file)
+		if err != nil {
+			return err
+		}
+		if err := policy.WriteConfigDir(*configDir); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d variables", len(policy.Env))
+		if policy.Config != nil {
+			fmt.Print(" and pricing.json")
+		}
+		fmt.Printf(" to %s\n", *configDir)
+		return nil
+
+	case "diff":
+		if len(args) < 2 || len(args) > 3 {
+			return fmt.Errorf("usage: policy diff <from.yaml> [<to.yaml>]")
+		}
+		from, err := readPolicy(args[1])
+		if err != nil {
+			return err
+		}
+		// Without a second file, the policy is compared with the current configuration
+		to, err := pricing.CurrentPolicy()
+		if len(args) == 3 {
+			to, err = readPolicy(args[2])
+		}
+		if err != nil {
+			return err
+		}
+		changes, err := pricing.DiffPolicies(from, to)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			fmt.Println("Policies are identical")
+			return nil
+		}
+		for _, change := range changes {
+			fmt.Println(change)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown policy subcommand %q", args[0])
+	}
+}
+
+// tuneHelp lists the commands of the tune prompt.
+const tuneHelp = `Commands:
+  set <target> <value>   Set a target, e.g. "set cpu 1.8" or "set gpu_mappings a100=120,h100=250"
+  unset <target>         Remove a target, falling back to its default
+  targets                List the targets and their values
+  diff                   Show the settings changed since the session started
+  reset                  Undo all changes
+  save                   Write the tuned policy to the configuration directory, or to --out without one
+  quit                   Leave without saving
+`
+
+// runTune lets the operator adjust price targets at a line-based prompt, repricing the sample groups after
+// every change and comparing them with the prices the session started with. Samples are previewed, so
+// tuning never records bids. With a configuration directory, the tuned policy is saved into it, where
+// running daemons reload it; otherwise it is saved as a policy document, which "policy import" turns into
+// a configuration directory.
+func runTune(args []string) error {
+	fs := flag.NewFlagSet("tune", flag.ExitOnError)
+	sdlPath := fs.String("sdl", "", "SDL file of the sample deployment")
+	groupSpecPath := fs.String("groupspec", "", "GroupSpec JSON file (object or array) of the sample groups")
+	aktPrice := fs.Float64("akt-price", 0, "AKT price in USD, fetched once at start when unset")
+	owner := fs.String("owner", "akash1tune", "owner address the samples are priced for")
+	out := fs.String("out", "policy.yaml", "file the tuned policy is saved to without --config-dir")
+	fs.Parse(args)
+
+	specs, err := readGroupSpecs(*sdlPath, *groupSpecPath)
+	if err != nil {
+		return err
+	}
+	initial, err := pricing.CurrentPolicy()
+	if err != nil {
+		return err
+	}
+
+	stdout := os.Stdout
+	restore, err := quietPricingOutput()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	if *aktPrice <= 0 {
+		if *aktPrice, err = pricing.GetAKTPrice(); err != nil {
+			return err
+		}
+	}
+	request := pricing.Request{Owner: *owner, PricePrecision: 6, USDPerAKT: *aktPrice}
+	baseline, err := pricing.PreviewGroups(context.Background(), request, specs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Tuning %d sample group(s) at %.4f USD/AKT. Type help for commands.\n", len(specs), *aktPrice)
+	printTuneSamples(stdout, baseline, baseline)
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Fprint(stdout, "tune> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(stdout)
+			return scanner.Err()
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		reprice := false
+		switch fields[0] {
+		case "set", "unset":
+			if (fields[0] == "set" && len(fields) != 3) || (fields[0] == "unset" && len(fields) != 2) {
+				fmt.Fprintln(stdout, "usage: set <target> <value> | unset <target>")
+				continue
+			}
+			value := ""
+			if fields[0] == "set" {
+				value = fields[2]
+			}
+			name, err := pricing.TargetVariable(fields[1])
+			if err == nil {
+				err = pricing.SetPolicyVariable(name, value)
+			}
+			if err != nil {
+				fmt.Fprintf(stdout, "Error: %v\n", err)
+				continue
+			}
+			reprice = true
+		case "targets":
+			w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+			for _, target := range pricing.TargetSettings() {
+				value := target.Value
+				if value == "" {
+					value = "(default)"
+				}
+				fmt.Fprintf(w, "%s\t%s\n", target.Name, value)
+			}
+			w.Flush()
+		case "diff":
+			current, err := pricing.CurrentPolicy()
+			if err == nil {
+				var changes []pricing.PolicyChange
+				if changes, err = pricing.DiffPolicies(initial, current); len(changes) == 0 && err == nil {
+					fmt.Fprintln(stdout, "No changes")
+				}
+				for _, change := range changes {
+					fmt.Fprintln(stdout, change)
+				}
+			}
+			if err != nil {
+				fmt.Fprintf(stdout, "Error: %v\n", err)
+			}
+		case "reset":
+			current, err := pricing.CurrentPolicy()
+			if err != nil {
+				fmt.Fprintf(stdout, "Error: %v\n", err)
+				continue
+			}
+			for key := range current.Env {
+				os.Unsetenv(key)
+			}
+			for key, value := range initial.Env {
+				os.Setenv(key, value)
+			}
+			reprice = true
+		case "save":
+			if activeConfigDir != "" {
+				policy, err := pricing.CurrentPolicy()
+				if err == nil {
+					err = policy.WriteConfigDir(activeConfigDir)
+				}
+				if err != nil {
+					fmt.Fprintf(stdout, "Error: %v\n", err)
+					continue
+				}
+				fmt.Fprintf(stdout, "Saved the policy to the configuration directory %s; running daemons reload it\n", activeConfigDir)
+				continue
+			}
+			data, err := pricing.ExportPolicy()
+			if err == nil {
+				err = ioutil.WriteFile(*out, data, 0644)
+			}
+			if err != nil {
+				fmt.Fprintf(stdout, "Error: %v\n", err)
+				continue
+			}
+			fmt.Fprintf(stdout, "Saved the policy to %s; apply it with: pricing-tool policy import --file %s --config-dir <dir>\n", *out, *out)
+		case "quit", "exit":
+			return nil
+		case "help":
+			fmt.Fprint(stdout, tuneHelp)
+		default:
+			fmt.Fprintf(stdout, "Unknown command %q\n%s", fields[0], tuneHelp)
+		}
+
+		if reprice {
+			bid, err := pricing.PreviewGroups(context.Background(), request, specs)
+			if err != nil {
+				fmt.Fprintf(stdout, "Error: %v\n", err)
+				continue
+			}
+			printTuneSamples(stdout, baseline, bid)
+		}
+	}
+}
+
+// printTuneSamples prints the monthly cost and bid of each sample group next to its price at the start of
+// the session.
+func printTuneSamples(w io.Writer, baseline, bid *pricing.DeploymentBid) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tUSD/MONTH\tSTART\tCHANGE\tBID")
+	for i, group := range bid.Groups {
+		if group.Err != nil {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\trejected: %v\n", group.Name, group.Err)
+			continue
+		}
+		start, change := "-", "-"
+		if base := baseline.Groups[i]; base.Err == nil {
+			start = pricing.FormatDec(base.Result.TotalCostUsd, 2)
+			if base.Result.TotalCostUsd.IsPositive() {
+				percent := group.Result.TotalCostUsd.Sub(base.Result.TotalCostUsd).Quo(base.Result.TotalCostUsd).MulInt64(100)
+				change = fmt.Sprintf("%+.1f%%", percent.MustFloat64())
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s%s/block\n", group.Name, pricing.FormatDec(group.Result.TotalCostUsd, 2),
+			start, change, group.Result.Price, group.Result.Denom)
+	}
+	tw.Flush()
+}
+
+// readPolicy reads and validates a policy file.
+func readPolicy(path string) (*pricing.Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := pricing.ImportPolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// runFeedback handles the feedback subcommands.
+func runFeedback(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("feedback requires a subcommand: record, report or revenue")
+	}
+
+	switch args[0] {
+	case "record":
+		fs := flag.NewFlagSet("feedback record", flag.ExitOnError)
+		orderID := fs.String("order", "", "order ID (dseq/gseq/oseq) the bid was placed for")
+		won := fs.Bool("won", false, "the bid won the lease")
+		lost := fs.Bool("lost", false, "the bid did not win the lease")
+		fs.Parse(args[1:])
+
+		if *won == *lost {
+			return fmt.Errorf("exactly one of --won or --lost is required")
+		}
+		if err := pricing.RecordBidOutcome(*orderID, *won); err != nil {
+			return err
+		}
+		fmt.Printf("Recorded %s for order %s\n", outcomeName(*won), *orderID)
+		return nil
+
+	case "report":
+		fs := flag.NewFlagSet("feedback report", flag.ExitOnError)
+		bandsStr := fs.String("bands", "", "ascending monthly USD band edges (default 10,50,100,500,1000)")
+		fs.Parse(args[1:])
+
+		bands, err := pricing.ParsePriceBands(*bandsStr)
+		if err != nil {
+			return err
+		}
+		stats, err := pricing.BidWinRates(bands)
+		if err != nil {
+			return err
+		}
+		if len(stats) == 0 {
+			fmt.Println("No bids with recorded outcomes")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "GPU MODEL\tUSD/MONTH\tBIDS\tWINS\tWIN RATE")
+		for _, stat := range stats {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%.1f%%\n", stat.GPUModel, bandLabel(stat), stat.Bids, stat.Wins, stat.WinRate()*100)
+		}
+		return w.Flush()
+
+	case "revenue":
+		fs := flag.NewFlagSet("feedback revenue", flag.ExitOnError)
+		aktPrice := fs.Float64("akt-price", 0, "AKT price in USD to use instead of the oracle")
+		leases := fs.Bool("leases", false, "list every won lease instead of totals by profile and denom")
+		fs.Parse(args[1:])
+
+		usdPerAkt := *aktPrice
+		if usdPerAkt <= 0 {
+			var err error
+			if usdPerAkt, err = pricing.NewPricingEngine().AKTPrice(); err != nil {
+				return fmt.Errorf("error getting AKT price: %w", err)
+			}
+		}
+		estimate, err := pricing.EstimateRevenue(usdPerAkt)
+		if err != nil {
+			return err
+		}
+		if len(estimate.Leases) == 0 {
+			fmt.Println("No won leases in the bid history")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		if *leases {
+			fmt.Fprintln(w, "ORDER\tOWNER\tPROFILE\tPRICE\tUSD/MONTH\tAT BID")
+			for _, lease := range estimate.Leases {
+				var monthly string
+				if lease.Err != nil {
+					monthly = "error: " + lease.Err.Error()
+				} else {
+					monthly = pricing.FormatDec(lease.MonthlyUsd, 2)
+				}
+				atBid := "-"
+				if !lease.BidUsd.IsNil() {
+					atBid = pricing.FormatDec(lease.BidUsd, 2)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s%s\t%s\t%s\n", lease.OrderID, lease.Owner, lease.Profile, lease.Price, lease.Denom, monthly, atBid)
+			}
+		} else {
+			fmt.Fprintln(w, "PROFILE\tDENOM\tLEASES\tUSD/MONTH\tAT BID")
+			for _, group := range estimate.Groups() {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", group.Profile, group.Denom, group.Leases, pricing.FormatDec(group.MonthlyUsd, 2), pricing.FormatDec(group.BidUsd, 2))
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\nTotal: %s USD/month at %g USD/AKT (%s USD/month when bid)\n", pricing.FormatDec(estimate.MonthlyUsd, 2), estimate.USDPerAKT, pricing.FormatDec(estimate.BidUsd, 2))
+		if estimate.Unvalued > 0 {
+			fmt.Printf("%d lease(s) could not be valued\n", estimate.Unvalued)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown feedback subcommand %q", args[0])
+	}
+}
+
+// runFixture writes one fixture per deployment group of an SDL file.
+func runFixture(args []string) error {
+	fs := flag.NewFlagSet("fixture", flag.ExitOnError)
+	sdlPath := fs.String("sdl", "", "SDL file to convert")
+	name := fs.String("name", "", "fixture name prefix (default: SDL file name)")
+	aktPrice := fs.Float64("akt-price", 1, "AKT price in USD recorded in the fixture")
+	dir := fs.String("dir", filepath.Join("testdata", "fixtures"), "directory to write fixtures to")
+	fs.Parse(args)
+
+	if *sdlPath == "" {
+		return fmt.Errorf("--sdl is required")
+	}
+	if *name == "" {
+		base := filepath.Base(*sdlPath)
+		*name = base[:len(base)-len(filepath.Ext(base))]
+	}
+
+	fixtures, err := pricing.NewFixturesFromSDL(*sdlPath, *name, *aktPrice)
+	if err != nil {
+		return err
+	}
+	for _, fixture := range fixtures {
+		data, err := json.MarshalIndent(fixture, "", "  ")
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(*dir, fixture.Name+".json")
+		if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s\n", path)
+	}
+	return nil
+}
+
+// outcomeName returns the display name of an outcome.
+func outcomeName(won bool) string {
+	if won {
+		return "win"
+	}
+	return "loss"
+}
+
+// bandLabel formats the price band of a statistic.
+func bandLabel(stat pricing.WinRateStat) string {
+	if stat.BandMax == 0 {
+		return fmt.Sprintf("%g+", stat.BandMin)
+	}
+	return fmt.Sprintf("%g-%g", stat.BandMin, stat.BandMax)
+}
@@ -0,0 +1,344 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// Config holds the optional file-based configuration, loaded from the JSON file named by PRICING_CONFIG.
+// Environment variables remain the source of the base price targets.
+type Config struct {
+	Profiles       map[string]PricingProfile     `json:"profiles,omitempty"`
+	DefaultProfile string                        `json:"default_profile,omitempty"` // Profile used when no selector matches
+	Denoms         DenomRegistry                 `json:"denoms,omitempty"`          // Additional or overridden denoms, merged over DefaultDenomRegistry
+	ChainGRPC      string                        `json:"chain_grpc,omitempty"`      // Node gRPC endpoint used to resolve denom metadata
+	Regions        map[string]PriceTargetsConfig `json:"regions,omitempty"`         // Target overrides per region, applied before profiles
+	StoragePools   map[string]StoragePool        `json:"storage_pools,omitempty"`   // Persistent storage capacity pools
+	Services       map[string]PriceTargetsConfig `json:"services,omitempty"`        // Target overrides for the resource units of a service
+	Coupons        map[string]Coupon             `json:"coupons,omitempty"`         // Promo codes by code
+	CostModel      *CostModel                    `json:"cost_model,omitempty"`      // Node costs the cost floors are derived from
+	Capacity       *Capacity                     `json:"capacity,omitempty"`        // What the provider can provision, unless CAPACITY_URL or CAPACITY_FILE is set
+	Providers      map[string]ProviderConfig     `json:"providers,omitempty"`       // Configuration namespaces by provider address
+}
+
+// PricingProfile is a named set of price targets and GPU table applied to requests matching its selector.
+type PricingProfile struct {
+	Match    ProfileMatch       `json:"match"`
+	Priority int                `json:"priority,omitempty"` // Higher priority profiles are evaluated first
+	Targets  PriceTargetsConfig `json:"targets"`
+	Shading  *ShadingStrategy   `json:"shading,omitempty"` // Overrides BID_SHADING_PERCENT for this profile
+}
+
+// ProfileMatch selects the requests a profile applies to. All non-empty criteria must match.
+type ProfileMatch struct {
+	Owners     []string          `json:"owners,omitempty"`     // Owner addresses the profile applies to
+	Attributes map[string]string `json:"attributes,omitempty"` // Placement attributes the GroupSpec must request
+	SignedBy   []string          `json:"signed_by,omitempty"`  // Auditors of which at least one must be required by the GroupSpec
+	GPU        *bool             `json:"gpu,omitempty"`        // Whether the GroupSpec must (true) or must not (false) request GPUs
+	Denoms     []string          `json:"denoms,omitempty"`     // Order denoms the profile applies to
+}
+
+// PriceTargetsConfig overrides individual price targets. Unset fields keep the base value.
+type PriceTargetsConfig struct {
+	CPU         *float64 `json:"cpu,omitempty"`
+	Memory      *float64 `json:"memory,omitempty"`
+	HDEphemeral *float64 `json:"hd_ephemeral,omitempty"`
+	HDPersHDD   *float64 `json:"hd_pers_hdd,omitempty"`
+	HDPersSSD   *float64 `json:"hd_pers_ssd,omitempty"`
+	HDPersNVME  *float64 `json:"hd_pers_nvme,omitempty"`
+	HDRAM       *float64 `json:"hd_ram,omitempty"`
+	Endpoint    *float64 `json:"endpoint,omitempty"`
+	RandomPort  *float64 `json:"random_port,omitempty"`
+	IP          *float64 `json:"ip,omitempty"`
+	IPv6        *float64 `json:"ipv6,omitempty"`
+	EgressGB    *float64 `json:"egress_gb,omitempty"`
+	IPDiscounts string   `json:"ip_discounts,omitempty"` // Same format as PRICE_TARGET_IP_DISCOUNTS, replaces the base curve
+	CPUAMD64    *float64 `json:"cpu_amd64,omitempty"`
+	CPUARM64    *float64 `json:"cpu_arm64,omitempty"`
+	CPUClasses  string   `json:"cpu_classes,omitempty"`  // Same format as PRICE_TARGET_CPU_CLASSES, replaces the base table
+	GPUMappings string   `json:"gpu_mappings,omitempty"` // Same format as PRICE_TARGET_GPU_MAPPINGS, replaces the base table
+	Currency    string   `json:"currency,omitempty"`     // Same as PRICE_TARGET_CURRENCY
+
+	StorageClasses    string   `json:"storage_classes,omitempty"`    // Same format as PRICE_TARGET_STORAGE_CLASSES, replaces the base table
+	StorageIOPS       string   `json:"storage_iops,omitempty"`       // Same format as PRICE_TARGET_STORAGE_IOPS, replaces the base table
+	StorageThroughput string   `json:"storage_throughput,omitempty"` // Same format as PRICE_TARGET_STORAGE_THROUGHPUT, replaces the base table
+	StorageBackup     *float64 `json:"storage_backup,omitempty"`     // Same as PRICE_TARGET_STORAGE_BACKUP
+}
+
+// LoadConfig reads the configuration file named by PRICING_CONFIG, returning an empty Config if unset.
+// The parsed file is reused until it changes on disk; the returned Config must not be modified.
+func LoadConfig() (*Config, error) {
+	path := os.Getenv("PRICING_CONFIG")
+	if path == "" {
+		return &Config{Denoms: DefaultDenomRegistry()}, nil
+	}
+	return loadCachedConfigFile(path)
+}
+
+// LoadConfigFile reads and parses a JSON configuration file.
+func LoadConfigFile(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config %s: %w", path, err)
+	}
+
+	for name, profile := range cfg.Profiles {
+		if err := profile.Targets.Validate(); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", name, err)
+		}
+		if profile.Shading != nil {
+			if err := profile.Shading.Validate(); err != nil {
+				return nil, fmt.Errorf("profile %s: %w", name, err)
+			}
+		}
+	}
+	for region, targets := range cfg.Regions {
+		if err := targets.Validate(); err != nil {
+			return nil, fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	if err := validateStoragePools(cfg.StoragePools); err != nil {
+		return nil, err
+	}
+	if err := validateServices(cfg.Services); err != nil {
+		return nil, err
+	}
+	if err := validateCoupons(cfg.Coupons); err != nil {
+		return nil, err
+	}
+	if err := validateProviders(cfg.Providers); err != nil {
+		return nil, err
+	}
+	if cfg.CostModel != nil {
+		if err := cfg.CostModel.Validate(); err != nil {
+			return nil, fmt.Errorf("cost model: %w", err)
+		}
+	}
+	if cfg.Capacity != nil {
+		if err := cfg.Capacity.Validate(); err != nil {
+			return nil, fmt.Errorf("capacity: %w", err)
+		}
+	}
+	if _, ok := cfg.Profiles[cfg.DefaultProfile]; cfg.DefaultProfile != "" && !ok {
+		return nil, fmt.Errorf("default profile %s is not defined", cfg.DefaultProfile)
+	}
+
+	cfg.Denoms = DefaultDenomRegistry().Merge(cfg.Denoms)
+	if cfg.ChainGRPC != "" {
+		if err := cfg.Denoms.ResolveExponents(cfg.ChainGRPC); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.Denoms.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// Validate checks the numeric overrides are in range and the GPU mappings and storage class and tier tables parse.
+func (c PriceTargetsConfig) Validate() error {
+	if err := c.validateTargetValues(); err != nil {
+		return err
+	}
+	if _, err := ParseGPUPriceMappings(c.GPUMappings); err != nil {
+		return err
+	}
+	if _, err := ParseCPUClassTargets(c.CPUClasses); err != nil {
+		return err
+	}
+	if _, err := ParseStorageClassTargets(c.StorageClasses); err != nil {
+		return err
+	}
+	if _, err := ParseStorageTierTargets(c.StorageIOPS); err != nil {
+		return err
+	}
+	if _, err := ParseStorageTierTargets(c.StorageThroughput); err != nil {
+		return err
+	}
+	_, err := ParseIPDiscounts(c.IPDiscounts)
+	return err
+}
+
+// ApplyTo returns base with the configured overrides applied.
+func (c PriceTargetsConfig) ApplyTo(base PriceTargets) PriceTargets {
+	override := func(target *float64, value *float64) {
+		if value != nil {
+			*target = *value
+		}
+	}
+
+	override(&base.CPUTarget, c.CPU)
+	override(&base.MemoryTarget, c.Memory)
+	override(&base.HDEphemeralTarget, c.HDEphemeral)
+	override(&base.HDPersHDDTarget, c.HDPersHDD)
+	override(&base.HDPersSSDTarget, c.HDPersSSD)
+	override(&base.HDPersNVMETarget, c.HDPersNVME)
+	override(&base.HDRAMTarget, c.HDRAM)
+	override(&base.EndpointTarget, c.Endpoint)
+	override(&base.RandomPortTarget, c.RandomPort)
+	override(&base.IPTarget, c.IP)
+	override(&base.IPv6Target, c.IPv6)
+	override(&base.EgressGBTarget, c.EgressGB)
+	override(&base.StorageBackupTarget, c.StorageBackup)
+
+	if c.GPUMappings != "" {
+		// Mappings are validated when the config is loaded
+		base.GPUMappings, _ = gpuMappingsCache.get(gpuMappingsKey(c.GPUMappings))
+	}
+	if c.CPUAMD64 != nil || c.CPUARM64 != nil {
+		// The base map may be shared with other bids, so it is copied before overriding
+		archTargets := make(map[string]float64, len(base.CPUArchTargets)+2)
+		for arch, target := range base.CPUArchTargets {
+			archTargets[arch] = target
+		}
+		if c.CPUAMD64 != nil {
+			archTargets[CPUArchAMD64] = *c.CPUAMD64
+		}
+		if c.CPUARM64 != nil {
+			archTargets[CPUArchARM64] = *c.CPUARM64
+		}
+		base.CPUArchTargets = archTargets
+	}
+	if c.CPUClasses != "" {
+		base.CPUClassTargets, _ = cpuClassTargetsCache.get(c.CPUClasses)
+	}
+	if c.StorageClasses != "" {
+		base.StorageClassTargets, _ = storageTargetsCache.get(c.StorageClasses)
+	}
+	if c.StorageIOPS != "" {
+		base.StorageIOPSTargets, _ = storageTierTargetsCache.get(c.StorageIOPS)
+	}
+	if c.StorageThroughput != "" {
+		base.StorageThroughputTargets, _ = storageTierTargetsCache.get(c.StorageThroughput)
+	}
+	if c.IPDiscounts != "" {
+		base.IPDiscounts, _ = ipDiscountsCache.get(c.IPDiscounts)
+	}
+	if c.Currency != "" {
+		base.Currency = strings.ToUpper(c.Currency)
+	}
+
+	return base
+}
+
+// SelectProfile returns the name and profile matching the request, or an empty name if none matches.
+// Profiles are evaluated by descending priority, then by number of criteria, then by name, and the
+// default profile is used when no selector matches.
+func (c *Config) SelectProfile(request Request) (string, *PricingProfile) {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		pi, pj := c.Profiles[names[i]], c.Profiles[names[j]]
+		if pi.Priority != pj.Priority {
+			return pi.Priority > pj.Priority
+		}
+		if ci, cj := pi.Match.criteria(), pj.Match.criteria(); ci != cj {
+			return ci > cj
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		profile := c.Profiles[name]
+		if profile.Match.Matches(request) {
+			return name, &profile
+		}
+	}
+
+	if profile, ok := c.Profiles[c.DefaultProfile]; ok {
+		return c.DefaultProfile, &profile
+	}
+	return "", nil
+}
+
+// criteria returns the number of criteria in the selector, used to prefer more specific profiles.
+func (m ProfileMatch) criteria() int {
+	n := len(m.Attributes)
+	for _, set := range [][]string{m.Owners, m.SignedBy, m.Denoms} {
+		if len(set) > 0 {
+			n++
+		}
+	}
+	if m.GPU != nil {
+		n++
+	}
+	return n
+}
+
+// Matches reports whether the request satisfies the selector.
+// A selector without criteria never matches, so profiles must be opted into explicitly.
+func (m ProfileMatch) Matches(request Request) bool {
+	gSpec := request.GSpec
+	if m.criteria() == 0 || gSpec == nil {
+		return false
+	}
+
+	if len(m.Owners) > 0 && !containsAny(m.Owners, []string{request.Owner}) {
+		return false
+	}
+
+	requested := make(map[string]string, len(gSpec.Requirements.Attributes))
+	for _, attr := range gSpec.Requirements.Attributes {
+		requested[attr.Key] = attr.Value
+	}
+	for key, value := range m.Attributes {
+		if requested[key] != value {
+			return false
+		}
+	}
+
+	if len(m.SignedBy) > 0 {
+		signers := append(append([]string{}, gSpec.Requirements.SignedBy.AllOf...), gSpec.Requirements.SignedBy.AnyOf...)
+		if !containsAny(signers, m.SignedBy) {
+			return false
+		}
+	}
+
+	if m.GPU != nil && requestsGPU(gSpec) != *m.GPU {
+		return false
+	}
+
+	if len(m.Denoms) > 0 {
+		if len(gSpec.Resources) == 0 || !containsAny(m.Denoms, []string{gSpec.Resources[0].Price.Denom}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// requestsGPU reports whether any resource unit of the GroupSpec requests GPU units.
+func requestsGPU(gSpec *dtypes.GroupSpec) bool {
+	for _, resourceUnit := range gSpec.Resources {
+		if gpu := resourceUnit.Resources.GPU; gpu != nil && gpu.Units.Val.IsPositive() {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny reports whether any element of want is present in have.
+func containsAny(have []string, want []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultConfigDirInterval is how often a watched configuration directory is checked for changes.
+const DefaultConfigDirInterval = 10 * time.Second
+
+// configDirKeyPattern matches the file names read as environment variables.
+var configDirKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// configDirFiles maps the file names set as paths rather than values to their environment variable.
+var configDirFiles = map[string]string{
+	"pricing.json":   "PRICING_CONFIG",
+	"whitelist":      "WHITELIST_URL",
+	"whitelist.csv":  "WHITELIST_URL",
+	"whitelist.json": "WHITELIST_URL",
+	"whitelist.txt":  "WHITELIST_URL",
+}
+
+// ConfigDir assembles the configuration from a directory of files, one per key, as Kubernetes mounts a
+// ConfigMap or Secret. A file named like an environment variable (PRICE_TARGET_CPU) sets it to its content,
+// with the non-empty lines of multi-line files such as PRICE_TARGET_GPU_MAPPINGS joined by commas. A
+// pricing.json file becomes PRICING_CONFIG and a whitelist file (whitelist, whitelist.csv, .json or .txt)
+// becomes a local WHITELIST_URL. Hidden files, such as the ..data links of Kubernetes mounts, and other
+// names are ignored.
+//
+// Directory values take precedence over the process environment, and a key whose file is removed gets its
+// original value back. Values that are secret references are resolved with ResolveSecret, so a watched
+// directory also picks up rotated secrets.
+type ConfigDir struct {
+	Path string
+
+	mu       sync.Mutex
+	applied  map[string]string  // Values set by the last load
+	original map[string]*string // Environment values before the directory first set them, nil if unset
+}
+
+// NewConfigDir returns the configuration directory at path.
+func NewConfigDir(path string) *ConfigDir {
+	return &ConfigDir{Path: path, applied: map[string]string{}, original: map[string]*string{}}
+}
+
+// Load reads the directory and applies it to the environment. It returns the keys that changed since the
+// previous load, sorted.
+func (d *ConfigDir) Load() ([]string, error) {
+	values, refs, err := d.read()
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var changed []string
+	for key, value := range values {
+		if applied, ok := d.applied[key]; ok && applied == value {
+			continue
+		}
+		if _, ok := d.original[key]; !ok {
+			if original, set := os.LookupEnv(key); set {
+				d.original[key] = &original
+			} else {
+				d.original[key] = nil
+			}
+		}
+		os.Setenv(key, value)
+		changed = append(changed, key)
+	}
+	for key := range values {
+		if ref, ok := refs[key]; ok {
+			envSecretRefs.Store(key, ref)
+		} else {
+			envSecretRefs.Delete(key)
+		}
+	}
+	for key := range d.applied {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		if original := d.original[key]; original != nil {
+			os.Setenv(key, *original)
+		} else {
+			os.Unsetenv(key)
+		}
+		delete(d.original, key)
+		envSecretRefs.Delete(key)
+		changed = append(changed, key)
+	}
+	d.applied = values
+	sort.Strings(changed)
+	if len(changed) > 0 {
+		recordConfigDirReload(len(changed))
+	}
+	return changed, nil
+}
+
+// read returns the environment values of the directory's files and the secret references of resolved values.
+func (d *ConfigDir) read() (map[string]string, map[string]string, error) {
+	dir, err := filepath.Abs(d.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading config dir: %w", err)
+	}
+
+	values, refs := make(map[string]string), make(map[string]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			continue // Directories, and links to them or to nothing
+		}
+
+		if key, ok := configDirFiles[name]; ok {
+			if _, set := values[key]; set {
+				return nil, nil, fmt.Errorf("config dir has more than one file for %s", key)
+			}
+			if key == "WHITELIST_URL" {
+				path = "file://" + path
+			}
+			values[key] = path
+			continue
+		}
+		if !configDirKeyPattern.MatchString(name) {
+			continue
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading config dir: %w", err)
+		}
+		value := configDirValue(string(data))
+		if values[name], err = ResolveSecret(value); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if IsSecretRef(value) {
+			refs[name] = value
+		}
+	}
+	return values, refs, nil
+}
+
+// configDirValue returns the value of a key file: its trimmed content, with the non-empty lines of a
+// multi-line file joined by commas.
+func configDirValue(content string) string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, ",")
+}
+
+// Watch reloads the directory every interval until ctx is done, logging the keys that changed. Values are
+// never logged, since the directory may hold secrets. A failed reload keeps the previous configuration.
+func (d *ConfigDir) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := d.Load()
+			if err != nil {
+				log.Printf("Error reloading %s, keeping the previous configuration: %v", d.Path, err)
+			} else if len(changed) > 0 {
+				log.Printf("Reloaded %s from %s", strings.Join(changed, ", "), d.Path)
+			}
+		}
+	}
+}
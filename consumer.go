@@ -0,0 +1,113 @@
+package pricing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultConsumerTimeout bounds the pricing of one queued order when NATS_TIMEOUT is unset.
+const DefaultConsumerTimeout = 10 * time.Second
+
+// OrderMessage is an order delivered over a message queue: the bid script payload of the order and the
+// owner placing it.
+type OrderMessage struct {
+	Owner   string          `json:"owner"`
+	OrderID string          `json:"order_id,omitempty"` // dseq/gseq/oseq, echoed in the result
+	Order   json.RawMessage `json:"order"`
+}
+
+// OrderResult is the message published for a priced order: the BidResponse, with the owner and order ID of
+// the order so results can be matched to orders on a shared subject.
+type OrderResult struct {
+	Owner   string `json:"owner,omitempty"`
+	OrderID string `json:"order_id,omitempty"`
+	BidResponse
+}
+
+// NATSConsumer prices orders published on a NATS subject through one shared PricingEngine, like the pricing
+// daemon does for HTTP requests. Results are sent to the message's reply subject when it has one, as for
+// NATS requests, and published on ResultSubject otherwise.
+type NATSConsumer struct {
+	URL           string
+	Subject       string        // Subject the orders are published on
+	Queue         string        // Queue group, so several consumers share the orders; empty receives every order
+	ResultSubject string        // Subject of results for orders without a reply subject
+	Credentials   string        // NATS credentials file, if the server requires one
+	Timeout       time.Duration // Deadline of pricing one order
+
+	engine *PricingEngine
+}
+
+// NATSConsumerFromEnv reads NATS_URL, NATS_SUBJECT, NATS_QUEUE, NATS_RESULT_SUBJECT, NATS_CREDS and
+// NATS_TIMEOUT. NATS_SUBJECT is required.
+func NATSConsumerFromEnv() (*NATSConsumer, error) {
+	consumer := &NATSConsumer{
+		URL:           os.Getenv("NATS_URL"),
+		Subject:       os.Getenv("NATS_SUBJECT"),
+		Queue:         os.Getenv("NATS_QUEUE"),
+		ResultSubject: os.Getenv("NATS_RESULT_SUBJECT"),
+		Credentials:   os.Getenv("NATS_CREDS"),
+		Timeout:       DefaultConsumerTimeout,
+		engine:        NewPricingEngine(),
+	}
+	if consumer.Subject == "" {
+		return nil, fmt.Errorf("NATS_SUBJECT is required")
+	}
+	if val := os.Getenv("NATS_TIMEOUT"); val != "" {
+		timeout, err := time.ParseDuration(val)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid NATS_TIMEOUT %q", val)
+		}
+		consumer.Timeout = timeout
+	}
+	return consumer, nil
+}
+
+// priceMessage prices the order in a queued message. Invalid messages are answered with an
+// invalid_request result rather than dropped, so the publisher learns why no bid was made.
+func (c *NATSConsumer) priceMessage(ctx context.Context, data []byte) OrderResult {
+	var message OrderMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		err = fmt.Errorf("error decoding order message: %w", err)
+		return OrderResult{BidResponse: NewBidResponse(Request{}, nil, withReason(ErrInvalidRequest, err))}
+	}
+	return priceOrderMessage(ctx, c.engine, message, c.Timeout)
+}
+
+// priceOrderMessage prices the order of a message with engine within timeout. Invalid orders get an
+// invalid_request result.
+func priceOrderMessage(ctx context.Context, engine *PricingEngine, message OrderMessage, timeout time.Duration) OrderResult {
+	var request Request
+	var err error
+	if message.Owner == "" {
+		err = fmt.Errorf("missing owner")
+	} else if len(message.Order) == 0 {
+		err = fmt.Errorf("missing order")
+	} else {
+		var order *DeploymentOrder
+		if order, err = DecodeDeploymentOrder(bytes.NewReader(message.Order)); err == nil {
+			if request, err = order.Request(message.Owner); err != nil {
+				request.PricePrecision = order.PricePrecision
+			}
+		}
+	}
+	result := OrderResult{Owner: message.Owner, OrderID: message.OrderID}
+	if err != nil {
+		result.BidResponse = NewBidResponse(request, nil, withReason(ErrInvalidRequest, err))
+		return result
+	}
+	if message.OrderID != "" {
+		request.OrderID = message.OrderID
+	}
+	result.OrderID = request.OrderID
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	bid, err := engine.CalculateBid(ctx, request)
+	result.BidResponse = NewBidResponse(request, bid, err)
+	return result
+}
@@ -0,0 +1,312 @@
+package pricing
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// Actions for bids priced below the cost floor of their resources.
+const (
+	CostActionWarn   = "warn"
+	CostActionReject = "reject"
+)
+
+// DefaultAmortizationMonths is the period node hardware is written off over when a node sets none.
+const DefaultAmortizationMonths = 36
+
+// CostModel describes what the provider's nodes cost to run. Per-unit cost floors are derived from it, and
+// bids priced below the floor of their resources are logged or rejected.
+type CostModel struct {
+	Action      string     `json:"action,omitempty"`      // CostActionWarn (default) or CostActionReject
+	Utilization float64    `json:"utilization,omitempty"` // Expected fraction of capacity leased (0-1], 1 when unset
+	Nodes       []NodeCost `json:"nodes"`
+}
+
+// NodeCost is the capacity and monthly cost of one kind of node.
+type NodeCost struct {
+	Name               string      `json:"name"`
+	Count              int         `json:"count,omitempty"` // Nodes of this kind, 1 when unset
+	CPUCores           float64     `json:"cpu_cores"`
+	MemoryGB           float64     `json:"memory_gb"`
+	StorageGB          float64     `json:"storage_gb,omitempty"`          // Local disk for ephemeral storage
+	GPUs               int         `json:"gpus,omitempty"`                // GPUs of GPUModel
+	GPUModel           string      `json:"gpu_model,omitempty"`           // Model as in the GPU attributes, e.g. a100
+	HardwareUsd        float64     `json:"hardware_usd,omitempty"`        // Purchase price of the node
+	AmortizationMonths float64     `json:"amortization_months,omitempty"` // DefaultAmortizationMonths when unset
+	PowerWatts         float64     `json:"power_watts,omitempty"`         // Average draw, including cooling overhead
+	PowerUsdPerKWh     float64     `json:"power_usd_per_kwh,omitempty"`   // Electricity price
+	ColoUsdMonthly     float64     `json:"colo_usd_monthly,omitempty"`    // Rack space, cooling and network fees
+	Shares             *CostShares `json:"shares,omitempty"`              // How the cost is split between resources
+}
+
+// CostShares splits the monthly cost of a node between its resources. The shares sum to 1.
+type CostShares struct {
+	CPU     float64 `json:"cpu,omitempty"`
+	Memory  float64 `json:"memory,omitempty"`
+	Storage float64 `json:"storage,omitempty"`
+	GPU     float64 `json:"gpu,omitempty"`
+}
+
+// CostFloors are the monthly USD costs of single resource units.
+type CostFloors struct {
+	CPU       float64            // One CPU core
+	MemoryGB  float64            // One GB of memory
+	StorageGB float64            // One GB of storage
+	GPUs      map[string]float64 // One GPU by model
+}
+
+// Validate checks the action, the utilization and that every node has a capacity and a cost.
+func (m *CostModel) Validate() error {
+	if m.Action != "" && m.Action != CostActionWarn && m.Action != CostActionReject {
+		return fmt.Errorf("invalid action %q: must be %s or %s", m.Action, CostActionWarn, CostActionReject)
+	}
+	if m.Utilization < 0 || m.Utilization > 1 {
+		return fmt.Errorf("utilization must be between 0 and 1")
+	}
+	if len(m.Nodes) == 0 {
+		return fmt.Errorf("at least one node is required")
+	}
+	names := make(map[string]bool, len(m.Nodes))
+	for _, node := range m.Nodes {
+		if node.Name == "" {
+			return fmt.Errorf("node name is required")
+		}
+		if names[node.Name] {
+			return fmt.Errorf("node %s is defined twice", node.Name)
+		}
+		names[node.Name] = true
+		if err := node.Validate(); err != nil {
+			return fmt.Errorf("node %s: %w", node.Name, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks the node's capacity, costs and shares.
+func (n NodeCost) Validate() error {
+	if n.CPUCores <= 0 || n.MemoryGB <= 0 {
+		return fmt.Errorf("cpu_cores and memory_gb must be positive")
+	}
+	if n.Count < 0 || n.StorageGB < 0 || n.GPUs < 0 {
+		return fmt.Errorf("count, storage_gb and gpus must not be negative")
+	}
+	if n.GPUs > 0 && n.GPUModel == "" {
+		return fmt.Errorf("gpu_model is required with gpus")
+	}
+	for _, value := range []float64{n.HardwareUsd, n.AmortizationMonths, n.PowerWatts, n.PowerUsdPerKWh, n.ColoUsdMonthly} {
+		if value < 0 || checkDecFloat(value) != nil {
+			return fmt.Errorf("costs must be finite and not negative")
+		}
+	}
+	if n.MonthlyUsd() <= 0 {
+		return fmt.Errorf("hardware_usd, power or colo_usd_monthly is required")
+	}
+	if s := n.Shares; s != nil {
+		if s.CPU < 0 || s.Memory < 0 || s.Storage < 0 || s.GPU < 0 {
+			return fmt.Errorf("shares must not be negative")
+		}
+		if math.Abs(s.CPU+s.Memory+s.Storage+s.GPU-1) > 0.001 {
+			return fmt.Errorf("shares must sum to 1")
+		}
+		if (s.Storage > 0 && n.StorageGB == 0) || (s.GPU > 0 && n.GPUs == 0) {
+			return fmt.Errorf("shares assign cost to storage or GPUs the node does not have")
+		}
+	}
+	return nil
+}
+
+// MonthlyUsd returns the monthly cost of the node: amortized hardware, power and colocation fees.
+func (n NodeCost) MonthlyUsd() float64 {
+	months := n.AmortizationMonths
+	if months == 0 {
+		months = DefaultAmortizationMonths
+	}
+	kWhPerMonth := n.PowerWatts / 1000 * 24 * daysPerMonth()
+	return n.HardwareUsd/months + kWhPerMonth*n.PowerUsdPerKWh + n.ColoUsdMonthly
+}
+
+// costShares returns the node's shares, or when unset splits the cost in proportion to what its capacity
+// earns at the base targets.
+func (n NodeCost) costShares(base PriceTargets) CostShares {
+	if n.Shares != nil {
+		return *n.Shares
+	}
+	earnings := CostShares{
+		CPU:     n.CPUCores * base.CPUTarget,
+		Memory:  n.MemoryGB * base.MemoryTarget,
+		Storage: n.StorageGB * base.HDEphemeralTarget,
+		GPU:     float64(n.GPUs) * gpuModelTarget(base.GPUMappings, n.GPUModel),
+	}
+	total := earnings.CPU + earnings.Memory + earnings.Storage + earnings.GPU
+	if total <= 0 {
+		return CostShares{CPU: 1}
+	}
+	return CostShares{
+		CPU:     earnings.CPU / total,
+		Memory:  earnings.Memory / total,
+		Storage: earnings.Storage / total,
+		GPU:     earnings.GPU / total,
+	}
+}
+
+// gpuModelTarget returns the lowest GPU table price of a model across its VRAM and interface variants, or
+// the maximum GPU price if the model is not in the table.
+func gpuModelTarget(gpuMappings map[string]float64, model string) float64 {
+	price, found := 0.0, false
+	for key, value := range gpuMappings {
+		if key == model || strings.HasPrefix(key, model+".") {
+			if !found || value < price {
+				price, found = value, true
+			}
+		}
+	}
+	if !found {
+		return MaxGPUPrice(gpuMappings)
+	}
+	return price
+}
+
+// Floors derives the cost of single resource units. The cost of each node, divided by the utilization, is
+// split between its resources, and a unit's floor is the cost of that resource across all nodes over its
+// capacity, so a GPU node's costly cores are averaged with those of the CPU nodes.
+func (m *CostModel) Floors(base PriceTargets) CostFloors {
+	utilization := m.Utilization
+	if utilization == 0 {
+		utilization = 1
+	}
+
+	var cost, capacity CostFloors
+	cost.GPUs, capacity.GPUs = make(map[string]float64), make(map[string]float64)
+	for _, node := range m.Nodes {
+		count := float64(node.Count)
+		if node.Count == 0 {
+			count = 1
+		}
+		nodeCost := count * node.MonthlyUsd() / utilization
+		shares := node.costShares(base)
+		cost.CPU += nodeCost * shares.CPU
+		cost.MemoryGB += nodeCost * shares.Memory
+		cost.StorageGB += nodeCost * shares.Storage
+		capacity.CPU += count * node.CPUCores
+		capacity.MemoryGB += count * node.MemoryGB
+		capacity.StorageGB += count * node.StorageGB
+		if node.GPUs > 0 {
+			cost.GPUs[node.GPUModel] += nodeCost * shares.GPU
+			capacity.GPUs[node.GPUModel] += count * float64(node.GPUs)
+		}
+	}
+
+	floors := CostFloors{
+		CPU:      cost.CPU / capacity.CPU,
+		MemoryGB: cost.MemoryGB / capacity.MemoryGB,
+		GPUs:     make(map[string]float64, len(cost.GPUs)),
+	}
+	if capacity.StorageGB > 0 {
+		floors.StorageGB = cost.StorageGB / capacity.StorageGB
+	}
+	for model, modelCost := range cost.GPUs {
+		floors.GPUs[model] = modelCost / capacity.GPUs[model]
+	}
+	return floors
+}
+
+// GPUFloor returns the highest floor of the requested GPU models. Models without a node, including "any",
+// get the highest floor of all models, since the GPU they are placed on is not known.
+func (f CostFloors) GPUFloor(models []string) float64 {
+	highest := 0.0
+	for _, floor := range f.GPUs {
+		highest = math.Max(highest, floor)
+	}
+	gpuFloor := 0.0
+	for _, model := range models {
+		floor, ok := f.GPUs[model]
+		if !ok {
+			floor = highest
+		}
+		gpuFloor = math.Max(gpuFloor, floor)
+	}
+	return gpuFloor
+}
+
+// ResourceCost returns the monthly USD cost floor of the requested resources.
+func (f CostFloors) ResourceCost(resources ResourceRequests, gpuModels []string) sdkmath.LegacyDec {
+	cost := sdkmath.LegacyZeroDec()
+	if !resources.CPURequested.IsNil() {
+		cost = cost.Add(resources.CPURequested.Mul(decFromFloat(f.CPU)))
+	}
+	if !resources.MemoryRequested.IsNil() {
+		cost = cost.Add(resources.MemoryRequested.Mul(decFromFloat(f.MemoryGB)))
+	}
+	cost = cost.Add(resources.TotalStorageGB().Mul(decFromFloat(f.StorageGB)))
+	if resources.GPUsRequested > 0 {
+		cost = cost.Add(decFromFloat(f.GPUFloor(gpuModels)).MulInt64(resources.GPUsRequested))
+	}
+	return cost
+}
+
+// CostComparison is the cost floor of a resource unit next to its target, both in monthly USD.
+type CostComparison struct {
+	Unit      string // cpu, memory, hd_ephemeral or "gpu <model>"
+	FloorUsd  float64
+	TargetUsd float64
+}
+
+// BelowCost reports whether the target is below the cost floor.
+func (c CostComparison) BelowCost() bool {
+	return c.TargetUsd < c.FloorUsd
+}
+
+// Compare lists the units with a cost floor next to their targets. usdPerUnit converts targets in another
+// currency to USD.
+func (f CostFloors) Compare(targets PriceTargets, usdPerUnit float64) []CostComparison {
+	var comparisons []CostComparison
+	add := func(unit string, target, floor float64) {
+		if floor > 0 {
+			comparisons = append(comparisons, CostComparison{Unit: unit, FloorUsd: floor, TargetUsd: target * usdPerUnit})
+		}
+	}
+	add("cpu", targets.CPUTarget, f.CPU)
+	add("memory", targets.MemoryTarget, f.MemoryGB)
+	add("hd_ephemeral", targets.HDEphemeralTarget, f.StorageGB)
+
+	models := make([]string, 0, len(f.GPUs))
+	for model := range f.GPUs {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		add("gpu "+model, gpuModelTarget(targets.GPUMappings, model), f.GPUs[model])
+	}
+	return comparisons
+}
+
+// TargetProblems lists the targets below their cost floor.
+func (f CostFloors) TargetProblems(targets PriceTargets, usdPerUnit float64) []string {
+	var problems []string
+	for _, c := range f.Compare(targets, usdPerUnit) {
+		if c.BelowCost() {
+			problems = append(problems, fmt.Sprintf("%s %.2f USD is below cost %.2f USD", c.Unit, c.TargetUsd, c.FloorUsd))
+		}
+	}
+	return problems
+}
+
+// CheckBid compares the monthly USD cost of a bid with the cost floor of its resources. A bid below cost is
+// logged, or rejected with ErrBelowCost when the action is CostActionReject.
+func (m *CostModel) CheckBid(base PriceTargets, resources ResourceRequests, gpuModels []string, totalCostUsd sdkmath.LegacyDec) error {
+	floor := m.Floors(base).ResourceCost(resources, gpuModels)
+	if !totalCostUsd.LT(floor) {
+		return nil
+	}
+	err := fmt.Errorf("monthly cost %s USD is below the cost floor %s USD", FormatDec(totalCostUsd, 2), FormatDec(floor, 2))
+	if m.Action == CostActionReject {
+		return withReason(ErrBelowCost, err)
+	}
+	log.Printf("Cost floor warning: %v", err)
+	return nil
+}
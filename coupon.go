@@ -0,0 +1,148 @@
+package pricing
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// DefaultCouponAttribute is the placement attribute read for a promo code when COUPON_ATTRIBUTE is unset.
+const DefaultCouponAttribute = "promo"
+
+// Coupon is a promo code tenants can request with a placement attribute. Percent is taken off the monthly
+// cost first, then FlatUsd.
+type Coupon struct {
+	Percent float64 `json:"percent,omitempty"`  // Percentage off the monthly USD cost (0-100)
+	FlatUsd float64 `json:"flat_usd,omitempty"` // USD off the monthly cost
+	Expiry  string  `json:"expiry,omitempty"`   // Date (2006-01-02) or RFC3339 timestamp, empty never expires
+	MaxUses int     `json:"max_uses,omitempty"` // Leases won with the code, 0 means unlimited
+}
+
+// Validate checks the coupon has a discount in range and a valid expiry.
+func (c Coupon) Validate() error {
+	if c.Percent < 0 || c.Percent > 100 {
+		return fmt.Errorf("percent must be between 0 and 100")
+	}
+	if c.FlatUsd < 0 || checkDecFloat(c.FlatUsd) != nil {
+		return fmt.Errorf("invalid flat_usd %g", c.FlatUsd)
+	}
+	if c.Percent == 0 && c.FlatUsd == 0 {
+		return fmt.Errorf("percent or flat_usd is required")
+	}
+	if c.MaxUses < 0 {
+		return fmt.Errorf("max_uses must not be negative")
+	}
+	if _, err := parseWhitelistExpiry(c.Expiry); err != nil {
+		return fmt.Errorf("invalid expiry %q: %v", c.Expiry, err)
+	}
+	return nil
+}
+
+// validateCoupons checks every coupon. Codes are matched case-insensitively, so two codes differing only
+// in case are rejected.
+func validateCoupons(coupons map[string]Coupon) error {
+	codes := make(map[string]string, len(coupons))
+	for code, coupon := range coupons {
+		if strings.TrimSpace(code) == "" {
+			return fmt.Errorf("coupon code must not be empty")
+		}
+		if other, ok := codes[strings.ToLower(code)]; ok {
+			return fmt.Errorf("coupons %s and %s differ only in case", other, code)
+		}
+		codes[strings.ToLower(code)] = code
+		if err := coupon.Validate(); err != nil {
+			return fmt.Errorf("coupon %s: %w", code, err)
+		}
+	}
+	return nil
+}
+
+// RequestCoupon returns the promo code requested by the GroupSpec through the coupon placement attribute
+// (named by COUPON_ATTRIBUTE, default "promo"), or "" if none.
+func RequestCoupon(gSpec *dtypes.GroupSpec) string {
+	if gSpec == nil {
+		return ""
+	}
+	key := os.Getenv("COUPON_ATTRIBUTE")
+	if key == "" {
+		key = DefaultCouponAttribute
+	}
+	value, _ := attributeValue(gSpec.Requirements.Attributes, key)
+	return value
+}
+
+// LookupCoupon returns the configured code and coupon matching a requested code, ignoring case.
+func (c *Config) LookupCoupon(requested string) (string, Coupon, bool) {
+	if requested == "" {
+		return "", Coupon{}, false
+	}
+	for code, coupon := range c.Coupons {
+		if strings.EqualFold(code, requested) {
+			return code, coupon, true
+		}
+	}
+	return "", Coupon{}, false
+}
+
+// Apply takes the coupon's discounts off the monthly USD cost, never going below zero.
+func (c Coupon) Apply(code string, totalCostUsd sdkmath.LegacyDec) (sdkmath.LegacyDec, Adjustment) {
+	var discounts []string
+	if c.Percent > 0 {
+		totalCostUsd = totalCostUsd.Sub(totalCostUsd.Mul(decFromFloat(c.Percent)).QuoInt64(100))
+		discounts = append(discounts, fmt.Sprintf("%g%%", c.Percent))
+	}
+	if c.FlatUsd > 0 {
+		totalCostUsd = sdkmath.LegacyMaxDec(totalCostUsd.Sub(decFromFloat(c.FlatUsd)), sdkmath.LegacyZeroDec())
+		discounts = append(discounts, fmt.Sprintf("$%g", c.FlatUsd))
+	}
+	return totalCostUsd, Adjustment{
+		Name:   "coupon",
+		Detail: fmt.Sprintf("promo code %s, %s off", code, strings.Join(discounts, " and ")),
+	}
+}
+
+// applyCoupon applies the coupon the request asks for, if it is configured, not expired and not used up.
+// It returns the configured code of an applied coupon. Codes that cannot be applied are logged and the
+// request is priced without them, as is a capped code whose uses cannot be counted.
+func applyCoupon(config *Config, requested string, totalCostUsd sdkmath.LegacyDec, now time.Time) (sdkmath.LegacyDec, string, *Adjustment) {
+	if requested == "" {
+		return totalCostUsd, "", nil
+	}
+	code, coupon, ok := config.LookupCoupon(requested)
+	if !ok {
+		log.Printf("Ignoring unknown promo code %q", requested)
+		return totalCostUsd, "", nil
+	}
+	if expiry, _ := parseWhitelistExpiry(coupon.Expiry); !expiry.IsZero() && now.After(expiry) {
+		log.Printf("Ignoring promo code %s, expired on %s", code, expiry.Format("2006-01-02"))
+		return totalCostUsd, "", nil
+	}
+	if coupon.MaxUses > 0 {
+		uses, err := couponUses(code)
+		if err != nil {
+			log.Printf("Ignoring promo code %s, error counting its uses: %v", code, err)
+			return totalCostUsd, "", nil
+		}
+		if uses >= coupon.MaxUses {
+			log.Printf("Ignoring promo code %s, used %d of %d times", code, uses, coupon.MaxUses)
+			return totalCostUsd, "", nil
+		}
+	}
+
+	totalCostUsd, adjustment := coupon.Apply(code, totalCostUsd)
+	return totalCostUsd, code, &adjustment
+}
+
+// couponUses counts the leases won with a promo code in the bid history named by BID_HISTORY_DB.
+func couponUses(code string) (int, error) {
+	store, err := openBidHistoryFromEnv()
+	if err != nil {
+		return 0, err
+	}
+	return store.CouponUses(code)
+}
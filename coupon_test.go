@@ -0,0 +1,85 @@
+package pricing
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	attrtypes "pkg.akt.dev/go/node/types/attributes/v1"
+)
+
+// TestCouponExpiry checks a dated expiry lasts through the end of that day.
+func TestCouponExpiry(t *testing.T) {
+	config := &Config{Coupons: map[string]Coupon{"SPRING": {Percent: 10, Expiry: "2026-03-31"}}}
+	tests := []struct {
+		now     time.Time
+		applied bool
+	}{
+		{time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 3, 31, 23, 59, 59, 0, time.UTC), true},
+		{time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		total, code, adjustment := applyCoupon(config, "spring", sdkmath.LegacyNewDec(100), tt.now)
+		want := sdkmath.LegacyNewDec(100)
+		if tt.applied {
+			want = sdkmath.LegacyNewDec(90)
+		}
+		if !total.Equal(want) || (code == "SPRING") != tt.applied || (adjustment != nil) != tt.applied {
+			t.Errorf("%s: got %s with code %q and %v, want %s", tt.now, total, code, adjustment, want)
+		}
+	}
+}
+
+// TestCouponBid prices the cpu-only fixture, 6.85 USD a month, requesting promo codes from a PRICING_CONFIG.
+func TestCouponBid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{"coupons": {
+		"SPRING10": {"percent": 10, "expiry": "2999-12-31"},
+		"LAUNCH": {"percent": 20, "expiry": "2020-01-01"},
+		"FLAT": {"flat_usd": 1.37},
+		"BOTH": {"percent": 10, "flat_usd": 0.685},
+		"CAPPED": {"percent": 20, "max_uses": 5}
+	}}`
+	if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		promo     string
+		totalCost string
+		price     string
+		code      string
+		detail    string
+	}{
+		{"spring10", "6.165000", "4.097207", "SPRING10", "promo code SPRING10, 10% off"},
+		{"LAUNCH", "6.850000", "4.552452", "", ""}, // Expired
+		{"FLAT", "5.480000", "3.641962", "FLAT", "promo code FLAT, $1.37 off"},
+		{"BOTH", "5.480000", "3.641962", "BOTH", "promo code BOTH, 10% and $0.685 off"},
+		{"CAPPED", "6.850000", "4.552452", "", ""}, // Uses cannot be counted without BID_HISTORY_DB
+		{"UNKNOWN", "6.850000", "4.552452", "", ""},
+	}
+	for _, tt := range tests {
+		request := fixtureRequest(t, "cpu-only")
+		request.GSpec.Requirements.Attributes = attrtypes.Attributes{{Key: DefaultCouponAttribute, Value: tt.promo}}
+		result, err := priceWithEnv(request, map[string]string{"PRICING_CONFIG": path})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.promo, err)
+		}
+		if got := FormatDec(result.TotalCostUsd, 6); got != tt.totalCost || result.Price != tt.price {
+			t.Errorf("%s: got %s USD at %s, want %s USD at %s", tt.promo, got, result.Price, tt.totalCost, tt.price)
+		}
+		if result.Coupon != tt.code {
+			t.Errorf("%s: got coupon %q, want %q", tt.promo, result.Coupon, tt.code)
+		}
+		adjustment := findAdjustment(result, "coupon")
+		switch {
+		case tt.detail == "" && adjustment != nil:
+			t.Errorf("%s: got adjustment %q, want none", tt.promo, adjustment.Detail)
+		case tt.detail != "" && (adjustment == nil || adjustment.Detail != tt.detail):
+			t.Errorf("%s: got adjustment %v, want %q", tt.promo, adjustment, tt.detail)
+		}
+	}
+}
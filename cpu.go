@@ -0,0 +1,134 @@
+package pricing
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	attrtypes "pkg.akt.dev/go/node/types/attributes/v1"
+)
+
+// DefaultCPUClassAttribute is the attribute read for the CPU class of a resource unit when CPU_CLASS_ATTRIBUTE
+// is unset.
+const DefaultCPUClassAttribute = "cpu-class"
+
+// CPUClassDedicated is the class of CPUs requested with a dedicated=true attribute.
+const CPUClassDedicated = "dedicated"
+
+// CPU architectures with their own targets, as normalized by cpuArch.
+const (
+	CPUArchAMD64 = "amd64"
+	CPUArchARM64 = "arm64"
+)
+
+// CPUKind is the class and architecture of requested cores. Either may be empty.
+type CPUKind struct {
+	Class string
+	Arch  string
+}
+
+// String returns the class and architecture separated by a comma, e.g. "dedicated, arm64".
+func (k CPUKind) String() string {
+	if k.Class == "" || k.Arch == "" {
+		return k.Class + k.Arch
+	}
+	return k.Class + ", " + k.Arch
+}
+
+// ParseCPUClassTargets parses CPU class to price mappings of the form "dedicated=2.40,shared=1.20". Cores of
+// classes without a mapping are priced at PRICE_TARGET_CPU.
+func ParseCPUClassTargets(mappingStr string) (map[string]float64, error) {
+	return parseClassTargets(mappingStr, "CPU")
+}
+
+// loadCPUTargets reads the CPU class and architecture targets of the price targets from the environment.
+// Architectures without a target variable are priced at the generic CPU target.
+func loadCPUTargets(targets *PriceTargets) error {
+	classTargets, err := cpuClassTargetsCache.get(os.Getenv("PRICE_TARGET_CPU_CLASSES"))
+	if err != nil {
+		return err
+	}
+	targets.CPUClassTargets = classTargets
+
+	for _, arch := range []struct{ name, envVar string }{
+		{CPUArchAMD64, "PRICE_TARGET_CPU_AMD64"},
+		{CPUArchARM64, "PRICE_TARGET_CPU_ARM64"},
+	} {
+		if _, ok := os.LookupEnv(arch.envVar); !ok {
+			continue
+		}
+		if targets.CPUArchTargets == nil {
+			targets.CPUArchTargets = make(map[string]float64)
+		}
+		targets.CPUArchTargets[arch.name] = getTargetFloat(arch.envVar, targets.CPUTarget)
+	}
+	return nil
+}
+
+// CPUKindTarget returns the per-core target of a CPU kind and whether it has one of its own: the class
+// target if the class is mapped, otherwise the architecture target, otherwise the generic CPU target.
+func (t PriceTargets) CPUKindTarget(kind CPUKind) (float64, bool) {
+	if price, ok := t.CPUClassTargets[kind.Class]; ok && kind.Class != "" {
+		return price, true
+	}
+	if price, ok := t.CPUArchTargets[kind.Arch]; ok && kind.Arch != "" {
+		return price, true
+	}
+	return t.CPUTarget, false
+}
+
+// CPUKinds returns the CPU kinds requested, sorted by their String.
+func (r ResourceRequests) CPUKinds() []CPUKind {
+	kinds := make([]CPUKind, 0, len(r.CPUKindRequested))
+	for kind := range r.CPUKindRequested {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i].String() < kinds[j].String() })
+	return kinds
+}
+
+// cpuClassAttribute returns the name of the CPU class attribute.
+func cpuClassAttribute() string {
+	if key := os.Getenv("CPU_CLASS_ATTRIBUTE"); key != "" {
+		return key
+	}
+	return DefaultCPUClassAttribute
+}
+
+// cpuClass returns the CPU class attributes select: the value of the class attribute, "dedicated" for a
+// dedicated=true attribute, or "" if neither is set. Overcommitting providers use the classes to price
+// shared and dedicated CPUs differently.
+func cpuClass(attributes attrtypes.Attributes, key string) string {
+	dedicated := false
+	for _, attr := range attributes {
+		switch attr.Key {
+		case key:
+			return strings.TrimSpace(attr.Value)
+		case "dedicated":
+			dedicated = strings.EqualFold(strings.TrimSpace(attr.Value), "true")
+		}
+	}
+	if dedicated {
+		return CPUClassDedicated
+	}
+	return ""
+}
+
+// cpuArch returns the normalized value of the CPU's arch attribute, e.g. "arm64" for "aarch64", or "" if
+// it has none.
+func cpuArch(attributes attrtypes.Attributes) string {
+	for _, attr := range attributes {
+		if attr.Key != "arch" {
+			continue
+		}
+		switch arch := strings.ToLower(strings.TrimSpace(attr.Value)); arch {
+		case "x86_64", "x86-64", "x64":
+			return CPUArchAMD64
+		case "aarch64", "arm":
+			return CPUArchARM64
+		default:
+			return arch
+		}
+	}
+	return ""
+}
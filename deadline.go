@@ -0,0 +1,165 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// lookupBudgetShare is the share of BID_DEADLINE the AKT price and whitelist lookups may take before they
+// fall back to cached data, leaving the rest of the budget to price with it.
+const lookupBudgetShare = 0.75
+
+// BidDeadlineFromEnv returns BID_DEADLINE, the time budget of pricing one request such as "2s", or 0 when
+// requests are not bounded.
+func BidDeadlineFromEnv() (time.Duration, error) {
+	val := strings.TrimSpace(os.Getenv("BID_DEADLINE"))
+	if val == "" {
+		return 0, nil
+	}
+	deadline, err := time.ParseDuration(val)
+	if err != nil || deadline <= 0 {
+		return 0, fmt.Errorf("invalid BID_DEADLINE %q: must be a positive duration such as 2s", val)
+	}
+	return deadline, nil
+}
+
+// calculateBidWithin runs calculateBid within BID_DEADLINE. The AKT price and whitelist lookups fall back to
+// cached data once lookupBudgetShare of the budget is spent, and a request still being priced when the
+// budget runs out is declined with ErrDeadlineExceeded, so a slow data source never holds up the provider's
+// bid window. The abandoned pricing is cancelled: requests in flight are aborted and it stops before its
+// next network call.
+func calculateBidWithin(ctx context.Context, request Request) (*BidResult, error) {
+	budget, err := BidDeadlineFromEnv()
+	if err != nil {
+		return nil, withReason(ErrConfig, err)
+	}
+	if budget == 0 {
+		return calculateBid(ctx, request)
+	}
+
+	request.lookupDeadline = lookupDeadline(time.Now(), budget)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type outcome struct {
+		result *BidResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := calculateBid(ctx, request)
+		done <- outcome{result, err}
+	}()
+
+	timer := time.NewTimer(budget)
+	defer timer.Stop()
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case <-timer.C:
+		log.Printf("Pricing exceeded BID_DEADLINE of %s, declining", budget)
+		return nil, withReason(ErrDeadlineExceeded, fmt.Errorf("pricing did not finish within %s", budget))
+	}
+}
+
+// lookupDeadline returns when lookups started at start fall back to cached data under a budget.
+func lookupDeadline(start time.Time, budget time.Duration) time.Time {
+	return start.Add(time.Duration(float64(budget) * lookupBudgetShare))
+}
+
+// lookupWithin runs lookup until deadline or until ctx is done. It reports whether the deadline passed
+// first, in which case the lookup is left running in the background, until ctx is done, and its result
+// discarded; when ctx is done first it returns ctx.Err(). A zero deadline and a ctx that is never done wait
+// for the lookup. Lookups should be bound to ctx so they stop with it.
+func lookupWithin[T any](ctx context.Context, deadline time.Time, lookup func() (T, error)) (value T, err error, timedOut bool) {
+	if err := ctx.Err(); err != nil {
+		return value, err, false
+	}
+	if deadline.IsZero() && ctx.Done() == nil {
+		value, err = lookup()
+		return value, err, false
+	}
+
+	type outcome struct {
+		value T
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := lookup()
+		done <- outcome{value, err}
+	}()
+
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case out := <-done:
+		return out.value, out.err, false
+	case <-expired:
+		return value, nil, true
+	case <-ctx.Done():
+		return value, ctx.Err(), false
+	}
+}
+
+// aktPriceWithin is getAKTPrice bounded by the request's lookup deadline. An oracle still being queried at
+// the deadline is given up on for a stale cached price within AKT_PRICE_MAX_STALENESS, if there is one.
+func aktPriceWithin(ctx context.Context, request Request) (float64, string, error) {
+	type price struct {
+		usdPerAkt float64
+		source    string
+	}
+	p, err, timedOut := lookupWithin(ctx, request.lookupDeadline, func() (price, error) {
+		usdPerAkt, source, err := getAKTPrice(ctx)
+		return price{usdPerAkt, source}, err
+	})
+	if !timedOut {
+		return p.usdPerAkt, p.source, err
+	}
+	if stale, ok := staleAKTPrice(AKTPriceCacheFile); ok {
+		log.Printf("WARNING: AKT price oracles did not answer within BID_DEADLINE, using stale cached price %g", stale)
+		return stale, AKTPriceSourceStale, nil
+	}
+	return 0, "", withReason(ErrDeadlineExceeded, fmt.Errorf("AKT price oracles did not answer within BID_DEADLINE and no cached price is available"))
+}
+
+// whitelistEntryWithin is lookupWhitelistEntry bounded by the request's lookup deadline. A WHITELIST_URL
+// still being fetched at the deadline is given up on for its cached copy, however old.
+func whitelistEntryWithin(ctx context.Context, request Request) (*WhitelistEntry, error) {
+	if request.lookups != nil {
+		return request.lookupWhitelistEntry(ctx)
+	}
+	entry, err, timedOut := lookupWithin(ctx, request.lookupDeadline, func() (*WhitelistEntry, error) {
+		return request.lookupWhitelistEntry(ctx)
+	})
+	if !timedOut {
+		return entry, err
+	}
+	if entry, err, ok := cachedWhitelistEntry(request.Owner); ok {
+		log.Printf("WARNING: whitelist did not answer within BID_DEADLINE, using the cached copy")
+		return entry, err
+	}
+	return nil, withReason(ErrDeadlineExceeded, fmt.Errorf("whitelist did not answer within BID_DEADLINE and no cached copy is available"))
+}
+
+// cachedWhitelistEntry looks the owner up in the cached copy of WHITELIST_URL regardless of its age. It
+// returns false when the whitelist is not a cached URL or no copy has been fetched yet.
+func cachedWhitelistEntry(owner string) (*WhitelistEntry, error, bool) {
+	whitelistURL := strings.Trim(os.Getenv("WHITELIST_URL"), "\"")
+	if _, local := localWhitelistPath(whitelistURL); whitelistURL == "" || local || os.Getenv("WHITELIST_CHAIN_GRPC") != "" {
+		return nil, nil, false
+	}
+	whitelistFile := whitelistCacheFile(whitelistURL)
+	if _, err := os.Stat(whitelistFile); err != nil {
+		return nil, nil, false
+	}
+	entry, err := verifyInWhitelist(whitelistFile, owner, NewWhitelistResolverFromEnv())
+	return entry, err, true
+}
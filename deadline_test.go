@@ -0,0 +1,36 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestCalculateBidWithinCancelsLookups checks a whitelist fetch still running when BID_DEADLINE runs out is
+// aborted with the bid rather than left running.
+func TestCalculateBidWithinCancelsLookups(t *testing.T) {
+	cancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	defer server.Close()
+	whitelistURL := server.URL + "/whitelist.txt"
+	defer os.Remove(whitelistCacheFile(whitelistURL))
+
+	restore := isolateEnv(map[string]string{"BID_DEADLINE": "200ms", "WHITELIST_URL": whitelistURL})
+	defer restore()
+	if _, err := calculateBidWithin(context.Background(), fixtureRequest(t, "cpu-only")); !errors.Is(err, ErrDeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, ErrDeadlineExceeded)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("whitelist fetch kept running after the bid was declined")
+	}
+}
@@ -0,0 +1,67 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// decFromFloat converts a configured float to a decimal using its shortest decimal representation,
+// so a target such as 1.60 is exactly 1.6 rather than carrying binary floating point error.
+// Digits beyond the 18 decimal places supported by LegacyDec are truncated.
+func decFromFloat(f float64) sdkmath.LegacyDec {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if i := strings.IndexByte(s, '.'); i >= 0 && len(s)-i-1 > sdkmath.LegacyPrecision {
+		s = s[:i+1+sdkmath.LegacyPrecision]
+	}
+	return sdkmath.LegacyMustNewDecFromStr(s)
+}
+
+// maxDecFloat bounds the floats accepted from configuration and input; larger values would overflow
+// decimal arithmetic once multiplied by resource quantities and block counts.
+const maxDecFloat = 1e30
+
+// checkDecFloat returns an error if f cannot be safely converted with decFromFloat.
+func checkDecFloat(f float64) error {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("%v is not a finite number", f)
+	}
+	if math.Abs(f) > maxDecFloat {
+		return fmt.Errorf("%g is out of range", f)
+	}
+	return nil
+}
+
+// pow10Dec returns 10^exp as a decimal.
+func pow10Dec(exp int) sdkmath.LegacyDec {
+	return sdkmath.LegacyNewDec(10).Power(uint64(exp))
+}
+
+// FormatDec rounds d to precision decimal places and formats it without exponent notation.
+// This is the only place a decimal amount is turned into a bid string.
+func FormatDec(d sdkmath.LegacyDec, precision int) string {
+	if precision < 0 {
+		precision = 0
+	}
+	if precision > sdkmath.LegacyPrecision {
+		precision = sdkmath.LegacyPrecision
+	}
+
+	digits := d.Mul(pow10Dec(precision)).RoundInt().String()
+	negative := strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(digits, "-")
+
+	if precision > 0 {
+		if len(digits) <= precision {
+			digits = strings.Repeat("0", precision-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-precision] + "." + digits[len(digits)-precision:]
+	}
+	if negative {
+		digits = "-" + digits
+	}
+	return digits
+}
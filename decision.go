@@ -0,0 +1,40 @@
+package pricing
+
+import (
+	"fmt"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// Decisions of a priced request, reported in BidResponse.
+const (
+	DecisionBid     = "bid"     // The provider bids Price
+	DecisionDecline = "decline" // The provider chose not to bid, see the reason
+	DecisionQuote   = "quote"   // Price quoted for a request without a max price, see Request.QuoteOnly
+	DecisionRetry   = "retry"   // The pricing daemon is saturated, send the request again later
+)
+
+// DeclineError reports an order whose max price is below the rate the provider requires. It wraps
+// ErrRateTooLow, so the provider declines to bid rather than treating it as a pricing failure.
+type DeclineError struct {
+	Denom     string
+	Required  sdkmath.LegacyDec // Lowest rate per block in Denom the provider bids
+	MaxPrice  sdkmath.LegacyDec // Order's max price per block in Denom
+	Precision int               // Decimal places the rates are reported with
+}
+
+func (e *DeclineError) Error() string {
+	return fmt.Sprintf("%v. min expected %s%s", ErrRateTooLow, FormatDec(e.Required, e.Precision), e.Denom)
+}
+
+func (e *DeclineError) Unwrap() error { return ErrRateTooLow }
+
+// RequiredPrice returns the required rate per block, formatted at Precision.
+func (e *DeclineError) RequiredPrice() string {
+	return FormatDec(e.Required, e.Precision)
+}
+
+// PriceGap returns how far the order's max price is below the required rate, formatted at Precision.
+func (e *DeclineError) PriceGap() string {
+	return FormatDec(e.Required.Sub(e.MaxPrice), e.Precision)
+}
@@ -0,0 +1,245 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// AKTCoinGeckoID is the CoinGecko ID of AKT. Denoms priced with it use the AKT oracle (GetAKTPrice).
+const AKTCoinGeckoID = "akash-network"
+
+// DenomInfo describes how to convert a USD rate into amounts of a denom.
+type DenomInfo struct {
+	Display      string `json:"display,omitempty"`       // Human-readable name, e.g. USDC
+	Exponent     *int   `json:"exponent,omitempty"`      // Decimal places between the base denom and Display, resolved from chain metadata when unset
+	USDPegged    bool   `json:"usd_pegged,omitempty"`    // One Display unit is worth one USD
+	CoinGeckoID  string `json:"coingecko_id,omitempty"`  // Price oracle ID for denoms that are not USD-pegged
+	MinPrecision *int   `json:"min_precision,omitempty"` // Fewest decimal places of a bid, raising lower order precisions
+	MaxPrecision *int   `json:"max_precision,omitempty"` // Most decimal places of a bid, capping higher order precisions
+	Rounding     string `json:"rounding,omitempty"`      // How bids are rounded to their precision: nearest (default), up or down
+}
+
+// Rounding modes of bids, see DenomInfo.Rounding.
+const (
+	RoundingNearest = "nearest" // Half to even, as FormatDec
+	RoundingUp      = "up"      // Never below the cost-based rate, declining orders whose max price is in between
+	RoundingDown    = "down"    // Never above the cost-based rate
+)
+
+// DenomRegistry maps on-chain denoms to their conversion settings.
+type DenomRegistry map[string]DenomInfo
+
+// DefaultDenomRegistry returns the denoms supported without configuration: uakt and the IBC USDC denoms.
+// Their bids are rounded to the nearest value at the order's precision, at most the 18 decimal places an
+// on-chain DecCoin carries.
+func DefaultDenomRegistry() DenomRegistry {
+	six, maxPrecision := 6, sdkmath.LegacyPrecision
+	return DenomRegistry{
+		"uakt": {Display: "AKT", Exponent: &six, CoinGeckoID: AKTCoinGeckoID, MaxPrecision: &maxPrecision, Rounding: RoundingNearest},
+		"ibc/12C6A0C374171B595A0A9E18B83FA09D295FB1F2D8C6DAA3AC28683471752D84": {Display: "USDC", Exponent: &six, USDPegged: true, MaxPrecision: &maxPrecision, Rounding: RoundingNearest},
+		"ibc/170C677610AC31DF0904FFE09CD3B5C657492170E7E52372E48756B71E56F2F1": {Display: "USDC", Exponent: &six, USDPegged: true, MaxPrecision: &maxPrecision, Rounding: RoundingNearest},
+	}
+}
+
+// Merge returns a registry with the entries of other added to, or replacing, those of r.
+func (r DenomRegistry) Merge(other DenomRegistry) DenomRegistry {
+	merged := make(DenomRegistry, len(r)+len(other))
+	for denom, info := range r {
+		merged[denom] = info
+	}
+	for denom, info := range other {
+		merged[denom] = info
+	}
+	return merged
+}
+
+// Validate checks every entry has an exponent and a price source.
+func (r DenomRegistry) Validate() error {
+	for denom, info := range r {
+		if info.Exponent == nil || *info.Exponent < 0 {
+			return fmt.Errorf("denom %s: exponent is missing or negative", denom)
+		}
+		if !info.USDPegged && info.CoinGeckoID == "" {
+			return fmt.Errorf("denom %s: must be usd_pegged or set coingecko_id", denom)
+		}
+		for name, precision := range map[string]*int{"min_precision": info.MinPrecision, "max_precision": info.MaxPrecision} {
+			if precision != nil && (*precision < 0 || *precision > sdkmath.LegacyPrecision) {
+				return fmt.Errorf("denom %s: %s must be between 0 and %d", denom, name, sdkmath.LegacyPrecision)
+			}
+		}
+		if info.MinPrecision != nil && info.MaxPrecision != nil && *info.MinPrecision > *info.MaxPrecision {
+			return fmt.Errorf("denom %s: min_precision %d exceeds max_precision %d", denom, *info.MinPrecision, *info.MaxPrecision)
+		}
+		switch info.Rounding {
+		case "", RoundingNearest, RoundingUp, RoundingDown:
+		default:
+			return fmt.Errorf("denom %s: rounding must be nearest, up or down, not %q", denom, info.Rounding)
+		}
+	}
+	return nil
+}
+
+// Precision returns the decimal places of a bid in denom for an order asking for precision, raised to the
+// denom's min_precision and capped at its max_precision.
+func (r DenomRegistry) Precision(denom string, precision int) int {
+	info := r[denom]
+	if info.MinPrecision != nil && precision < *info.MinPrecision {
+		precision = *info.MinPrecision
+	}
+	if info.MaxPrecision != nil && precision > *info.MaxPrecision {
+		precision = *info.MaxPrecision
+	}
+	return precision
+}
+
+// FormatRate formats a rate per block in denom at precision with the denom's rounding. The chain refuses
+// bids above the order's max price amount, so a rate that only rounds past it, up or to the nearest value,
+// is reported as a *DeclineError rather than bid below the cost-based rate.
+func (r DenomRegistry) FormatRate(denom string, rate sdkmath.LegacyDec, precision int, amount sdkmath.LegacyDec) (string, error) {
+	if precision < 0 {
+		precision = 0
+	}
+	if precision > sdkmath.LegacyPrecision {
+		precision = sdkmath.LegacyPrecision
+	}
+	scale := pow10Dec(precision)
+	var rounded sdkmath.LegacyDec
+	switch r[denom].Rounding {
+	case RoundingUp:
+		rounded = rate.Mul(scale).Ceil().Quo(scale)
+	case RoundingDown:
+		rounded = rate.Mul(scale).TruncateDec().Quo(scale)
+	default:
+		// Half to even, as FormatDec
+		rounded = sdkmath.LegacyNewDecFromInt(rate.Mul(scale).RoundInt()).Quo(scale)
+	}
+	if !amount.IsNil() && rounded.GT(amount) {
+		return "", &DeclineError{Denom: denom, Required: rounded, MaxPrice: amount, Precision: precision}
+	}
+	return FormatDec(rounded, precision), nil
+}
+
+// ResolveExponents fills in missing exponents and display names from the chain's bank denom metadata.
+func (r DenomRegistry) ResolveExponents(grpcAddress string) error {
+	var missing []string
+	for denom, info := range r {
+		if info.Exponent == nil {
+			missing = append(missing, denom)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	conn, err := grpc.NewClient(grpcAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("error connecting to %s: %w", grpcAddress, err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := banktypes.NewQueryClient(conn)
+	for _, denom := range missing {
+		resp, err := client.DenomMetadata(ctx, &banktypes.QueryDenomMetadataRequest{Denom: denom})
+		if err != nil {
+			return fmt.Errorf("error querying metadata for %s: %w", denom, err)
+		}
+
+		info := r[denom]
+		for _, unit := range resp.Metadata.DenomUnits {
+			if unit.Denom == resp.Metadata.Display {
+				exponent := int(unit.Exponent)
+				info.Exponent = &exponent
+			}
+		}
+		if info.Exponent == nil {
+			return fmt.Errorf("metadata for %s has no display unit", denom)
+		}
+		if info.Display == "" {
+			info.Display = resp.Metadata.Symbol
+		}
+		r[denom] = info
+	}
+	return nil
+}
+
+// RatePerBlock converts the per-block USD rate into base units of the denom.
+// ratePerBlockUakt is used directly for denoms priced by the AKT oracle so no second oracle lookup is needed.
+func (r DenomRegistry) RatePerBlock(denom string, ratePerBlockUakt sdkmath.LegacyDec, ratePerBlockUsd sdkmath.LegacyDec) (sdkmath.LegacyDec, error) {
+	info, ok := r[denom]
+	if !ok || info.Exponent == nil {
+		return sdkmath.LegacyDec{}, fmt.Errorf("%w: %s", ErrUnsupportedDenom, denom)
+	}
+	scale := pow10Dec(*info.Exponent)
+
+	switch {
+	case info.USDPegged:
+		return ratePerBlockUsd.Mul(scale), nil
+	case info.CoinGeckoID == AKTCoinGeckoID:
+		return ratePerBlockUakt.Mul(scale).QuoInt64(1000000), nil
+	default:
+		usdPerUnit, err := GetCoinGeckoPrice(info.CoinGeckoID)
+		if err != nil {
+			return sdkmath.LegacyDec{}, withReason(ErrOracle, fmt.Errorf("error getting %s price: %w", info.Display, err))
+		}
+		if usdPerUnit <= 0 {
+			return sdkmath.LegacyDec{}, withReason(ErrOracle, fmt.Errorf("invalid %s price: %f", info.Display, usdPerUnit))
+		}
+		return ratePerBlockUsd.Mul(scale).Quo(decFromFloat(usdPerUnit)), nil
+	}
+}
+
+// USDValue converts an amount of base units of the denom into USD, valuing AKT denoms at usdPerAkt.
+func (r DenomRegistry) USDValue(denom string, amount sdkmath.LegacyDec, usdPerAkt float64) (sdkmath.LegacyDec, error) {
+	info, ok := r[denom]
+	if !ok || info.Exponent == nil {
+		return sdkmath.LegacyDec{}, fmt.Errorf("%w: %s", ErrUnsupportedDenom, denom)
+	}
+	amount = amount.Quo(pow10Dec(*info.Exponent))
+
+	switch {
+	case info.USDPegged:
+		return amount, nil
+	case info.CoinGeckoID == AKTCoinGeckoID:
+		return amount.Mul(decFromFloat(usdPerAkt)), nil
+	default:
+		usdPerUnit, err := GetCoinGeckoPrice(info.CoinGeckoID)
+		if err != nil {
+			return sdkmath.LegacyDec{}, withReason(ErrOracle, fmt.Errorf("error getting %s price: %w", info.Display, err))
+		}
+		return amount.Mul(decFromFloat(usdPerUnit)), nil
+	}
+}
+
+// BidRate converts the per-block rates into the denom and checks the result does not exceed the order amount.
+// A rate above the amount is reported as a *DeclineError.
+func (r DenomRegistry) BidRate(denom string, ratePerBlockUakt sdkmath.LegacyDec, ratePerBlockUsd sdkmath.LegacyDec, precision int, amount sdkmath.LegacyDec) (sdkmath.LegacyDec, error) {
+	rate, err := r.RatePerBlock(denom, ratePerBlockUakt, ratePerBlockUsd)
+	if err != nil {
+		return sdkmath.LegacyDec{}, err
+	}
+
+	// Orders without a max price accept any rate
+	if !amount.IsNil() && rate.GT(amount) {
+		return sdkmath.LegacyDec{}, &DeclineError{Denom: denom, Required: rate, MaxPrice: amount, Precision: precision}
+	}
+	return rate, nil
+}
+
+// HandleDenom validates the computed rate against the order amount and formats it for the denom, following
+// its precision and rounding rules.
+func (r DenomRegistry) HandleDenom(denom string, ratePerBlockUakt sdkmath.LegacyDec, ratePerBlockUsd sdkmath.LegacyDec, precision int, amount sdkmath.LegacyDec) (string, error) {
+	rate, err := r.BidRate(denom, ratePerBlockUakt, ratePerBlockUsd, precision, amount)
+	if err != nil {
+		return "", err
+	}
+	return r.FormatRate(denom, rate, r.Precision(denom, precision), amount)
+}
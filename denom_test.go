@@ -0,0 +1,52 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+func TestFormatRateMaxPrice(t *testing.T) {
+	registry := DenomRegistry{
+		"unearest": {Rounding: RoundingNearest},
+		"udefault": {},
+		"uup":      {Rounding: RoundingUp},
+		"udown":    {Rounding: RoundingDown},
+	}
+	tests := []struct {
+		denom, rate, amount string
+		precision           int
+		want                string // Empty for a decline
+	}{
+		// The rate is within the max price, but rounds past it at the order's precision
+		{"unearest", "1.0000006", "1.0000007", 6, ""},
+		{"udefault", "1.0000006", "1.0000007", 6, ""},
+		{"uup", "1.0000001", "1.0000007", 6, ""},
+		// Rounding to a value within the max price
+		{"unearest", "1.0000004", "1.0000007", 6, "1.000000"},
+		{"unearest", "1.0000006", "1.000001", 6, "1.000001"},
+		{"unearest", "1.0000005", "1.0000007", 6, "1.000000"}, // Half to even
+		{"uup", "1.0000001", "1.000001", 6, "1.000001"},
+		{"udown", "1.0000009", "1.0000009", 6, "1.000000"},
+		// Without a max price, e.g. for previews of every denom
+		{"unearest", "1.0000006", "", 6, "1.000001"},
+	}
+	for _, tt := range tests {
+		amount := sdkmath.LegacyDec{}
+		if tt.amount != "" {
+			amount = sdkmath.LegacyMustNewDecFromStr(tt.amount)
+		}
+		got, err := registry.FormatRate(tt.denom, sdkmath.LegacyMustNewDecFromStr(tt.rate), tt.precision, amount)
+		if tt.want == "" {
+			var decline *DeclineError
+			if !errors.As(err, &decline) {
+				t.Errorf("%s %s at max %s: got %q, %v, want a decline", tt.denom, tt.rate, tt.amount, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s %s at max %s: got %q, %v, want %q", tt.denom, tt.rate, tt.amount, got, err, tt.want)
+		}
+	}
+}
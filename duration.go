@@ -0,0 +1,116 @@
+package pricing
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// DurationTier adjusts the monthly cost of leases expected to run at least MinDuration.
+// A positive Percent is a discount and a negative Percent a surcharge.
+type DurationTier struct {
+	MinDuration time.Duration
+	Percent     float64
+}
+
+// ParseDurationTiers parses tiers of the form "0s=-20,24h=0,720h=5,2160h=10", where each entry is
+// minimum duration=discount percent. Durations use Go syntax; a "d" suffix is accepted for days.
+// Tiers are returned sorted by ascending duration.
+func ParseDurationTiers(tiersStr string) ([]DurationTier, error) {
+	var tiers []DurationTier
+	if tiersStr == "" {
+		return tiers, nil
+	}
+
+	for _, pair := range strings.Split(tiersStr, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.Split(pair, "=")
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid duration tier: %s", pair)
+		}
+		minDuration, err := parseDays(strings.TrimSpace(kv[0]))
+		if err != nil || minDuration < 0 {
+			return nil, fmt.Errorf("invalid duration tier minimum: %s", kv[0])
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil || percent > 100 {
+			return nil, fmt.Errorf("invalid duration tier percent for %s: %s", kv[0], kv[1])
+		}
+		tiers = append(tiers, DurationTier{MinDuration: minDuration, Percent: percent})
+	}
+
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinDuration < tiers[j].MinDuration })
+	return tiers, nil
+}
+
+// parseDays parses a Go duration, additionally accepting whole or fractional days such as "30d".
+func parseDays(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// maxDurationSeconds is the longest duration representable as a time.Duration, in whole seconds.
+const maxDurationSeconds = int64(1<<63-1) / int64(time.Second)
+
+// ExpectedLeaseDuration returns the expected duration of the lease for a request. When the request has
+// no ExpectedDuration, it is estimated as the number of blocks the deposit pays for at the order's max
+// price. ok is false if neither is available.
+func ExpectedLeaseDuration(request Request, maxPricePerBlock sdkmath.LegacyDec, priceDenom string, blockTimeSeconds float64) (time.Duration, bool) {
+	if request.ExpectedDuration > 0 {
+		return request.ExpectedDuration, true
+	}
+
+	deposit := request.Deposit
+	if deposit == nil || deposit.Denom != priceDenom || deposit.Amount.IsNil() || !deposit.Amount.IsPositive() {
+		return 0, false
+	}
+	if maxPricePerBlock.IsNil() || !maxPricePerBlock.IsPositive() {
+		return 0, false
+	}
+
+	blocks := deposit.Amount.Quo(maxPricePerBlock)
+	seconds := blocks.Mul(decFromFloat(blockTimeSeconds)).TruncateInt()
+	// Cap absurd deposits rather than overflowing time.Duration
+	if !seconds.IsInt64() || seconds.Int64() > maxDurationSeconds {
+		return time.Duration(maxDurationSeconds) * time.Second, true
+	}
+	return time.Duration(seconds.Int64()) * time.Second, true
+}
+
+// ApplyDurationTier applies the tier with the longest MinDuration not exceeding duration. The returned
+// adjustment is nil if no tier applies or the tier's percent is zero.
+func ApplyDurationTier(tiers []DurationTier, duration time.Duration, totalCostUsd sdkmath.LegacyDec) (sdkmath.LegacyDec, *Adjustment) {
+	var tier *DurationTier
+	for i := range tiers {
+		if duration >= tiers[i].MinDuration {
+			tier = &tiers[i]
+		}
+	}
+	if tier == nil || tier.Percent == 0 {
+		return totalCostUsd, nil
+	}
+
+	adjusted := totalCostUsd.Sub(totalCostUsd.Mul(decFromFloat(tier.Percent)).QuoInt64(100))
+	kind := "discount"
+	percent := tier.Percent
+	if percent < 0 {
+		kind = "surcharge"
+		percent = -percent
+	}
+	return adjusted, &Adjustment{
+		Name:   "duration_" + kind,
+		Detail: fmt.Sprintf("%g%% %s for expected lease duration %s", percent, kind, duration.Round(time.Hour)),
+	}
+}
@@ -0,0 +1,54 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// TestDurationTierBid prices the cpu-only fixture, 6.85 USD a month at a max price of 100uakt per block,
+// under DURATION_TIERS="0s=-20,72h=10,720h=20". A 5000000uakt deposit covers 50000 blocks, about 85 hours.
+func TestDurationTierBid(t *testing.T) {
+	tests := []struct {
+		name       string
+		deposit    *sdk.DecCoin
+		expected   time.Duration
+		totalCost  string
+		price      string
+		adjustment string
+		detail     string
+	}{
+		{"no commitment", nil, 0, "6.850000", "4.552452", "", ""},
+		{"zero deposit", &sdk.DecCoin{Denom: "uakt", Amount: sdkmath.LegacyZeroDec()}, 0, "6.850000", "4.552452", "", ""},
+		{"deposit in another denom", &sdk.DecCoin{Denom: usdcDenom, Amount: sdkmath.LegacyNewDec(5000000)}, 0, "6.850000", "4.552452", "", ""},
+		{"deposit", &sdk.DecCoin{Denom: "uakt", Amount: sdkmath.LegacyNewDec(5000000)}, 0, "6.165000", "4.097207",
+			"duration_discount", "10% discount for expected lease duration 85h0m0s"},
+		{"expected duration over deposit", &sdk.DecCoin{Denom: "uakt", Amount: sdkmath.LegacyNewDec(5000000)}, 720 * time.Hour, "5.480000", "3.641962",
+			"duration_discount", "20% discount for expected lease duration 720h0m0s"},
+		{"short lease", nil, time.Hour, "8.220000", "5.462943",
+			"duration_surcharge", "20% surcharge for expected lease duration 1h0m0s"},
+	}
+	for _, tt := range tests {
+		request := fixtureRequest(t, "cpu-only")
+		request.Deposit = tt.deposit
+		request.ExpectedDuration = tt.expected
+		result, err := priceWithEnv(request, map[string]string{"DURATION_TIERS": "0s=-20,72h=10,720h=20"})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := FormatDec(result.TotalCostUsd, 6); got != tt.totalCost || result.Price != tt.price {
+			t.Errorf("%s: got %s USD at %s, want %s USD at %s", tt.name, got, result.Price, tt.totalCost, tt.price)
+		}
+		for _, name := range []string{"duration_discount", "duration_surcharge"} {
+			adjustment := findAdjustment(result, name)
+			switch {
+			case name != tt.adjustment && adjustment != nil:
+				t.Errorf("%s: got %s %q, want none", tt.name, name, adjustment.Detail)
+			case name == tt.adjustment && (adjustment == nil || adjustment.Detail != tt.detail):
+				t.Errorf("%s: got %s %v, want %q", tt.name, name, adjustment, tt.detail)
+			}
+		}
+	}
+}
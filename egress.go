@@ -0,0 +1,55 @@
+package pricing
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// DefaultEgressAttribute is the placement attribute read for a group's expected monthly egress in GB when
+// EGRESS_ATTRIBUTE is unset.
+const DefaultEgressAttribute = "egress-gb"
+
+// RequestedEgress returns the expected monthly egress of a group in GB. The egress placement attribute
+// (named by EGRESS_ATTRIBUTE, default "egress-gb") takes precedence; otherwise every exposed endpoint,
+// random port and leased IP is assumed to send EGRESS_GB_PER_ENDPOINT. The result is nil when neither
+// is set, so groups are not charged for egress by default.
+func RequestedEgress(gSpec *dtypes.GroupSpec, resourceRequests ResourceRequests) sdkmath.LegacyDec {
+	if hint, ok := egressHint(gSpec); ok {
+		return hint
+	}
+
+	perEndpoint := getTargetFloat("EGRESS_GB_PER_ENDPOINT", 0)
+	if perEndpoint <= 0 {
+		return sdkmath.LegacyDec{}
+	}
+	endpoints := resourceRequests.EndpointsRequested + resourceRequests.RandomPortsRequested + resourceRequests.IPsRequested
+	return decFromFloat(perEndpoint).MulInt64(endpoints)
+}
+
+// egressHint returns the egress placement attribute of the GroupSpec, if it is set to a valid amount.
+func egressHint(gSpec *dtypes.GroupSpec) (sdkmath.LegacyDec, bool) {
+	if gSpec == nil {
+		return sdkmath.LegacyDec{}, false
+	}
+
+	key := os.Getenv("EGRESS_ATTRIBUTE")
+	if key == "" {
+		key = DefaultEgressAttribute
+	}
+	for _, attr := range gSpec.Requirements.Attributes {
+		if attr.Key != key {
+			continue
+		}
+		gigabytes, err := sdkmath.LegacyNewDecFromStr(strings.TrimSpace(attr.Value))
+		if err != nil || gigabytes.IsNegative() {
+			log.Printf("Ignoring invalid %s attribute %q", key, attr.Value)
+			return sdkmath.LegacyDec{}, false
+		}
+		return gigabytes, true
+	}
+	return sdkmath.LegacyDec{}, false
+}
@@ -0,0 +1,152 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// DefaultEnginePriceTTL is how long a PricingEngine reuses an AKT price before reading the cache again.
+const DefaultEnginePriceTTL = time.Minute
+
+// PricingEngine prices requests from many goroutines at once. Its bids share one in-memory AKT price,
+// refreshed by a single goroutine at most once per PriceTTL, so a burst of orders reads the price cache
+// and queries the oracle once rather than once per bid. Requests that set USDPerAKT keep their own price.
+// Bids priced ahead of time with Precompute answer requests for the same order for PrecomputedTTL.
+type PricingEngine struct {
+	PriceTTL       time.Duration
+	PrecomputedTTL time.Duration
+
+	mu        sync.Mutex
+	usdPerAkt float64
+	source    string // Source of usdPerAkt when it was fetched
+	fetchedAt time.Time
+	lastErr   string // Why the last refresh failed, cleared by the next successful one
+
+	priceCounts       cacheCounter // Bids reusing the shared AKT price or refreshing it
+	precomputedCounts cacheCounter // Requests with an order ID answered by a precomputed bid or priced
+
+	bidsMu      sync.Mutex
+	precomputed map[string]precomputedBid // By owner/dseq/gseq/oseq
+
+	warmMu    sync.Mutex
+	warmState int // warmUpNotStarted, warmUpRunning or warmUpDone
+	warmUp    WarmUpReport
+}
+
+// NewPricingEngine returns an engine that refreshes the AKT price every DefaultEnginePriceTTL.
+func NewPricingEngine() *PricingEngine {
+	return &PricingEngine{PriceTTL: DefaultEnginePriceTTL, PrecomputedTTL: DefaultPrecomputedBidTTL}
+}
+
+// AKTPrice returns the AKT price shared by the engine's bids, refreshing it when older than PriceTTL.
+// Concurrent callers wait for a single refresh.
+func (e *PricingEngine) AKTPrice() (float64, error) {
+	usdPerAkt, _, err := e.aktPrice()
+	return usdPerAkt, err
+}
+
+// aktPrice is AKTPrice, also returning the source of the price. Reusing the shared copy counts as the
+// cache, except for pinned and stale prices.
+func (e *PricingEngine) aktPrice() (float64, string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	hit := e.usdPerAkt > 0 && time.Since(e.fetchedAt) < e.PriceTTL
+	e.priceCounts.count(hit)
+	if hit {
+		if e.source == AKTPriceSourcePin || e.source == AKTPriceSourceStale {
+			return e.usdPerAkt, e.source, nil
+		}
+		return e.usdPerAkt, AKTPriceSourceCache, nil
+	}
+	usdPerAkt, source, err := getAKTPrice(context.Background())
+	if err != nil {
+		log.Printf("Error getting AKT price: %v", err)
+		e.lastErr = err.Error()
+		return 0, "", withReason(ErrOracle, fmt.Errorf("error getting AKT price: %v", err))
+	}
+	e.usdPerAkt, e.source, e.fetchedAt, e.lastErr = usdPerAkt, source, time.Now(), ""
+	return usdPerAkt, source, nil
+}
+
+// CalculateBid prices a request with the engine's AKT price. It returns ctx.Err() if ctx is done first.
+// Requests with the OrderID of a precomputed bid get that bid.
+func (e *PricingEngine) CalculateBid(ctx context.Context, request Request) (*BidResult, error) {
+	result, err, ok := e.precomputedBid(request)
+	if request.OrderID != "" {
+		e.precomputedCounts.count(ok)
+	}
+	if ok {
+		return result, err
+	}
+	if err := e.withAKTPrice(&request); err != nil {
+		return nil, err
+	}
+	return calculateBidContext(ctx, request)
+}
+
+// CalculatePrice prices a request as a coin in the order's denom, like the package level CalculatePrice.
+func (e *PricingEngine) CalculatePrice(ctx context.Context, request Request) (sdk.DecCoin, error) {
+	result, err := e.CalculateBid(ctx, request)
+	if err != nil {
+		return sdk.DecCoin{}, err
+	}
+	return bidCoin(result)
+}
+
+// PriceGroups prices every group of a deployment with the engine's AKT price, like the package level
+// PriceGroups.
+func (e *PricingEngine) PriceGroups(ctx context.Context, owner string, specs []*dtypes.GroupSpec) (*DeploymentBid, error) {
+	base := Request{Owner: owner}
+	if err := e.withAKTPrice(&base); err != nil {
+		return nil, err
+	}
+	return PriceGroupsWithRequest(ctx, base, specs)
+}
+
+// withAKTPrice sets the request's AKT price to the engine's unless the request has its own.
+func (e *PricingEngine) withAKTPrice(request *Request) error {
+	if request.USDPerAKT > 0 {
+		return nil
+	}
+	usdPerAkt, source, err := e.aktPrice()
+	if err != nil {
+		return err
+	}
+	request.USDPerAKT, request.aktPriceSource = usdPerAkt, source
+	return nil
+}
+
+// calculateBidContext runs CalculateBid, returning ctx.Err() if ctx is done first. The abandoned pricing is
+// cancelled, stops at its next lookup or step and records the cancellation in the bid history.
+func calculateBidContext(ctx context.Context, request Request) (*BidResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	pricingCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		result *BidResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := priceBid(pricingCtx, request)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case out := <-done:
+		return out.result, out.err
+	}
+}
@@ -0,0 +1,116 @@
+package pricing
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestPricingEngineConcurrent prices the golden fixtures from many goroutines through one engine and checks
+// every bid matches the fixture priced on its own. Run it with -race to also check the engine for data races.
+func TestPricingEngineConcurrent(t *testing.T) {
+	fixtures, err := LoadFixtures("testdata/fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The engine shares one AKT price, so only fixtures priced at the same price without env of their own
+	const aktPrice = 3.5
+	var priced []Fixture
+	var expected []goldenResult
+	for _, fixture := range fixtures {
+		if fixture.AKTPriceUsd != aktPrice || len(fixture.Env) > 0 {
+			continue
+		}
+		priced = append(priced, fixture)
+		expected = append(expected, runFixture(fixture))
+	}
+	if len(priced) == 0 {
+		t.Fatal("no fixture is priced at the shared AKT price")
+	}
+
+	restore := isolateEnv(map[string]string{"AKT_PRICE_PIN": "3.5"})
+	defer restore()
+
+	engine := NewPricingEngine()
+	const workers, requests = 32, 20
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < requests; i++ {
+				n := (w + i) % len(priced)
+				owner := priced[n].Owner
+				if owner == "" {
+					owner = "akash1fixture"
+				}
+				result, err := engine.CalculateBid(context.Background(), Request{
+					Owner:          owner,
+					GSpec:          priced[n].GroupSpec,
+					PricePrecision: priced[n].Precision,
+				})
+				switch {
+				case err != nil:
+					if err.Error() != expected[n].Error {
+						t.Errorf("%s: error %q, want %q", priced[n].Name, err, expected[n].Error)
+					}
+				case expected[n].Error != "":
+					t.Errorf("%s: priced at %s, want error %q", priced[n].Name, result.Price, expected[n].Error)
+				case result.Price != expected[n].Price:
+					t.Errorf("%s: price %s, want %s", priced[n].Name, result.Price, expected[n].Price)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+func TestPricingEngineStart(t *testing.T) {
+	restore := isolateEnv(map[string]string{"AKT_PRICE_PIN": "3.5", "GPU_INVENTORY": "a100=lots"})
+	defer restore()
+
+	engine := NewPricingEngine()
+	if _, started := engine.WarmUp(); started {
+		t.Fatal("warm-up reported before Start")
+	}
+	err := engine.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "GPU inventory") {
+		t.Fatalf("got %v, want the invalid GPU inventory reported", err)
+	}
+
+	report, started := engine.WarmUp()
+	if !started || report.Running {
+		t.Fatalf("got started %v, running %v after Start returned", started, report.Running)
+	}
+	for _, source := range report.Sources {
+		if source.Err != nil && source.Name != "GPU inventory" {
+			t.Errorf("%s: %v", source.Name, source.Err)
+		}
+	}
+	if usdPerAkt, err := engine.AKTPrice(); err != nil || usdPerAkt != 3.5 {
+		t.Errorf("got AKT price %g, %v after warm-up, want the pinned 3.5", usdPerAkt, err)
+	}
+}
+
+func TestPricingEngineStats(t *testing.T) {
+	restore := isolateEnv(map[string]string{"AKT_PRICE_PIN": "3.5"})
+	defer restore()
+
+	engine := NewPricingEngine()
+	for i := 0; i < 3; i++ {
+		if _, err := engine.AKTPrice(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := engine.Stats()
+	if got := stats.Caches["akt_price"]; got != (CacheCounts{Hits: 2, Misses: 1}) {
+		t.Errorf("got AKT price cache counts %+v, want 2 hits and 1 miss", got)
+	}
+	if stats.AKTPrice.USDPerAKT != 3.5 || stats.AKTPrice.Source != AKTPriceSourcePin || stats.AKTPrice.RefreshedAt.IsZero() {
+		t.Errorf("got AKT price stats %+v, want the pinned 3.5", stats.AKTPrice)
+	}
+	if stats.Whitelist.Source != "none" || stats.Config.Hash != ConfigHash() {
+		t.Errorf("got whitelist %+v and config %+v, want no whitelist and the current hash", stats.Whitelist, stats.Config)
+	}
+}
@@ -0,0 +1,194 @@
+package pricing
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvProblem is an environment variable that is set but cannot be parsed.
+type EnvProblem struct {
+	Name  string
+	Value string
+	Want  string // What the value must be, e.g. "a number"
+}
+
+// Error implements error in the style of the other configuration errors.
+func (p EnvProblem) Error() string {
+	return fmt.Sprintf("invalid %s %q: must be %s", p.Name, p.Value, p.Want)
+}
+
+// lookupEnv returns a variable's trimmed value and whether it is set to anything but blanks.
+func lookupEnv(name string) (string, bool) {
+	val, ok := os.LookupEnv(name)
+	val = strings.TrimSpace(val)
+	return val, ok && val != ""
+}
+
+// LookupEnvFloat reads a finite number from name. ok is false when the variable is unset or blank, and err is
+// an EnvProblem when it is set to anything else that is not a number.
+func LookupEnvFloat(name string) (value float64, ok bool, err error) {
+	val, ok := lookupEnv(name)
+	if !ok {
+		return 0, false, nil
+	}
+	value, err = strconv.ParseFloat(val, 64)
+	if err != nil || checkDecFloat(value) != nil {
+		return 0, true, EnvProblem{Name: name, Value: val, Want: "a number"}
+	}
+	return value, true, nil
+}
+
+// LookupEnvInt reads an integer from name, like LookupEnvFloat.
+func LookupEnvInt(name string) (value int64, ok bool, err error) {
+	val, ok := lookupEnv(name)
+	if !ok {
+		return 0, false, nil
+	}
+	value, err = strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, true, EnvProblem{Name: name, Value: val, Want: "an integer"}
+	}
+	return value, true, nil
+}
+
+// LookupEnvDuration reads a Go duration such as "30s" from name, like LookupEnvFloat.
+func LookupEnvDuration(name string) (value time.Duration, ok bool, err error) {
+	val, ok := lookupEnv(name)
+	if !ok {
+		return 0, false, nil
+	}
+	value, err = time.ParseDuration(val)
+	if err != nil {
+		return 0, true, EnvProblem{Name: name, Value: val, Want: "a duration such as 30s"}
+	}
+	return value, true, nil
+}
+
+// LookupEnvBool reads a boolean such as "true", "1" or "false" from name, like LookupEnvFloat.
+func LookupEnvBool(name string) (value bool, ok bool, err error) {
+	val, ok := lookupEnv(name)
+	if !ok {
+		return false, false, nil
+	}
+	value, err = strconv.ParseBool(val)
+	if err != nil {
+		return false, true, EnvProblem{Name: name, Value: val, Want: "true or false"}
+	}
+	return value, true, nil
+}
+
+// EnvLoader reads typed settings from the environment, using the default of each unset variable and
+// collecting every malformed one, so all of them are reported at once rather than the first only.
+//
+//	var env EnvLoader
+//	workers := env.Int("SERVE_WORKERS", 64)
+//	timeout := env.Duration("SERVE_REQUEST_TIMEOUT", 30*time.Second)
+//	if err := env.Err(); err != nil {
+//		return err
+//	}
+type EnvLoader struct {
+	problems []EnvProblem
+}
+
+// Float returns name as a number, or def when it is unset or invalid.
+func (l *EnvLoader) Float(name string, def float64) float64 {
+	value, ok, err := LookupEnvFloat(name)
+	return envValue(l, value, def, ok, err)
+}
+
+// Int returns name as an integer, or def when it is unset or invalid.
+func (l *EnvLoader) Int(name string, def int64) int64 {
+	value, ok, err := LookupEnvInt(name)
+	return envValue(l, value, def, ok, err)
+}
+
+// Duration returns name as a duration, or def when it is unset or invalid.
+func (l *EnvLoader) Duration(name string, def time.Duration) time.Duration {
+	value, ok, err := LookupEnvDuration(name)
+	return envValue(l, value, def, ok, err)
+}
+
+// Bool returns name as a boolean, or def when it is unset or invalid.
+func (l *EnvLoader) Bool(name string, def bool) bool {
+	value, ok, err := LookupEnvBool(name)
+	return envValue(l, value, def, ok, err)
+}
+
+// envValue returns value if it was read, def otherwise, recording a parse error in l.
+func envValue[T any](l *EnvLoader, value, def T, ok bool, err error) T {
+	if err != nil {
+		if problem, isProblem := err.(EnvProblem); isProblem {
+			l.problems = append(l.problems, problem)
+		}
+		return def
+	}
+	if !ok {
+		return def
+	}
+	return value
+}
+
+// Problems returns the malformed variables read so far, in the order they were read.
+func (l *EnvLoader) Problems() []EnvProblem {
+	return l.problems
+}
+
+// Err returns an error naming every malformed variable read so far, or nil.
+func (l *EnvLoader) Err() error {
+	if len(l.problems) == 0 {
+		return nil
+	}
+	messages := make([]string, len(l.problems))
+	for i, problem := range l.problems {
+		messages[i] = problem.Error()
+	}
+	return fmt.Errorf("%s", strings.Join(messages, "; "))
+}
+
+// warnedEnvProblems holds the messages warnEnvProblem has logged.
+var warnedEnvProblems sync.Map
+
+// warnEnvProblem logs that a malformed variable was replaced by its default, once per message rather than
+// on every bid reading it.
+func warnEnvProblem(err error) {
+	if _, warned := warnedEnvProblems.LoadOrStore(err.Error(), true); !warned {
+		log.Printf("WARNING: %v, using the default", err)
+	}
+}
+
+// ConfigStrict reports whether CONFIG_STRICT refuses to start with invalid configuration.
+func ConfigStrict() (bool, error) {
+	strict, _, err := LookupEnvBool("CONFIG_STRICT")
+	return strict, err
+}
+
+// CheckStartupConfig runs the local checks of ValidateConfig when a command starts and logs each one that
+// fails, so malformed values no longer go unnoticed behind their defaults. With CONFIG_STRICT=true it
+// returns an error naming every failed check instead, and the command refuses to start.
+func CheckStartupConfig() error {
+	strict, err := ConfigStrict()
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for _, check := range ValidateConfig() {
+		if check.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", check.Name, check.Err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	if strict {
+		return fmt.Errorf("invalid configuration with CONFIG_STRICT set: %s", strings.Join(failed, "; "))
+	}
+	for _, problem := range failed {
+		log.Printf("WARNING: invalid configuration, %s", problem)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package pricing
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvLoader(t *testing.T) {
+	t.Setenv("TEST_ENV_FLOAT", "1,60")
+	t.Setenv("TEST_ENV_INT", " 42 ")
+	t.Setenv("TEST_ENV_DURATION", "soon")
+	t.Setenv("TEST_ENV_BOOL", "")
+
+	if _, ok, err := LookupEnvFloat("TEST_ENV_UNSET"); ok || err != nil {
+		t.Errorf("unset variable: got ok %v, err %v, want neither", ok, err)
+	}
+	if _, ok, err := LookupEnvFloat("TEST_ENV_FLOAT"); !ok || err == nil {
+		t.Errorf("malformed variable: got ok %v, err %v, want both", ok, err)
+	}
+
+	var env EnvLoader
+	if got := env.Float("TEST_ENV_FLOAT", 1.6); got != 1.6 {
+		t.Errorf("malformed float: got %v, want the default", got)
+	}
+	if got := env.Int("TEST_ENV_INT", 1); got != 42 {
+		t.Errorf("int: got %d, want 42", got)
+	}
+	if got := env.Duration("TEST_ENV_DURATION", time.Second); got != time.Second {
+		t.Errorf("malformed duration: got %v, want the default", got)
+	}
+	if got := env.Bool("TEST_ENV_BOOL", true); !got {
+		t.Errorf("blank bool: got %v, want the default", got)
+	}
+
+	// Both malformed variables are reported, not only the first
+	problems := env.Problems()
+	if len(problems) != 2 || problems[0].Name != "TEST_ENV_FLOAT" || problems[1].Name != "TEST_ENV_DURATION" {
+		t.Fatalf("got problems %v, want TEST_ENV_FLOAT and TEST_ENV_DURATION", problems)
+	}
+	if err := env.Err(); err == nil || !strings.Contains(err.Error(), `invalid TEST_ENV_FLOAT "1,60": must be a number`) {
+		t.Errorf("got %v, want an error naming TEST_ENV_FLOAT", err)
+	}
+}
+
+func TestCheckStartupConfig(t *testing.T) {
+	t.Setenv("BLOCK_TIME_SAMPLE_SIZE", "1k")
+	t.Setenv("CONFIG_STRICT", "false")
+	if err := CheckStartupConfig(); err != nil {
+		t.Errorf("without CONFIG_STRICT: got %v, want the problem only logged", err)
+	}
+
+	t.Setenv("CONFIG_STRICT", "true")
+	err := CheckStartupConfig()
+	if err == nil || !strings.Contains(err.Error(), "BLOCK_TIME_SAMPLE_SIZE") {
+		t.Errorf("with CONFIG_STRICT: got %v, want an error naming BLOCK_TIME_SAMPLE_SIZE", err)
+	}
+}
@@ -0,0 +1,125 @@
+package pricing
+
+import "errors"
+
+// Errors of rejected requests. Returned errors wrap them with details, so callers branch with errors.Is.
+var (
+	ErrInvalidRequest      = errors.New("invalid request")
+	ErrEmptyGroupSpec      = errors.New("GroupSpec has no resources")
+	ErrInvalidGroupSpec    = errors.New("invalid GroupSpec")
+	ErrUnsupportedDenom    = errors.New("denom is not supported")
+	ErrRateTooLow          = errors.New("requested rate is too low")
+	ErrNotWhitelisted      = errors.New("owner is not whitelisted")
+	ErrWhitelistLimit      = errors.New("whitelist entry does not allow the request")
+	ErrReputationRejected  = errors.New("owner reputation is in a rejected band")
+	ErrUnknownStorageClass = errors.New("storage class has no price target")
+	ErrBidCeiling          = errors.New("bid exceeds the ceiling")
+	ErrOwnerExposure       = errors.New("owner exceeds the monthly exposure cap")
+	ErrStrategyRejected    = errors.New("pricing strategy rejected the request")
+	ErrBelowCost           = errors.New("bid is below the cost floor")
+	ErrGPUUnavailable      = errors.New("cluster does not have the requested GPUs")
+	ErrUnsatisfiable       = errors.New("provider cannot provision the request")
+	ErrRequestTooLarge     = errors.New("request exceeds the resource caps")
+	ErrGPUMismatch         = errors.New("GPU units and attributes do not match")
+)
+
+// Errors of pricing failures, where no bid was made because something broke rather than by choice.
+var (
+	ErrConfig           = errors.New("invalid pricing configuration")
+	ErrOracle           = errors.New("price oracle failure")
+	ErrDataSource       = errors.New("data source failure") // Whitelist, reputation, strategy, inventory or capacity backends
+	ErrDeadlineExceeded = errors.New("pricing deadline exceeded")
+	ErrQueueFull        = errors.New("pricing queue is full") // The daemon is saturated, retry later
+)
+
+// Reason codes of pricing errors, reported in JSON responses and golden results.
+const (
+	ReasonInvalidRequest      = "invalid_request"
+	ReasonEmptyGroupSpec      = "empty_group_spec"
+	ReasonInvalidGroupSpec    = "invalid_group_spec"
+	ReasonUnsupportedDenom    = "unsupported_denom"
+	ReasonRateTooLow          = "rate_too_low"
+	ReasonNotWhitelisted      = "not_whitelisted"
+	ReasonWhitelistLimit      = "whitelist_limit"
+	ReasonReputationRejected  = "reputation_rejected"
+	ReasonUnknownStorageClass = "unknown_storage_class"
+	ReasonBidCeiling          = "bid_ceiling"
+	ReasonOwnerExposure       = "owner_exposure"
+	ReasonStrategyRejected    = "strategy_rejected"
+	ReasonBelowCost           = "below_cost"
+	ReasonGPUUnavailable      = "gpu_unavailable"
+	ReasonUnsatisfiable       = "unsatisfiable"
+	ReasonRequestTooLarge     = "request_too_large"
+	ReasonGPUMismatch         = "gpu_mismatch"
+	ReasonConfig              = "config_error"
+	ReasonOracle              = "oracle_failure"
+	ReasonDataSource          = "data_source_failure"
+	ReasonDeadlineExceeded    = "deadline_exceeded"
+	ReasonQueueFull           = "queue_full"
+	ReasonInternal            = "internal_error" // Errors not wrapping any of the above
+)
+
+// errorReasons maps each sentinel to its reason code and whether it is a deliberate rejection.
+var errorReasons = []struct {
+	err      error
+	code     string
+	rejected bool
+}{
+	{ErrInvalidRequest, ReasonInvalidRequest, true},
+	{ErrEmptyGroupSpec, ReasonEmptyGroupSpec, true},
+	{ErrInvalidGroupSpec, ReasonInvalidGroupSpec, true},
+	{ErrUnsupportedDenom, ReasonUnsupportedDenom, true},
+	{ErrRateTooLow, ReasonRateTooLow, true},
+	{ErrNotWhitelisted, ReasonNotWhitelisted, true},
+	{ErrWhitelistLimit, ReasonWhitelistLimit, true},
+	{ErrReputationRejected, ReasonReputationRejected, true},
+	{ErrUnknownStorageClass, ReasonUnknownStorageClass, true},
+	{ErrBidCeiling, ReasonBidCeiling, true},
+	{ErrOwnerExposure, ReasonOwnerExposure, true},
+	{ErrStrategyRejected, ReasonStrategyRejected, true},
+	{ErrBelowCost, ReasonBelowCost, true},
+	{ErrGPUUnavailable, ReasonGPUUnavailable, true},
+	{ErrUnsatisfiable, ReasonUnsatisfiable, true},
+	{ErrRequestTooLarge, ReasonRequestTooLarge, true},
+	{ErrGPUMismatch, ReasonGPUMismatch, true},
+	{ErrConfig, ReasonConfig, false},
+	{ErrOracle, ReasonOracle, false},
+	{ErrDeadlineExceeded, ReasonDeadlineExceeded, false},
+	{ErrQueueFull, ReasonQueueFull, false},
+	{ErrDataSource, ReasonDataSource, false},
+}
+
+// ErrorReason returns the reason code of a pricing error and whether the request was deliberately rejected
+// ("we chose not to bid") rather than pricing having failed. It returns "" and false for a nil error.
+func ErrorReason(err error) (code string, rejected bool) {
+	if err == nil {
+		return "", false
+	}
+	for _, reason := range errorReasons {
+		if errors.Is(err, reason.err) {
+			return reason.code, reason.rejected
+		}
+	}
+	return ReasonInternal, false
+}
+
+// reasonError tags an error with a sentinel without changing its message.
+type reasonError struct {
+	err    error
+	reason error
+}
+
+func (e *reasonError) Error() string   { return e.err.Error() }
+func (e *reasonError) Unwrap() []error { return []error{e.err, e.reason} }
+
+// withReason tags err with the reason sentinel unless it already wraps one, so the most specific reason
+// found deeper in the pipeline wins.
+func withReason(reason, err error) error {
+	if err == nil {
+		return nil
+	}
+	if code, _ := ErrorReason(err); code != ReasonInternal {
+		return err
+	}
+	return &reasonError{err: err, reason: reason}
+}
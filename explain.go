@@ -0,0 +1,256 @@
+package pricing
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// Formats of Explain, selected by the price command's --explain.
+const (
+	ExplainText     = "text"
+	ExplainMarkdown = "markdown"
+	ExplainHTML     = "html"
+)
+
+// explanation is a bid result laid out for rendering: one row per cost line, the adjustments and a summary
+// of how the monthly cost became the bid.
+type explanation struct {
+	rows        []explanationRow
+	subtotal    string
+	adjustments []Adjustment
+	summary     [][2]string // Label and value pairs, in order
+}
+
+// explanationRow is a cost line with its quantities formatted.
+type explanationRow struct {
+	resource string // e.g. "CPU (dedicated)" or "GiB storage (beta3)"
+	quantity string
+	target   string // Empty when the cost is not a plain product
+	cost     string
+	detail   string
+}
+
+// Explain describes how a bid was priced in plain text, e.g. "4 CPU × $1.60 = $6.40" for each resource
+// followed by the adjustments and the conversion to a rate per block, for support tickets and dashboards.
+// Amounts are in the display format of DISPLAY_LOCALE and DISPLAY_CURRENCY_SYMBOLS, see MoneyFormat.
+func Explain(result *BidResult) string {
+	text, _ := ExplainAs(result, ExplainText)
+	return text
+}
+
+// ExplainAs is Explain in a format of text, markdown or html. It is generated from the result's cost lines,
+// adjustments and rates, so it explains bids priced earlier as well as fresh ones.
+func ExplainAs(result *BidResult, format string) (string, error) {
+	if result == nil {
+		return "", fmt.Errorf("no bid to explain")
+	}
+	e := newExplanation(result)
+	switch format {
+	case ExplainText, "":
+		return e.text(), nil
+	case ExplainMarkdown:
+		return e.markdown(), nil
+	case ExplainHTML:
+		return e.html(), nil
+	}
+	return "", fmt.Errorf("unknown explanation format %q: must be %s, %s or %s", format, ExplainText, ExplainMarkdown, ExplainHTML)
+}
+
+// newExplanation lays out result.
+func newExplanation(result *BidResult) explanation {
+	currency := result.Currency
+	if currency == "" {
+		currency = DefaultPriceTargetCurrency
+	}
+	money := displayMoneyFormat()
+	e := explanation{adjustments: result.Adjustments}
+	for _, line := range result.CostLines {
+		row := explanationRow{
+			resource: costLineResource(line),
+			quantity: trimDec(line.Quantity, 3),
+			cost:     money.Format(line.Cost, currency, 2),
+			detail:   line.Detail,
+		}
+		if line.Target > 0 {
+			row.target = formatTarget(money, line.Target, currency)
+		}
+		if line.Resource == CostService {
+			row.quantity = ""
+		}
+		e.rows = append(e.rows, row)
+	}
+	if len(result.CostLines) > 0 {
+		e.subtotal = money.Format(totalCost(result.CostLines), currency, 2)
+	}
+
+	if !result.TotalCostUsd.IsNil() {
+		e.summary = append(e.summary, [2]string{"Monthly cost", money.Format(result.TotalCostUsd, DefaultPriceTargetCurrency, 2) + " per month"})
+	}
+	if result.USDPerAKT > 0 {
+		price := money.formatDigits(strconv.FormatFloat(result.USDPerAKT, 'f', -1, 64), DefaultPriceTargetCurrency)
+		if result.AKTPriceSource != "" {
+			price += " (" + result.AKTPriceSource + ")"
+		}
+		e.summary = append(e.summary, [2]string{"AKT price", price})
+	}
+	if schedule := result.Schedule; !schedule.BlocksPerMonth.IsNil() {
+		e.summary = append(e.summary, [2]string{"Blocks per month", fmt.Sprintf("%s (%.3fs blocks over %g days)",
+			FormatDec(schedule.BlocksPerMonth, 1), schedule.BlockTimeSeconds, schedule.DaysPerMonth)})
+	}
+	if !result.RatePerBlockUakt.IsNil() {
+		e.summary = append(e.summary, [2]string{"Rate per block", fmt.Sprintf("%s uakt (%s)",
+			FormatDec(result.RatePerBlockUakt, result.Precision), money.Format(result.RatePerBlockUsd, DefaultPriceTargetCurrency, 8))})
+	}
+	if result.CostPrice != "" && result.CostPrice != result.Price {
+		e.summary = append(e.summary, [2]string{"Cost-based bid", result.CostPrice + " " + result.Denom + " per block"})
+	}
+	bid := result.Price + " " + result.Denom + " per block"
+	if result.Quote {
+		bid += ", quoted"
+	}
+	e.summary = append(e.summary, [2]string{"Bid", bid})
+	return e
+}
+
+// text renders the explanation as plain text.
+func (e explanation) text() string {
+	var b strings.Builder
+	if len(e.rows) > 0 {
+		b.WriteString("Monthly cost by resource:\n")
+		for _, row := range e.rows {
+			b.WriteString("  ")
+			if row.quantity != "" {
+				b.WriteString(row.quantity + " ")
+			}
+			b.WriteString(row.resource)
+			if row.target != "" {
+				b.WriteString(" × " + row.target)
+			}
+			b.WriteString(" = " + row.cost)
+			if row.detail != "" {
+				b.WriteString(", " + row.detail)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("  Subtotal: " + e.subtotal + "\n")
+	}
+	if len(e.adjustments) > 0 {
+		b.WriteString("Adjustments:\n")
+		for _, adjustment := range e.adjustments {
+			b.WriteString("  - " + adjustment.Name + ": " + adjustment.Detail + "\n")
+		}
+	}
+	for _, item := range e.summary {
+		b.WriteString(item[0] + ": " + item[1] + "\n")
+	}
+	return b.String()
+}
+
+// markdown renders the explanation as a Markdown table and lists.
+func (e explanation) markdown() string {
+	escape := strings.NewReplacer("|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`").Replace
+	var b strings.Builder
+	if len(e.rows) > 0 {
+		b.WriteString("| Resource | Quantity | Target | Monthly cost |\n")
+		b.WriteString("|---|---:|---:|---:|\n")
+		for _, row := range e.rows {
+			cost := row.cost
+			if row.detail != "" {
+				cost += " (" + row.detail + ")"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", escape(row.resource), row.quantity, escape(row.target), escape(cost))
+		}
+		fmt.Fprintf(&b, "| **Subtotal** | | | **%s** |\n", escape(e.subtotal))
+		b.WriteString("\n")
+	}
+	if len(e.adjustments) > 0 {
+		b.WriteString("**Adjustments**\n\n")
+		for _, adjustment := range e.adjustments {
+			fmt.Fprintf(&b, "- `%s`: %s\n", adjustment.Name, escape(adjustment.Detail))
+		}
+		b.WriteString("\n")
+	}
+	for _, item := range e.summary {
+		fmt.Fprintf(&b, "- **%s:** %s\n", item[0], escape(item[1]))
+	}
+	return b.String()
+}
+
+// html renders the explanation as an HTML fragment.
+func (e explanation) html() string {
+	escape := html.EscapeString
+	var b strings.Builder
+	b.WriteString("<div class=\"pricing-explanation\">\n")
+	if len(e.rows) > 0 {
+		b.WriteString("<table>\n<thead><tr><th>Resource</th><th>Quantity</th><th>Target</th><th>Monthly cost</th></tr></thead>\n<tbody>\n")
+		for _, row := range e.rows {
+			cost := escape(row.cost)
+			if row.detail != "" {
+				cost += " <small>(" + escape(row.detail) + ")</small>"
+			}
+			fmt.Fprintf(&b, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n", escape(row.resource), row.quantity, escape(row.target), cost)
+		}
+		fmt.Fprintf(&b, "</tbody>\n<tfoot><tr><th colspan=\"3\">Subtotal</th><th>%s</th></tr></tfoot>\n</table>\n", escape(e.subtotal))
+	}
+	if len(e.adjustments) > 0 {
+		b.WriteString("<ul class=\"adjustments\">\n")
+		for _, adjustment := range e.adjustments {
+			fmt.Fprintf(&b, "<li><code>%s</code>: %s</li>\n", escape(adjustment.Name), escape(adjustment.Detail))
+		}
+		b.WriteString("</ul>\n")
+	}
+	b.WriteString("<dl>\n")
+	for _, item := range e.summary {
+		fmt.Fprintf(&b, "<dt>%s</dt><dd>%s</dd>\n", escape(item[0]), escape(item[1]))
+	}
+	b.WriteString("</dl>\n</div>\n")
+	return b.String()
+}
+
+// costLineResource names the resource of a cost line, e.g. "GiB storage (beta3)", "GiB backup" or "HTTP endpoints".
+func costLineResource(line CostLine) string {
+	var name string
+	switch line.Resource {
+	case CostMemory, CostStorage, CostBackup:
+		name = line.Unit + " " + line.Resource
+	case CostEndpoint, CostRandomPort, CostGPU:
+		name = line.Unit
+		if !line.Quantity.Equal(sdkmath.LegacyOneDec()) {
+			name += "s"
+		}
+	case CostStorageTier:
+		name = line.Unit + " storage tier"
+	case CostService:
+		name = "Service repricing"
+	default:
+		name = line.Unit
+	}
+	if line.Kind != "" {
+		name += " (" + line.Kind + ")"
+	}
+	return name
+}
+
+// formatTarget formats a per-unit price target in the display format, keeping sub-cent digits.
+func formatTarget(money MoneyFormat, target float64, currency string) string {
+	digits := strconv.FormatFloat(target, 'f', -1, 64)
+	if i := strings.IndexByte(digits, '.'); i < 0 {
+		digits += ".00"
+	} else if len(digits)-i < 3 {
+		digits += strings.Repeat("0", 3-(len(digits)-i))
+	}
+	return money.formatDigits(digits, currency)
+}
+
+// trimDec formats d with up to precision decimals, without trailing zeros.
+func trimDec(d sdkmath.LegacyDec, precision int) string {
+	digits := FormatDec(d, precision)
+	if strings.Contains(digits, ".") {
+		digits = strings.TrimRight(strings.TrimRight(digits, "0"), ".")
+	}
+	return digits
+}
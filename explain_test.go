@@ -0,0 +1,55 @@
+package pricing
+
+import (
+	"strings"
+	"testing"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// TestExplain checks each format itemizes the cost lines of a result and lists its adjustments.
+func TestExplain(t *testing.T) {
+	resources := ResourceRequests{
+		CPURequested:       sdkmath.LegacyNewDec(4),
+		MemoryRequested:    sdkmath.LegacyNewDec(2),
+		StorageRequested:   map[string]sdkmath.LegacyDec{"beta3": sdkmath.LegacyNewDec(100)},
+		EndpointsRequested: 1,
+		SizeUnit:           SizeUnitGiB,
+	}
+	targets := PriceTargets{CPUTarget: 1.6, MemoryTarget: 0.8, HDPersNVMETarget: 0.03, EndpointTarget: 0.05}
+	lines := CalculateCostLines(resources, targets)
+	if total := totalCost(lines); !total.Equal(CalculateTotalCostUsdTarget(resources, targets)) {
+		t.Fatalf("cost lines add up to %s, want the total cost", total)
+	}
+	result := &BidResult{
+		Denom:       "uakt",
+		Price:       "4.55",
+		Precision:   2,
+		CostLines:   lines,
+		Currency:    "USD",
+		Adjustments: []Adjustment{{Name: "whitelist_discount", Detail: "10% <whitelist> discount"}},
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{ExplainText, []string{"4 CPU × $1.60 = $6.40", "2 GiB memory × $0.80 = $1.60", "100 GiB storage (beta3) × $0.03 = $3.00", "1 HTTP endpoint × $0.05 = $0.05", "Subtotal: $11.05", "whitelist_discount: 10% <whitelist> discount", "Bid: 4.55 uakt per block"}},
+		{ExplainMarkdown, []string{"| CPU | 4 | $1.60 | $6.40 |", "| **Subtotal** | | | **$11.05** |", "- `whitelist_discount`: 10% <whitelist> discount"}},
+		{ExplainHTML, []string{"<td>CPU</td><td>4</td><td>$1.60</td><td>$6.40</td>", "10% &lt;whitelist&gt; discount"}},
+	}
+	for _, tt := range tests {
+		explanation, err := ExplainAs(result, tt.format)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(explanation, want) {
+				t.Errorf("%s explanation is missing %q:\n%s", tt.format, want, explanation)
+			}
+		}
+	}
+	if _, err := ExplainAs(result, "pdf"); err == nil {
+		t.Error("unknown format was accepted")
+	}
+}
@@ -0,0 +1,255 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// DefaultExporterInterval is how often the exporter recomputes the price preview when no interval is set.
+const DefaultExporterInterval = time.Minute
+
+// PricePreview is the effective bid price of single resource units, in uakt per block, before any owner
+// specific adjustment such as whitelist discounts, reputation or volume discounts.
+type PricePreview struct {
+	Time      time.Time
+	USDPerAKT float64
+	CPU       sdkmath.LegacyDec            // One CPU core
+	MemoryGB  sdkmath.LegacyDec            // One GB of memory
+	GPUs      map[string]sdkmath.LegacyDec // One GPU per PRICE_TARGET_GPU_MAPPINGS key, e.g. "a100.80Gi"
+}
+
+// PreviewUnitPrices prices single resource units with the current price targets, surge and currency, at the
+// given AKT price.
+func PreviewUnitPrices(usdPerAkt float64) (*PricePreview, error) {
+	if err := checkDecFloat(usdPerAkt); err != nil || usdPerAkt <= 0 {
+		return nil, fmt.Errorf("invalid AKT price %g", usdPerAkt)
+	}
+	priceTargets, err := LoadPriceTargets()
+	if err != nil {
+		return nil, fmt.Errorf("error loading price targets: %v", err)
+	}
+	surgeTiers, err := surgeTiersCache.get(os.Getenv("SURGE_TIERS"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing surge tiers: %v", err)
+	}
+	priceTargets, _ = ApplySurge(NewUtilizationProviderFromEnv(), surgeTiers, priceTargets)
+
+	usdPerUnit, err := GetUSDPerUnit(priceTargets.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s/USD rate: %v", priceTargets.Currency, err)
+	}
+	schedule, err := BlockScheduleFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	uaktPerBlock := func(monthlyTarget float64) sdkmath.LegacyDec {
+		monthlyUsd := decFromFloat(monthlyTarget).Mul(decFromFloat(usdPerUnit))
+		rate, _, _ := calculateBlockRates(monthlyUsd, decFromFloat(usdPerAkt), schedule.BlocksPerMonth, DefaultPricePrecision)
+		return rate
+	}
+
+	preview := &PricePreview{
+		Time:      time.Now(),
+		USDPerAKT: usdPerAkt,
+		CPU:       uaktPerBlock(priceTargets.CPUTarget),
+		MemoryGB:  uaktPerBlock(priceTargets.MemoryTarget),
+		GPUs:      make(map[string]sdkmath.LegacyDec, len(priceTargets.GPUMappings)),
+	}
+	for model, price := range priceTargets.GPUMappings {
+		preview.GPUs[model] = uaktPerBlock(price)
+	}
+	return preview, nil
+}
+
+// WritePricePreviewMetrics writes the preview as gauges in the Prometheus text exposition format.
+func WritePricePreviewMetrics(w io.Writer, preview *PricePreview) error {
+	ew := &errWriter{w: w}
+	gauge := func(name, help string) {
+		ew.printf("# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+
+	gauge("akash_pricing_akt_price_usd", "AKT price in USD used for the preview.")
+	ew.printf("akash_pricing_akt_price_usd %g\n", preview.USDPerAKT)
+	gauge("akash_pricing_cpu_uakt_per_block", "Effective bid price of one CPU core in uakt per block.")
+	ew.printf("akash_pricing_cpu_uakt_per_block %s\n", preview.CPU)
+	gauge("akash_pricing_memory_uakt_per_block", "Effective bid price of one GB of memory in uakt per block.")
+	ew.printf("akash_pricing_memory_uakt_per_block %s\n", preview.MemoryGB)
+
+	if len(preview.GPUs) > 0 {
+		models := make([]string, 0, len(preview.GPUs))
+		for model := range preview.GPUs {
+			models = append(models, model)
+		}
+		sort.Strings(models)
+		gauge("akash_pricing_gpu_uakt_per_block", "Effective bid price of one GPU in uakt per block, by GPU mapping.")
+		for _, model := range models {
+			ew.printf("akash_pricing_gpu_uakt_per_block{model=%q} %s\n", model, preview.GPUs[model])
+		}
+	}
+
+	gauge("akash_pricing_preview_timestamp_seconds", "Unix time the preview was computed.")
+	ew.printf("akash_pricing_preview_timestamp_seconds %d\n", preview.Time.Unix())
+	return ew.err
+}
+
+// PriceExporter periodically recomputes the price preview and serves it to Prometheus. When BID_HISTORY_DB
+// is set it also re-estimates the revenue of the won leases at the same AKT price.
+type PriceExporter struct {
+	Interval  time.Duration // How often to recompute, DefaultExporterInterval if zero
+	USDPerAKT float64       // AKT price override, the oracle is queried when zero
+
+	engine *PricingEngine
+
+	mu              sync.Mutex
+	preview         *PricePreview
+	failures        int64 // Failed refreshes
+	revenue         *RevenueEstimate
+	revenueFailures int64 // Failed revenue estimates
+}
+
+// NewPriceExporter returns an exporter refreshing every interval, with the AKT price from the oracle unless
+// usdPerAkt is positive.
+func NewPriceExporter(interval time.Duration, usdPerAkt float64) *PriceExporter {
+	if interval <= 0 {
+		interval = DefaultExporterInterval
+	}
+	engine := NewPricingEngine()
+	engine.PriceTTL = interval
+	return &PriceExporter{Interval: interval, USDPerAKT: usdPerAkt, engine: engine}
+}
+
+// Refresh recomputes the preview. On failure the previous preview is kept and the error counted.
+func (e *PriceExporter) Refresh() error {
+	usdPerAkt := e.USDPerAKT
+	var err error
+	if usdPerAkt <= 0 {
+		usdPerAkt, err = e.engine.AKTPrice()
+	}
+	if err == nil && os.Getenv("BID_HISTORY_DB") != "" {
+		e.refreshRevenue(usdPerAkt)
+	}
+	var preview *PricePreview
+	if err == nil {
+		preview, err = PreviewUnitPrices(usdPerAkt)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		e.failures++
+		return err
+	}
+	e.preview = preview
+	return nil
+}
+
+// refreshRevenue re-estimates the revenue of the won leases. On failure the previous estimate is kept and
+// the error counted, without failing the price preview.
+func (e *PriceExporter) refreshRevenue(usdPerAkt float64) {
+	estimate, err := EstimateRevenue(usdPerAkt)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		log.Printf("Error estimating revenue: %v", err)
+		e.revenueFailures++
+		return
+	}
+	e.revenue = estimate
+}
+
+// Run refreshes the preview every Interval until ctx is done.
+func (e *PriceExporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+	for {
+		if err := e.Refresh(); err != nil {
+			log.Printf("Error refreshing price preview: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ServeHTTP writes the latest preview and the refresh error count in the Prometheus text format.
+func (e *PriceExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	preview, failures := e.preview, e.failures
+	revenue, revenueFailures := e.revenue, e.revenueFailures
+	e.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if preview != nil {
+		if err := WritePricePreviewMetrics(w, preview); err != nil {
+			log.Printf("Error writing price preview metrics: %v", err)
+			return
+		}
+	}
+	fmt.Fprintf(w, "# HELP akash_pricing_preview_errors_total Failed price preview refreshes.\n")
+	fmt.Fprintf(w, "# TYPE akash_pricing_preview_errors_total counter\n")
+	fmt.Fprintf(w, "akash_pricing_preview_errors_total %d\n", failures)
+	if err := WriteAKTPriceMetrics(w); err != nil {
+		log.Printf("Error writing AKT price metrics: %v", err)
+		return
+	}
+	if stats := CurrentProviderStats(); len(stats) > 0 {
+		if err := WriteProviderMetrics(w, stats); err != nil {
+			log.Printf("Error writing provider metrics: %v", err)
+			return
+		}
+	}
+	if os.Getenv("SHADOW_PRICE_TARGETS") != "" {
+		if err := WriteShadowMetrics(w, CurrentShadowStats()); err != nil {
+			log.Printf("Error writing shadow pricing metrics: %v", err)
+			return
+		}
+	}
+	if os.Getenv("BID_HISTORY_DB") != "" {
+		if revenue != nil {
+			if err := WriteRevenueMetrics(w, revenue); err != nil {
+				log.Printf("Error writing revenue metrics: %v", err)
+				return
+			}
+		}
+		fmt.Fprintf(w, "# HELP akash_pricing_revenue_errors_total Failed revenue estimates.\n")
+		fmt.Fprintf(w, "# TYPE akash_pricing_revenue_errors_total counter\n")
+		fmt.Fprintf(w, "akash_pricing_revenue_errors_total %d\n", revenueFailures)
+	}
+}
+
+// ServeRevenue writes the latest revenue estimate as JSON, or 503 until one has been made.
+func (e *PriceExporter) ServeRevenue(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	revenue := e.revenue
+	e.mu.Unlock()
+
+	if revenue == nil {
+		http.Error(w, "no revenue estimate yet, is BID_HISTORY_DB set?", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusOK, revenue)
+}
+
+// errWriter keeps the first write error so a sequence of writes is checked once.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
@@ -0,0 +1,72 @@
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// OwnerExposureCap limits the provider's exposure to a single tenant: bids are rejected once the monthly
+// USD of an owner's active leases plus the new bid exceeds MaxMonthlyUsd.
+type OwnerExposureCap struct {
+	MaxMonthlyUsd float64
+	Window        time.Duration // How long a won lease counts as active, 0 for as long as the bid history keeps it
+}
+
+// OwnerExposureCapFromEnv reads OWNER_MAX_MONTHLY_USD and OWNER_EXPOSURE_WINDOW (a Go duration or days
+// such as "30d"). It returns nil when no cap is configured. The cap needs the bid history named by
+// BID_HISTORY_DB to look up the owner's leases.
+func OwnerExposureCapFromEnv() (*OwnerExposureCap, error) {
+	maxStr := os.Getenv("OWNER_MAX_MONTHLY_USD")
+	if maxStr == "" {
+		return nil, nil
+	}
+	maxMonthly, err := strconv.ParseFloat(maxStr, 64)
+	if err != nil || maxMonthly <= 0 || checkDecFloat(maxMonthly) != nil {
+		return nil, fmt.Errorf("invalid OWNER_MAX_MONTHLY_USD %q: must be a positive amount", maxStr)
+	}
+
+	exposureCap := &OwnerExposureCap{MaxMonthlyUsd: maxMonthly}
+	if val := strings.TrimSpace(os.Getenv("OWNER_EXPOSURE_WINDOW")); val != "" {
+		exposureCap.Window, err = parseDays(val)
+		if err != nil || exposureCap.Window < 0 {
+			return nil, fmt.Errorf("invalid OWNER_EXPOSURE_WINDOW %q", val)
+		}
+	}
+	if os.Getenv("BID_HISTORY_DB") == "" {
+		return nil, fmt.Errorf("OWNER_MAX_MONTHLY_USD requires BID_HISTORY_DB to look up owner leases")
+	}
+	return exposureCap, nil
+}
+
+// OwnerExposure returns the monthly USD of the leases won within window before now, or of all leases if
+// window is 0. Leases with an unparseable cost are skipped.
+func OwnerExposure(leases []OwnerLease, window time.Duration, now time.Time) sdkmath.LegacyDec {
+	exposure := sdkmath.LegacyZeroDec()
+	for _, lease := range leases {
+		if window > 0 && now.Sub(lease.Time) > window {
+			continue
+		}
+		if monthly, err := sdkmath.LegacyNewDecFromStr(lease.TotalCostUsd); err == nil {
+			exposure = exposure.Add(monthly)
+		}
+	}
+	return exposure
+}
+
+// Check rejects a bid of totalCostUsd per month if it would take the owner's exposure past the cap.
+func (c *OwnerExposureCap) Check(owner string, leases []OwnerLease, totalCostUsd sdkmath.LegacyDec, now time.Time) error {
+	if c == nil {
+		return nil
+	}
+	exposure := OwnerExposure(leases, c.Window, now)
+	if total := exposure.Add(totalCostUsd); total.GT(decFromFloat(c.MaxMonthlyUsd)) {
+		return withReason(ErrOwnerExposure, fmt.Errorf("owner %s would have %s USD/month of leases (%s active + %s bid), above the cap of %g USD/month",
+			owner, FormatDec(total, 2), FormatDec(exposure, 2), FormatDec(totalCostUsd, 2), c.MaxMonthlyUsd))
+	}
+	return nil
+}
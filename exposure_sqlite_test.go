@@ -0,0 +1,62 @@
+//go:build sqlite
+
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestOwnerExposureBid prices the cpu-only fixture, 6.85 USD a month, for an owner whose won leases are in the
+// bid history, under OWNER_MAX_MONTHLY_USD=20.
+func TestOwnerExposureBid(t *testing.T) {
+	tests := []struct {
+		name     string
+		leases   []string
+		window   string
+		rejected bool
+	}{
+		{"no leases", nil, "", false},
+		{"at the cap", []string{"6.85", "6.30"}, "", false},
+		{"past the cap", []string{"6.85", "6.31"}, "", true},
+		{"past the cap before the window", []string{"6.85", "6.31"}, "1h", false},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "history.db")
+		store, err := sharedBidHistory(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		won := time.Now().Add(-2 * time.Hour)
+		for i, cost := range tt.leases {
+			orderID := fmt.Sprintf("1000/1/%d", i+1)
+			if err := store.Record(BidRecord{Time: won, Owner: "akash1fixture", OrderID: orderID, TotalCostUsd: cost, Accepted: true}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.RecordOutcome(BidOutcome{OrderID: orderID, Won: true, Time: won}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		result, err := priceWithEnv(fixtureRequest(t, "cpu-only"), map[string]string{
+			"BID_HISTORY_DB":        path,
+			"OWNER_MAX_MONTHLY_USD": "20",
+			"OWNER_EXPOSURE_WINDOW": tt.window,
+		})
+		if tt.rejected {
+			if !errors.Is(err, ErrOwnerExposure) {
+				t.Errorf("%s: got %v, want an exposure rejection", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if result.Price != "4.552452" {
+			t.Errorf("%s: got %s, want 4.552452", tt.name, result.Price)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// TestOwnerExposureCapCheck checks a 20 USD/month cap against an owner with 13.15 USD/month of leases.
+func TestOwnerExposureCapCheck(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	leases := []OwnerLease{
+		{OrderID: "1/1/1", TotalCostUsd: "6.85", Time: now.Add(-24 * time.Hour)},
+		{OrderID: "2/1/1", TotalCostUsd: "6.30", Time: now.Add(-20 * 24 * time.Hour)},
+		{OrderID: "3/1/1", TotalCostUsd: "not a cost", Time: now},
+	}
+	tests := []struct {
+		name     string
+		window   time.Duration
+		bid      string
+		exposure string
+		rejected bool
+	}{
+		{"below the cap", 0, "6.00", "13.15", false},
+		{"at the cap", 0, "6.85", "13.15", false},
+		{"past the cap", 0, "6.86", "13.15", true},
+		{"older lease outside the window", 7 * 24 * time.Hour, "13.15", "6.85", false},
+		{"older lease inside the window", 30 * 24 * time.Hour, "13.15", "13.15", true},
+	}
+	for _, tt := range tests {
+		exposureCap := &OwnerExposureCap{MaxMonthlyUsd: 20, Window: tt.window}
+		if got := FormatDec(OwnerExposure(leases, tt.window, now), 2); got != tt.exposure {
+			t.Errorf("%s: got exposure %s, want %s", tt.name, got, tt.exposure)
+		}
+		err := exposureCap.Check("akash1owner", leases, sdkmath.LegacyMustNewDecFromStr(tt.bid), now)
+		if tt.rejected != errors.Is(err, ErrOwnerExposure) || (!tt.rejected && err != nil) {
+			t.Errorf("%s: got %v, want rejected %v", tt.name, err, tt.rejected)
+		}
+	}
+
+	var unset *OwnerExposureCap
+	if err := unset.Check("akash1owner", leases, sdkmath.LegacyNewDec(1000), now); err != nil {
+		t.Errorf("no cap: got %v", err)
+	}
+}
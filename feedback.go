@@ -0,0 +1,190 @@
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPriceBands are the monthly USD band edges used for win-rate statistics.
+var DefaultPriceBands = []float64{10, 50, 100, 500, 1000}
+
+// BidOutcome records whether the bid for an order won the lease.
+type BidOutcome struct {
+	OrderID string
+	Won     bool
+	Time    time.Time
+}
+
+// OutcomeRow is an order with a recorded outcome joined with the bid that was placed for it.
+type OutcomeRow struct {
+	OrderID      string
+	GPUModels    []string
+	TotalCostUsd string
+	Won          bool
+}
+
+// OwnerLease is an order of an owner whose bid won the lease, with the monthly cost of the winning bid.
+type OwnerLease struct {
+	OrderID      string
+	TotalCostUsd string
+	Time         time.Time // When the win was recorded
+}
+
+// WinRateStat is the win rate of bids for one GPU model within a monthly USD price band [BandMin, BandMax).
+// A BandMax of 0 means the band is unbounded.
+type WinRateStat struct {
+	GPUModel string
+	BandMin  float64
+	BandMax  float64
+	Bids     int
+	Wins     int
+}
+
+// WinRate returns the fraction of bids won.
+func (s WinRateStat) WinRate() float64 {
+	if s.Bids == 0 {
+		return 0
+	}
+	return float64(s.Wins) / float64(s.Bids)
+}
+
+// RecordBidOutcome stores the outcome of the bid for orderID in the bid history named by BID_HISTORY_DB.
+func RecordBidOutcome(orderID string, won bool) error {
+	orderID = strings.TrimSpace(orderID)
+	if orderID == "" {
+		return fmt.Errorf("order ID is required")
+	}
+
+	store, err := openBidHistoryFromEnv()
+	if err != nil {
+		return err
+	}
+	return store.RecordOutcome(BidOutcome{OrderID: orderID, Won: won, Time: time.Now()})
+}
+
+// BidWinRates computes win-rate statistics from the bid history named by BID_HISTORY_DB.
+func BidWinRates(bands []float64) ([]WinRateStat, error) {
+	store, err := openBidHistoryFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := store.OutcomeRows()
+	if err != nil {
+		return nil, err
+	}
+	return WinRateStats(rows, bands), nil
+}
+
+// openBidHistoryFromEnv returns the shared store of the bid history named by BID_HISTORY_DB. Callers must
+// not close it.
+func openBidHistoryFromEnv() (BidHistoryStore, error) {
+	path := os.Getenv("BID_HISTORY_DB")
+	if path == "" {
+		return nil, fmt.Errorf("BID_HISTORY_DB is not set")
+	}
+	return sharedBidHistory(path)
+}
+
+// ownerLeaseLookup reads the leases an owner won from the bid history named by BID_HISTORY_DB, at most once
+// per bid however many pricing steps need them.
+type ownerLeaseLookup struct {
+	owner  string
+	done   bool
+	leases []OwnerLease
+	err    error
+}
+
+// get returns the owner's leases, reading them on the first call.
+func (l *ownerLeaseLookup) get() ([]OwnerLease, error) {
+	if l.done {
+		return l.leases, l.err
+	}
+	l.done = true
+	store, err := openBidHistoryFromEnv()
+	if err != nil {
+		l.err = err
+		return nil, err
+	}
+	l.leases, l.err = store.OwnerLeases(l.owner)
+	return l.leases, l.err
+}
+
+// ParsePriceBands parses ascending band edges such as "10,50,100,500".
+func ParsePriceBands(bandsStr string) ([]float64, error) {
+	if bandsStr == "" {
+		return DefaultPriceBands, nil
+	}
+
+	var bands []float64
+	for _, edge := range strings.Split(bandsStr, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(edge), 64)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("invalid price band edge: %s", edge)
+		}
+		if len(bands) > 0 && value <= bands[len(bands)-1] {
+			return nil, fmt.Errorf("price band edges must be ascending: %s", bandsStr)
+		}
+		bands = append(bands, value)
+	}
+	return bands, nil
+}
+
+// WinRateStats groups outcomes by GPU model and monthly USD band. Deployments without GPUs are grouped
+// under "none" and deployments with several models count once for each model.
+func WinRateStats(rows []OutcomeRow, bands []float64) []WinRateStat {
+	type key struct {
+		model string
+		band  int
+	}
+	stats := make(map[key]*WinRateStat)
+
+	for _, row := range rows {
+		cost, err := strconv.ParseFloat(row.TotalCostUsd, 64)
+		if err != nil {
+			continue
+		}
+		band := sort.SearchFloat64s(bands, cost)
+		if band < len(bands) && bands[band] == cost {
+			band++ // Edges belong to the band above them
+		}
+
+		models := row.GPUModels
+		if len(models) == 0 {
+			models = []string{"none"}
+		}
+		for _, model := range models {
+			k := key{model, band}
+			stat, ok := stats[k]
+			if !ok {
+				stat = &WinRateStat{GPUModel: model}
+				if band > 0 {
+					stat.BandMin = bands[band-1]
+				}
+				if band < len(bands) {
+					stat.BandMax = bands[band]
+				}
+				stats[k] = stat
+			}
+			stat.Bids++
+			if row.Won {
+				stat.Wins++
+			}
+		}
+	}
+
+	result := make([]WinRateStat, 0, len(stats))
+	for _, stat := range stats {
+		result = append(result, *stat)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].GPUModel != result[j].GPUModel {
+			return result[i].GPUModel < result[j].GPUModel
+		}
+		return result[i].BandMin < result[j].BandMin
+	})
+	return result
+}
@@ -0,0 +1,79 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// Fixture is a recorded pricing input with the environment it is priced under. Fixtures live in
+// testdata/fixtures and their expected results in testdata/golden, so pricing changes show up as diffs.
+type Fixture struct {
+	Name        string            `json:"-"` // File name without extension
+	Description string            `json:"description,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	Precision   int               `json:"precision,omitempty"`
+	OrderID     string            `json:"order_id,omitempty"`
+	Provider    string            `json:"provider,omitempty"`
+	AKTPriceUsd float64           `json:"akt_price_usd"`
+	Env         map[string]string `json:"env,omitempty"`
+	GroupSpec   *dtypes.GroupSpec `json:"group_spec"`
+}
+
+// fixtureEnvPrefixes are cleared before a fixture runs so only its own env applies and nothing remote is queried.
+var fixtureEnvPrefixes = []string{
+	"AKT_PRICE_", "AUDIT_", "PRICE_TARGET_", "PRICING_CONFIG", "BID_", "BLOCK_TIME_", "BLOCKS_PER_MONTH", "CAPACITY_", "COUPON_", "CPU_CLASS_", "DEPLOYMENT_OVERHEAD_", "DURATION_TIERS", "EGRESS_", "GPU_INVENTORY", "GPU_MISMATCH", "IP_VERSION_", "OWNER_", "PROVIDER_ADDRESS", "LOYALTY_TIERS", "ORDER_PRICE_MODE", "REGION", "RENEWAL_", "REQUEST_MAX_", "REPUTATION_", "SERVICE_", "SHADOW_",
+	"STORAGE_", "STRATEGY_", "SURGE_TIERS", "TRIAL_", "UTILIZATION_", "VOLUME_DISCOUNTS", "WEBHOOK_", "WHITELIST_",
+}
+
+// LoadFixtures reads every fixture in dir, sorted by name.
+func LoadFixtures(dir string) ([]Fixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	fixtures := make([]Fixture, 0, len(paths))
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var fixture Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("error parsing fixture %s: %w", path, err)
+		}
+		if fixture.GroupSpec == nil {
+			return nil, fmt.Errorf("fixture %s has no group_spec", path)
+		}
+		fixture.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, nil
+}
+
+// NewFixturesFromSDL builds one fixture per deployment group of an SDL file, named <name>-<group>.
+func NewFixturesFromSDL(sdlPath, name string, aktPriceUsd float64) ([]Fixture, error) {
+	specs, err := ReadSDLGroupSpecs(sdlPath)
+	if err != nil {
+		return nil, err
+	}
+
+	fixtures := make([]Fixture, 0, len(specs))
+	for _, spec := range specs {
+		fixtures = append(fixtures, Fixture{
+			Name:        name + "-" + spec.Name,
+			Description: fmt.Sprintf("Group %s of %s", spec.Name, filepath.Base(sdlPath)),
+			AKTPriceUsd: aktPriceUsd,
+			Precision:   6,
+			GroupSpec:   spec,
+		})
+	}
+	return fixtures, nil
+}
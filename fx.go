@@ -0,0 +1,165 @@
+package pricing
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPriceTargetCurrency is the currency price targets are expressed in when PRICE_TARGET_CURRENCY is unset.
+const DefaultPriceTargetCurrency = "USD"
+
+// FX rate sources. The ECB publishes daily reference rates against EUR; exchangerate.host is the fallback.
+var (
+	ecbDailyRatesURL    = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+	exchangeRateHostURL = "https://api.exchangerate.host/latest"
+)
+
+// GetUSDPerUnit returns how many USD one unit of the given fiat currency is worth, caching the rate.
+// If both sources fail, an expired cached rate is used rather than failing the bid.
+func GetUSDPerUnit(currency string) (float64, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" || currency == "USD" {
+		return 1, nil
+	}
+	if len(currency) != 3 {
+		return 0, fmt.Errorf("invalid currency code: %s", currency)
+	}
+
+	cacheFile := fxCacheFile(currency)
+	rate, err := readCachedPrice(cacheFile)
+	if err == nil {
+		return rate, nil
+	}
+
+	unlock := lockCache(cacheFile)
+	defer unlock()
+	// Another bid may have refreshed the cache while this one waited for the lock
+	if rate, err := readCachedPrice(cacheFile); err == nil {
+		return rate, nil
+	}
+
+	rate, err = fetchFXRate(currency)
+	if err != nil {
+		if stale, staleErr := readStalePrice(cacheFile); staleErr == nil {
+			log.Printf("Error fetching %s/USD rate, using stale cached rate: %v", currency, err)
+			return stale, nil
+		}
+		return 0, err
+	}
+
+	if err := cachePrice(cacheFile, rate); err != nil {
+		return 0, err
+	}
+	return rate, nil
+}
+
+// fetchFXRate tries the ECB reference rates first and falls back to exchangerate.host.
+func fetchFXRate(currency string) (float64, error) {
+	rate, err := fetchECBRate(currency)
+	if err == nil {
+		return rate, nil
+	}
+	log.Printf("ECB FX rates failed, trying fallback: %v", err)
+
+	rate, fallbackErr := fetchExchangeRateHostRate(currency)
+	notifyEvent(WebhookEvent{
+		Type:    EventOracleFailover,
+		Message: fmt.Sprintf("%s/USD rate failed over to exchangerate.host", currency),
+		Fields:  map[string]string{"primary_error": err.Error(), "fallback_ok": strconv.FormatBool(fallbackErr == nil)},
+	})
+	if fallbackErr != nil {
+		return 0, fmt.Errorf("error fetching %s/USD rate: %v; fallback: %w", currency, err, fallbackErr)
+	}
+	return rate, nil
+}
+
+// ecbEnvelope is the subset of the ECB daily reference rate document that is used.
+type ecbEnvelope struct {
+	Cube struct {
+		Cube struct {
+			Rates []struct {
+				Currency string  `xml:"currency,attr"`
+				Rate     float64 `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+// fetchECBRate derives USD per unit of currency from the EUR-based ECB reference rates.
+func fetchECBRate(currency string) (float64, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(ecbDailyRatesURL)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code %d from ECB", resp.StatusCode)
+	}
+
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return 0, err
+	}
+
+	// Rates are units of currency per EUR
+	perEUR := map[string]float64{"EUR": 1}
+	for _, r := range envelope.Cube.Cube.Rates {
+		perEUR[r.Currency] = r.Rate
+	}
+	usd, cur := perEUR["USD"], perEUR[currency]
+	if usd <= 0 || cur <= 0 {
+		return 0, fmt.Errorf("ECB has no rate for %s", currency)
+	}
+	return usd / cur, nil
+}
+
+// fetchExchangeRateHostRate fetches USD per unit of currency from exchangerate.host.
+func fetchExchangeRateHostRate(currency string) (float64, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(exchangeRateHostURL + "?base=" + currency + "&symbols=USD")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code %d from exchangerate.host", resp.StatusCode)
+	}
+
+	var data struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return 0, err
+	}
+	rate := data.Rates["USD"]
+	if rate <= 0 {
+		return 0, fmt.Errorf("exchangerate.host has no USD rate for %s", currency)
+	}
+	return rate, nil
+}
+
+// readStalePrice reads a cached price regardless of its age.
+func readStalePrice(cacheFile string) (float64, error) {
+	data, err := ioutil.ReadFile(cacheFile)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+}
+
+// priceTargetCurrency returns the currency price targets are configured in.
+func priceTargetCurrency() string {
+	if currency := strings.TrimSpace(os.Getenv("PRICE_TARGET_CURRENCY")); currency != "" {
+		return strings.ToUpper(currency)
+	}
+	return DefaultPriceTargetCurrency
+}
@@ -0,0 +1,141 @@
+package pricing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite testdata/golden with the current results")
+
+// goldenResult is the part of a BidResult compared against golden files.
+type goldenResult struct {
+	Denom            string       `json:"denom,omitempty"`
+	Price            string       `json:"price,omitempty"`
+	TotalCostUsd     string       `json:"total_cost_usd,omitempty"`
+	RatePerBlockUakt string       `json:"rate_per_block_uakt,omitempty"`
+	Adjustments      []Adjustment `json:"adjustments,omitempty"`
+	Error            string       `json:"error,omitempty"`
+	ReasonCode       string       `json:"reason_code,omitempty"` // Reason code of Error
+	PriceGap         string       `json:"price_gap,omitempty"`   // Shortfall of the order's max price when declined
+}
+
+// TestGolden prices every fixture in testdata/fixtures and compares it with testdata/golden/<name>.golden.json.
+// Run it with -update to rewrite the golden files after an intended pricing change.
+func TestGolden(t *testing.T) {
+	fixtures, err := LoadFixtures(filepath.Join("testdata", "fixtures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no fixtures in testdata/fixtures")
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name, func(t *testing.T) {
+			actual, err := json.MarshalIndent(runFixture(fixture), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, '\n')
+
+			goldenPath := filepath.Join("testdata", "golden", fixture.Name+".golden.json")
+			if *updateGolden {
+				if err := ioutil.WriteFile(goldenPath, actual, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := ioutil.ReadFile(goldenPath)
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(expected, actual) {
+				t.Errorf("result differs from %s, rerun with -update if the change is intended\n--- expected\n%s+++ actual\n%s", goldenPath, expected, actual)
+			}
+		})
+	}
+}
+
+// runFixture prices the fixture under its own environment and restores the process environment afterwards.
+func runFixture(f Fixture) goldenResult {
+	restore := isolateEnv(f.Env)
+	defer restore()
+
+	owner := f.Owner
+	if owner == "" {
+		owner = "akash1fixture"
+	}
+	result, err := CalculateBid(Request{
+		Owner:          owner,
+		GSpec:          f.GroupSpec,
+		PricePrecision: f.Precision,
+		OrderID:        f.OrderID,
+		Provider:       f.Provider,
+		USDPerAKT:      f.AKTPriceUsd,
+	})
+	if err != nil {
+		code, _ := ErrorReason(err)
+		golden := goldenResult{Error: err.Error(), ReasonCode: code}
+		var decline *DeclineError
+		if errors.As(err, &decline) {
+			golden.PriceGap = decline.PriceGap()
+		}
+		return golden
+	}
+
+	return goldenResult{
+		Denom:            result.Denom,
+		Price:            result.Price,
+		TotalCostUsd:     FormatDec(result.TotalCostUsd, 6),
+		RatePerBlockUakt: FormatDec(result.RatePerBlockUakt, 18),
+		Adjustments:      result.Adjustments,
+	}
+}
+
+// isolateEnv clears the pricing environment, applies env and returns a function restoring the previous values.
+func isolateEnv(env map[string]string) func() {
+	saved := make(map[string]*string)
+	save := func(key string) {
+		if _, ok := saved[key]; ok {
+			return
+		}
+		if value, set := os.LookupEnv(key); set {
+			saved[key] = &value
+		} else {
+			saved[key] = nil
+		}
+	}
+
+	for _, kv := range os.Environ() {
+		key := strings.SplitN(kv, "=", 2)[0]
+		for _, prefix := range fixtureEnvPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				save(key)
+				os.Unsetenv(key)
+				break
+			}
+		}
+	}
+	for key, value := range env {
+		save(key)
+		os.Setenv(key, value)
+	}
+
+	return func() {
+		for key, value := range saved {
+			if value == nil {
+				os.Unsetenv(key)
+			} else {
+				os.Setenv(key, *value)
+			}
+		}
+	}
+}
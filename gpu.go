@@ -3,14 +3,61 @@ package pricing
 import (
 	"fmt"
 	"log"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
 
+	sdkmath "cosmossdk.io/math"
+
 	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+	attrtypes "pkg.akt.dev/go/node/types/attributes/v1"
+)
+
+// Bounds of PRICE_TARGET_HOURS_PER_MONTH, from a 28-day month running 24/7 to a 31-day one.
+const (
+	minHoursPerMonth = 28 * 24
+	maxHoursPerMonth = 31 * 24
 )
 
-// ParseGPUPriceMappings parses a string of GPU model to price mappings and returns a map
+// HoursPerMonthFromEnv returns PRICE_TARGET_HOURS_PER_MONTH, the hours an hourly GPU price is multiplied by
+// to get its monthly price, defaulting to 24 hours a day over PRICE_TARGET_DAYS_PER_MONTH.
+func HoursPerMonthFromEnv() (float64, error) {
+	return ParseHoursPerMonth(os.Getenv("PRICE_TARGET_HOURS_PER_MONTH"))
+}
+
+// ParseHoursPerMonth validates an hours per month setting, defaulting to 24 hours a day over
+// PRICE_TARGET_DAYS_PER_MONTH.
+func ParseHoursPerMonth(val string) (float64, error) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return 24 * daysPerMonth(), nil
+	}
+	hours, err := strconv.ParseFloat(val, 64)
+	if err != nil || hours < minHoursPerMonth || hours > maxHoursPerMonth {
+		return 0, fmt.Errorf("invalid PRICE_TARGET_HOURS_PER_MONTH %q: must be between %d and %d", val, minHoursPerMonth, maxHoursPerMonth)
+	}
+	return hours, nil
+}
+
+// hoursPerMonth returns PRICE_TARGET_HOURS_PER_MONTH. Invalid values fall back to the default here, since
+// LoadPriceTargets rejects them before any request is priced.
+func hoursPerMonth() float64 {
+	hours, err := HoursPerMonthFromEnv()
+	if err != nil {
+		return 24 * daysPerMonth()
+	}
+	return hours
+}
+
+// ParseGPUPriceMappings parses a string of GPU model to monthly price mappings, converting prices suffixed
+// with /hr at PRICE_TARGET_HOURS_PER_MONTH, and returns a map
 func ParseGPUPriceMappings(mappingStr string) (map[string]float64, error) {
+	return parseGPUPriceMappings(mappingStr, hoursPerMonth())
+}
+
+// parseGPUPriceMappings is ParseGPUPriceMappings with the hours per month of hourly prices.
+func parseGPUPriceMappings(mappingStr string, hoursPerMonth float64) (map[string]float64, error) {
 	gpuMappings := make(map[string]float64)
 
 	// Return an empty map if the input string is empty, avoiding an error
@@ -30,10 +77,23 @@ func ParseGPUPriceMappings(mappingStr string) (map[string]float64, error) {
 		}
 
 		key := kv[0]
-		value, err := strconv.ParseFloat(kv[1], 64)
+		if key == "" {
+			return nil, fmt.Errorf("invalid GPU mapping: %s", pair)
+		}
+		amount, perHour, err := splitGPUPriceUnit(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPU price for %s: %v", key, err)
+		}
+		value, err := strconv.ParseFloat(amount, 64)
 		if err != nil {
 			return nil, fmt.Errorf("invalid GPU price for %s: %v", key, err)
 		}
+		if perHour {
+			value *= hoursPerMonth
+		}
+		if err := checkDecFloat(value); err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid GPU price for %s: %s", key, kv[1])
+		}
 
 		gpuMappings[key] = value
 	}
@@ -41,6 +101,22 @@ func ParseGPUPriceMappings(mappingStr string) (map[string]float64, error) {
 	return gpuMappings, nil
 }
 
+// splitGPUPriceUnit splits a GPU price such as "1.5/hr" into its amount and whether it is hourly. Prices
+// without a unit, or with /mo, are monthly.
+func splitGPUPriceUnit(price string) (amount string, perHour bool, err error) {
+	amount, unit, found := strings.Cut(price, "/")
+	if !found {
+		return amount, false, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(unit)) {
+	case "hr", "h", "hour":
+		return strings.TrimSpace(amount), true, nil
+	case "mo", "month":
+		return strings.TrimSpace(amount), false, nil
+	}
+	return "", false, fmt.Errorf("unknown unit /%s in %s, must be /hr or /mo", unit, price)
+}
+
 // MaxGPUPrice returns the maximum GPU price from the mappings or a default value
 func MaxGPUPrice(gpuMappings map[string]float64) float64 {
 	maxPrice := 100.0 // Default value
@@ -52,67 +128,290 @@ func MaxGPUPrice(gpuMappings map[string]float64) float64 {
 	return maxPrice
 }
 
-// CalculateTotalGPUPrice calculates the total GPU price based on the GroupSpec and GPU price mappings
-func CalculateTotalGPUPrice(gSpec *dtypes.GroupSpec, gpuMappings map[string]float64, maxGPUPrice float64) float64 {
-	totalGPUPrice := 0.0
+// Ways of pricing a GPU resource that accepts any of several models, see PriceTargets.GPUMixedModels.
+const (
+	GPUMixedMax = "max" // The most expensive accepted GPU, since the provider may have to supply it (default)
+	GPUMixedMin = "min" // The cheapest accepted GPU
+	GPUMixedAvg = "avg" // The average of the accepted GPUs
+)
+
+// ParseGPUMixedModels validates a PRICE_TARGET_GPU_MIXED_MODELS setting, defaulting to GPUMixedMax.
+func ParseGPUMixedModels(val string) (string, error) {
+	switch val = strings.TrimSpace(val); val {
+	case "":
+		return GPUMixedMax, nil
+	case GPUMixedMax, GPUMixedMin, GPUMixedAvg:
+		return val, nil
+	}
+	return "", fmt.Errorf("invalid PRICE_TARGET_GPU_MIXED_MODELS %q: must be max, min or avg", val)
+}
+
+// gpuSpec is a GPU accepted by a resource, from one attribute key such as
+// "vendor/nvidia/model/rtx4090/ram/24Gi/interface/pcie".
+type gpuSpec struct {
+	model, vram, interfaceType string
+}
+
+// String returns the spec as a GPU mapping key, model[.vram[.interface]].
+func (g gpuSpec) String() string {
+	key := g.model
+	if g.vram != "" {
+		key += "." + g.vram
+	}
+	if g.interfaceType != "" {
+		key += "." + g.interfaceType
+	}
+	return key
+}
+
+// parseGPUSpec extracts the model, VRAM and interface from a GPU attribute key.
+func parseGPUSpec(key string, spec *gpuSpec) {
+	// Walk the key segment by segment rather than splitting it, so pricing a GPU does not allocate
+	for rest := key; rest != ""; {
+		var part string
+		var found bool
+		if part, rest, found = strings.Cut(rest, "/"); !found {
+			break // A trailing segment has no value
+		}
+		value, _, _ := strings.Cut(rest, "/")
+		switch part {
+		case "model":
+			spec.model = value
+		case "ram":
+			spec.vram = value
+		case "interface":
+			spec.interfaceType = value
+		}
+	}
+}
+
+// parseGPUSpecs returns the distinct GPUs accepted by a resource's attributes, in order. Each key naming a
+// model is one alternative; keys without a model refine the single GPU of attributes that name none.
+func parseGPUSpecs(attributes attrtypes.Attributes) []gpuSpec {
+	var specs []gpuSpec
+	var unnamed gpuSpec
+	for _, attr := range attributes {
+		var spec gpuSpec
+		parseGPUSpec(attr.Key, &spec)
+		if spec.model == "" {
+			parseGPUSpec(attr.Key, &unnamed)
+			continue
+		}
+		duplicate := false
+		for _, seen := range specs {
+			duplicate = duplicate || seen == spec
+		}
+		if !duplicate {
+			specs = append(specs, spec)
+		}
+	}
+	if len(specs) == 0 && unnamed != (gpuSpec{}) {
+		specs = append(specs, unnamed)
+	}
+	return specs
+}
+
+// parseGPUAttributes extracts the model, VRAM and interface from GPU attribute keys such as
+// "vendor/nvidia/model/rtx4090/ram/24Gi/interface/pcie". Of attributes accepting several models it returns
+// the first.
+func parseGPUAttributes(attributes attrtypes.Attributes) (model, vram, interfaceType string) {
+	if specs := parseGPUSpecs(attributes); len(specs) > 0 {
+		return specs[0].model, specs[0].vram, specs[0].interfaceType
+	}
+	return "", "", ""
+}
+
+// GPUModels returns the sorted, distinct GPU models requested by the GroupSpec.
+func GPUModels(gSpec *dtypes.GroupSpec) []string {
+	seen := make(map[string]bool)
+	var models []string
+	for _, resourceUnit := range gSpec.Resources {
+		if resourceUnit.Resources.GPU == nil || resourceUnit.Resources.GPU.Units.Val.IsZero() {
+			continue
+		}
+		model, _, _ := parseGPUAttributes(resourceUnit.Resources.GPU.Attributes)
+		if model == "" {
+			model = "any"
+		}
+		if !seen[model] {
+			seen[model] = true
+			models = append(models, model)
+		}
+	}
+	sort.Strings(models)
+	return models
+}
+
+// Handling of resources whose GPU units and attributes disagree, selected by GPU_MISMATCH.
+const (
+	GPUMismatchDefault = "default" // Price GPUs without attributes at the default GPU price and ignore attributes without units (default)
+	GPUMismatchReject  = "reject"  // Reject both with ErrGPUMismatch
+)
+
+// ParseGPUMismatch validates a GPU_MISMATCH setting, defaulting to GPUMismatchDefault.
+func ParseGPUMismatch(val string) (string, error) {
+	switch val = strings.TrimSpace(val); val {
+	case "":
+		return GPUMismatchDefault, nil
+	case GPUMismatchDefault, GPUMismatchReject:
+		return val, nil
+	}
+	return "", fmt.Errorf("invalid GPU_MISMATCH %q: must be default or reject", val)
+}
+
+// GPUMismatches describes each resource of the GroupSpec requesting GPU units without attributes naming
+// the GPU, or GPU attributes without units.
+func GPUMismatches(gSpec *dtypes.GroupSpec) []string {
+	var mismatches []string
+	for _, resourceUnit := range gSpec.Resources {
+		gpu := resourceUnit.Resources.GPU
+		if gpu == nil {
+			continue
+		}
+		switch hasAttributes := len(parseGPUSpecs(gpu.Attributes)) > 0; {
+		case !gpu.Units.Val.IsZero() && !hasAttributes:
+			mismatches = append(mismatches, fmt.Sprintf("resource %d requests %s GPUs without attributes", resourceUnit.Resources.ID, gpu.Units.Val))
+		case gpu.Units.Val.IsZero() && hasAttributes:
+			mismatches = append(mismatches, fmt.Sprintf("resource %d has GPU attributes but no GPU units", resourceUnit.Resources.ID))
+		}
+	}
+	return mismatches
+}
+
+// CheckGPUAttributes applies GPU_MISMATCH to the GroupSpec: under GPUMismatchReject it returns an error
+// wrapping ErrGPUMismatch for any mismatch, otherwise mismatches are only logged.
+func CheckGPUAttributes(gSpec *dtypes.GroupSpec, mismatch string) error {
+	mismatches := GPUMismatches(gSpec)
+	if len(mismatches) == 0 {
+		return nil
+	}
+	if mismatch == GPUMismatchReject {
+		return withReason(ErrGPUMismatch, fmt.Errorf("%s", strings.Join(mismatches, "; ")))
+	}
+	log.Printf("GPU units and attributes mismatch: %s", strings.Join(mismatches, "; "))
+	return nil
+}
+
+// CalculateTotalGPUPrice calculates the total GPU price based on the GroupSpec and GPU price mappings.
+// Resources accepting several GPU models, or GPUs without attributes, are priced at the most expensive one.
+func CalculateTotalGPUPrice(gSpec *dtypes.GroupSpec, gpuMappings map[string]float64, maxGPUPrice float64) sdkmath.LegacyDec {
+	totalGPUPrice, _ := calculateGPUPrice(gSpec, gpuMappings, maxGPUPrice, maxGPUPrice, GPUMixedMax)
+	return totalGPUPrice
+}
+
+// CalculateGPUPrice is CalculateTotalGPUPrice at the targets' GPU mappings, with resources accepting several
+// GPU models priced by GPUMixedModels and GPUs without attributes at GPUDefaultTarget. Each such resource is
+// reported in a gpu_mixed_models or gpu_default_price adjustment.
+func CalculateGPUPrice(gSpec *dtypes.GroupSpec, targets PriceTargets) (sdkmath.LegacyDec, []Adjustment) {
+	maxGPUPrice := MaxGPUPrice(targets.GPUMappings)
+	defaultGPUPrice := targets.GPUDefaultTarget
+	if defaultGPUPrice <= 0 {
+		defaultGPUPrice = maxGPUPrice
+	}
+	return calculateGPUPrice(gSpec, targets.GPUMappings, maxGPUPrice, defaultGPUPrice, targets.GPUMixedModels)
+}
+
+// calculateGPUPrice prices the GPUs of a GroupSpec, see CalculateGPUPrice.
+func calculateGPUPrice(gSpec *dtypes.GroupSpec, gpuMappings map[string]float64, maxGPUPrice, defaultGPUPrice float64, mixed string) (sdkmath.LegacyDec, []Adjustment) {
+	// GPUs are summed per price and each price is converted once; multiplying by whole units is exact, so
+	// the total equals pricing every resource unit on its own
+	unitsByPrice := make(map[float64]sdkmath.Int)
+	var adjustments []Adjustment
 
 	for _, resourceUnit := range gSpec.Resources {
 		if resourceUnit.Resources.GPU != nil {
-			count := float64(resourceUnit.Count)
-			gpuUnits := float64(resourceUnit.Resources.GPU.Units.Val.Int64())
-
-			var model, vram, interfaceType string
-			// Parse GPU attributes to extract model, vram, and interface
-			for _, attr := range resourceUnit.Resources.GPU.Attributes {
-				parts := strings.Split(attr.Key, "/")
-				for i, part := range parts {
-					switch part {
-					case "model":
-						if i+1 < len(parts) {
-							model = parts[i+1]
-						}
-					case "ram":
-						if i+1 < len(parts) {
-							vram = parts[i+1]
-						}
-					case "interface":
-						if i+1 < len(parts) {
-							interfaceType = parts[i+1]
-						}
-					}
+			count := int64(resourceUnit.Count)
+			gpuUnits := resourceUnit.Resources.GPU.Units.Val
+
+			// Parse GPU attributes to extract the model, vram and interface of each accepted GPU
+			specs := parseGPUSpecs(resourceUnit.Resources.GPU.Attributes)
+			var price float64
+			switch len(specs) {
+			case 0:
+				// Any GPU satisfies the request, and the provider cannot tell which it will have to supply
+				price = defaultGPUPrice
+				if !gpuUnits.IsZero() {
+					adjustments = append(adjustments, Adjustment{
+						Name:   "gpu_default_price",
+						Detail: fmt.Sprintf("resource %d requests %s GPUs without attributes, priced at the default GPU price %s", resourceUnit.Resources.ID, gpuUnits, strconv.FormatFloat(price, 'f', -1, 64)),
+					})
+				}
+			case 1:
+				price = gpuPrice(specs[0], gpuMappings, maxGPUPrice)
+			default:
+				var adjustment Adjustment
+				price, adjustment = mixedGPUPrice(resourceUnit.Resources.ID, specs, gpuMappings, maxGPUPrice, mixed)
+				if !gpuUnits.IsZero() {
+					adjustments = append(adjustments, adjustment)
 				}
 			}
 
-			// Construct the key for price lookup
-			gpuKey := model
-			if vram != "" {
-				gpuKey += "." + vram
+			units, ok := unitsByPrice[price]
+			if !ok {
+				units = sdkmath.ZeroInt()
 			}
-			if interfaceType != "" {
-				gpuKey += "." + interfaceType
+			unitsByPrice[price] = units.Add(gpuUnits.MulRaw(count))
+			logged := specs
+			if len(logged) == 0 {
+				logged = []gpuSpec{{}}
 			}
-
-			// Find the best price matching the complete key or fallbacks
-			price, found := gpuMappings[gpuKey]
-			if !found && interfaceType != "" {
-				// Try model.vram or model
-				gpuKey = model + "." + vram
-				price, found = gpuMappings[gpuKey]
-				if !found {
-					// Try model only
-					gpuKey = model
-					price, found = gpuMappings[gpuKey]
-					if !found {
-						price = maxGPUPrice
-					}
-				}
+			for _, spec := range logged {
+				log.Printf("GPU Pricing: Model=%s, VRAM=%s, Interface=%s, Units=%s, Count=%d, Price=%f",
+					spec.model, spec.vram, spec.interfaceType, gpuUnits, count, price)
 			}
+		}
+	}
+
+	totalGPUPrice := sdkmath.LegacyZeroDec()
+	for price, units := range unitsByPrice {
+		totalGPUPrice = totalGPUPrice.Add(decFromFloat(price).MulInt(units))
+	}
+	return totalGPUPrice, adjustments
+}
 
-			totalGPUPrice += count * gpuUnits * price
-			log.Printf("GPU Pricing: Model=%s, VRAM=%s, Interface=%s, Units=%f, Price=%f, Total=%f",
-				model, vram, interfaceType, gpuUnits, price, count*gpuUnits*price)
+// gpuPrice returns the monthly price of a GPU from the mappings, trying model.vram.interface, then model.vram
+// and model, and maxGPUPrice when a GPU with an interface matches none.
+func gpuPrice(spec gpuSpec, gpuMappings map[string]float64, maxGPUPrice float64) float64 {
+	// Find the best price matching the complete key or fallbacks
+	price, found := gpuMappings[spec.String()]
+	if !found && spec.interfaceType != "" {
+		// Try model.vram or model
+		price, found = gpuMappings[spec.model+"."+spec.vram]
+		if !found {
+			// Try model only
+			price, found = gpuMappings[spec.model]
+			if !found {
+				price = maxGPUPrice
+			}
 		}
 	}
+	return price
+}
 
-	return totalGPUPrice
+// mixedGPUPrice prices a resource accepting any of several GPUs by mixed, describing the choice in an
+// adjustment.
+func mixedGPUPrice(id uint32, specs []gpuSpec, gpuMappings map[string]float64, maxGPUPrice float64, mixed string) (float64, Adjustment) {
+	names := make([]string, len(specs))
+	var price, sum float64
+	for i, spec := range specs {
+		names[i] = spec.String()
+		specPrice := gpuPrice(spec, gpuMappings, maxGPUPrice)
+		sum += specPrice
+		switch {
+		case i == 0, mixed == GPUMixedMin && specPrice < price, mixed != GPUMixedMin && specPrice > price:
+			price = specPrice
+		}
+	}
+	label := "highest"
+	switch mixed {
+	case GPUMixedMin:
+		label = "lowest"
+	case GPUMixedAvg:
+		price = sum / float64(len(specs))
+		label = "average"
+	}
+	return price, Adjustment{
+		Name:   "gpu_mixed_models",
+		Detail: fmt.Sprintf("resource %d accepts %s, priced at the %s GPU price %s", id, strings.Join(names, " or "), label, strconv.FormatFloat(price, 'f', -1, 64)),
+	}
 }
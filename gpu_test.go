@@ -0,0 +1,135 @@
+package pricing
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+	attrtypes "pkg.akt.dev/go/node/types/attributes/v1"
+	rtypes "pkg.akt.dev/go/node/types/resources/v1beta4"
+)
+
+// FuzzParseGPUPriceMappings checks malformed PRICE_TARGET_GPU_MAPPINGS values are rejected rather than
+// panicking, and that accepted prices convert to decimals.
+func FuzzParseGPUPriceMappings(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"a100=120,h100=250",
+		"rtx4090.24Gi=90,a100.80Gi.sxm4=1.5/hr",
+		"a100=1/mo,,h100=2/hour",
+		"=1",
+		"a100==1",
+		"a100=NaN",
+		"a100=Inf",
+		"a100=-1",
+		"a100=1e300",
+		"a100=1/day",
+	} {
+		f.Add(seed, DefaultHoursPerMonth)
+	}
+
+	f.Fuzz(func(t *testing.T, mappings string, hours float64) {
+		if hours <= 0 || math.IsNaN(hours) || math.IsInf(hours, 0) {
+			t.Skip()
+		}
+		parsed, err := parseGPUPriceMappings(mappings, hours)
+		if err != nil {
+			return
+		}
+		for model, price := range parsed {
+			if model == "" || strings.ContainsAny(model, ",=") {
+				t.Fatalf("%q: invalid model %q", mappings, model)
+			}
+			if price < 0 || checkDecFloat(price) != nil {
+				t.Fatalf("%q: invalid price %g for %s", mappings, price, model)
+			}
+			decFromFloat(price)
+		}
+	})
+}
+
+// FuzzGPUAttributes checks GPU attribute keys of any shape parse into single path segments and price
+// without panicking.
+func FuzzGPUAttributes(f *testing.F) {
+	for _, seed := range []string{
+		"vendor/nvidia/model/rtx4090/ram/24Gi/interface/pcie",
+		"vendor/nvidia/model/a100",
+		"vendor/nvidia/model/",
+		"model",
+		"/model/a100/",
+		"vendor//model//ram",
+		"",
+	} {
+		f.Add(seed, uint64(1), uint32(1))
+	}
+
+	mappings := map[string]float64{"rtx4090.24Gi.pcie": 90, "a100": 120, "a100.80Gi": 150}
+	f.Fuzz(func(t *testing.T, key string, units uint64, count uint32) {
+		attributes := attrtypes.Attributes{{Key: key, Value: "true"}}
+		model, vram, interfaceType := parseGPUAttributes(attributes)
+		for _, part := range []string{model, vram, interfaceType} {
+			if strings.Contains(part, "/") {
+				t.Fatalf("%q: segment %q spans a separator", key, part)
+			}
+		}
+
+		gSpec := &dtypes.GroupSpec{Resources: dtypes.ResourceUnits{{
+			Resources: rtypes.Resources{GPU: &rtypes.GPU{Units: resourceValue(units), Attributes: attributes}},
+			Count:     count,
+		}}}
+		total := CalculateTotalGPUPrice(gSpec, mappings, MaxGPUPrice(mappings))
+		if total.IsNegative() {
+			t.Fatalf("%q: negative GPU price %s", key, total)
+		}
+		GPUModels(gSpec)
+	})
+}
+
+func BenchmarkCalculateTotalGPUPrice(b *testing.B) {
+	restore := isolateEnv(benchEnv)
+	defer restore()
+
+	priceTargets, err := LoadPriceTargets()
+	if err != nil {
+		b.Fatal(err)
+	}
+	gSpec := benchGroupSpec(128)
+	maxGPUPrice := MaxGPUPrice(priceTargets.GPUMappings)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		CalculateTotalGPUPrice(gSpec, priceTargets.GPUMappings, maxGPUPrice)
+	}
+}
+
+func TestGPUMismatches(t *testing.T) {
+	a100 := attrtypes.Attributes{{Key: "vendor/nvidia/model/a100", Value: "true"}}
+	gSpec := &dtypes.GroupSpec{Resources: dtypes.ResourceUnits{
+		{Resources: rtypes.Resources{ID: 1, GPU: &rtypes.GPU{Units: resourceValue(2)}}, Count: 1},
+		{Resources: rtypes.Resources{ID: 2, GPU: &rtypes.GPU{Units: resourceValue(0), Attributes: a100}}, Count: 1},
+		{Resources: rtypes.Resources{ID: 3, GPU: &rtypes.GPU{Units: resourceValue(1), Attributes: a100}}, Count: 1},
+	}}
+
+	mismatches := GPUMismatches(gSpec)
+	want := []string{"resource 1 requests 2 GPUs without attributes", "resource 2 has GPU attributes but no GPU units"}
+	if strings.Join(mismatches, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got mismatches %q, want %q", mismatches, want)
+	}
+	if err := CheckGPUAttributes(gSpec, GPUMismatchDefault); err != nil {
+		t.Fatalf("default mode rejected the mismatch: %v", err)
+	}
+	if err := CheckGPUAttributes(gSpec, GPUMismatchReject); !errors.Is(err, ErrGPUMismatch) {
+		t.Fatalf("got %v, want ErrGPUMismatch", err)
+	}
+
+	// The attribute-less GPUs cost the default price, the attributes without units nothing
+	price, adjustments := CalculateGPUPrice(gSpec, PriceTargets{GPUMappings: map[string]float64{"a100": 120}, GPUDefaultTarget: 90})
+	if want := decFromFloat(2*90 + 120); !price.Equal(want) {
+		t.Errorf("got GPU price %s, want %s", price, want)
+	}
+	if len(adjustments) != 1 || adjustments[0].Name != "gpu_default_price" {
+		t.Errorf("got adjustments %v, want one gpu_default_price", adjustments)
+	}
+}
@@ -0,0 +1,135 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// GroupBid is the pricing outcome of one group of a deployment.
+type GroupBid struct {
+	Name   string
+	Result *BidResult // nil when Err is set
+	Err    error
+}
+
+// DeploymentBid is the outcome of pricing every group of a deployment.
+type DeploymentBid struct {
+	Groups                []GroupBid        // In the order of the specs
+	TotalCostUsd          sdkmath.LegacyDec // Monthly cost of the groups that were priced
+	TotalRatePerBlockUakt sdkmath.LegacyDec // Rate per block of the groups that were priced, in uakt
+	USDPerAKT             float64           // AKT price shared by every group
+}
+
+// groupLookups holds the lookups made once for all the groups of a deployment.
+type groupLookups struct {
+	whitelistEntry *WhitelistEntry
+	whitelistErr   error
+}
+
+// PriceGroups prices every group of a deployment placed by owner, fetching the AKT price and whitelist entry
+// once for all groups. Groups that are rejected carry their error in the result; an error is only returned
+// when no group can be priced, because the AKT price is unavailable or ctx is done.
+func PriceGroups(ctx context.Context, owner string, specs []*dtypes.GroupSpec) (*DeploymentBid, error) {
+	return PriceGroupsWithRequest(ctx, Request{Owner: owner}, specs)
+}
+
+// PriceGroupsWithRequest is PriceGroups with the owner, precision, AKT price override and other request
+// fields taken from base. base.GSpec is ignored.
+func PriceGroupsWithRequest(ctx context.Context, base Request, specs []*dtypes.GroupSpec) (*DeploymentBid, error) {
+	return priceGroups(ctx, base, specs, priceBid)
+}
+
+// PreviewGroups prices every group like PriceGroupsWithRequest, without the side effects of a bid: nothing
+// is recorded in the bid history or the audit log, no webhook is sent, provider bids are not counted, no
+// shadow bid is priced and no order is pinned. Previews such as the price and tune commands use it, so
+// they never feed the history that discounts, exposure caps and pins read.
+func PreviewGroups(ctx context.Context, base Request, specs []*dtypes.GroupSpec) (*DeploymentBid, error) {
+	return priceGroups(ctx, base, specs, calculateBidWithin)
+}
+
+// priceGroups prices every group with price, sharing the AKT price and whitelist lookups.
+func priceGroups(ctx context.Context, base Request, specs []*dtypes.GroupSpec, price func(context.Context, Request) (*BidResult, error)) (bid *DeploymentBid, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ctx, span := startSpan(ctx, spanPriceGroups, stringAttr("akash.owner", base.Owner), intAttr("akash.groups", int64(len(specs))))
+	defer func() { span.End(err) }()
+
+	budget, err := BidDeadlineFromEnv()
+	if err != nil {
+		return nil, withReason(ErrConfig, err)
+	}
+	if budget > 0 {
+		base.lookupDeadline = lookupDeadline(time.Now(), budget)
+	}
+
+	usdPerAkt, aktPriceSource := base.USDPerAKT, base.aktPriceSource
+	if usdPerAkt <= 0 {
+		_, oracleSpan := startSpan(ctx, spanOracle)
+		usdPerAkt, aktPriceSource, err = aktPriceWithin(ctx, base)
+		if err != nil {
+			log.Printf("Error getting AKT price: %v", err)
+			err = withReason(ErrOracle, fmt.Errorf("error getting AKT price: %w", err))
+			oracleSpan.End(err)
+			return nil, err
+		}
+		oracleSpan.SetAttributes(floatAttr("akash.akt_price_usd", usdPerAkt))
+		oracleSpan.End(nil)
+	}
+
+	// Special pricing owners skip the whitelist, so only look it up for everyone else
+	lookups := &groupLookups{}
+	if base.Owner != "" && !SpecialPricing(base.Owner) {
+		_, whitelistSpan := startSpan(ctx, spanWhitelist)
+		lookups.whitelistEntry, lookups.whitelistErr = whitelistEntryWithin(ctx, base)
+		whitelistSpan.End(lookups.whitelistErr)
+	}
+
+	bid = &DeploymentBid{
+		Groups:                make([]GroupBid, 0, len(specs)),
+		TotalCostUsd:          sdkmath.LegacyZeroDec(),
+		TotalRatePerBlockUakt: sdkmath.LegacyZeroDec(),
+		USDPerAKT:             usdPerAkt,
+	}
+	for _, spec := range specs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		request := base
+		request.GSpec = spec
+		request.USDPerAKT, request.aktPriceSource = usdPerAkt, aktPriceSource
+		request.lookups = lookups
+
+		group := GroupBid{}
+		if spec != nil {
+			group.Name = spec.Name
+		}
+		group.Result, group.Err = price(ctx, request)
+		if group.Err == nil {
+			if !group.Result.TotalCostUsd.IsNil() {
+				bid.TotalCostUsd = bid.TotalCostUsd.Add(group.Result.TotalCostUsd)
+			}
+			if !group.Result.RatePerBlockUakt.IsNil() {
+				bid.TotalRatePerBlockUakt = bid.TotalRatePerBlockUakt.Add(group.Result.RatePerBlockUakt)
+			}
+		}
+		bid.Groups = append(bid.Groups, group)
+	}
+	return bid, nil
+}
+
+// lookupWhitelistEntry returns the request owner's whitelist entry, shared across groups when the request
+// comes from PriceGroups. The lookup is cancelled with ctx.
+func (r Request) lookupWhitelistEntry(ctx context.Context) (*WhitelistEntry, error) {
+	if r.lookups != nil {
+		return r.lookups.whitelistEntry, r.lookups.whitelistErr
+	}
+	return LookupWhitelistContext(ctx, WhitelistOptions{Owner: r.Owner})
+}
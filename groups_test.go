@@ -0,0 +1,51 @@
+package pricing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// TestPreviewGroupsHasNoSideEffects checks previews, as priced by the price command, leave the bid history
+// and the audit log untouched, while bids write them.
+func TestPreviewGroupsHasNoSideEffects(t *testing.T) {
+	fixtures, err := LoadFixtures("testdata/fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec *dtypes.GroupSpec
+	for _, fixture := range fixtures {
+		if fixture.Name == "cpu-only" {
+			spec = fixture.GroupSpec
+		}
+	}
+	if spec == nil {
+		t.Fatal("no cpu-only fixture")
+	}
+
+	dir := t.TempDir()
+	auditLog, historyDB := filepath.Join(dir, "audit.jsonl"), filepath.Join(dir, "history.db")
+	restore := isolateEnv(map[string]string{"AKT_PRICE_PIN": "3.5", "AUDIT_LOG": auditLog, "BID_HISTORY_DB": historyDB})
+	defer restore()
+
+	base := Request{Owner: "akash1preview", OrderID: "1/1/1", PricePrecision: 6}
+	bid, err := PreviewGroups(context.Background(), base, []*dtypes.GroupSpec{spec})
+	if err != nil || bid.Groups[0].Err != nil {
+		t.Fatalf("preview failed: %v %v", err, bid.Groups[0].Err)
+	}
+	for _, path := range []string{auditLog, historyDB} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s written by a preview", filepath.Base(path))
+		}
+	}
+
+	if _, err := PriceGroupsWithRequest(context.Background(), base, []*dtypes.GroupSpec{spec}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(auditLog); err != nil {
+		t.Errorf("audit log not written by a bid: %v", err)
+	}
+}
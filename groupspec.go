@@ -0,0 +1,114 @@
+package pricing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	sdkmath "cosmossdk.io/math"
+
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+	"pkg.akt.dev/go/sdl"
+)
+
+// ReadSDLGroupSpecs parses an SDL file and returns the GroupSpecs of its deployment groups, including
+// the max price of each placement.
+func ReadSDLGroupSpecs(path string) ([]*dtypes.GroupSpec, error) {
+	parsed, err := sdl.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading SDL %s: %w", path, err)
+	}
+
+	groups, err := parsed.DeploymentGroups()
+	if err != nil {
+		return nil, fmt.Errorf("error building deployment groups from %s: %w", path, err)
+	}
+
+	specs := make([]*dtypes.GroupSpec, 0, len(groups))
+	for i := range groups {
+		specs = append(specs, asGroupSpec(&groups[i]))
+	}
+	return specs, nil
+}
+
+// asGroupSpec returns the GroupSpec behind an element of the SDL package's group slice, which has held
+// values or pointers depending on the API version.
+func asGroupSpec(v interface{}) *dtypes.GroupSpec {
+	switch g := v.(type) {
+	case *dtypes.GroupSpec:
+		return g
+	case **dtypes.GroupSpec:
+		return *g
+	}
+	return nil
+}
+
+// ReadGroupSpecFile reads a JSON file holding a single GroupSpec or an array of them.
+func ReadGroupSpecFile(path string) ([]*dtypes.GroupSpec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading GroupSpec %s: %w", path, err)
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var specs []*dtypes.GroupSpec
+		if err := json.Unmarshal(trimmed, &specs); err != nil {
+			return nil, fmt.Errorf("error parsing GroupSpecs %s: %w", path, err)
+		}
+		return specs, nil
+	}
+
+	var spec dtypes.GroupSpec
+	if err := json.Unmarshal(trimmed, &spec); err != nil {
+		return nil, fmt.Errorf("error parsing GroupSpec %s: %w", path, err)
+	}
+	return []*dtypes.GroupSpec{&spec}, nil
+}
+
+// ValidateGroupSpec checks that every resource quantity of the GroupSpec is set, non-negative and fits an
+// int64, so malformed input is rejected instead of panicking during pricing.
+func ValidateGroupSpec(gSpec *dtypes.GroupSpec) error {
+	if gSpec == nil {
+		return fmt.Errorf("GroupSpec is nil")
+	}
+
+	// Messages are only built for invalid quantities, as this runs for every resource unit of every bid
+	for i, resourceUnit := range gSpec.Resources {
+		if cpu := resourceUnit.Resources.CPU; cpu != nil {
+			if problem := quantityProblem(cpu.Units.Val); problem != "" {
+				return fmt.Errorf("resource %d: cpu units %s", i, problem)
+			}
+		}
+		if memory := resourceUnit.Resources.Memory; memory != nil {
+			if problem := quantityProblem(memory.Quantity.Val); problem != "" {
+				return fmt.Errorf("resource %d: memory size %s", i, problem)
+			}
+		}
+		if gpu := resourceUnit.Resources.GPU; gpu != nil {
+			if problem := quantityProblem(gpu.Units.Val); problem != "" {
+				return fmt.Errorf("resource %d: gpu units %s", i, problem)
+			}
+		}
+		for _, storage := range resourceUnit.Resources.Storage {
+			if problem := quantityProblem(storage.Quantity.Val); problem != "" {
+				return fmt.Errorf("resource %d: storage %s size %s", i, storage.Name, problem)
+			}
+		}
+	}
+	return nil
+}
+
+// quantityProblem describes why a resource quantity is invalid, or returns "" if it is valid.
+func quantityProblem(value sdkmath.Int) string {
+	switch {
+	case value.IsNil():
+		return "is not set"
+	case value.IsNegative():
+		return "is negative"
+	case !value.IsInt64():
+		return "is out of range"
+	}
+	return ""
+}
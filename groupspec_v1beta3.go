@@ -0,0 +1,97 @@
+package pricing
+
+import (
+	dv1beta3 "pkg.akt.dev/go/node/deployment/v1beta3"
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+	attrtypes "pkg.akt.dev/go/node/types/attributes/v1"
+	rtypes "pkg.akt.dev/go/node/types/resources/v1beta4"
+	tv1beta3 "pkg.akt.dev/go/node/types/v1beta3"
+)
+
+// GroupSpecFromV1beta3 converts a v1beta3 GroupSpec, as sent by providers that have not migrated to the
+// v1beta4 API yet, into the v1beta4 GroupSpec the pricing pipeline works on.
+func GroupSpecFromV1beta3(gSpec *dv1beta3.GroupSpec) *dtypes.GroupSpec {
+	if gSpec == nil {
+		return nil
+	}
+
+	converted := &dtypes.GroupSpec{
+		Name: gSpec.Name,
+		Requirements: attrtypes.PlacementRequirements{
+			SignedBy: attrtypes.SignedBy{
+				AllOf: gSpec.Requirements.SignedBy.AllOf,
+				AnyOf: gSpec.Requirements.SignedBy.AnyOf,
+			},
+			Attributes: attributesFromV1beta3(gSpec.Requirements.Attributes),
+		},
+		Resources: make(dtypes.ResourceUnits, 0, len(gSpec.Resources)),
+	}
+
+	for _, resourceUnit := range gSpec.Resources {
+		converted.Resources = append(converted.Resources, dtypes.ResourceUnit{
+			Resources: resourcesFromV1beta3(resourceUnit.Resources),
+			Count:     resourceUnit.Count,
+			Price:     resourceUnit.Price,
+		})
+	}
+	return converted
+}
+
+// RequestFromV1beta3 builds a Request for a v1beta3 GroupSpec.
+func RequestFromV1beta3(owner string, gSpec *dv1beta3.GroupSpec, pricePrecision int) Request {
+	return Request{
+		Owner:          owner,
+		GSpec:          GroupSpecFromV1beta3(gSpec),
+		PricePrecision: pricePrecision,
+	}
+}
+
+// resourcesFromV1beta3 converts the resources of a v1beta3 resource unit.
+func resourcesFromV1beta3(resources tv1beta3.Resources) rtypes.Resources {
+	converted := rtypes.Resources{ID: resources.ID}
+
+	if resources.CPU != nil {
+		converted.CPU = &rtypes.CPU{
+			Units:      rtypes.ResourceValue{Val: resources.CPU.Units.Val},
+			Attributes: attributesFromV1beta3(resources.CPU.Attributes),
+		}
+	}
+	if resources.Memory != nil {
+		converted.Memory = &rtypes.Memory{
+			Quantity:   rtypes.ResourceValue{Val: resources.Memory.Quantity.Val},
+			Attributes: attributesFromV1beta3(resources.Memory.Attributes),
+		}
+	}
+	if resources.GPU != nil {
+		converted.GPU = &rtypes.GPU{
+			Units:      rtypes.ResourceValue{Val: resources.GPU.Units.Val},
+			Attributes: attributesFromV1beta3(resources.GPU.Attributes),
+		}
+	}
+	for _, storage := range resources.Storage {
+		converted.Storage = append(converted.Storage, rtypes.Storage{
+			Name:       storage.Name,
+			Quantity:   rtypes.ResourceValue{Val: storage.Quantity.Val},
+			Attributes: attributesFromV1beta3(storage.Attributes),
+		})
+	}
+	for _, endpoint := range resources.Endpoints {
+		converted.Endpoints = append(converted.Endpoints, rtypes.Endpoint{
+			Kind:           rtypes.Endpoint_Kind(endpoint.Kind), // Kinds share their values across versions
+			SequenceNumber: endpoint.SequenceNumber,
+		})
+	}
+	return converted
+}
+
+// attributesFromV1beta3 converts v1beta3 attributes.
+func attributesFromV1beta3(attributes tv1beta3.Attributes) attrtypes.Attributes {
+	if attributes == nil {
+		return nil
+	}
+	converted := make(attrtypes.Attributes, 0, len(attributes))
+	for _, attr := range attributes {
+		converted = append(converted, attrtypes.Attribute{Key: attr.Key, Value: attr.Value})
+	}
+	return converted
+}
@@ -0,0 +1,93 @@
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// Ceiling actions for bids above BidGuards.CeilingUsdMonthly.
+const (
+	CeilingActionReject = "reject"
+	CeilingActionCap    = "cap"
+)
+
+// BidGuards bound the computed bid so misconfigured targets or bad oracle data can't produce absurd bids.
+// A zero value disables the corresponding guard.
+type BidGuards struct {
+	FloorUakt         float64 // Minimum rate per block in uakt
+	CeilingUsdMonthly float64 // Maximum monthly cost in USD
+	CeilingAction     string  // CeilingActionReject or CeilingActionCap
+}
+
+// NewBidGuardsFromEnv reads BID_FLOOR_UAKT, BID_CEILING_USD_MONTHLY and BID_CEILING_ACTION.
+func NewBidGuardsFromEnv() (BidGuards, error) {
+	guards := BidGuards{CeilingAction: CeilingActionReject}
+
+	for env, target := range map[string]*float64{
+		"BID_FLOOR_UAKT":          &guards.FloorUakt,
+		"BID_CEILING_USD_MONTHLY": &guards.CeilingUsdMonthly,
+	} {
+		val := strings.TrimSpace(os.Getenv(env))
+		if val == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil || f < 0 {
+			return BidGuards{}, fmt.Errorf("invalid %s: %q", env, val)
+		}
+		*target = f
+	}
+
+	if action := strings.ToLower(strings.TrimSpace(os.Getenv("BID_CEILING_ACTION"))); action != "" {
+		if action != CeilingActionReject && action != CeilingActionCap {
+			return BidGuards{}, fmt.Errorf("invalid BID_CEILING_ACTION %q: must be %s or %s", action, CeilingActionReject, CeilingActionCap)
+		}
+		guards.CeilingAction = action
+	}
+
+	return guards, nil
+}
+
+// ApplyCeiling rejects or caps a monthly USD cost above the ceiling. The returned adjustment is nil if
+// the guard was not triggered.
+func (g BidGuards) ApplyCeiling(totalCostUsd sdkmath.LegacyDec) (sdkmath.LegacyDec, *Adjustment, error) {
+	if g.CeilingUsdMonthly <= 0 {
+		return totalCostUsd, nil, nil
+	}
+
+	ceiling := decFromFloat(g.CeilingUsdMonthly)
+	if !totalCostUsd.GT(ceiling) {
+		return totalCostUsd, nil, nil
+	}
+
+	if g.CeilingAction == CeilingActionCap {
+		return ceiling, &Adjustment{
+			Name:   "ceiling",
+			Detail: fmt.Sprintf("monthly cost %s USD capped at %s USD", FormatDec(totalCostUsd, 2), FormatDec(ceiling, 2)),
+		}, nil
+	}
+	return sdkmath.LegacyDec{}, nil, withReason(ErrBidCeiling, fmt.Errorf("monthly cost %s USD exceeds bid ceiling %s USD", FormatDec(totalCostUsd, 2), FormatDec(ceiling, 2)))
+}
+
+// ApplyFloor raises per-block rates below the floor. The USD rate is raised to the floor's value at
+// usdPerAkt, so USD-pegged denoms, whose bids are converted from the USD rate, get the floor too, even when
+// the uakt rate was zero. The returned adjustment is nil if the guard was not triggered.
+func (g BidGuards) ApplyFloor(ratePerBlockUakt, ratePerBlockUsd, usdPerAkt sdkmath.LegacyDec) (sdkmath.LegacyDec, sdkmath.LegacyDec, *Adjustment) {
+	if g.FloorUakt <= 0 {
+		return ratePerBlockUakt, ratePerBlockUsd, nil
+	}
+
+	floor := decFromFloat(g.FloorUakt)
+	if !ratePerBlockUakt.LT(floor) {
+		return ratePerBlockUakt, ratePerBlockUsd, nil
+	}
+
+	return floor, floor.Mul(usdPerAkt).QuoInt64(1000000), &Adjustment{
+		Name:   "floor",
+		Detail: fmt.Sprintf("rate %s uakt/block raised to floor %s uakt/block", FormatDec(ratePerBlockUakt, 6), FormatDec(floor, 6)),
+	}
+}
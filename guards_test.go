@@ -0,0 +1,88 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// usdcDenom is one of the USD-pegged denoms of DefaultDenomRegistry.
+const usdcDenom = "ibc/170C677610AC31DF0904FFE09CD3B5C657492170E7E52372E48756B71E56F2F1"
+
+func TestBidGuardsApplyFloor(t *testing.T) {
+	usdPerAkt := sdkmath.LegacyMustNewDecFromStr("3.5")
+	tests := []struct {
+		name      string
+		floor     float64
+		uakt, usd string
+		wantUakt  string
+		wantUsd   string
+		wantUusdc string // Rate of a USDC bid priced from the guarded USD rate
+		triggered bool
+	}{
+		{"disabled", 0, "10", "0.000035", "10", "0.000035", "35", false},
+		{"above the floor", 100, "150", "0.000525", "150", "0.000525", "525", false},
+		{"at the floor", 100, "100", "0.00035", "100", "0.00035", "350", false},
+		{"below the floor", 100, "40", "0.00014", "100", "0.00035", "350", true},
+		{"zero rate", 100, "0", "0", "100", "0.00035", "350", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guards := BidGuards{FloorUakt: tt.floor}
+			uakt, usd, adjustment := guards.ApplyFloor(sdkmath.LegacyMustNewDecFromStr(tt.uakt), sdkmath.LegacyMustNewDecFromStr(tt.usd), usdPerAkt)
+			if (adjustment != nil) != tt.triggered {
+				t.Errorf("got adjustment %v, want triggered %v", adjustment, tt.triggered)
+			}
+			if adjustment != nil && adjustment.Name != "floor" {
+				t.Errorf("got adjustment %s, want floor", adjustment.Name)
+			}
+			if !uakt.Equal(sdkmath.LegacyMustNewDecFromStr(tt.wantUakt)) || !usd.Equal(sdkmath.LegacyMustNewDecFromStr(tt.wantUsd)) {
+				t.Errorf("got %s uakt, %s USD, want %s uakt, %s USD", uakt, usd, tt.wantUakt, tt.wantUsd)
+			}
+			uusdc, err := DefaultDenomRegistry().RatePerBlock(usdcDenom, uakt, usd)
+			if err != nil || !uusdc.Equal(sdkmath.LegacyMustNewDecFromStr(tt.wantUusdc)) {
+				t.Errorf("got %s USDC base units, %v, want %s", uusdc, err, tt.wantUusdc)
+			}
+		})
+	}
+}
+
+func TestBidGuardsApplyCeiling(t *testing.T) {
+	tests := []struct {
+		name      string
+		ceiling   float64
+		action    string
+		cost      string
+		want      string
+		triggered bool
+		rejected  bool
+	}{
+		{"disabled", 0, CeilingActionReject, "5000", "5000", false, false},
+		{"below the ceiling", 1000, CeilingActionReject, "999.99", "999.99", false, false},
+		{"at the ceiling", 1000, CeilingActionReject, "1000", "1000", false, false},
+		{"rejected above the ceiling", 1000, CeilingActionReject, "1000.01", "", false, true},
+		{"capped above the ceiling", 1000, CeilingActionCap, "1500", "1000", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guards := BidGuards{CeilingUsdMonthly: tt.ceiling, CeilingAction: tt.action}
+			cost, adjustment, err := guards.ApplyCeiling(sdkmath.LegacyMustNewDecFromStr(tt.cost))
+			if tt.rejected {
+				if !errors.Is(err, ErrBidCeiling) {
+					t.Errorf("got %v, want a bid ceiling rejection", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (adjustment != nil) != tt.triggered {
+				t.Errorf("got adjustment %v, want triggered %v", adjustment, tt.triggered)
+			}
+			if !cost.Equal(sdkmath.LegacyMustNewDecFromStr(tt.want)) {
+				t.Errorf("got %s USD, want %s", cost, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package pricing
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBidHistoryRetention is how long bid records are kept when BID_HISTORY_RETENTION is unset.
+const DefaultBidHistoryRetention = 90 * 24 * time.Hour
+
+// bidHistoryPruneInterval is how often a shared bid history deletes the records older than the retention.
+const bidHistoryPruneInterval = time.Hour
+
+// BidRecord is one priced request as stored in the bid history.
+type BidRecord struct {
+	Time         time.Time
+	Owner        string
+	OrderID      string // dseq/gseq/oseq when the provider supplies it
+	CPUCores     string
+	MemoryGB     string
+	GPUs         int64
+	GPUModels    []string
+	StorageGB    string
+	TotalCostUsd string // Monthly cost, empty if pricing stopped before it was computed
+	Price        string // Bid rate per block, empty for rejected requests
+	Denom        string
+	Precision    int     // Decimal places of Price
+	USDPerAKT    float64 // AKT price the bid was converted with
+	Profile      string
+	Accepted     bool
+	Reason       string // Rejection reason
+	Adjustments  []Adjustment
+	Coupon       string // Promo code applied to the bid, counted against its uses once the lease is won
+}
+
+// BidHistoryStore persists bid records and their win/loss outcomes.
+type BidHistoryStore interface {
+	Record(record BidRecord) error
+	RecordOutcome(outcome BidOutcome) error
+	// OutcomeRows returns the latest accepted bid of every order with a recorded outcome.
+	OutcomeRows() ([]OutcomeRow, error)
+	// OwnerLeases returns the orders of an owner whose bid won the lease.
+	OwnerLeases(owner string) ([]OwnerLease, error)
+	// WonBids returns the latest accepted bid of every order whose bid won the lease.
+	WonBids() ([]BidRecord, error)
+	// CouponUses counts the orders won with a bid that applied the promo code.
+	CouponUses(code string) (int, error)
+	// PinnedBid returns the latest accepted bid of an owner's order recorded at or after since, or nil.
+	PinnedBid(owner, orderID string, since time.Time) (*BidRecord, error)
+	// Prune deletes the bids and outcomes recorded before cutoff.
+	Prune(cutoff time.Time) error
+	Close() error
+}
+
+// NewBidRecord builds the history record of a pricing outcome. result may be nil for rejected requests.
+func NewBidRecord(request Request, result *BidResult, bidErr error, now time.Time) BidRecord {
+	record := BidRecord{
+		Time:     now,
+		Owner:    request.Owner,
+		OrderID:  request.OrderID,
+		Accepted: bidErr == nil,
+	}
+	if bidErr != nil {
+		record.Reason = bidErr.Error()
+	}
+
+	if request.GSpec != nil {
+		resources := CalculateRequestedResources(request.GSpec)
+		record.CPUCores = resources.CPURequested.String()
+		record.MemoryGB = resources.MemoryRequested.String()
+		record.GPUs = resources.GPUsRequested
+		record.GPUModels = GPUModels(request.GSpec)
+		record.StorageGB = resources.TotalStorageGB().String()
+	}
+
+	if result != nil {
+		record.Price = result.Price
+		record.Denom = result.Denom
+		record.Precision = result.Precision
+		record.USDPerAKT = result.USDPerAKT
+		record.Profile = result.Profile
+		record.Adjustments = result.Adjustments
+		record.Coupon = result.Coupon
+		if !result.TotalCostUsd.IsNil() {
+			record.TotalCostUsd = FormatDec(result.TotalCostUsd, 6)
+		}
+	}
+	return record
+}
+
+// bidHistoryRetention reads BID_HISTORY_RETENTION (a Go duration or days such as "30d").
+func bidHistoryRetention() time.Duration {
+	val := strings.TrimSpace(os.Getenv("BID_HISTORY_RETENTION"))
+	if val == "" {
+		return DefaultBidHistoryRetention
+	}
+	retention, err := parseDays(val)
+	if err != nil || retention <= 0 {
+		log.Printf("Invalid BID_HISTORY_RETENTION %q, using %s", val, DefaultBidHistoryRetention)
+		return DefaultBidHistoryRetention
+	}
+	return retention
+}
+
+// BidPinWindowFromEnv returns BID_HISTORY_PIN_WINDOW (a Go duration or days such as "1d"), how long the
+// bid of an order is repeated to retries of the same order. 0, the default, prices every retry afresh.
+func BidPinWindowFromEnv() (time.Duration, error) {
+	val := strings.TrimSpace(os.Getenv("BID_HISTORY_PIN_WINDOW"))
+	if val == "" {
+		return 0, nil
+	}
+	window, err := parseDays(val)
+	if err != nil || window < 0 {
+		return 0, fmt.Errorf("invalid BID_HISTORY_PIN_WINDOW %q: must be a duration such as 10m or days such as 1d", val)
+	}
+	return window, nil
+}
+
+// pinnedBid returns the bid a retried request repeats: the latest accepted bid of the request's order
+// within BID_HISTORY_PIN_WINDOW, in the request's denom and precision. It returns nil for requests without
+// an OrderID and when the history cannot be read, so the request is priced afresh.
+func pinnedBid(request Request) *BidRecord {
+	path := os.Getenv("BID_HISTORY_DB")
+	if request.OrderID == "" || path == "" {
+		return nil
+	}
+	window, err := BidPinWindowFromEnv()
+	if err != nil {
+		warnEnvProblem(err)
+		return nil
+	}
+	if window == 0 {
+		return nil
+	}
+
+	store, err := sharedBidHistory(path)
+	if err != nil {
+		log.Printf("Error opening bid history: %v", err)
+		return nil
+	}
+	record, err := store.PinnedBid(request.Owner, request.OrderID, time.Now().Add(-window))
+	if err != nil {
+		log.Printf("Error reading the pinned bid of order %s: %v", request.OrderID, err)
+		return nil
+	}
+	// Bids recorded before the AKT price was kept cannot be repriced
+	if record == nil || record.USDPerAKT <= 0 {
+		return nil
+	}
+	if denom, precision := requestDenomPrecision(request); record.Denom != denom || record.Precision != precision {
+		return nil
+	}
+	return record
+}
+
+// repeatPinnedBid gives result, repriced at the AKT price of the pinned bid, the pinned bid's price. They
+// only differ when the configuration changed in between, which is listed as an order_pin adjustment.
+func repeatPinnedBid(result *BidResult, pinned *BidRecord) {
+	if result.Price == pinned.Price {
+		return
+	}
+	result.Adjustments = append(result.Adjustments, Adjustment{
+		Name:   "order_pin",
+		Detail: fmt.Sprintf("price %s of the bid at %s repeated, repriced at %s", pinned.Price, pinned.Time.UTC().Format(time.RFC3339), result.Price),
+	})
+	result.Price = pinned.Price
+}
+
+// recordBid stores the outcome in the history database named by BID_HISTORY_DB, if set.
+// Failures are logged and never affect the bid.
+func recordBid(request Request, result *BidResult, bidErr error) {
+	path := os.Getenv("BID_HISTORY_DB")
+	if path == "" {
+		return
+	}
+
+	store, err := sharedBidHistory(path)
+	if err != nil {
+		log.Printf("Error opening bid history: %v", err)
+		return
+	}
+	if err := store.Record(NewBidRecord(request, result, bidErr, time.Now())); err != nil {
+		log.Printf("Error recording bid history: %v", err)
+	}
+}
+
+// bidHistories holds the stores opened by sharedBidHistory, by path.
+var bidHistories struct {
+	mu     sync.Mutex
+	stores map[string]BidHistoryStore
+}
+
+// sharedBidHistory returns the process's store of the bid history at path, opening it on first use. Bids
+// share its connections rather than opening the database, migrating it and pruning it on every lookup, and
+// records older than BID_HISTORY_RETENTION are pruned every bidHistoryPruneInterval instead. The store is
+// never closed; failures to open it are not kept, so the next bid tries again.
+func sharedBidHistory(path string) (BidHistoryStore, error) {
+	bidHistories.mu.Lock()
+	defer bidHistories.mu.Unlock()
+	if store, ok := bidHistories.stores[path]; ok {
+		return store, nil
+	}
+	store, err := OpenBidHistory(path, bidHistoryRetention())
+	if err != nil {
+		return nil, err
+	}
+	if bidHistories.stores == nil {
+		bidHistories.stores = make(map[string]BidHistoryStore)
+	}
+	bidHistories.stores[path] = store
+	go pruneBidHistory(store, bidHistoryPruneInterval)
+	return store, nil
+}
+
+// pruneBidHistory deletes the records of store older than BID_HISTORY_RETENTION every interval.
+func pruneBidHistory(store BidHistoryStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := store.Prune(time.Now().Add(-bidHistoryRetention())); err != nil {
+			log.Printf("Error pruning bid history: %v", err)
+		}
+	}
+}
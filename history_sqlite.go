@@ -0,0 +1,246 @@
+//go:build sqlite
+
+package pricing
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // Pure Go driver, keeps the binary static
+)
+
+// bidHistorySchema creates the bid history tables.
+const bidHistorySchema = `
+CREATE TABLE IF NOT EXISTS bids (
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at      INTEGER NOT NULL,
+	owner           TEXT NOT NULL,
+	order_id        TEXT NOT NULL DEFAULT '',
+	cpu_cores       TEXT NOT NULL DEFAULT '',
+	memory_gb       TEXT NOT NULL DEFAULT '',
+	gpus            INTEGER NOT NULL DEFAULT 0,
+	gpu_models      TEXT NOT NULL DEFAULT '',
+	storage_gb      TEXT NOT NULL DEFAULT '',
+	total_cost_usd  TEXT NOT NULL DEFAULT '',
+	price           TEXT NOT NULL DEFAULT '',
+	denom           TEXT NOT NULL DEFAULT '',
+	profile         TEXT NOT NULL DEFAULT '',
+	accepted        INTEGER NOT NULL,
+	reason          TEXT NOT NULL DEFAULT '',
+	adjustments     TEXT NOT NULL DEFAULT '[]',
+	coupon          TEXT NOT NULL DEFAULT '',
+	price_precision INTEGER NOT NULL DEFAULT 0,
+	usd_per_akt     REAL NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS bids_created_at ON bids (created_at);
+CREATE INDEX IF NOT EXISTS bids_order_id ON bids (order_id);
+CREATE TABLE IF NOT EXISTS outcomes (
+	order_id    TEXT PRIMARY KEY,
+	won         INTEGER NOT NULL,
+	recorded_at INTEGER NOT NULL
+);
+`
+
+// SQLiteBidHistory stores bid records in an SQLite database.
+type SQLiteBidHistory struct {
+	db *sql.DB
+}
+
+// OpenBidHistory opens (creating if needed and migrating) the SQLite bid history at path and deletes records
+// older than retention. Pricing shares one store per process, see sharedBidHistory.
+func OpenBidHistory(path string, retention time.Duration) (BidHistoryStore, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("error opening bid history %s: %w", path, err)
+	}
+	if _, err := db.Exec(bidHistorySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating bid history schema in %s: %w", path, err)
+	}
+
+	// Databases created before GPU models, coupons or the AKT price were recorded lack the columns
+	for _, column := range []string{
+		"gpu_models TEXT NOT NULL DEFAULT ''",
+		"coupon TEXT NOT NULL DEFAULT ''",
+		"price_precision INTEGER NOT NULL DEFAULT 0",
+		"usd_per_akt REAL NOT NULL DEFAULT 0",
+	} {
+		if _, err := db.Exec(`ALTER TABLE bids ADD COLUMN ` + column); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			db.Close()
+			return nil, fmt.Errorf("error migrating bid history %s: %w", path, err)
+		}
+	}
+
+	// One connection serializes the process's writers, rather than having them fail with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	store := &SQLiteBidHistory{db: db}
+	if retention > 0 {
+		if err := store.Prune(time.Now().Add(-retention)); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("error pruning bid history %s: %w", path, err)
+		}
+	}
+	return store, nil
+}
+
+// Prune deletes the bids and outcomes recorded before cutoff.
+func (h *SQLiteBidHistory) Prune(cutoff time.Time) error {
+	if _, err := h.db.Exec(`DELETE FROM bids WHERE created_at < ?`, cutoff.Unix()); err != nil {
+		return err
+	}
+	_, err := h.db.Exec(`DELETE FROM outcomes WHERE recorded_at < ?`, cutoff.Unix())
+	return err
+}
+
+// Record inserts a bid record.
+func (h *SQLiteBidHistory) Record(record BidRecord) error {
+	adjustments, err := json.Marshal(record.Adjustments)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.db.Exec(`INSERT INTO bids (created_at, owner, order_id, cpu_cores, memory_gb, gpus, gpu_models, storage_gb,
+		total_cost_usd, price, denom, profile, accepted, reason, adjustments, coupon, price_precision, usd_per_akt)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.Time.Unix(), record.Owner, record.OrderID, record.CPUCores, record.MemoryGB, record.GPUs,
+		strings.Join(record.GPUModels, ","), record.StorageGB, record.TotalCostUsd, record.Price, record.Denom,
+		record.Profile, record.Accepted, record.Reason, string(adjustments), record.Coupon, record.Precision,
+		record.USDPerAKT)
+	return err
+}
+
+// RecordOutcome stores whether the bid for an order won the lease, replacing any earlier outcome.
+func (h *SQLiteBidHistory) RecordOutcome(outcome BidOutcome) error {
+	_, err := h.db.Exec(`INSERT INTO outcomes (order_id, won, recorded_at) VALUES (?, ?, ?)
+		ON CONFLICT (order_id) DO UPDATE SET won = excluded.won, recorded_at = excluded.recorded_at`,
+		outcome.OrderID, outcome.Won, outcome.Time.Unix())
+	return err
+}
+
+// OutcomeRows joins outcomes with the latest accepted bid of each order.
+func (h *SQLiteBidHistory) OutcomeRows() ([]OutcomeRow, error) {
+	rows, err := h.db.Query(`SELECT o.order_id, b.gpu_models, b.total_cost_usd, o.won
+		FROM outcomes o
+		JOIN bids b ON b.id = (SELECT MAX(id) FROM bids WHERE order_id = o.order_id AND accepted = 1)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []OutcomeRow
+	for rows.Next() {
+		var row OutcomeRow
+		var gpuModels string
+		if err := rows.Scan(&row.OrderID, &gpuModels, &row.TotalCostUsd, &row.Won); err != nil {
+			return nil, err
+		}
+		if gpuModels != "" {
+			row.GPUModels = strings.Split(gpuModels, ",")
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// OwnerLeases returns the won orders of an owner, joined with the latest accepted bid of each.
+func (h *SQLiteBidHistory) OwnerLeases(owner string) ([]OwnerLease, error) {
+	rows, err := h.db.Query(`SELECT o.order_id, b.total_cost_usd, o.recorded_at
+		FROM outcomes o
+		JOIN bids b ON b.id = (SELECT MAX(id) FROM bids WHERE order_id = o.order_id AND accepted = 1)
+		WHERE o.won = 1 AND b.owner = ?
+		ORDER BY o.recorded_at`, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []OwnerLease
+	for rows.Next() {
+		var lease OwnerLease
+		var recordedAt int64
+		if err := rows.Scan(&lease.OrderID, &lease.TotalCostUsd, &recordedAt); err != nil {
+			return nil, err
+		}
+		lease.Time = time.Unix(recordedAt, 0)
+		result = append(result, lease)
+	}
+	return result, rows.Err()
+}
+
+// WonBids returns the latest accepted bid of every won order, oldest first.
+func (h *SQLiteBidHistory) WonBids() ([]BidRecord, error) {
+	rows, err := h.db.Query(`SELECT b.created_at, b.owner, b.order_id, b.cpu_cores, b.memory_gb, b.gpus, b.gpu_models,
+		b.storage_gb, b.total_cost_usd, b.price, b.denom, b.profile, b.coupon
+		FROM outcomes o
+		JOIN bids b ON b.id = (SELECT MAX(id) FROM bids WHERE order_id = o.order_id AND accepted = 1)
+		WHERE o.won = 1
+		ORDER BY b.created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []BidRecord
+	for rows.Next() {
+		record := BidRecord{Accepted: true}
+		var createdAt int64
+		var gpuModels string
+		if err := rows.Scan(&createdAt, &record.Owner, &record.OrderID, &record.CPUCores, &record.MemoryGB, &record.GPUs,
+			&gpuModels, &record.StorageGB, &record.TotalCostUsd, &record.Price, &record.Denom, &record.Profile,
+			&record.Coupon); err != nil {
+			return nil, err
+		}
+		record.Time = time.Unix(createdAt, 0)
+		if gpuModels != "" {
+			record.GPUModels = strings.Split(gpuModels, ",")
+		}
+		result = append(result, record)
+	}
+	return result, rows.Err()
+}
+
+// CouponUses counts the won orders whose latest accepted bid applied the promo code.
+func (h *SQLiteBidHistory) CouponUses(code string) (int, error) {
+	var uses int
+	err := h.db.QueryRow(`SELECT COUNT(*)
+		FROM outcomes o
+		JOIN bids b ON b.id = (SELECT MAX(id) FROM bids WHERE order_id = o.order_id AND accepted = 1)
+		WHERE o.won = 1 AND b.coupon = ?`, code).Scan(&uses)
+	return uses, err
+}
+
+// PinnedBid returns the latest accepted bid of an owner's order created at or after since, or nil.
+func (h *SQLiteBidHistory) PinnedBid(owner, orderID string, since time.Time) (*BidRecord, error) {
+	record := BidRecord{Owner: owner, OrderID: orderID, Accepted: true}
+	var createdAt int64
+	var gpuModels, adjustments string
+	err := h.db.QueryRow(`SELECT created_at, cpu_cores, memory_gb, gpus, gpu_models, storage_gb, total_cost_usd, price,
+		denom, profile, adjustments, coupon, price_precision, usd_per_akt
+		FROM bids
+		WHERE owner = ? AND order_id = ? AND accepted = 1 AND created_at >= ?
+		ORDER BY id DESC LIMIT 1`, owner, orderID, since.Unix()).Scan(&createdAt, &record.CPUCores, &record.MemoryGB,
+		&record.GPUs, &gpuModels, &record.StorageGB, &record.TotalCostUsd, &record.Price, &record.Denom, &record.Profile,
+		&adjustments, &record.Coupon, &record.Precision, &record.USDPerAKT)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	record.Time = time.Unix(createdAt, 0)
+	if gpuModels != "" {
+		record.GPUModels = strings.Split(gpuModels, ",")
+	}
+	if err := json.Unmarshal([]byte(adjustments), &record.Adjustments); err != nil {
+		return nil, fmt.Errorf("invalid adjustments of order %s: %w", orderID, err)
+	}
+	return &record, nil
+}
+
+// Close closes the database.
+func (h *SQLiteBidHistory) Close() error {
+	return h.db.Close()
+}
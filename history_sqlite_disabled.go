@@ -0,0 +1,13 @@
+//go:build !sqlite
+
+package pricing
+
+import (
+	"fmt"
+	"time"
+)
+
+// OpenBidHistory reports that the bid history needs a build with the sqlite tag.
+func OpenBidHistory(path string, retention time.Duration) (BidHistoryStore, error) {
+	return nil, fmt.Errorf("bid history %s requires a build with -tags sqlite", path)
+}
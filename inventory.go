@@ -0,0 +1,404 @@
+package pricing
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// GPUInventoryCacheFile caches the GPU inventory discovered from Kubernetes between bid script runs.
+const GPUInventoryCacheFile = "/tmp/gpu-inventory.cache"
+
+// DefaultGPUInventoryTTL is how long a discovered GPU inventory is used when GPU_INVENTORY_TTL is unset.
+const DefaultGPUInventoryTTL = 5 * time.Minute
+
+// In-cluster service account credentials of the Kubernetes API.
+const (
+	serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceAccountCAFile    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// Node labels GPUs are discovered from.
+const (
+	akashGPULabelPrefix   = "akash.network/capabilities.gpu.vendor."
+	nvidiaGPUProductLabel = "nvidia.com/gpu.product"
+	nvidiaGPUCountLabel   = "nvidia.com/gpu.count"
+	nvidiaGPUResource     = "nvidia.com/gpu"
+)
+
+// GPUInventory counts the GPUs of the provider's cluster by model, e.g. {"a100": 16, "rtx4090": 4}.
+type GPUInventory map[string]int64
+
+// GPUInventoryProvider reports the GPUs of the provider's cluster.
+type GPUInventoryProvider interface {
+	GPUInventory() (GPUInventory, error)
+}
+
+// StaticGPUInventory is a fixed inventory, for providers outside Kubernetes.
+type StaticGPUInventory GPUInventory
+
+// GPUInventory returns the fixed inventory.
+func (i StaticGPUInventory) GPUInventory() (GPUInventory, error) {
+	return GPUInventory(i), nil
+}
+
+// KubernetesGPUInventory discovers the GPUs of the schedulable nodes of a Kubernetes cluster from their
+// labels: the akash.network/capabilities.gpu.vendor.<vendor>.model.<model> labels of the Akash inventory
+// operator, or else the nvidia.com/gpu.product and nvidia.com/gpu.count labels of NVIDIA GPU feature
+// discovery. Discovered inventories are cached in CacheFile for TTL.
+type KubernetesGPUInventory struct {
+	APIURL       string // Kubernetes API server
+	Token        string // Bearer token, empty for an authenticating proxy such as kubectl proxy
+	CAFile       string // CA of the API server, system roots if empty
+	NodeSelector string // Label selector of the nodes leased to tenants, all nodes if empty
+	CacheFile    string
+	TTL          time.Duration
+}
+
+// NewGPUInventoryProviderFromEnv returns the inventory configured by GPU_INVENTORY, or nil if it is unset.
+// GPU_INVENTORY=kubernetes discovers the GPUs of the cluster the provider runs in, with its service account,
+// from the nodes matching GPU_INVENTORY_NODE_SELECTOR, refreshed every GPU_INVENTORY_TTL. KUBERNETES_API_URL
+// points discovery at another API server, such as a local kubectl proxy. Any other value is a fixed
+// inventory such as "a100=16,rtx4090=4".
+func NewGPUInventoryProviderFromEnv() (GPUInventoryProvider, error) {
+	value := strings.TrimSpace(os.Getenv("GPU_INVENTORY"))
+	switch value {
+	case "":
+		return nil, nil
+	case "kubernetes":
+		ttl := DefaultGPUInventoryTTL
+		if val := os.Getenv("GPU_INVENTORY_TTL"); val != "" {
+			var err error
+			if ttl, err = time.ParseDuration(val); err != nil || ttl <= 0 {
+				return nil, fmt.Errorf("invalid GPU_INVENTORY_TTL %q", val)
+			}
+		}
+		inventory := &KubernetesGPUInventory{
+			APIURL:       strings.TrimRight(os.Getenv("KUBERNETES_API_URL"), "/"),
+			NodeSelector: os.Getenv("GPU_INVENTORY_NODE_SELECTOR"),
+			CacheFile:    GPUInventoryCacheFile,
+			TTL:          ttl,
+		}
+		if inventory.APIURL == "" {
+			host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+			if host == "" {
+				return nil, fmt.Errorf("GPU_INVENTORY=kubernetes requires running in a cluster or KUBERNETES_API_URL")
+			}
+			token, err := ioutil.ReadFile(serviceAccountTokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("error reading service account token: %w", err)
+			}
+			inventory.APIURL = "https://" + host + ":" + port
+			inventory.Token = strings.TrimSpace(string(token))
+			inventory.CAFile = serviceAccountCAFile
+		}
+		return inventory, nil
+	}
+	inventory, err := ParseGPUInventory(value)
+	if err != nil {
+		return nil, err
+	}
+	return StaticGPUInventory(inventory), nil
+}
+
+// ParseGPUInventory parses an inventory of the form "a100=16,rtx4090=4". Models are lowercased, as in GPU
+// attributes.
+func ParseGPUInventory(inventoryStr string) (GPUInventory, error) {
+	inventory := make(GPUInventory)
+	for _, pair := range strings.Split(inventoryStr, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		model, countStr, found := strings.Cut(pair, "=")
+		model = strings.ToLower(strings.TrimSpace(model))
+		count, err := strconv.ParseInt(strings.TrimSpace(countStr), 10, 64)
+		if !found || model == "" || err != nil || count < 0 {
+			return nil, fmt.Errorf("invalid GPU inventory entry: %s", pair)
+		}
+		inventory[model] += count
+	}
+	return inventory, nil
+}
+
+// String formats the inventory as "a100=16,rtx4090=4", sorted by model.
+func (i GPUInventory) String() string {
+	models := make([]string, 0, len(i))
+	for model := range i {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	pairs := make([]string, len(models))
+	for n, model := range models {
+		pairs[n] = fmt.Sprintf("%s=%d", model, i[model])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// GPUInventory returns the cached inventory, discovering it again once it is older than the TTL.
+func (k *KubernetesGPUInventory) GPUInventory() (GPUInventory, error) {
+	if inventory, err := k.readCache(); err == nil {
+		return inventory, nil
+	}
+	unlock := lockCache(k.CacheFile)
+	defer unlock()
+	// Another bid may have refreshed the cache while this one waited for the lock
+	if inventory, err := k.readCache(); err == nil {
+		return inventory, nil
+	}
+	return k.refresh()
+}
+
+// refresh discovers the inventory and caches it. The caller holds the cache lock.
+func (k *KubernetesGPUInventory) refresh() (GPUInventory, error) {
+	inventory, err := k.Discover()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(inventory)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(k.CacheFile, data); err != nil {
+		return nil, err
+	}
+	return inventory, nil
+}
+
+// readCache returns the cached inventory unless it is missing or expired.
+func (k *KubernetesGPUInventory) readCache() (GPUInventory, error) {
+	info, err := os.Stat(k.CacheFile)
+	if err != nil || time.Since(info.ModTime()) > k.TTL {
+		return nil, fmt.Errorf("cache file does not exist or is expired")
+	}
+	data, err := ioutil.ReadFile(k.CacheFile)
+	if err != nil {
+		return nil, err
+	}
+	var inventory GPUInventory
+	if err := json.Unmarshal(data, &inventory); err != nil {
+		return nil, err
+	}
+	return inventory, nil
+}
+
+// k8sNodeList is the part of a Kubernetes NodeList read for GPU discovery.
+type k8sNodeList struct {
+	Metadata struct {
+		Continue string `json:"continue"`
+	} `json:"metadata"`
+	Items []k8sNode `json:"items"`
+}
+
+// k8sNode is the part of a Kubernetes Node read for GPU discovery.
+type k8sNode struct {
+	Metadata struct {
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Spec struct {
+		Unschedulable bool `json:"unschedulable"`
+	} `json:"spec"`
+	Status struct {
+		Allocatable map[string]string `json:"allocatable"`
+	} `json:"status"`
+}
+
+// Discover lists the nodes from the API server and counts their GPUs, bypassing the cache. Cordoned nodes
+// are left out, since no new lease can be placed on them.
+func (k *KubernetesGPUInventory) Discover() (GPUInventory, error) {
+	client, err := k.client()
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := make(GPUInventory)
+	query := url.Values{"limit": {"500"}}
+	if k.NodeSelector != "" {
+		query.Set("labelSelector", k.NodeSelector)
+	}
+	for {
+		var nodes k8sNodeList
+		if err := k.get(client, "/api/v1/nodes?"+query.Encode(), &nodes); err != nil {
+			return nil, err
+		}
+		for _, node := range nodes.Items {
+			if node.Spec.Unschedulable {
+				continue
+			}
+			for model, count := range nodeGPUs(node) {
+				inventory[model] += count
+			}
+		}
+		if nodes.Metadata.Continue == "" {
+			return inventory, nil
+		}
+		query.Set("continue", nodes.Metadata.Continue)
+	}
+}
+
+// client returns an HTTP client trusting the API server's CA.
+func (k *KubernetesGPUInventory) client() (*http.Client, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if k.CAFile == "" {
+		return client, nil
+	}
+	ca, err := ioutil.ReadFile(k.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Kubernetes CA: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates in %s", k.CAFile)
+	}
+	client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}
+	return client, nil
+}
+
+// get decodes the JSON response of a GET request to the API server.
+func (k *KubernetesGPUInventory) get(client *http.Client, path string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, k.APIURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if k.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.Token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error listing nodes: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error listing nodes: Kubernetes API returned %s", resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<20)).Decode(v); err != nil {
+		return fmt.Errorf("error decoding node list: %w", err)
+	}
+	return nil
+}
+
+// nodeGPUs counts the GPUs of a node by model from its Akash capability labels, or else its NVIDIA GPU
+// feature discovery labels and allocatable GPUs.
+func nodeGPUs(node k8sNode) GPUInventory {
+	gpus := make(GPUInventory)
+	for key, value := range node.Metadata.Labels {
+		// akash.network/capabilities.gpu.vendor.nvidia.model.a100=8; the .ram and .interface variants
+		// count the same GPUs again
+		rest := strings.TrimPrefix(key, akashGPULabelPrefix)
+		if rest == key {
+			continue
+		}
+		parts := strings.Split(rest, ".")
+		if len(parts) != 3 || parts[1] != "model" {
+			continue
+		}
+		if count, err := strconv.ParseInt(value, 10, 64); err == nil && count > 0 {
+			gpus[strings.ToLower(parts[2])] += count
+		}
+	}
+	if len(gpus) > 0 {
+		return gpus
+	}
+
+	product := node.Metadata.Labels[nvidiaGPUProductLabel]
+	if product == "" {
+		return gpus
+	}
+	count, err := strconv.ParseInt(node.Metadata.Labels[nvidiaGPUCountLabel], 10, 64)
+	if err != nil {
+		count, err = strconv.ParseInt(node.Status.Allocatable[nvidiaGPUResource], 10, 64)
+	}
+	if err == nil && count > 0 {
+		gpus[GPUProductModel(product)] = count
+	}
+	return gpus
+}
+
+// GPUProductModel converts an NVIDIA product name as labelled by GPU feature discovery into the model
+// name of GPU attributes: "NVIDIA-A100-SXM4-80GB" becomes "a100" and "NVIDIA-GeForce-RTX-4090" "rtx4090".
+// Vendor and brand words are dropped and words are joined up to the first one with a digit.
+func GPUProductModel(product string) string {
+	var model string
+	for _, word := range strings.FieldsFunc(strings.ToLower(product), func(r rune) bool { return r == '-' || r == ' ' || r == '_' }) {
+		switch word {
+		case "nvidia", "tesla", "geforce", "quadro":
+			continue
+		}
+		model += word
+		if strings.ContainsAny(word, "0123456789") {
+			break
+		}
+	}
+	return model
+}
+
+// GPUUnitsByModel counts the GPUs requested by the GroupSpec per model, across all replicas. GPUs of no
+// particular model are counted under "any".
+func GPUUnitsByModel(gSpec *dtypes.GroupSpec) map[string]int64 {
+	totals := make(map[string]sdkmath.Int)
+	for _, resourceUnit := range gSpec.Resources {
+		if resourceUnit.Resources.GPU == nil || resourceUnit.Resources.GPU.Units.Val.IsZero() {
+			continue
+		}
+		model, _, _ := parseGPUAttributes(resourceUnit.Resources.GPU.Attributes)
+		if model == "" {
+			model = "any"
+		}
+		model = strings.ToLower(model)
+		total, ok := totals[model]
+		if !ok {
+			total = sdkmath.ZeroInt()
+		}
+		totals[model] = total.Add(resourceUnit.Resources.GPU.Units.Val.MulRaw(int64(resourceUnit.Count)))
+	}
+	units := make(map[string]int64, len(totals))
+	for model, total := range totals {
+		units[model] = saturatingInt64(total)
+	}
+	return units
+}
+
+// CheckGPUInventory rejects requests for more GPUs of a model than the cluster has, with ErrGPUUnavailable.
+// GPUs of any model count against all GPUs of the cluster.
+func CheckGPUInventory(inventory GPUInventory, gSpec *dtypes.GroupSpec) error {
+	var requested, total int64
+	for _, count := range inventory {
+		total += count
+	}
+	units := GPUUnitsByModel(gSpec)
+	models := make([]string, 0, len(units))
+	for model, count := range units {
+		requested += count
+		if model != "any" {
+			models = append(models, model)
+		}
+	}
+	sort.Strings(models)
+
+	for _, model := range models {
+		if inventory[model] == 0 {
+			return withReason(ErrGPUUnavailable, fmt.Errorf("the cluster has no %s GPUs", model))
+		}
+		if units[model] > inventory[model] {
+			return withReason(ErrGPUUnavailable, fmt.Errorf("%d %s GPUs requested, the cluster has %d", units[model], model, inventory[model]))
+		}
+	}
+	if requested > total {
+		return withReason(ErrGPUUnavailable, fmt.Errorf("%d GPUs requested, the cluster has %d", requested, total))
+	}
+	return nil
+}
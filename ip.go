@@ -0,0 +1,142 @@
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// DefaultIPVersionAttribute is the placement attribute read for the IP version of a group's leased IPs
+// when IP_VERSION_ATTRIBUTE is unset.
+const DefaultIPVersionAttribute = "ip-version"
+
+// IPDiscount takes Percent off every leased IP of a group from the From-th one on.
+type IPDiscount struct {
+	From    int64
+	Percent float64
+}
+
+// ParseIPDiscounts parses a per-additional-IP discount curve of the form "2=10,5=25", where each entry is
+// ip=percent: the 2nd to 4th leased IP of a group get 10% off and the 5th and later 25% off. The curve is
+// returned sorted by From.
+func ParseIPDiscounts(discountsStr string) ([]IPDiscount, error) {
+	var discounts []IPDiscount
+	for _, pair := range strings.Split(discountsStr, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.Split(pair, "=")
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid IP discount: %s", pair)
+		}
+
+		from, err := strconv.ParseInt(strings.TrimSpace(kv[0]), 10, 64)
+		if err != nil || from < 1 {
+			return nil, fmt.Errorf("invalid IP discount position: %s", kv[0])
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid IP discount percent for %s: %s", kv[0], kv[1])
+		}
+		discounts = append(discounts, IPDiscount{From: from, Percent: percent})
+	}
+
+	sort.Slice(discounts, func(i, j int) bool { return discounts[i].From < discounts[j].From })
+	for i := 1; i < len(discounts); i++ {
+		if discounts[i].From == discounts[i-1].From {
+			return nil, fmt.Errorf("duplicate IP discount position: %d", discounts[i].From)
+		}
+	}
+	return discounts, nil
+}
+
+// loadIPTargets reads the IPv6 target and the IP discount curve of the price targets from the environment.
+func loadIPTargets(targets *PriceTargets) error {
+	discounts, err := ipDiscountsCache.get(os.Getenv("PRICE_TARGET_IP_DISCOUNTS"))
+	if err != nil {
+		return err
+	}
+
+	targets.IPv6Target = getTargetFloat("PRICE_TARGET_IPV6", targets.IPTarget)
+	targets.IPDiscounts = discounts
+	return nil
+}
+
+// RequestsIPv6 reports whether the GroupSpec's leased IPs are IPv6, i.e. it requires the IP version
+// placement attribute (named by IP_VERSION_ATTRIBUTE, default "ip-version") to be "6", "v6" or "ipv6".
+// Leased IPs are IPv4 otherwise.
+func RequestsIPv6(gSpec *dtypes.GroupSpec) bool {
+	if gSpec == nil {
+		return false
+	}
+
+	key := os.Getenv("IP_VERSION_ATTRIBUTE")
+	if key == "" {
+		key = DefaultIPVersionAttribute
+	}
+	for _, attr := range gSpec.Requirements.Attributes {
+		if attr.Key == key {
+			switch strings.ToLower(strings.TrimSpace(attr.Value)) {
+			case "6", "v6", "ipv6":
+				return true
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// leasedIPCostLines itemizes the monthly cost of the requested leased IPv4 and IPv6 addresses. IPv4
+// addresses are counted first, so with a discount curve the IPv6 addresses of a group take the later
+// positions.
+func leasedIPCostLines(resourceRequests ResourceRequests, priceTargets PriceTargets) []CostLine {
+	ipv4 := resourceRequests.IPsRequested - resourceRequests.IPv6Requested
+	ipv6 := resourceRequests.IPv6Requested
+
+	var lines []CostLine
+	if ipv4 > 0 {
+		line := CostLine{Resource: CostIP, Quantity: sdkmath.LegacyNewDec(ipv4), Unit: "leased IPv4", Target: priceTargets.IPTarget}
+		line.Cost = decFromFloat(priceTargets.IPTarget).Mul(discountedIPs(priceTargets.IPDiscounts, 1, ipv4))
+		if !line.Cost.Equal(line.Quantity.Mul(decFromFloat(line.Target))) {
+			line.Detail = "additional IP discounts"
+		}
+		lines = append(lines, line)
+	}
+	if ipv6 > 0 {
+		line := CostLine{Resource: CostIPv6, Quantity: sdkmath.LegacyNewDec(ipv6), Unit: "leased IPv6", Target: priceTargets.IPv6Target}
+		line.Cost = decFromFloat(priceTargets.IPv6Target).Mul(discountedIPs(priceTargets.IPDiscounts, ipv4+1, ipv6))
+		if !line.Cost.Equal(line.Quantity.Mul(decFromFloat(line.Target))) {
+			line.Detail = "additional IP discounts"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// discountedIPs returns the number of IPs at positions first to first+count-1 after the discount curve, e.g.
+// 2.8 for three IPs of which the 2nd and 3rd get 10% off.
+func discountedIPs(discounts []IPDiscount, first, count int64) sdkmath.LegacyDec {
+	total := sdkmath.LegacyNewDec(count)
+	last := first + count - 1
+	for i, discount := range discounts {
+		// The tier covers positions From up to the next tier's From, exclusive
+		start, end := discount.From, last
+		if i+1 < len(discounts) && discounts[i+1].From-1 < end {
+			end = discounts[i+1].From - 1
+		}
+		if start < first {
+			start = first
+		}
+		if end < start {
+			continue
+		}
+		off := decFromFloat(discount.Percent).MulInt64(end - start + 1).QuoInt64(100)
+		total = total.Sub(off)
+	}
+	return total
+}
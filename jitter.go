@@ -0,0 +1,62 @@
+package pricing
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// BidJitter moves bids by up to ±Percent, so providers running the same configuration don't place identical
+// bids and lose tie-breaks to each other. The offset is derived from Seed and the order ID, so pricing the
+// same order again bids the same price.
+type BidJitter struct {
+	Percent float64
+	Seed    string // Mixed into the offset, e.g. the provider address, so providers sharing a config differ
+}
+
+// BidJitterFromEnv reads BID_JITTER_PERCENT and BID_JITTER_SEED, returning nil if jitter is not configured.
+func BidJitterFromEnv() (*BidJitter, error) {
+	val := strings.TrimSpace(os.Getenv("BID_JITTER_PERCENT"))
+	if val == "" {
+		return nil, nil
+	}
+	percent, err := strconv.ParseFloat(val, 64)
+	if err != nil || percent < 0 || percent >= 100 {
+		return nil, fmt.Errorf("invalid BID_JITTER_PERCENT %q: must be at least 0 and below 100", val)
+	}
+	if percent == 0 {
+		return nil, nil
+	}
+	return &BidJitter{Percent: percent, Seed: os.Getenv("BID_JITTER_SEED")}, nil
+}
+
+// Jitter returns rate moved by the order's offset, kept at or above floor and, when set, at or below
+// maxPrice. Requests without an order ID have no stable seed and keep rate.
+func (j *BidJitter) Jitter(rate, floor, maxPrice sdkmath.LegacyDec, orderID string) sdkmath.LegacyDec {
+	if j == nil || orderID == "" {
+		return rate
+	}
+	jittered := rate.Add(rate.Mul(j.offset(orderID)))
+	if jittered.LT(floor) {
+		jittered = floor
+	}
+	if !maxPrice.IsNil() && jittered.GT(maxPrice) {
+		jittered = maxPrice
+	}
+	return jittered
+}
+
+// offset returns the order's relative offset, uniform in [-Percent, +Percent] percent.
+func (j *BidJitter) offset(orderID string) sdkmath.LegacyDec {
+	sum := sha256.Sum256([]byte(j.Seed + "/" + orderID))
+	// A fraction in [0, 1) from the first 8 bytes of the hash
+	fraction := sdkmath.LegacyNewDecFromInt(sdkmath.NewIntFromUint64(binary.BigEndian.Uint64(sum[:8]))).
+		Quo(sdkmath.LegacyNewDecFromInt(sdkmath.NewIntFromUint64(math.MaxUint64).AddRaw(1)))
+	return fraction.MulInt64(2).Sub(sdkmath.LegacyOneDec()).Mul(decFromFloat(j.Percent)).QuoInt64(100)
+}
@@ -0,0 +1,117 @@
+package pricing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// BidScriptCompatLegacy is the BID_SCRIPT_COMPAT mode reading the order from the environment of bash-era
+// bid script wiring in addition to stdin.
+const BidScriptCompatLegacy = "legacy"
+
+// LegacyEnvMode reports whether BID_SCRIPT_COMPAT selects the legacy environment mode.
+func LegacyEnvMode() (bool, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(os.Getenv("BID_SCRIPT_COMPAT"))); mode {
+	case "":
+		return false, nil
+	case BidScriptCompatLegacy:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid BID_SCRIPT_COMPAT %q: must be %s or empty", mode, BidScriptCompatLegacy)
+	}
+}
+
+// LegacyEnvRequest builds the request of a bid script wired like the original bash script. stdin holds an
+// order, or only the array of its resources, and the environment supplies what it lacks:
+//
+//   - AKASH_OWNER: the tenant, required
+//   - PRICE: the max price per block, a coin such as "100uakt" or an amount in PRICE_DENOM
+//   - PRICE_DENOM: the denom of a plain PRICE amount, default uakt
+//   - PRICE_PRECISION: decimal places of the bid
+//   - AKASH_ORDER_ID: the order's dseq/gseq/oseq
+//
+// Fields present in the stdin JSON take precedence over the environment. The request carries the precision
+// even when it cannot be built, so the response can report it.
+func LegacyEnvRequest(r io.Reader) (Request, error) {
+	order, err := decodeLegacyOrder(r)
+	if err != nil {
+		return Request{}, err
+	}
+	if err := order.applyLegacyEnv(); err != nil {
+		return Request{PricePrecision: order.PricePrecision}, err
+	}
+
+	owner := strings.TrimSpace(os.Getenv("AKASH_OWNER"))
+	if owner == "" {
+		return Request{PricePrecision: order.PricePrecision}, withReason(ErrInvalidRequest, fmt.Errorf("AKASH_OWNER is required in %s mode", BidScriptCompatLegacy))
+	}
+	request, err := order.Request(owner)
+	if err != nil {
+		return Request{PricePrecision: order.PricePrecision}, err
+	}
+	return request, nil
+}
+
+// decodeLegacyOrder decodes an order, or a bare array of resources as sent by providers predating the
+// order object.
+func decodeLegacyOrder(r io.Reader) (*DeploymentOrder, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading deployment order: %w", err)
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return &DeploymentOrder{Resources: json.RawMessage(trimmed)}, nil
+	}
+	return DecodeDeploymentOrder(bytes.NewReader(data))
+}
+
+// applyLegacyEnv fills the order's price, precision and ID from the legacy environment where it has none.
+func (o *DeploymentOrder) applyLegacyEnv() error {
+	if val := strings.TrimSpace(os.Getenv("PRICE")); val != "" && o.Price == nil {
+		price, err := parseLegacyPrice(val, strings.TrimSpace(os.Getenv("PRICE_DENOM")))
+		if err != nil {
+			return err
+		}
+		o.Price = price
+	}
+	if val := strings.TrimSpace(os.Getenv("PRICE_PRECISION")); val != "" && o.PricePrecision == 0 {
+		precision, err := strconv.Atoi(val)
+		if err != nil || precision < 0 {
+			return fmt.Errorf("invalid PRICE_PRECISION %q: must be a non-negative integer", val)
+		}
+		o.PricePrecision = precision
+	}
+	if val := strings.TrimSpace(os.Getenv("AKASH_ORDER_ID")); val != "" && o.OrderID == "" {
+		quoted, _ := json.Marshal(val)
+		if err := o.OrderID.UnmarshalJSON(quoted); err != nil {
+			return fmt.Errorf("invalid AKASH_ORDER_ID: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseLegacyPrice parses PRICE as a coin, or as an amount in denom.
+func parseLegacyPrice(val, denom string) (*Price, error) {
+	if coin, err := sdk.ParseDecCoin(val); err == nil {
+		if denom != "" && coin.Denom != denom {
+			return nil, fmt.Errorf("PRICE %q is not in PRICE_DENOM %s", val, denom)
+		}
+		return &Price{Denom: coin.Denom, Amount: coin.Amount.String()}, nil
+	}
+	if denom == "" {
+		denom = LegacyOrderDenom
+	}
+	if _, err := sdkmath.LegacyNewDecFromStr(val); err != nil {
+		return nil, fmt.Errorf("invalid PRICE %q: must be a coin such as 100uakt or an amount", val)
+	}
+	return &Price{Denom: denom, Amount: val}, nil
+}
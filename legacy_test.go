@@ -0,0 +1,65 @@
+package pricing
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestLegacyEnvRequest checks the environment fills what the stdin order lacks and never overrides it.
+func TestLegacyEnvRequest(t *testing.T) {
+	const resources = `[{"cpu": 1000, "memory": 1073741824, "storage": [{"class": "default", "size": 1073741824}], "count": 1}]`
+	tests := []struct {
+		name      string
+		env       map[string]string
+		stdin     string
+		denom     string
+		amount    string
+		precision int
+		orderID   string
+		err       string
+	}{
+		{
+			name:  "resources array and coin price",
+			env:   map[string]string{"AKASH_OWNER": "akash1legacy", "PRICE": "100uakt", "PRICE_PRECISION": "4", "AKASH_ORDER_ID": "12/1/1"},
+			stdin: resources, denom: "uakt", amount: "100.000000000000000000", precision: 4, orderID: "12/1/1",
+		},
+		{
+			name:  "plain amount in PRICE_DENOM",
+			env:   map[string]string{"AKASH_OWNER": "akash1legacy", "PRICE": "0.25", "PRICE_DENOM": "ibc/170C677610AC31DF0904FFE09CD3B5C657492170E7E52372E48756B71E56F2F1"},
+			stdin: resources, denom: "ibc/170C677610AC31DF0904FFE09CD3B5C657492170E7E52372E48756B71E56F2F1", amount: "0.250000000000000000",
+		},
+		{
+			name:  "order JSON takes precedence",
+			env:   map[string]string{"AKASH_OWNER": "akash1legacy", "PRICE": "1uakt", "PRICE_PRECISION": "2"},
+			stdin: `{"price": {"denom": "uakt", "amount": "50"}, "price_precision": 6, "resources": ` + resources + `}`,
+			denom: "uakt", amount: "50.000000000000000000", precision: 6,
+		},
+		{name: "missing owner", env: map[string]string{"PRICE": "100uakt"}, stdin: resources, err: "AKASH_OWNER is required"},
+		{name: "price in another denom", env: map[string]string{"AKASH_OWNER": "akash1legacy", "PRICE": "100uakt", "PRICE_DENOM": "uusdc"}, stdin: resources, err: "not in PRICE_DENOM"},
+		{name: "invalid order ID", env: map[string]string{"AKASH_OWNER": "akash1legacy", "AKASH_ORDER_ID": "12"}, stdin: resources, err: "AKASH_ORDER_ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"AKASH_OWNER", "PRICE", "PRICE_DENOM", "PRICE_PRECISION", "AKASH_ORDER_ID"} {
+				t.Setenv(key, tt.env[key])
+			}
+			request, err := LegacyEnvRequest(strings.NewReader(tt.stdin))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			price := request.GSpec.Resources[0].Price
+			if price.Denom != tt.denom || price.Amount.String() != tt.amount {
+				t.Errorf("price %s%s, want %s%s", price.Amount, price.Denom, tt.amount, tt.denom)
+			}
+			if request.PricePrecision != tt.precision || request.OrderID != tt.orderID || request.Owner != "akash1legacy" {
+				t.Errorf("precision %d, order %q, owner %q", request.PricePrecision, request.OrderID, request.Owner)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// Default absolute caps on the resources of one group, far above any real deployment but low enough that
+// every total fits an int64 and prices stay in range.
+const (
+	DefaultMaxRequestCPU       = 100000    // Cores
+	DefaultMaxRequestMemoryGB  = 1000000   // Gigabytes of PRICE_TARGET_SIZE_UNIT
+	DefaultMaxRequestStorageGB = 100000000 // Gigabytes of PRICE_TARGET_SIZE_UNIT, all classes together
+	DefaultMaxRequestGPUs      = 10000
+)
+
+// RequestLimits caps the total resources a group may request across all its replicas. Requests beyond them
+// are rejected with ErrRequestTooLarge before they are priced.
+type RequestLimits struct {
+	CPU       float64 // Cores
+	MemoryGB  float64
+	StorageGB float64
+	GPUs      int64
+}
+
+// RequestLimitsFromEnv returns the caps of REQUEST_MAX_CPU, REQUEST_MAX_MEMORY_GB, REQUEST_MAX_STORAGE_GB and
+// REQUEST_MAX_GPUS, defaulting to the DefaultMaxRequest constants.
+func RequestLimitsFromEnv() (RequestLimits, error) {
+	limits := RequestLimits{
+		CPU:       DefaultMaxRequestCPU,
+		MemoryGB:  DefaultMaxRequestMemoryGB,
+		StorageGB: DefaultMaxRequestStorageGB,
+		GPUs:      DefaultMaxRequestGPUs,
+	}
+	for name, limit := range map[string]*float64{
+		"REQUEST_MAX_CPU":        &limits.CPU,
+		"REQUEST_MAX_MEMORY_GB":  &limits.MemoryGB,
+		"REQUEST_MAX_STORAGE_GB": &limits.StorageGB,
+	} {
+		val := strings.TrimSpace(os.Getenv(name))
+		if val == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil || f <= 0 || checkDecFloat(f) != nil {
+			return RequestLimits{}, fmt.Errorf("invalid %s %q: must be a positive number", name, val)
+		}
+		*limit = f
+	}
+	if val := strings.TrimSpace(os.Getenv("REQUEST_MAX_GPUS")); val != "" {
+		gpus, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || gpus <= 0 {
+			return RequestLimits{}, fmt.Errorf("invalid REQUEST_MAX_GPUS %q: must be a positive integer", val)
+		}
+		limits.GPUs = gpus
+	}
+	return limits, nil
+}
+
+// Check totals the GroupSpec's resources with arbitrary-precision integers, so replica counts multiplying
+// huge quantities cannot wrap around, and rejects the request if any total exceeds its cap. Quantities must
+// already be valid, see ValidateGroupSpec.
+func (l RequestLimits) Check(gSpec *dtypes.GroupSpec) error {
+	milliCPUs, memory, storage, gpus := sdkmath.ZeroInt(), sdkmath.ZeroInt(), sdkmath.ZeroInt(), sdkmath.ZeroInt()
+	for _, resourceUnit := range gSpec.Resources {
+		count := int64(resourceUnit.Count)
+		if cpu := resourceUnit.Resources.CPU; cpu != nil {
+			milliCPUs = milliCPUs.Add(cpu.Units.Val.MulRaw(count))
+		}
+		if mem := resourceUnit.Resources.Memory; mem != nil {
+			memory = memory.Add(mem.Quantity.Val.MulRaw(count))
+		}
+		if gpu := resourceUnit.Resources.GPU; gpu != nil {
+			gpus = gpus.Add(gpu.Units.Val.MulRaw(count))
+		}
+		for _, volume := range resourceUnit.Resources.Storage {
+			storage = storage.Add(volume.Quantity.Val.MulRaw(count))
+		}
+	}
+
+	unit := sizeUnit()
+	perUnit := bytesPerUnit(unit)
+	var problems []string
+	if cores := sdkmath.LegacyNewDecFromInt(milliCPUs).QuoInt64(1000); cores.GT(decFromFloat(l.CPU)) {
+		problems = append(problems, fmt.Sprintf("%s CPU cores exceed the cap of %s", FormatDec(cores, 3), formatLimit(l.CPU)))
+	}
+	if gb := sdkmath.LegacyNewDecFromInt(memory).Quo(perUnit); gb.GT(decFromFloat(l.MemoryGB)) {
+		problems = append(problems, fmt.Sprintf("%s %s of memory exceed the cap of %s", FormatDec(gb, 3), unit, formatLimit(l.MemoryGB)))
+	}
+	if gb := sdkmath.LegacyNewDecFromInt(storage).Quo(perUnit); gb.GT(decFromFloat(l.StorageGB)) {
+		problems = append(problems, fmt.Sprintf("%s %s of storage exceed the cap of %s", FormatDec(gb, 3), unit, formatLimit(l.StorageGB)))
+	}
+	if gpus.GT(sdkmath.NewInt(l.GPUs)) {
+		problems = append(problems, fmt.Sprintf("%s GPUs exceed the cap of %d", gpus, l.GPUs))
+	}
+	if len(problems) > 0 {
+		return withReason(ErrRequestTooLarge, fmt.Errorf("%s", strings.Join(problems, "; ")))
+	}
+	return nil
+}
+
+// formatLimit formats a cap without an exponent, e.g. 1000000 rather than 1e+06.
+func formatLimit(limit float64) string {
+	return strconv.FormatFloat(limit, 'f', -1, 64)
+}
+
+// saturatingInt64 converts a non-negative total to an int64, capping it at the largest int64 rather than
+// wrapping around to a negative number.
+func saturatingInt64(total sdkmath.Int) int64 {
+	if total.IsInt64() {
+		return total.Int64()
+	}
+	return math.MaxInt64
+}
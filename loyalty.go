@@ -0,0 +1,105 @@
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// LoyaltyTier takes Percent off the monthly cost of owners whose lifetime spend with the provider reaches
+// MinSpendUsd.
+type LoyaltyTier struct {
+	MinSpendUsd float64
+	Percent     float64
+}
+
+// ParseLoyaltyTiers parses loyalty tiers of the form "1000=3,5000=5", where each entry is the minimum
+// lifetime spend in USD and the discount percent. Tiers are returned sorted by spend.
+func ParseLoyaltyTiers(tiersStr string) ([]LoyaltyTier, error) {
+	var tiers []LoyaltyTier
+	if tiersStr == "" {
+		return tiers, nil
+	}
+
+	seen := make(map[float64]bool)
+	for _, pair := range strings.Split(tiersStr, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.Split(pair, "=")
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid loyalty tier: %s", pair)
+		}
+		minSpend, err := strconv.ParseFloat(strings.TrimSpace(kv[0]), 64)
+		if err != nil || minSpend < 0 || checkDecFloat(minSpend) != nil {
+			return nil, fmt.Errorf("invalid loyalty tier spend: %s", kv[0])
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid loyalty tier percent for %s: %s", kv[0], kv[1])
+		}
+		if seen[minSpend] {
+			return nil, fmt.Errorf("duplicate loyalty tier: %s", kv[0])
+		}
+		seen[minSpend] = true
+		tiers = append(tiers, LoyaltyTier{MinSpendUsd: minSpend, Percent: percent})
+	}
+
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinSpendUsd < tiers[j].MinSpendUsd })
+	return tiers, nil
+}
+
+// LoyaltyTiersFromEnv returns the parsed LOYALTY_TIERS. Tiers need the bid history named by BID_HISTORY_DB
+// to look up owners' spend.
+func LoyaltyTiersFromEnv() ([]LoyaltyTier, error) {
+	tiers, err := loyaltyTiersCache.get(os.Getenv("LOYALTY_TIERS"))
+	if err != nil {
+		return nil, err
+	}
+	if len(tiers) > 0 && os.Getenv("BID_HISTORY_DB") == "" {
+		return nil, fmt.Errorf("LOYALTY_TIERS requires BID_HISTORY_DB to look up owner spend")
+	}
+	return tiers, nil
+}
+
+// OwnerSpend estimates an owner's lifetime spend in USD from the leases they won: the monthly cost of
+// each winning bid for the months since the win was recorded. Lease closures are not recorded, so a lease
+// counts until now. Leases with an unparseable cost are skipped.
+func OwnerSpend(leases []OwnerLease, now time.Time) sdkmath.LegacyDec {
+	spend := sdkmath.LegacyZeroDec()
+	month := time.Duration(daysPerMonth() * 24 * float64(time.Hour))
+	for _, lease := range leases {
+		monthly, err := sdkmath.LegacyNewDecFromStr(lease.TotalCostUsd)
+		if err != nil || !lease.Time.Before(now) {
+			continue
+		}
+		months := sdkmath.LegacyNewDec(int64(now.Sub(lease.Time))).QuoInt64(int64(month))
+		spend = spend.Add(monthly.Mul(months))
+	}
+	return spend
+}
+
+// ApplyLoyalty applies the highest tier the owner's lifetime spend reaches. The returned adjustment is nil
+// if no tier applies.
+func ApplyLoyalty(tiers []LoyaltyTier, spendUsd, totalCostUsd sdkmath.LegacyDec) (sdkmath.LegacyDec, *Adjustment) {
+	var tier *LoyaltyTier
+	for i := range tiers {
+		if spendUsd.GTE(decFromFloat(tiers[i].MinSpendUsd)) {
+			tier = &tiers[i]
+		}
+	}
+	if tier == nil || tier.Percent == 0 {
+		return totalCostUsd, nil
+	}
+
+	discounted := totalCostUsd.Sub(totalCostUsd.Mul(decFromFloat(tier.Percent)).QuoInt64(100))
+	return discounted, &Adjustment{
+		Name:   "loyalty",
+		Detail: fmt.Sprintf("%g%% loyalty discount, $%s lifetime spend >= $%g", tier.Percent, FormatDec(spendUsd, 2), tier.MinSpendUsd),
+	}
+}
@@ -0,0 +1,247 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// Market strategy defaults.
+const (
+	DefaultMarketPercentile   = 50
+	DefaultMarketMinSamples   = 5
+	DefaultMarketFloorPercent = 100
+	DefaultMarketDataTTL      = 10 * time.Minute
+)
+
+// MarketStats are the monthly USD prices of recent winning bids for leases comparable to a request.
+type MarketStats struct {
+	PricesUsdMonth []string `json:"prices_usd_month"`
+}
+
+// MarketDataClient fetches winning bid statistics from a market data API, e.g. an Akash indexer or a small
+// adapter in front of one. The API is queried with GET <URL>?cpu=<cores>&memory_gb=<GB>&gpus=<units> and
+// answers with MarketStats as JSON. Answers are cached per resource profile for TTL.
+type MarketDataClient struct {
+	URL     string
+	Timeout time.Duration
+	TTL     time.Duration
+
+	mu    sync.Mutex
+	cache map[string]marketStatsEntry
+}
+
+// marketStatsEntry is a cached MarketDataClient answer.
+type marketStatsEntry struct {
+	prices  []sdkmath.LegacyDec
+	fetched time.Time
+}
+
+// marketClients shares one client, and so its cache, per market data URL across bids of the process.
+var (
+	marketClientsMu sync.Mutex
+	marketClients   = make(map[string]*MarketDataClient)
+)
+
+// Prices returns the sorted winning monthly USD prices of leases comparable to the resource profile.
+func (c *MarketDataClient) Prices(cpuCores, memoryGB string, gpus int64) ([]sdkmath.LegacyDec, error) {
+	query := url.Values{}
+	query.Set("cpu", trimDecimal(cpuCores))
+	query.Set("memory_gb", trimDecimal(memoryGB))
+	query.Set("gpus", strconv.FormatInt(gpus, 10))
+	key := query.Encode()
+
+	c.mu.Lock()
+	entry, ok := c.cache[key]
+	ttl, timeout := c.TTL, c.Timeout
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetched) < ttl {
+		return entry.prices, nil
+	}
+
+	prices, err := c.fetch(key, timeout)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.cache == nil {
+		c.cache = make(map[string]marketStatsEntry)
+	}
+	c.cache[key] = marketStatsEntry{prices: prices, fetched: time.Now()}
+	c.mu.Unlock()
+	return prices, nil
+}
+
+// trimDecimal drops the trailing zeros of a decimal string, e.g. "2.500000000000000000" becomes "2.5".
+func trimDecimal(value string) string {
+	if !strings.Contains(value, ".") {
+		return value
+	}
+	return strings.TrimRight(strings.TrimRight(value, "0"), ".")
+}
+
+// fetch queries the market data API and parses and sorts its prices.
+func (c *MarketDataClient) fetch(query string, timeout time.Duration) ([]sdkmath.LegacyDec, error) {
+	endpoint, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid market data URL: %v", err)
+	}
+	if endpoint.RawQuery != "" {
+		query = endpoint.RawQuery + "&" + query
+	}
+	endpoint.RawQuery = query
+
+	client := &http.Client{Timeout: strategyTimeout(timeout)}
+	resp, err := client.Get(endpoint.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP request error: %s", resp.Status)
+	}
+
+	var stats MarketStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("invalid response from market data API: %w", err)
+	}
+	prices := make([]sdkmath.LegacyDec, 0, len(stats.PricesUsdMonth))
+	for _, price := range stats.PricesUsdMonth {
+		dec, err := sdkmath.LegacyNewDecFromStr(price)
+		if err != nil || dec.IsNegative() {
+			return nil, fmt.Errorf("invalid market price %q", price)
+		}
+		prices = append(prices, dec)
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].LT(prices[j]) })
+	return prices, nil
+}
+
+// MarketStrategy bids at a percentile of the winning prices of comparable leases, but never below
+// FloorPercent of the provider's own monthly cost. Profiles with fewer than MinSamples recent wins keep
+// the computed price.
+type MarketStrategy struct {
+	Client       *MarketDataClient
+	Percentile   float64
+	MinSamples   int
+	FloorPercent float64
+}
+
+// Adjust positions the bid within the market prices of the request's resource profile.
+func (s *MarketStrategy) Adjust(input StrategyInput) (StrategyOutput, error) {
+	cost, err := sdkmath.LegacyNewDecFromStr(input.TotalCostUsd)
+	if err != nil {
+		return StrategyOutput{}, fmt.Errorf("invalid total cost %q: %v", input.TotalCostUsd, err)
+	}
+
+	prices, err := s.Client.Prices(input.CPUCores, input.MemoryGB, input.GPUs)
+	if err != nil {
+		return StrategyOutput{}, fmt.Errorf("market data unavailable: %w", err)
+	}
+	if len(prices) == 0 || len(prices) < s.MinSamples {
+		return StrategyOutput{}, nil
+	}
+
+	market := MarketPercentile(prices, s.Percentile)
+	floor := cost.Mul(decFromFloat(s.FloorPercent)).QuoInt64(100)
+	label := "p" + strconv.FormatFloat(s.Percentile, 'f', -1, 64)
+	if market.LT(floor) {
+		total := FormatDec(floor, 6)
+		return StrategyOutput{
+			TotalCostUsd: &total,
+			Reason:       fmt.Sprintf("market %s %s USD below cost floor, %d samples", label, FormatDec(market, 2), len(prices)),
+		}, nil
+	}
+	total := FormatDec(market, 6)
+	return StrategyOutput{
+		TotalCostUsd: &total,
+		Reason:       fmt.Sprintf("market %s of %d samples", label, len(prices)),
+	}, nil
+}
+
+// MarketPercentile returns the percentile (0-100) of sorted prices, interpolating linearly between the
+// closest ranks.
+func MarketPercentile(sorted []sdkmath.LegacyDec, percentile float64) sdkmath.LegacyDec {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := decFromFloat(percentile).MulInt64(int64(len(sorted) - 1)).QuoInt64(100)
+	lower := rank.TruncateInt().Int64()
+	if lower >= int64(len(sorted)-1) {
+		return sorted[len(sorted)-1]
+	}
+	fraction := rank.Sub(sdkmath.LegacyNewDec(lower))
+	return sorted[lower].Add(sorted[lower+1].Sub(sorted[lower]).Mul(fraction))
+}
+
+// NewMarketStrategyFromEnv returns the market strategy configured by STRATEGY_MARKET_URL, or nil.
+// STRATEGY_MARKET_PERCENTILE, STRATEGY_MARKET_MIN_SAMPLES, STRATEGY_MARKET_FLOOR_PERCENT and
+// STRATEGY_MARKET_TTL (a Go duration) override the defaults.
+func NewMarketStrategyFromEnv(timeout time.Duration) (*MarketStrategy, error) {
+	marketURL := os.Getenv("STRATEGY_MARKET_URL")
+	if marketURL == "" {
+		return nil, nil
+	}
+	if _, err := url.ParseRequestURI(marketURL); err != nil {
+		return nil, fmt.Errorf("invalid STRATEGY_MARKET_URL: %v", err)
+	}
+
+	strategy := &MarketStrategy{
+		Percentile:   DefaultMarketPercentile,
+		MinSamples:   DefaultMarketMinSamples,
+		FloorPercent: DefaultMarketFloorPercent,
+	}
+	if val := os.Getenv("STRATEGY_MARKET_PERCENTILE"); val != "" {
+		percentile, err := strconv.ParseFloat(val, 64)
+		if err != nil || percentile < 0 || percentile > 100 {
+			return nil, fmt.Errorf("invalid STRATEGY_MARKET_PERCENTILE %q: must be between 0 and 100", val)
+		}
+		strategy.Percentile = percentile
+	}
+	if val := os.Getenv("STRATEGY_MARKET_MIN_SAMPLES"); val != "" {
+		minSamples, err := strconv.Atoi(val)
+		if err != nil || minSamples < 1 {
+			return nil, fmt.Errorf("invalid STRATEGY_MARKET_MIN_SAMPLES %q: must be a positive integer", val)
+		}
+		strategy.MinSamples = minSamples
+	}
+	if val := os.Getenv("STRATEGY_MARKET_FLOOR_PERCENT"); val != "" {
+		floorPercent, err := strconv.ParseFloat(val, 64)
+		if err != nil || floorPercent < 0 || checkDecFloat(floorPercent) != nil {
+			return nil, fmt.Errorf("invalid STRATEGY_MARKET_FLOOR_PERCENT %q", val)
+		}
+		strategy.FloorPercent = floorPercent
+	}
+	ttl := DefaultMarketDataTTL
+	if val := os.Getenv("STRATEGY_MARKET_TTL"); val != "" {
+		var err error
+		ttl, err = time.ParseDuration(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STRATEGY_MARKET_TTL: %v", err)
+		}
+	}
+
+	marketClientsMu.Lock()
+	defer marketClientsMu.Unlock()
+	client, ok := marketClients[marketURL]
+	if !ok {
+		client = &MarketDataClient{URL: marketURL}
+		marketClients[marketURL] = client
+	}
+	client.mu.Lock()
+	client.Timeout = timeout
+	client.TTL = ttl
+	client.mu.Unlock()
+	strategy.Client = client
+	return strategy, nil
+}
@@ -0,0 +1,230 @@
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// MoneyFormat renders monetary amounts for people: explanations, reports and CLI output. Bid strings, JSON
+// results and metrics keep the canonical FormatDec digits whatever the format.
+//
+// The zero MoneyFormat is the canonical display, e.g. "$1234.50" and "1234.50 EUR".
+type MoneyFormat struct {
+	Locale  string            // Key of moneyLocales, e.g. de or de-CH; "" for the canonical display
+	Symbols map[string]string // Symbols per ISO 4217 code, overriding the locale's, e.g. EUR=€
+}
+
+// moneyLocale is how a locale separates digits and places the currency symbol.
+type moneyLocale struct {
+	group       string // Thousands separator
+	decimal     string // Decimal separator
+	symbolAfter bool   // "1.234,50 €" rather than "€1,234.50"
+	spaced      bool   // Space between the symbol and the amount
+}
+
+// moneyLocales are the locales of DISPLAY_LOCALE, looked up by full tag, then by language.
+var moneyLocales = map[string]moneyLocale{
+	"en":    {",", ".", false, false},
+	"de":    {".", ",", true, true},
+	"de-CH": {"'", ".", false, true},
+	"es":    {".", ",", true, true},
+	"fr":    {"\u202f", ",", true, true}, // Narrow no-break space
+	"it":    {".", ",", true, true},
+	"ja":    {",", ".", false, false},
+	"nl":    {".", ",", false, true},
+	"pt":    {".", ",", false, true},
+}
+
+// canonicalLocale is the locale of the zero MoneyFormat.
+var canonicalLocale = moneyLocale{"", ".", false, false}
+
+// currencySymbols are the symbols localized formats use by default. Currencies without one are shown by
+// their ISO 4217 code.
+var currencySymbols = map[string]string{
+	"AUD": "A$",
+	"BRL": "R$",
+	"CAD": "CA$",
+	"CHF": "CHF",
+	"CNY": "CN¥",
+	"EUR": "€",
+	"GBP": "£",
+	"INR": "₹",
+	"JPY": "¥",
+	"USD": "$",
+}
+
+// ParseMoneyFormat parses a locale such as de or de-DE and a comma-separated list of CODE=symbol overrides,
+// e.g. "USD=US$,EUR=€". Both may be empty.
+func ParseMoneyFormat(locale, symbols string) (MoneyFormat, error) {
+	var format MoneyFormat
+	if locale = strings.TrimSpace(locale); locale != "" {
+		key, ok := moneyLocaleKey(locale)
+		if !ok {
+			return MoneyFormat{}, fmt.Errorf("unknown locale %q: must be one of %s", locale, strings.Join(moneyLocaleKeys(), ", "))
+		}
+		format.Locale = key
+	}
+	for _, entry := range strings.Split(symbols, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		code := strings.ToUpper(strings.TrimSpace(parts[0]))
+		if len(parts) != 2 || len(code) != 3 || strings.TrimSpace(parts[1]) == "" {
+			return MoneyFormat{}, fmt.Errorf("invalid currency symbol %q: must be CODE=symbol, e.g. EUR=€", entry)
+		}
+		if format.Symbols == nil {
+			format.Symbols = make(map[string]string)
+		}
+		format.Symbols[code] = strings.TrimSpace(parts[1])
+	}
+	return format, nil
+}
+
+// MoneyFormatFromEnv returns the format of DISPLAY_LOCALE and DISPLAY_CURRENCY_SYMBOLS.
+func MoneyFormatFromEnv() (MoneyFormat, error) {
+	format, err := ParseMoneyFormat(os.Getenv("DISPLAY_LOCALE"), os.Getenv("DISPLAY_CURRENCY_SYMBOLS"))
+	if err != nil {
+		return MoneyFormat{}, fmt.Errorf("invalid DISPLAY_LOCALE or DISPLAY_CURRENCY_SYMBOLS: %w", err)
+	}
+	return format, nil
+}
+
+// displayMoneyFormat returns MoneyFormatFromEnv, warning and using the canonical display when it is invalid.
+func displayMoneyFormat() MoneyFormat {
+	format, err := MoneyFormatFromEnv()
+	if err != nil {
+		warnEnvProblem(err)
+	}
+	return format
+}
+
+// FormatMoney formats an amount of currency to cents in the display format of the environment, e.g.
+// "$1,234.50" or "1.234,50 €". It is for people only; bids are formatted with FormatDec.
+func FormatMoney(amount sdkmath.LegacyDec, currency string) string {
+	return displayMoneyFormat().Format(amount, currency, 2)
+}
+
+// Format rounds amount to precision decimals and formats it with the locale's separators and the
+// currency's symbol.
+func (f MoneyFormat) Format(amount sdkmath.LegacyDec, currency string, precision int) string {
+	return f.formatDigits(FormatDec(amount, precision), currency)
+}
+
+// formatDigits formats canonical digits such as "-1234.5" like Format, keeping their precision.
+func (f MoneyFormat) formatDigits(digits, currency string) string {
+	locale := canonicalLocale
+	if f.Locale != "" {
+		locale, _ = moneyLocaleOf(f.Locale)
+	}
+	negative := strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(digits, "-")
+	whole, fraction := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		whole, fraction = digits[:i], digits[i+1:]
+	}
+	if locale.group != "" {
+		whole = groupDigits(whole, locale.group)
+	}
+	number := whole
+	if fraction != "" {
+		number += locale.decimal + fraction
+	}
+
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	symbol, ok := f.symbol(currency)
+	var formatted string
+	switch {
+	case !ok && f.Locale == "":
+		// The canonical display names currencies without a symbol after the amount, as in "12.00 EUR"
+		formatted = number + " " + currency
+	case locale.symbolAfter:
+		formatted = number + " " + symbol
+	case locale.spaced || !ok || endsWithLetter(symbol):
+		formatted = symbol + " " + number
+	default:
+		formatted = symbol + number
+	}
+	if negative {
+		formatted = "-" + formatted
+	}
+	return formatted
+}
+
+// symbol returns the currency's symbol, or its code and false when it has none in this format.
+func (f MoneyFormat) symbol(currency string) (string, bool) {
+	if symbol, ok := f.Symbols[currency]; ok {
+		return symbol, true
+	}
+	if f.Locale == "" {
+		return "$", currency == DefaultPriceTargetCurrency
+	}
+	if symbol, ok := currencySymbols[currency]; ok {
+		return symbol, true
+	}
+	return currency, false
+}
+
+// groupDigits inserts sep between each group of three digits of whole, e.g. "1,234,567".
+func groupDigits(whole, sep string) string {
+	if len(whole) <= 3 {
+		return whole
+	}
+	var b strings.Builder
+	head := len(whole) % 3
+	if head > 0 {
+		b.WriteString(whole[:head])
+	}
+	for i := head; i < len(whole); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(whole[i : i+3])
+	}
+	return b.String()
+}
+
+// endsWithLetter reports whether a symbol such as CHF needs a space before the amount.
+func endsWithLetter(symbol string) bool {
+	runes := []rune(symbol)
+	return len(runes) > 0 && unicode.IsLetter(runes[len(runes)-1])
+}
+
+// moneyLocaleKey returns the moneyLocales key of a locale tag such as de_DE.UTF-8 or de-CH.
+func moneyLocaleKey(tag string) (string, bool) {
+	tag = strings.Replace(strings.SplitN(tag, ".", 2)[0], "_", "-", -1)
+	parts := strings.SplitN(tag, "-", 2)
+	language := strings.ToLower(parts[0])
+	if len(parts) == 2 {
+		full := language + "-" + strings.ToUpper(parts[1])
+		if _, ok := moneyLocales[full]; ok {
+			return full, true
+		}
+	}
+	_, ok := moneyLocales[language]
+	return language, ok
+}
+
+// moneyLocaleOf returns the locale of a tag, falling back to the canonical display for unknown ones.
+func moneyLocaleOf(tag string) (moneyLocale, bool) {
+	key, ok := moneyLocaleKey(tag)
+	if !ok {
+		return canonicalLocale, false
+	}
+	return moneyLocales[key], true
+}
+
+// moneyLocaleKeys returns the supported locales, sorted.
+func moneyLocaleKeys() []string {
+	keys := make([]string, 0, len(moneyLocales))
+	for key := range moneyLocales {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
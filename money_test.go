@@ -0,0 +1,39 @@
+package pricing
+
+import (
+	"testing"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+func TestMoneyFormat(t *testing.T) {
+	amount := sdkmath.LegacyMustNewDecFromStr("-1234567.891")
+	tests := []struct {
+		locale, symbols, currency string
+		want                      string
+	}{
+		{"", "", "USD", "-$1234567.89"},
+		{"", "", "EUR", "-1234567.89 EUR"},
+		{"", "EUR=€", "EUR", "-€1234567.89"},
+		{"en_US.UTF-8", "", "EUR", "-€1,234,567.89"},
+		{"en", "", "SEK", "-SEK 1,234,567.89"},
+		{"de", "USD=US$", "USD", "-1.234.567,89 US$"},
+		{"de-ch", "", "CHF", "-CHF 1'234'567.89"},
+		{"fr", "", "EUR", "-1\u202f234\u202f567,89 €"},
+	}
+	for _, tt := range tests {
+		format, err := ParseMoneyFormat(tt.locale, tt.symbols)
+		if err != nil {
+			t.Fatalf("%q %q: %v", tt.locale, tt.symbols, err)
+		}
+		if got := format.Format(amount, tt.currency, 2); got != tt.want {
+			t.Errorf("%q %q %s: got %q, want %q", tt.locale, tt.symbols, tt.currency, got, tt.want)
+		}
+	}
+
+	for _, bad := range [][2]string{{"xx", ""}, {"", "EURO=€"}, {"", "EUR="}} {
+		if _, err := ParseMoneyFormat(bad[0], bad[1]); err == nil {
+			t.Errorf("%q %q: expected an error", bad[0], bad[1])
+		}
+	}
+}
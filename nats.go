@@ -0,0 +1,73 @@
+//go:build nats
+
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Run connects to the NATS server and prices orders until ctx is done, then drains the subscription so
+// orders already received are still answered.
+func (c *NATSConsumer) Run(ctx context.Context) error {
+	url := c.URL
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	closed := make(chan struct{})
+	options := []nats.Option{
+		nats.Name("akash-pricing"),
+		nats.MaxReconnects(-1),
+		nats.ClosedHandler(func(*nats.Conn) { close(closed) }),
+	}
+	if c.Credentials != "" {
+		options = append(options, nats.UserCredentials(c.Credentials))
+	}
+	conn, err := nats.Connect(url, options...)
+	if err != nil {
+		return fmt.Errorf("error connecting to NATS at %s: %w", url, err)
+	}
+
+	handler := func(msg *nats.Msg) {
+		// Orders drained after ctx is done are still priced, so they don't use ctx
+		result := c.priceMessage(context.Background(), msg.Data)
+		data, err := json.Marshal(result)
+		if err != nil {
+			log.Printf("Error encoding result of order %s: %v", result.OrderID, err)
+			return
+		}
+		switch {
+		case msg.Reply != "":
+			err = msg.Respond(data)
+		case c.ResultSubject != "":
+			err = conn.Publish(c.ResultSubject, data)
+		default:
+			log.Printf("Dropping result of order %s: the message has no reply subject and NATS_RESULT_SUBJECT is unset", result.OrderID)
+			return
+		}
+		if err != nil {
+			log.Printf("Error publishing result of order %s: %v", result.OrderID, err)
+		}
+	}
+	if c.Queue != "" {
+		_, err = conn.QueueSubscribe(c.Subject, c.Queue, handler)
+	} else {
+		_, err = conn.Subscribe(c.Subject, handler)
+	}
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("error subscribing to %s: %w", c.Subject, err)
+	}
+
+	<-ctx.Done()
+	if err := conn.Drain(); err != nil {
+		conn.Close()
+		return err
+	}
+	<-closed
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !nats
+
+package pricing
+
+import (
+	"context"
+	"fmt"
+)
+
+// Run reports that the NATS consumer needs a build with the nats tag.
+func (c *NATSConsumer) Run(ctx context.Context) error {
+	return fmt.Errorf("NATS consumer requires a build with -tags nats")
+}
@@ -0,0 +1,360 @@
+package pricing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+	attrtypes "pkg.akt.dev/go/node/types/attributes/v1"
+	rtypes "pkg.akt.dev/go/node/types/resources/v1beta4"
+)
+
+// LegacyOrderDenom is the denom of orders from providers that predate the price field.
+const LegacyOrderDenom = "uakt"
+
+// bidScriptResource is one element of the resources array the provider passes to bid scripts.
+type bidScriptResource struct {
+	CPU              uint64             `json:"cpu"`    // Millicores
+	Memory           uint64             `json:"memory"` // Bytes
+	GPU              *bidScriptGPU      `json:"gpu"`
+	Storage          []bidScriptStorage `json:"storage"`
+	Count            uint32             `json:"count"`
+	EndpointQuantity uint32             `json:"endpoint_quantity"`
+	IPLeaseQuantity  uint32             `json:"ip_lease_quantity"`
+}
+
+// bidScriptGPU is the GPU request of a bid script resource.
+type bidScriptGPU struct {
+	Units      uint64 `json:"units"`
+	Attributes struct {
+		// Vendor maps a vendor to one GPU spec (older providers) or a list of acceptable specs
+		Vendor map[string]json.RawMessage `json:"vendor"`
+	} `json:"attributes"`
+}
+
+// bidScriptGPUSpec is a GPU model accepted by a bid script resource.
+type bidScriptGPUSpec struct {
+	Model     string `json:"model"`
+	RAM       string `json:"ram"`
+	Interface string `json:"interface"`
+}
+
+// bidScriptStorage is a volume of a bid script resource.
+type bidScriptStorage struct {
+	Class string `json:"class"`
+	Size  uint64 `json:"size"` // Bytes
+}
+
+// DecodeDeploymentOrder reads the JSON payload the provider passes to bid scripts on stdin.
+func DecodeDeploymentOrder(r io.Reader) (*DeploymentOrder, error) {
+	var order DeploymentOrder
+	decoder := json.NewDecoder(r)
+	if err := decoder.Decode(&order); err != nil {
+		return nil, fmt.Errorf("error decoding deployment order: %w", err)
+	}
+	return &order, nil
+}
+
+// GroupSpec decodes the order's resources into a GroupSpec priced at the order's price.
+// Resources may be in the provider's bid script format or serialized v1beta4 resource units; errors name
+// the offending field, e.g. "resources[1].storage[0].size".
+func (o *DeploymentOrder) GroupSpec() (*dtypes.GroupSpec, error) {
+	price, err := o.price()
+	if err != nil {
+		return nil, err
+	}
+
+	var elements []json.RawMessage
+	if err := json.Unmarshal(o.Resources, &elements); err != nil {
+		return nil, fmt.Errorf("resources: %w", err)
+	}
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("resources: %w", ErrEmptyGroupSpec)
+	}
+
+	gSpec := &dtypes.GroupSpec{Name: "order"}
+	var ipSequence uint32
+	for i, element := range elements {
+		path := fmt.Sprintf("resources[%d]", i)
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(element, &fields); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		var resourceUnit dtypes.ResourceUnit
+		if _, ok := fields["resource"]; ok {
+			if err := json.Unmarshal(element, &resourceUnit); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		} else {
+			resourceUnit, err = decodeBidScriptResource(fields, path, &ipSequence)
+			if err != nil {
+				return nil, err
+			}
+		}
+		resourceUnit.Price = price
+		gSpec.Resources = append(gSpec.Resources, resourceUnit)
+	}
+
+	if err := ValidateGroupSpec(gSpec); err != nil {
+		return nil, err
+	}
+	return gSpec, nil
+}
+
+// Request builds the pricing request for the order placed by owner.
+func (o *DeploymentOrder) Request(owner string) (Request, error) {
+	gSpec, err := o.GroupSpec()
+	if err != nil {
+		return Request{}, err
+	}
+
+	request := Request{
+		Owner:            owner,
+		GSpec:            gSpec,
+		OrderID:          string(o.OrderID),
+		Provider:         o.Provider,
+		PricePrecision:   o.PricePrecision,
+		NoMaxPrice:       o.Price == nil,
+		ExpectedDuration: time.Duration(o.ExpectedDurationSeconds) * time.Second,
+	}
+	if o.Deposit != nil {
+		amount, err := sdkmath.LegacyNewDecFromStr(o.Deposit.Amount)
+		if err != nil {
+			return Request{}, fmt.Errorf("deposit.amount: invalid amount %q: %w", o.Deposit.Amount, err)
+		}
+		deposit := sdk.DecCoin{Denom: o.Deposit.Denom, Amount: amount}
+		request.Deposit = &deposit
+	}
+	return request, nil
+}
+
+// OrderID is the dseq/gseq/oseq of a deployment order. It decodes from that string or from the chain's
+// {"dseq", "gseq", "oseq"} object, whose dseq is a string or a number depending on the chain version.
+type OrderID string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (id *OrderID) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		var ref orderRef
+		if text != "" {
+			if _, err := fmt.Sscanf(text, "%d/%d/%d", &ref.DSeq, &ref.GSeq, &ref.OSeq); err != nil || ref.OrderID() != text {
+				return fmt.Errorf("order_id: %q is not dseq/gseq/oseq", text)
+			}
+		}
+		*id = OrderID(text)
+		return nil
+	}
+
+	var fields struct {
+		DSeq json.Number `json:"dseq"`
+		GSeq uint32      `json:"gseq"`
+		OSeq uint32      `json:"oseq"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("order_id: %w", err)
+	}
+	dseq, err := strconv.ParseUint(fields.DSeq.String(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("order_id.dseq: invalid dseq %q", fields.DSeq)
+	}
+	*id = OrderID(orderRef{DSeq: dseq, GSeq: fields.GSeq, OSeq: fields.OSeq}.OrderID())
+	return nil
+}
+
+// price returns the order's max price. Orders without one come from providers that predate the price
+// field; they are bid in LegacyOrderDenom without a max price. An empty amount is left nil, for quote
+// requests that only name the denom.
+func (o *DeploymentOrder) price() (sdk.DecCoin, error) {
+	if o.Price == nil {
+		return sdk.DecCoin{Denom: LegacyOrderDenom}, nil
+	}
+	if o.Price.Denom == "" {
+		return sdk.DecCoin{}, fmt.Errorf("price.denom: denom is empty")
+	}
+	if o.Price.Amount == "" {
+		return sdk.DecCoin{Denom: o.Price.Denom}, nil
+	}
+	amount, err := sdkmath.LegacyNewDecFromStr(o.Price.Amount)
+	if err != nil {
+		return sdk.DecCoin{}, fmt.Errorf("price.amount: invalid amount %q: %w", o.Price.Amount, err)
+	}
+	return sdk.DecCoin{Denom: o.Price.Denom, Amount: amount}, nil
+}
+
+// decodeBidScriptResource converts a bid script resource element into a resource unit. Leased IPs get
+// sequence numbers from ipSequence so IPs of different elements are not merged.
+func decodeBidScriptResource(fields map[string]json.RawMessage, path string, ipSequence *uint32) (dtypes.ResourceUnit, error) {
+	var resource bidScriptResource
+	// Fields are decoded in a fixed order so the first malformed field is always the one reported
+	for _, field := range []struct {
+		key string
+		dst interface{}
+	}{
+		{"cpu", &resource.CPU},
+		{"memory", &resource.Memory},
+		{"gpu", &resource.GPU},
+		{"count", &resource.Count},
+		{"endpoint_quantity", &resource.EndpointQuantity},
+		{"ip_lease_quantity", &resource.IPLeaseQuantity},
+	} {
+		raw, ok := fields[field.key]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, field.dst); err != nil {
+			return dtypes.ResourceUnit{}, fmt.Errorf("%s.%s: %w", path, field.key, err)
+		}
+	}
+	if _, ok := fields["cpu"]; !ok {
+		return dtypes.ResourceUnit{}, fmt.Errorf("%s.cpu: field is required", path)
+	}
+	if _, ok := fields["memory"]; !ok {
+		return dtypes.ResourceUnit{}, fmt.Errorf("%s.memory: field is required", path)
+	}
+	if resource.Count == 0 {
+		return dtypes.ResourceUnit{}, fmt.Errorf("%s.count: count must be at least 1", path)
+	}
+
+	resources := rtypes.Resources{
+		ID:     1,
+		CPU:    &rtypes.CPU{Units: resourceValue(resource.CPU)},
+		Memory: &rtypes.Memory{Quantity: resourceValue(resource.Memory)},
+	}
+
+	if resource.GPU != nil {
+		attributes, err := decodeGPUVendors(resource.GPU.Attributes.Vendor, path+".gpu.attributes.vendor")
+		if err != nil {
+			return dtypes.ResourceUnit{}, err
+		}
+		resources.GPU = &rtypes.GPU{Units: resourceValue(resource.GPU.Units), Attributes: attributes}
+	}
+
+	if raw, ok := fields["storage"]; ok {
+		var err error
+		resource.Storage, err = decodeBidScriptStorage(raw, path+".storage")
+		if err != nil {
+			return dtypes.ResourceUnit{}, err
+		}
+	}
+	for _, storage := range resource.Storage {
+		resources.Storage = append(resources.Storage, rtypes.Storage{
+			Name:       storage.Class,
+			Quantity:   resourceValue(storage.Size),
+			Attributes: attrtypes.Attributes{{Key: "class", Value: storage.Class}},
+		})
+	}
+
+	for j := uint32(0); j < resource.EndpointQuantity; j++ {
+		resources.Endpoints = append(resources.Endpoints, rtypes.Endpoint{Kind: rtypes.Endpoint_SHARED_HTTP})
+	}
+	for j := uint32(0); j < resource.IPLeaseQuantity; j++ {
+		*ipSequence++
+		resources.Endpoints = append(resources.Endpoints, rtypes.Endpoint{Kind: rtypes.Endpoint_LEASED_IP, SequenceNumber: *ipSequence})
+	}
+
+	return dtypes.ResourceUnit{Resources: resources, Count: resource.Count}, nil
+}
+
+// decodeBidScriptStorage decodes the volumes of a bid script resource one field at a time so errors name
+// the offending volume.
+func decodeBidScriptStorage(raw json.RawMessage, path string) ([]bidScriptStorage, error) {
+	var volumes []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &volumes); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	storage := make([]bidScriptStorage, 0, len(volumes))
+	for j, volume := range volumes {
+		var decoded bidScriptStorage
+		if err := json.Unmarshal(volume["class"], &decoded.Class); err != nil || decoded.Class == "" {
+			return nil, fmt.Errorf("%s[%d].class: class must be a non-empty string", path, j)
+		}
+		size, ok := volume["size"]
+		if !ok {
+			return nil, fmt.Errorf("%s[%d].size: field is required", path, j)
+		}
+		if err := json.Unmarshal(size, &decoded.Size); err != nil {
+			return nil, fmt.Errorf("%s[%d].size: %w", path, j, err)
+		}
+		storage = append(storage, decoded)
+	}
+	return storage, nil
+}
+
+// decodeGPUVendors converts the vendor map of a bid script GPU into GPU attributes such as
+// "vendor/nvidia/model/a100/ram/80Gi/interface/sxm". Each vendor holds one spec or a list of specs; payloads
+// that put a single spec directly under vendor yield a vendor-less key such as "vendor/model/rtx4090".
+func decodeGPUVendors(vendors map[string]json.RawMessage, path string) (attrtypes.Attributes, error) {
+	names := make([]string, 0, len(vendors))
+	flat := false
+	for name, raw := range vendors {
+		names = append(names, name)
+		if raw = bytes.TrimSpace(raw); len(raw) > 0 && raw[0] == '"' {
+			flat = true
+		}
+	}
+	sort.Strings(names)
+
+	if flat {
+		var spec bidScriptGPUSpec
+		raw, _ := json.Marshal(vendors)
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return attrtypes.Attributes{{Key: gpuAttributeKey("vendor", spec), Value: "true"}}, nil
+	}
+
+	var attributes attrtypes.Attributes
+	for _, name := range names {
+		raw := bytes.TrimSpace(vendors[name])
+
+		var specs []bidScriptGPUSpec
+		if len(raw) > 0 && raw[0] == '[' {
+			if err := json.Unmarshal(raw, &specs); err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", path, name, err)
+			}
+		} else {
+			var spec bidScriptGPUSpec
+			if err := json.Unmarshal(raw, &spec); err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", path, name, err)
+			}
+			specs = append(specs, spec)
+		}
+
+		for _, spec := range specs {
+			attributes = append(attributes, attrtypes.Attribute{Key: gpuAttributeKey("vendor/"+name, spec), Value: "true"})
+		}
+	}
+	return attributes, nil
+}
+
+// gpuAttributeKey appends the set fields of a GPU spec to prefix.
+func gpuAttributeKey(prefix string, spec bidScriptGPUSpec) string {
+	key := prefix
+	if spec.Model != "" {
+		key += "/model/" + spec.Model
+	}
+	if spec.RAM != "" {
+		key += "/ram/" + spec.RAM
+	}
+	if spec.Interface != "" {
+		key += "/interface/" + spec.Interface
+	}
+	return key
+}
+
+// resourceValue wraps an unsigned quantity as a resource value.
+func resourceValue(v uint64) rtypes.ResourceValue {
+	return rtypes.ResourceValue{Val: sdkmath.NewIntFromUint64(v)}
+}
@@ -0,0 +1,44 @@
+package pricing
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// FuzzDeploymentOrder checks bid script payloads of any shape are either rejected with an error or decode
+// into a valid GroupSpec whose resources can be totalled.
+func FuzzDeploymentOrder(f *testing.F) {
+	paths, err := filepath.Glob(filepath.Join("examples", "*.json"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte(`{"resources": [{"cpu": 1000, "memory": 1073741824, "count": 1}], "order_id": "1/1/1"}`))
+	f.Add([]byte(`{"resources": [{"cpu": 1000, "memory": 1, "count": 1, "gpu": {"units": 1, "attributes": {"vendor": {"nvidia": [{"model": "a100"}, {"model": "h100", "ram": "80Gi"}]}}}}]}`))
+	f.Add([]byte(`{"price": {"denom": "uakt", "amount": "-1"}, "resources": []}`))
+	f.Add([]byte(`{"resources": [{"cpu": -5}]}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		order, err := DecodeDeploymentOrder(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		request, err := order.Request("akash1fuzz")
+		if err != nil {
+			return
+		}
+		if err := ValidateGroupSpec(request.GSpec); err != nil {
+			t.Fatalf("decoded an invalid GroupSpec: %v", err)
+		}
+		CalculateRequestedResources(request.GSpec)
+		CalculateTotalGPUPrice(request.GSpec, nil, MaxGPUPrice(nil))
+	})
+}
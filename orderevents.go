@@ -0,0 +1,265 @@
+package pricing
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	dv1 "pkg.akt.dev/go/node/deployment/v1"
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// Defaults of the order event listener.
+const (
+	DefaultOrderEventsQuery   = "tm.event='Tx' AND akash.market.v1.EventOrderCreated.id EXISTS"
+	DefaultPrecomputedBidTTL  = 10 * time.Minute
+	DefaultOrderEventsTimeout = 10 * time.Second
+)
+
+// OrderEventListener subscribes to order-created events on an Akash node's Tendermint websocket and prices
+// every new order as soon as it is created, caching the bid in its PricingEngine under the order ID. When
+// the provider then asks the engine for the bid of that order, e.g. through /price?order_id=, the answer is
+// already there instead of waiting on the oracle, whitelist and other lookups.
+type OrderEventListener struct {
+	RPCURL      string        // Tendermint RPC endpoint of an Akash node, e.g. tcp://akash-node:26657
+	GRPCAddress string        // host:port of the node's gRPC endpoint, queried for the group of each order
+	UseTLS      bool          // Dial the gRPC endpoint with TLS
+	Query       string        // Event query subscribed to
+	Timeout     time.Duration // Deadline of pricing one order, including its group query
+
+	engine *PricingEngine
+}
+
+// OrderEventListenerFromEnv reads CHAIN_EVENTS_RPC, CHAIN_EVENTS_GRPC (defaulting to WHITELIST_CHAIN_GRPC),
+// CHAIN_EVENTS_GRPC_TLS, CHAIN_EVENTS_QUERY, CHAIN_EVENTS_TTL and CHAIN_EVENTS_TIMEOUT. Bids are cached in
+// engine, which gets CHAIN_EVENTS_TTL as its PrecomputedTTL. It returns nil when CHAIN_EVENTS_RPC is not set.
+func OrderEventListenerFromEnv(engine *PricingEngine) (*OrderEventListener, error) {
+	rpcURL := strings.TrimSpace(os.Getenv("CHAIN_EVENTS_RPC"))
+	if rpcURL == "" {
+		return nil, nil
+	}
+
+	listener := &OrderEventListener{
+		RPCURL:      rpcURL,
+		GRPCAddress: strings.Trim(os.Getenv("CHAIN_EVENTS_GRPC"), "\""),
+		UseTLS:      os.Getenv("CHAIN_EVENTS_GRPC_TLS") == "true",
+		Query:       os.Getenv("CHAIN_EVENTS_QUERY"),
+		Timeout:     DefaultOrderEventsTimeout,
+		engine:      engine,
+	}
+	if listener.GRPCAddress == "" {
+		listener.GRPCAddress = strings.Trim(os.Getenv("WHITELIST_CHAIN_GRPC"), "\"")
+		listener.UseTLS = os.Getenv("WHITELIST_CHAIN_GRPC_TLS") == "true"
+	}
+	if listener.GRPCAddress == "" {
+		return nil, fmt.Errorf("CHAIN_EVENTS_GRPC is required with CHAIN_EVENTS_RPC to query the groups of new orders")
+	}
+	if listener.Query == "" {
+		listener.Query = DefaultOrderEventsQuery
+	}
+
+	ttl := DefaultPrecomputedBidTTL
+	for name, value := range map[string]*time.Duration{"CHAIN_EVENTS_TTL": &ttl, "CHAIN_EVENTS_TIMEOUT": &listener.Timeout} {
+		val := os.Getenv(name)
+		if val == "" {
+			continue
+		}
+		d, err := time.ParseDuration(val)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid %s %q: must be a positive duration", name, val)
+		}
+		*value = d
+	}
+	if listener.engine != nil {
+		listener.engine.PrecomputedTTL = ttl
+	}
+	return listener, nil
+}
+
+// orderRef identifies a created order.
+type orderRef struct {
+	Owner string
+	DSeq  uint64
+	GSeq  uint32
+	OSeq  uint32
+}
+
+// OrderID returns the order ID in the dseq/gseq/oseq form of Request.OrderID.
+func (o orderRef) OrderID() string {
+	return fmt.Sprintf("%d/%d/%d", o.DSeq, o.GSeq, o.OSeq)
+}
+
+// parseOrderCreated extracts the orders of the EventOrderCreated events of a transaction. Typed events
+// carry the order ID as JSON in their id attribute, with dseq as a string or a number depending on the
+// chain version.
+func parseOrderCreated(events map[string][]string) ([]orderRef, error) {
+	var orders []orderRef
+	for key, values := range events {
+		if !strings.HasSuffix(key, "EventOrderCreated.id") {
+			continue
+		}
+		for _, value := range values {
+			var id struct {
+				Owner string      `json:"owner"`
+				DSeq  json.Number `json:"dseq"`
+				GSeq  json.Number `json:"gseq"`
+				OSeq  json.Number `json:"oseq"`
+			}
+			if err := json.Unmarshal([]byte(value), &id); err != nil {
+				return orders, fmt.Errorf("error decoding order ID %s: %w", value, err)
+			}
+			dseq, err := strconv.ParseUint(id.DSeq.String(), 10, 64)
+			if err != nil {
+				return orders, fmt.Errorf("invalid dseq in order ID %s", value)
+			}
+			gseq, err := strconv.ParseUint(id.GSeq.String(), 10, 32)
+			if err != nil {
+				return orders, fmt.Errorf("invalid gseq in order ID %s", value)
+			}
+			oseq, err := strconv.ParseUint(id.OSeq.String(), 10, 32)
+			if err != nil {
+				return orders, fmt.Errorf("invalid oseq in order ID %s", value)
+			}
+			if id.Owner == "" {
+				return orders, fmt.Errorf("missing owner in order ID %s", value)
+			}
+			orders = append(orders, orderRef{Owner: id.Owner, DSeq: dseq, GSeq: uint32(gseq), OSeq: uint32(oseq)})
+		}
+	}
+	return orders, nil
+}
+
+// handleEvents prices the orders created by an event in the background, logging events it cannot read.
+func (l *OrderEventListener) handleEvents(ctx context.Context, events map[string][]string) {
+	orders, err := parseOrderCreated(events)
+	if err != nil {
+		log.Printf("Error reading order event: %v", err)
+	}
+	for _, order := range orders {
+		go l.precompute(ctx, order)
+	}
+}
+
+// precompute queries the group of a created order and prices it into the engine's precomputed bids.
+func (l *OrderEventListener) precompute(ctx context.Context, order orderRef) {
+	ctx, cancel := context.WithTimeout(ctx, l.Timeout)
+	defer cancel()
+
+	spec, err := l.groupSpec(ctx, order)
+	if err != nil {
+		log.Printf("Error precomputing bid of order %s/%s: %v", order.Owner, order.OrderID(), err)
+		return
+	}
+	request := Request{Owner: order.Owner, GSpec: spec, OrderID: order.OrderID()}
+	if _, err := l.engine.Precompute(ctx, request); err != nil {
+		log.Printf("Precomputed bid of order %s/%s: %v", order.Owner, order.OrderID(), err)
+	}
+}
+
+// groupSpec queries the GroupSpec of an order's group over gRPC.
+func (l *OrderEventListener) groupSpec(ctx context.Context, order orderRef) (*dtypes.GroupSpec, error) {
+	creds := insecure.NewCredentials()
+	if l.UseTLS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.NewClient(l.GRPCAddress, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", l.GRPCAddress, err)
+	}
+	defer conn.Close()
+
+	resp, err := dtypes.NewQueryClient(conn).Group(ctx, &dtypes.QueryGroupRequest{
+		ID: dv1.GroupID{Owner: order.Owner, DSeq: order.DSeq, GSeq: order.GSeq},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error querying group %s/%d/%d: %w", order.Owner, order.DSeq, order.GSeq, err)
+	}
+	return &resp.Group.GroupSpec, nil
+}
+
+// precomputedBid is the outcome of pricing an order before the provider asked for it.
+type precomputedBid struct {
+	result    *BidResult
+	err       error
+	denom     string
+	precision int
+	pricedAt  time.Time
+}
+
+// precomputedKey keys a request's precomputed bid by owner and order ID.
+func precomputedKey(request Request) string {
+	return request.Owner + "/" + request.OrderID
+}
+
+// requestDenomPrecision returns the price denom and effective precision of a request, which a precomputed
+// bid must match to answer it.
+func requestDenomPrecision(request Request) (string, int) {
+	var denom string
+	if request.GSpec != nil && len(request.GSpec.Resources) > 0 {
+		denom = request.GSpec.Resources[0].Price.Denom
+	}
+	precision := request.PricePrecision
+	if precision == 0 {
+		precision = DefaultPricePrecision
+	}
+	return denom, precision
+}
+
+// Precompute prices a request that has an OrderID and keeps the outcome for PrecomputedTTL, so a later
+// CalculateBid of the same owner and order returns it without pricing again. Bids and rejections are kept;
+// failures are not, so the provider's own request retries them.
+func (e *PricingEngine) Precompute(ctx context.Context, request Request) (*BidResult, error) {
+	if request.OrderID == "" {
+		return nil, withReason(ErrInvalidRequest, fmt.Errorf("precomputed bids need an order ID"))
+	}
+	if err := e.withAKTPrice(&request); err != nil {
+		return nil, err
+	}
+	result, err := calculateBidContext(ctx, request)
+	if _, rejected := ErrorReason(err); err != nil && !rejected {
+		return result, err
+	}
+
+	denom, precision := requestDenomPrecision(request)
+	now := time.Now()
+	e.bidsMu.Lock()
+	defer e.bidsMu.Unlock()
+	if e.precomputed == nil {
+		e.precomputed = make(map[string]precomputedBid)
+	}
+	for key, bid := range e.precomputed {
+		if now.Sub(bid.pricedAt) >= e.PrecomputedTTL {
+			delete(e.precomputed, key)
+		}
+	}
+	e.precomputed[precomputedKey(request)] = precomputedBid{result, err, denom, precision, now}
+	return result, err
+}
+
+// precomputedBid returns the precomputed bid of a request's order, if one was priced within PrecomputedTTL
+// in the request's denom and precision.
+func (e *PricingEngine) precomputedBid(request Request) (*BidResult, error, bool) {
+	if request.OrderID == "" {
+		return nil, nil, false
+	}
+	e.bidsMu.Lock()
+	bid, ok := e.precomputed[precomputedKey(request)]
+	e.bidsMu.Unlock()
+	if !ok || time.Since(bid.pricedAt) >= e.PrecomputedTTL {
+		return nil, nil, false
+	}
+	if denom, precision := requestDenomPrecision(request); denom != bid.denom || precision != bid.precision {
+		return nil, nil, false
+	}
+	return bid.result, bid.err, true
+}
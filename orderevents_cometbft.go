@@ -0,0 +1,46 @@
+//go:build cometbft
+
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	rpchttp "github.com/cometbft/cometbft/rpc/client/http"
+)
+
+// orderEventsSubscriber names the listener's subscription on the node.
+const orderEventsSubscriber = "akash-pricing"
+
+// Run subscribes to Query on the node's websocket and precomputes the bid of every created order until ctx
+// is done. The client reconnects and resubscribes by itself; Run returns an error if the subscription ends
+// anyway.
+func (l *OrderEventListener) Run(ctx context.Context) error {
+	client, err := rpchttp.New(l.RPCURL, "/websocket")
+	if err != nil {
+		return fmt.Errorf("error creating RPC client for %s: %w", l.RPCURL, err)
+	}
+	if err := client.Start(); err != nil {
+		return fmt.Errorf("error connecting to %s: %w", l.RPCURL, err)
+	}
+	defer client.Stop()
+
+	events, err := client.Subscribe(ctx, orderEventsSubscriber, l.Query)
+	if err != nil {
+		return fmt.Errorf("error subscribing to %q on %s: %w", l.Query, l.RPCURL, err)
+	}
+	log.Printf("Precomputing bids of orders created on %s", l.RPCURL)
+	for {
+		select {
+		case <-ctx.Done():
+			client.UnsubscribeAll(context.Background(), orderEventsSubscriber)
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return fmt.Errorf("order event subscription on %s closed", l.RPCURL)
+			}
+			l.handleEvents(ctx, event.Events)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !cometbft
+
+package pricing
+
+import (
+	"context"
+	"fmt"
+)
+
+// Run reports that the order event listener needs a build with the cometbft tag.
+func (l *OrderEventListener) Run(ctx context.Context) error {
+	return fmt.Errorf("order event listener requires a build with -tags cometbft")
+}
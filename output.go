@@ -0,0 +1,110 @@
+package pricing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Output formats of the bid script, selected by --output or BID_SCRIPT_OUTPUT.
+const (
+	OutputPlain = "plain" // Bare price, as expected by all provider versions
+	OutputJSON  = "json"  // Versioned BidResponse object
+)
+
+// BidResponseVersion is the version of the JSON bid script response.
+const BidResponseVersion = 1
+
+// DefaultPricePrecision is the number of decimal places of a bid when the order does not specify one.
+const DefaultPricePrecision = 6
+
+// BidResponse is the JSON response of the bid script. Price is empty and Reason is set when no bid is made.
+// Decision is DecisionDecline for deliberate rejections, DecisionQuote for quotes, DecisionRetry when the
+// daemon's queue is full and empty when pricing failed.
+type BidResponse struct {
+	Version       int    `json:"version"`
+	Decision      string `json:"decision,omitempty"`
+	Price         string `json:"price,omitempty"`
+	Denom         string `json:"denom,omitempty"`
+	Precision     int    `json:"precision"`
+	Reason        string `json:"reason,omitempty"`
+	ReasonCode    string `json:"reason_code,omitempty"`    // Machine-readable Reason, see ErrorReason
+	Failed        bool   `json:"failed,omitempty"`         // No bid because pricing failed rather than by choice
+	RequiredPrice string `json:"required_price,omitempty"` // Lowest rate the provider bids when the max price is too low
+	PriceGap      string `json:"price_gap,omitempty"`      // RequiredPrice minus the order's max price
+	Build         string `json:"build,omitempty"`          // Build version of the pricing script, see BuildVersion
+	ConfigHash    string `json:"config_hash,omitempty"`    // Configuration the bid was priced with, see ConfigHash
+}
+
+// ParseOutputFormat validates a bid script output format, defaulting to plain.
+func ParseOutputFormat(format string) (string, error) {
+	switch format {
+	case "":
+		return OutputPlain, nil
+	case OutputPlain, OutputJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid output format %q: must be plain or json", format)
+}
+
+// OutputFormatFromEnv returns the output format requested by the provider through BID_SCRIPT_OUTPUT.
+func OutputFormatFromEnv() (string, error) {
+	return ParseOutputFormat(os.Getenv("BID_SCRIPT_OUTPUT"))
+}
+
+// NewBidResponse builds the response for a request priced with CalculateBid. err is the pricing error, if any.
+func NewBidResponse(request Request, result *BidResult, err error) BidResponse {
+	response := BidResponse{Version: BidResponseVersion, Precision: request.PricePrecision, Build: BuildVersion()}
+	if response.Precision == 0 {
+		response.Precision = DefaultPricePrecision
+	}
+	if request.GSpec != nil && len(request.GSpec.Resources) > 0 {
+		response.Denom = request.GSpec.Resources[0].Price.Denom
+	}
+
+	if err != nil {
+		var rejected bool
+		response.Reason = err.Error()
+		response.ReasonCode, rejected = ErrorReason(err)
+		response.Failed = !rejected
+		if rejected {
+			response.Decision = DecisionDecline
+		} else if errors.Is(err, ErrQueueFull) {
+			response.Decision = DecisionRetry
+		}
+		var decline *DeclineError
+		if errors.As(err, &decline) {
+			response.RequiredPrice = decline.RequiredPrice()
+			response.PriceGap = decline.PriceGap()
+		}
+		return response
+	}
+	response.Decision = DecisionBid
+	if result.Quote {
+		response.Decision = DecisionQuote
+	}
+	response.Price = result.Price
+	response.ConfigHash = result.ConfigHash
+	if result.Denom != "" {
+		response.Denom = result.Denom
+	}
+	if result.Precision != 0 {
+		response.Precision = result.Precision
+	}
+	return response
+}
+
+// WriteBidResponse writes the response in the given format. Plain output is the bare price, and nothing for
+// rejected requests; JSON output always writes the response so the reason reaches the provider.
+func WriteBidResponse(w io.Writer, format string, response BidResponse) error {
+	if format == OutputJSON {
+		return json.NewEncoder(w).Encode(response)
+	}
+	if response.Price == "" {
+		return nil
+	}
+	_, err := fmt.Fprintln(w, response.Price)
+	return err
+}
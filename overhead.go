@@ -0,0 +1,22 @@
+package pricing
+
+import (
+	"fmt"
+	"strconv"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// ApplyDeploymentOverhead adds a flat monthly overhead in USD, covering per-deployment costs such as IP
+// provisioning, storage setup and support, to the monthly cost of an order. It is added after the
+// percentage discounts, so they never reduce it. The returned adjustment is nil if overheadUsd is not
+// positive.
+func ApplyDeploymentOverhead(overheadUsd float64, totalCostUsd sdkmath.LegacyDec) (sdkmath.LegacyDec, *Adjustment) {
+	if overheadUsd <= 0 {
+		return totalCostUsd, nil
+	}
+	return totalCostUsd.Add(decFromFloat(overheadUsd)), &Adjustment{
+		Name:   "overhead",
+		Detail: fmt.Sprintf("$%s/month deployment overhead", strconv.FormatFloat(overheadUsd, 'f', -1, 64)),
+	}
+}
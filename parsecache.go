@@ -0,0 +1,119 @@
+package pricing
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxParseCacheEntries bounds a parse cache; it is reset rather than evicted entry by entry, since the
+// configuration strings of a running provider rarely change.
+const maxParseCacheEntries = 64
+
+// parseCache memoizes the parse of configuration strings such as PRICE_TARGET_GPU_MAPPINGS, so each bid
+// reuses the parsed table while the value is unchanged. Parsed values are shared between bids and must not
+// be modified.
+type parseCache[T any] struct {
+	parse func(string) (T, error)
+
+	mu      sync.Mutex
+	entries map[string]parseResult[T]
+}
+
+// parseResult is a memoized parse, errors included.
+type parseResult[T any] struct {
+	value T
+	err   error
+}
+
+// newParseCache returns a cache memoizing parse.
+func newParseCache[T any](parse func(string) (T, error)) *parseCache[T] {
+	return &parseCache[T]{parse: parse, entries: make(map[string]parseResult[T])}
+}
+
+// get returns the parse of raw, parsing it on first use.
+func (c *parseCache[T]) get(raw string) (T, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result, ok := c.entries[raw]
+	parseCacheCounts.count(ok)
+	if ok {
+		return result.value, result.err
+	}
+	if len(c.entries) >= maxParseCacheEntries {
+		c.entries = make(map[string]parseResult[T])
+	}
+	value, err := c.parse(raw)
+	c.entries[raw] = parseResult[T]{value, err}
+	return value, err
+}
+
+// Parse caches of the configuration read on every bid.
+var (
+	gpuMappingsCache        = newParseCache(parseGPUMappingsKey)
+	storageTargetsCache     = newParseCache(ParseStorageClassTargets)
+	storageTierTargetsCache = newParseCache(ParseStorageTierTargets)
+	cpuClassTargetsCache    = newParseCache(ParseCPUClassTargets)
+	surgeTiersCache         = newParseCache(ParseSurgeTiers)
+	reputationBandsCache    = newParseCache(ParseReputationBands)
+	volumeDiscountsCache    = newParseCache(ParseVolumeDiscounts)
+	durationTiersCache      = newParseCache(ParseDurationTiers)
+	ipDiscountsCache        = newParseCache(ParseIPDiscounts)
+	loyaltyTiersCache       = newParseCache(ParseLoyaltyTiers)
+)
+
+// gpuMappingsKey keys the GPU mappings cache by the hours per month of hourly prices as well as the mapping
+// string, so changing PRICE_TARGET_HOURS_PER_MONTH reprices hourly GPUs.
+func gpuMappingsKey(mappingStr string) string {
+	return strconv.FormatFloat(hoursPerMonth(), 'g', -1, 64) + " " + mappingStr
+}
+
+// parseGPUMappingsKey parses a gpuMappingsKey.
+func parseGPUMappingsKey(key string) (map[string]float64, error) {
+	hours, mappingStr, _ := strings.Cut(key, " ")
+	hoursPerMonth, err := strconv.ParseFloat(hours, 64)
+	if err != nil {
+		return nil, err
+	}
+	return parseGPUPriceMappings(mappingStr, hoursPerMonth)
+}
+
+// configCache holds the last configuration file loaded by LoadConfig. It is reloaded when PRICING_CONFIG
+// names another file or the file's size or modification time changes.
+var configCache struct {
+	mu      sync.Mutex
+	path    string
+	size    int64
+	modTime time.Time
+	config  *Config
+
+	loadedAt time.Time // When config was parsed
+}
+
+// loadCachedConfigFile returns the parsed configuration file at path, reusing the previous parse while the
+// file is unchanged. Failed loads are not cached.
+func loadCachedConfigFile(path string) (*Config, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return LoadConfigFile(path)
+	}
+
+	configCache.mu.Lock()
+	defer configCache.mu.Unlock()
+	hit := configCache.config != nil && configCache.path == path && configCache.size == info.Size() && configCache.modTime.Equal(info.ModTime())
+	configCacheCounts.count(hit)
+	if hit {
+		return configCache.config, nil
+	}
+
+	config, err := LoadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	configCache.path, configCache.size, configCache.modTime, configCache.config = path, info.Size(), info.ModTime(), config
+	configCache.loadedAt = time.Now()
+	return config, nil
+}
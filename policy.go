@@ -0,0 +1,247 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// PolicyVersion is the version of exported pricing policy documents.
+const PolicyVersion = 1
+
+// Policy is a complete pricing policy in one portable document: the pricing environment (targets, GPU
+// table, guards, tiers and discounts) and the configuration file (profiles, regions, services, coupons and
+// the denom registry). Variables that never change a bid, such as the audit log or webhooks, are left out.
+type Policy struct {
+	Version int               `json:"version"`
+	Env     map[string]string `json:"env,omitempty"`
+	Config  *Config           `json:"config,omitempty"`
+}
+
+// PolicyChange is a setting that differs between two policies. Old is empty for added settings and New for
+// removed ones.
+type PolicyChange struct {
+	Key string
+	Old string
+	New string
+}
+
+// String formats the change as "+ key: new", "- key: old" or "~ key: old -> new".
+func (c PolicyChange) String() string {
+	switch {
+	case c.Old == "":
+		return fmt.Sprintf("+ %s: %s", c.Key, c.New)
+	case c.New == "":
+		return fmt.Sprintf("- %s: %s", c.Key, c.Old)
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", c.Key, c.Old, c.New)
+}
+
+// policyVariable reports whether an environment variable belongs in a policy: a pricing variable that can
+// change a bid, other than PRICING_CONFIG, whose file is part of the policy itself.
+func policyVariable(key string) bool {
+	return key != "PRICING_CONFIG" && configHashVariable(key+"=")
+}
+
+// CurrentPolicy returns the policy of the current environment and PRICING_CONFIG file. The file is
+// included as written, without the default denoms it is merged with when loaded, and variables resolved
+// from secret references keep the reference.
+func CurrentPolicy() (*Policy, error) {
+	policy := &Policy{Version: PolicyVersion, Env: make(map[string]string)}
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if !policyVariable(key) {
+			continue
+		}
+		if ref := envSecretRef(key); ref != "" {
+			value = ref // Never export a resolved secret
+		}
+		policy.Env[key] = value
+	}
+
+	if path := os.Getenv("PRICING_CONFIG"); path != "" {
+		// Loading validates the file before it is exported
+		if _, err := LoadConfigFile(path); err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		policy.Config = &Config{}
+		if err := json.Unmarshal(data, policy.Config); err != nil {
+			return nil, fmt.Errorf("error parsing config %s: %w", path, err)
+		}
+	}
+	return policy, nil
+}
+
+// ExportPolicy returns the current policy as a YAML document.
+func ExportPolicy() ([]byte, error) {
+	policy, err := CurrentPolicy()
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(policy)
+}
+
+// ImportPolicy parses and validates a YAML (or JSON) policy document. Variables that are not pricing
+// variables are rejected, so a policy cannot set unrelated parts of the environment.
+func ImportPolicy(data []byte) (*Policy, error) {
+	var policy Policy
+	if err := yaml.UnmarshalStrict(data, &policy); err != nil {
+		return nil, fmt.Errorf("error parsing policy: %w", err)
+	}
+	if policy.Version != PolicyVersion {
+		return nil, fmt.Errorf("unsupported policy version %d, expected %d", policy.Version, PolicyVersion)
+	}
+	for key := range policy.Env {
+		if !configDirKeyPattern.MatchString(key) || !policyVariable(key) {
+			return nil, fmt.Errorf("policy variable %s is not a pricing variable", key)
+		}
+	}
+	if policy.Config != nil {
+		if err := policy.validateConfig(); err != nil {
+			return nil, err
+		}
+	}
+	return &policy, nil
+}
+
+// validateConfig checks the policy's configuration file as LoadConfigFile would.
+func (p *Policy) validateConfig() error {
+	tmp, err := ioutil.TempFile("", "policy-config-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	err = json.NewEncoder(tmp).Encode(p.Config)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := LoadConfigFile(tmp.Name()); err != nil {
+		return fmt.Errorf("policy config: %w", err)
+	}
+	return nil
+}
+
+// WriteConfigDir writes the policy as a configuration directory read by ConfigDir: one file per variable
+// and the configuration file as pricing.json. Pricing variable files of the directory that the policy does
+// not set are removed, so the directory holds exactly the policy.
+func (p *Policy) WriteConfigDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if _, set := p.Env[name]; !set && configDirKeyPattern.MatchString(name) && policyVariable(name) {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for key, value := range p.Env {
+		if err := ioutil.WriteFile(filepath.Join(dir, key), []byte(value+"\n"), 0644); err != nil {
+			return err
+		}
+	}
+	configPath := filepath.Join(dir, "pricing.json")
+	if p.Config == nil {
+		if err := os.Remove(configPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(p.Config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(configPath, append(data, '\n'), 0644)
+}
+
+// DiffPolicies lists the settings that differ from one policy to another, sorted by key. Variables are keyed
+// "env.NAME" and configuration file settings by their JSON path, e.g. "config.profiles.premium.targets.cpu".
+func DiffPolicies(from, to *Policy) ([]PolicyChange, error) {
+	oldSettings, err := from.settings()
+	if err != nil {
+		return nil, err
+	}
+	newSettings, err := to.settings()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []PolicyChange
+	for key, oldValue := range oldSettings {
+		if newValue := newSettings[key]; newValue != oldValue {
+			changes = append(changes, PolicyChange{Key: key, Old: oldValue, New: newValue})
+		}
+	}
+	for key, newValue := range newSettings {
+		if _, ok := oldSettings[key]; !ok {
+			changes = append(changes, PolicyChange{Key: key, New: newValue})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes, nil
+}
+
+// settings flattens the policy into setting keys and their values.
+func (p *Policy) settings() (map[string]string, error) {
+	settings := make(map[string]string, len(p.Env))
+	for key, value := range p.Env {
+		settings["env."+key] = value
+	}
+	if p.Config == nil {
+		return settings, nil
+	}
+
+	data, err := json.Marshal(p.Config)
+	if err != nil {
+		return nil, err
+	}
+	var config interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	flattenSetting(settings, "config", config)
+	return settings, nil
+}
+
+// flattenSetting adds the leaves of a decoded JSON value to settings under dotted keys. Empty values are
+// left out, so an empty list and an unset one compare equal.
+func flattenSetting(settings map[string]string, key string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, child := range v {
+			flattenSetting(settings, key+"."+name, child)
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			data, _ := json.Marshal(v)
+			settings[key] = string(data)
+		}
+	case nil:
+	case string:
+		if v != "" {
+			settings[key] = v
+		}
+	default:
+		data, _ := json.Marshal(v)
+		settings[key] = string(data)
+	}
+}
@@ -0,0 +1,125 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of the pricing daemon's worker pool.
+const (
+	DefaultServerWorkers        = 64
+	DefaultServerQueueLength    = 256
+	DefaultServerRequestTimeout = 30 * time.Second
+)
+
+// ServerLimits bounds the requests a PricingServer prices at once.
+type ServerLimits struct {
+	Workers        int           // Requests priced concurrently
+	QueueLength    int           // Requests waiting for a worker before new ones are turned away
+	RequestTimeout time.Duration // Longest a request may wait and be priced, 0 for no limit
+}
+
+// ServerLimitsFromEnv returns the limits of SERVE_WORKERS, SERVE_QUEUE_LENGTH and SERVE_REQUEST_TIMEOUT,
+// defaulting to DefaultServerWorkers, DefaultServerQueueLength and DefaultServerRequestTimeout.
+func ServerLimitsFromEnv() (ServerLimits, error) {
+	limits := ServerLimits{Workers: DefaultServerWorkers, QueueLength: DefaultServerQueueLength, RequestTimeout: DefaultServerRequestTimeout}
+	if val := strings.TrimSpace(os.Getenv("SERVE_WORKERS")); val != "" {
+		workers, err := strconv.Atoi(val)
+		if err != nil || workers <= 0 {
+			return ServerLimits{}, fmt.Errorf("invalid SERVE_WORKERS %q: must be a positive integer", val)
+		}
+		limits.Workers = workers
+	}
+	if val := strings.TrimSpace(os.Getenv("SERVE_QUEUE_LENGTH")); val != "" {
+		queueLength, err := strconv.Atoi(val)
+		if err != nil || queueLength < 0 {
+			return ServerLimits{}, fmt.Errorf("invalid SERVE_QUEUE_LENGTH %q: must be a non-negative integer", val)
+		}
+		limits.QueueLength = queueLength
+	}
+	if val := strings.TrimSpace(os.Getenv("SERVE_REQUEST_TIMEOUT")); val != "" {
+		timeout, err := time.ParseDuration(val)
+		if err != nil || timeout < 0 {
+			return ServerLimits{}, fmt.Errorf("invalid SERVE_REQUEST_TIMEOUT %q: must be a duration such as 30s, or 0", val)
+		}
+		limits.RequestTimeout = timeout
+	}
+	return limits, nil
+}
+
+// WorkerPool bounds concurrent pricing: at most Workers requests hold a worker, at most QueueLength more
+// wait for one, and the rest are turned away with ErrQueueFull instead of piling up goroutines, file
+// descriptors and memory during an order flood.
+type WorkerPool struct {
+	workers     int
+	queueLength int
+	slots       chan struct{}
+
+	queued   int64 // Requests waiting for a worker
+	rejected int64 // Requests turned away since the pool was created
+}
+
+// WorkerPoolStats is a snapshot of a WorkerPool.
+type WorkerPoolStats struct {
+	Workers     int   `json:"workers"`
+	QueueLength int   `json:"queue_length"`
+	Busy        int   `json:"busy"`     // Workers pricing a request
+	Queued      int64 `json:"queued"`   // Requests waiting for a worker
+	Rejected    int64 `json:"rejected"` // Requests turned away with ErrQueueFull
+}
+
+// NewWorkerPool returns a pool of workers, with up to queueLength requests waiting for them.
+func NewWorkerPool(workers, queueLength int) *WorkerPool {
+	if workers <= 0 {
+		workers = DefaultServerWorkers
+	}
+	if queueLength < 0 {
+		queueLength = 0
+	}
+	return &WorkerPool{workers: workers, queueLength: queueLength, slots: make(chan struct{}, workers)}
+}
+
+// Acquire takes a worker, waiting in the queue while all are busy, and returns the function releasing it.
+// It returns an error wrapping ErrQueueFull when the queue is full too, and ctx.Err() if ctx is done while
+// waiting.
+func (p *WorkerPool) Acquire(ctx context.Context) (func(), error) {
+	select {
+	case p.slots <- struct{}{}:
+		return p.release, nil
+	default:
+	}
+
+	if atomic.AddInt64(&p.queued, 1) > int64(p.queueLength) {
+		atomic.AddInt64(&p.queued, -1)
+		atomic.AddInt64(&p.rejected, 1)
+		return nil, withReason(ErrQueueFull, fmt.Errorf("all %d workers are busy and %d requests queued", p.workers, p.queueLength))
+	}
+	defer atomic.AddInt64(&p.queued, -1)
+	select {
+	case p.slots <- struct{}{}:
+		return p.release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// release returns a worker to the pool.
+func (p *WorkerPool) release() {
+	<-p.slots
+}
+
+// Stats returns the pool's current load.
+func (p *WorkerPool) Stats() WorkerPoolStats {
+	return WorkerPoolStats{
+		Workers:     p.workers,
+		QueueLength: p.queueLength,
+		Busy:        len(p.slots),
+		Queued:      atomic.LoadInt64(&p.queued),
+		Rejected:    atomic.LoadInt64(&p.rejected),
+	}
+}
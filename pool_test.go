@@ -0,0 +1,52 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool(t *testing.T) {
+	pool := NewWorkerPool(1, 1)
+	release, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second request queues for the busy worker, the third finds the queue full
+	acquired := make(chan error, 1)
+	go func() {
+		release, err := pool.Acquire(context.Background())
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+	for pool.Stats().Queued == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := pool.Acquire(context.Background()); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("got %v with the queue full, want ErrQueueFull", err)
+	}
+	if response := NewBidResponse(Request{}, nil, withReason(ErrQueueFull, errors.New("full"))); response.Decision != DecisionRetry || !response.Failed {
+		t.Errorf("got decision %q, failed %v, want a failed %s", response.Decision, response.Failed, DecisionRetry)
+	}
+
+	release()
+	if err := <-acquired; err != nil {
+		t.Fatalf("queued request: %v", err)
+	}
+	if stats := pool.Stats(); stats.Busy != 0 || stats.Queued != 0 || stats.Rejected != 1 {
+		t.Errorf("got %+v, want an idle pool with one rejection", stats)
+	}
+
+	// A request whose deadline passes in the queue gives up
+	release, _ = pool.Acquire(context.Background())
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v after the deadline, want context.DeadlineExceeded", err)
+	}
+}
@@ -1,15 +1,19 @@
 package pricing
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
-	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkmath "cosmossdk.io/math"
 
 	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
-	"pkg.akt.dev/go/node/types/v1beta3"
+	rtypes "pkg.akt.dev/go/node/types/resources/v1beta4"
 )
 
 // Define default price targets as constants
@@ -21,29 +25,78 @@ const (
 	DefaultHDPersSSDTarget   = 0.03
 	DefaultHDPersNVMETarget  = 0.04
 	DefaultEndpointTarget    = 0.05
+	DefaultRandomPortTarget  = DefaultEndpointTarget
 	DefaultIPTarget          = 5.00
 
-	AverageBlockTimeSeconds = 6.117 // Adjust as per the actual average block time
-	DaysPerMonth            = 30.437
+	AverageBlockTimeSeconds = 6.117  // Adjust as per the actual average block time
+	DaysPerMonth            = 30.437 // Default of PRICE_TARGET_DAYS_PER_MONTH
 	BlocksPerMonth          = (60 / AverageBlockTimeSeconds) * 24 * 60 * DaysPerMonth
+
+	DefaultHoursPerMonth = 24 * DaysPerMonth // Hours of hourly GPU prices, see PRICE_TARGET_HOURS_PER_MONTH
+	secondsPerDay        = 24 * 60 * 60
 )
 
+// blocksPerMonthDec computes the number of blocks per month of PRICE_TARGET_DAYS_PER_MONTH for an average
+// block time in seconds.
+func blocksPerMonthDec(blockTimeSeconds float64) sdkmath.LegacyDec {
+	return blocksPerMonthFor(daysPerMonth(), blockTimeSeconds)
+}
+
+// blocksPerMonthFor computes the number of blocks in a month of days for an average block time in seconds.
+func blocksPerMonthFor(days, blockTimeSeconds float64) sdkmath.LegacyDec {
+	return sdkmath.LegacyNewDec(secondsPerDay).Mul(decFromFloat(days)).Quo(decFromFloat(blockTimeSeconds))
+}
+
 // CalculateRequestedResources computes the total requested resources from the GroupSpec
 func CalculateRequestedResources(gSpec *dtypes.GroupSpec) ResourceRequests {
-	var result ResourceRequests
+	result := ResourceRequests{
+		MemoryRequested:  sdkmath.LegacyZeroDec(),
+		StorageRequested: make(map[string]sdkmath.LegacyDec),
+		SizeUnit:         sizeUnit(),
+	}
+	perUnit := bytesPerUnit(result.SizeUnit)
+	var leasedIPs map[uint32]bool             // Allocated on the first leased IP, most groups have none
+	var kindMilliCPUs map[CPUKind]sdkmath.Int // Allocated on the first unit with a CPU class or architecture
+	milliCPUs, gpuUnits := sdkmath.ZeroInt(), sdkmath.ZeroInt()
+	memorySizes := make(replicaSizes)
+	storageSizes := make(map[string]replicaSizes)
+	var tierSizes map[StorageTier]replicaSizes // Allocated on the first volume declaring a performance tier
+	var backupSizes replicaSizes               // Allocated on the first volume requesting backups
+	cpuClassKey := cpuClassAttribute()
+	groupCPUClass := cpuClass(gSpec.Requirements.Attributes, cpuClassKey)
 
 	for _, resourceUnit := range gSpec.Resources {
+		count := int64(resourceUnit.Count)
 
 		if resourceUnit.Resources.CPU != nil {
-			cpuUnits := resourceUnit.Resources.CPU.Units.Val.Int64() // Get the CPU units in milliCPUs
-			cpuCores := float64(cpuUnits) / 1000.0                   // Convert milliCPUs to CPU cores
-			result.CPURequested += cpuCores * float64(resourceUnit.Count)
+			// Millicores are summed as integers and converted to cores once, which is exact
+			unitMilliCPUs := resourceUnit.Resources.CPU.Units.Val.MulRaw(count)
+			milliCPUs = milliCPUs.Add(unitMilliCPUs)
+
+			kind := CPUKind{
+				Class: cpuClass(resourceUnit.Resources.CPU.Attributes, cpuClassKey),
+				Arch:  cpuArch(resourceUnit.Resources.CPU.Attributes),
+			}
+			if kind.Class == "" {
+				kind.Class = groupCPUClass
+			}
+			if kind != (CPUKind{}) {
+				if kindMilliCPUs == nil {
+					kindMilliCPUs = make(map[CPUKind]sdkmath.Int)
+				}
+				if total, ok := kindMilliCPUs[kind]; ok {
+					unitMilliCPUs = total.Add(unitMilliCPUs)
+				}
+				kindMilliCPUs[kind] = unitMilliCPUs
+			}
 		}
 
 		if resourceUnit.Resources.Memory != nil {
-			memoryBytes := resourceUnit.Resources.Memory.Quantity.Val.Int64()
-			memoryGB := float64(memoryBytes) / (1024.0 * 1024.0 * 1024.0) // Convert bytes to gigabytes
-			result.MemoryRequested += memoryGB * float64(resourceUnit.Count)
+			result.MemoryRequested = memorySizes.add(result.MemoryRequested, resourceUnit.Resources.Memory.Quantity.Val, count, perUnit)
+		}
+
+		if resourceUnit.Resources.GPU != nil {
+			gpuUnits = gpuUnits.Add(resourceUnit.Resources.GPU.Units.Val.MulRaw(count))
 		}
 
 		for _, storage := range resourceUnit.Resources.Storage {
@@ -58,195 +111,842 @@ func CalculateRequestedResources(gSpec *dtypes.GroupSpec) ResourceRequests {
 				}
 			}
 
-			storageBytes := storage.Quantity.Val.Int64()
-			storageGB := storageBytes / (1024 * 1024 * 1024) // Convert bytes to gigabytes
-
-			switch storageClass {
-			case "ephemeral", "default":
-				result.EphemeralStorageRequested += storageGB * int64(resourceUnit.Count)
-			case "beta1":
-				result.HDDPersStorageRequested += storageGB * int64(resourceUnit.Count)
-			case "beta2":
-				result.SSDPersStorageRequested += storageGB * int64(resourceUnit.Count)
-			case "beta3":
-				result.NVMePersStorageRequested += storageGB * int64(resourceUnit.Count)
+			sizes, ok := storageSizes[storageClass]
+			if !ok {
+				sizes = make(replicaSizes)
+				storageSizes[storageClass] = sizes
+				result.StorageRequested[storageClass] = sdkmath.LegacyZeroDec()
+			}
+			result.StorageRequested[storageClass] = sizes.add(result.StorageRequested[storageClass], storage.Quantity.Val, count, perUnit)
+
+			for _, tier := range storageTiers(storage.Attributes) {
+				if tierSizes == nil {
+					tierSizes = make(map[StorageTier]replicaSizes)
+					result.StorageTierRequested = make(map[StorageTier]sdkmath.LegacyDec)
+				}
+				sizes, ok := tierSizes[tier]
+				if !ok {
+					sizes = make(replicaSizes)
+					tierSizes[tier] = sizes
+					result.StorageTierRequested[tier] = sdkmath.LegacyZeroDec()
+				}
+				result.StorageTierRequested[tier] = sizes.add(result.StorageTierRequested[tier], storage.Quantity.Val, count, perUnit)
+			}
+			if requestsBackup(storage.Attributes) {
+				if backupSizes == nil {
+					backupSizes = make(replicaSizes)
+					result.BackupRequested = sdkmath.LegacyZeroDec()
+				}
+				result.BackupRequested = backupSizes.add(result.BackupRequested, storage.Quantity.Val, count, perUnit)
 			}
 		}
 
+		// Shared HTTP and random port endpoints are priced per exposed port and replica. A leased IP is
+		// identified by its sequence number and shared by every port and replica exposing it, so it is
+		// counted once per group below.
+		var httpEndpoints, randomPorts int64
 		for _, endpoint := range resourceUnit.Resources.Endpoints {
-			result.EndpointsRequested += int64(resourceUnit.Count) // Assuming 1 endpoint per resource unit count
-			if endpoint.Kind == v1beta3.Endpoint_LEASED_IP {
-				result.IPsRequested += int64(resourceUnit.Count) // Assuming 1 IP per resource unit count
+			switch endpoint.Kind {
+			case rtypes.Endpoint_SHARED_HTTP:
+				httpEndpoints++
+			case rtypes.Endpoint_RANDOM_PORT:
+				randomPorts++
+			case rtypes.Endpoint_LEASED_IP:
+				if leasedIPs == nil {
+					leasedIPs = make(map[uint32]bool)
+				}
+				leasedIPs[endpoint.SequenceNumber] = true
 			}
 		}
+		result.EndpointsRequested += httpEndpoints * count
+		result.RandomPortsRequested += randomPorts * count
+	}
+	result.CPURequested = sdkmath.LegacyNewDecFromInt(milliCPUs).QuoInt64(1000) // Convert milliCPUs to CPU cores
+	if kindMilliCPUs != nil {
+		result.CPUKindRequested = make(map[CPUKind]sdkmath.LegacyDec, len(kindMilliCPUs))
+		for kind, kindMilli := range kindMilliCPUs {
+			result.CPUKindRequested[kind] = sdkmath.LegacyNewDecFromInt(kindMilli).QuoInt64(1000)
+		}
+	}
+	result.MemoryRequested = result.MemoryRequested.Add(memorySizes.gigabytes(perUnit))
+	for storageClass, sizes := range storageSizes {
+		result.StorageRequested[storageClass] = result.StorageRequested[storageClass].Add(sizes.gigabytes(perUnit))
+	}
+	for tier, sizes := range tierSizes {
+		result.StorageTierRequested[tier] = result.StorageTierRequested[tier].Add(sizes.gigabytes(perUnit))
+	}
+	if backupSizes != nil {
+		result.BackupRequested = result.BackupRequested.Add(backupSizes.gigabytes(perUnit))
+	}
+	result.GPUsRequested = saturatingInt64(gpuUnits) // Only unvalidated GroupSpecs can exceed an int64
+	result.IPsRequested = int64(len(leasedIPs))
+	if result.IPsRequested > 0 && RequestsIPv6(gSpec) {
+		result.IPv6Requested = result.IPsRequested
 	}
+	result.EgressGBRequested = RequestedEgress(gSpec, result)
 
 	return result
 }
 
-// GetEnvFloat gets an environment variable as a float, returning a default value if not set or invalid
+// replicaSizes counts the replicas requesting each size in bytes, so each distinct size is converted to
+// gigabytes once. The total equals converting every resource unit on its own, as multiplying by a replica
+// count and adding are exact.
+type replicaSizes map[int64]int64
+
+// add counts count replicas of bytes and returns total. Sizes beyond int64, which only unvalidated
+// GroupSpecs have, are converted to gigabytes of perUnit bytes right away and added to total instead.
+func (s replicaSizes) add(total sdkmath.LegacyDec, bytes sdkmath.Int, count int64, perUnit sdkmath.LegacyDec) sdkmath.LegacyDec {
+	if bytes.IsInt64() {
+		s[bytes.Int64()] += count
+		return total
+	}
+	return total.Add(sdkmath.LegacyNewDecFromInt(bytes).Quo(perUnit).MulInt64(count))
+}
+
+// gigabytes returns the total size of the counted replicas in gigabytes of perUnit bytes.
+func (s replicaSizes) gigabytes(perUnit sdkmath.LegacyDec) sdkmath.LegacyDec {
+	total := sdkmath.LegacyZeroDec()
+	for bytes, count := range s {
+		total = total.Add(sdkmath.LegacyNewDec(bytes).Quo(perUnit).MulInt64(count))
+	}
+	return total
+}
+
+// GetEnvFloat gets an environment variable as a float, returning a default value if not set or invalid.
+// Invalid values are logged once per variable; ValidateConfig and CheckStartupConfig report them as errors.
 func GetEnvFloat(envVar string, defaultValue float64) float64 {
-	if val, ok := os.LookupEnv(envVar); ok {
-		if floatVal, err := strconv.ParseFloat(val, 64); err == nil {
-			return floatVal
-		}
+	floatVal, ok, err := LookupEnvFloat(envVar)
+	if err != nil {
+		warnEnvProblem(err)
+		return defaultValue
+	}
+	if !ok {
+		return defaultValue
 	}
-	return defaultValue
+	return floatVal
 }
 
 // SetPriceTargets sets the price targets from environment variables or uses defaults
 func SetPriceTargets() PriceTargets {
+	priceTargets, err := LoadPriceTargets()
+	if err != nil {
+		log.Fatalf("Error loading price targets: %v", err)
+	}
+	return priceTargets
+}
+
+// LoadPriceTargets reads the price targets from environment variables or uses defaults, returning an
+// error for an invalid GPU mapping or an out-of-range target instead of exiting.
+func LoadPriceTargets() (PriceTargets, error) {
+	if err := checkTargetRanges(); err != nil {
+		return PriceTargets{}, err
+	}
+	if _, err := ParseSizeUnit(os.Getenv("PRICE_TARGET_SIZE_UNIT")); err != nil {
+		return PriceTargets{}, err
+	}
+	if _, err := HoursPerMonthFromEnv(); err != nil {
+		return PriceTargets{}, err
+	}
+
 	gpuMappingsStr := os.Getenv("PRICE_TARGET_GPU_MAPPINGS") // Assuming this environment variable contains the mappings
-	gpuMappings, err := ParseGPUPriceMappings(gpuMappingsStr)
+	gpuMappings, err := gpuMappingsCache.get(gpuMappingsKey(gpuMappingsStr))
 	if err != nil {
-		log.Fatalf("Error parsing GPU mappings: %v", err)
+		return PriceTargets{}, err
+	}
+	gpuMixedModels, err := ParseGPUMixedModels(os.Getenv("PRICE_TARGET_GPU_MIXED_MODELS"))
+	if err != nil {
+		return PriceTargets{}, err
+	}
+	gpuMismatch, err := ParseGPUMismatch(os.Getenv("GPU_MISMATCH"))
+	if err != nil {
+		return PriceTargets{}, err
 	}
 
-	return PriceTargets{
-		CPUTarget:         GetEnvFloat("PRICE_TARGET_CPU", DefaultCPUTarget),
-		MemoryTarget:      GetEnvFloat("PRICE_TARGET_MEMORY", DefaultMemoryTarget),
-		HDEphemeralTarget: GetEnvFloat("PRICE_TARGET_HD_EPHEMERAL", DefaultHDEphemeralTarget),
-		HDPersHDDTarget:   GetEnvFloat("PRICE_TARGET_HD_PERS_HDD", DefaultHDPersHDDTarget),
-		HDPersSSDTarget:   GetEnvFloat("PRICE_TARGET_HD_PERS_SSD", DefaultHDPersSSDTarget),
-		HDPersNVMETarget:  GetEnvFloat("PRICE_TARGET_HD_PERS_NVME", DefaultHDPersNVMETarget),
-		EndpointTarget:    GetEnvFloat("PRICE_TARGET_ENDPOINT", DefaultEndpointTarget),
-		IPTarget:          GetEnvFloat("PRICE_TARGET_IP", DefaultIPTarget),
+	memoryTarget := getTargetFloat("PRICE_TARGET_MEMORY", DefaultMemoryTarget)
+	endpointTarget := getTargetFloat("PRICE_TARGET_ENDPOINT", DefaultEndpointTarget)
+	priceTargets := PriceTargets{
+		CPUTarget:         getTargetFloat("PRICE_TARGET_CPU", DefaultCPUTarget),
+		MemoryTarget:      memoryTarget,
+		HDEphemeralTarget: getTargetFloat("PRICE_TARGET_HD_EPHEMERAL", DefaultHDEphemeralTarget),
+		HDPersHDDTarget:   getTargetFloat("PRICE_TARGET_HD_PERS_HDD", DefaultHDPersHDDTarget),
+		HDPersSSDTarget:   getTargetFloat("PRICE_TARGET_HD_PERS_SSD", DefaultHDPersSSDTarget),
+		HDPersNVMETarget:  getTargetFloat("PRICE_TARGET_HD_PERS_NVME", DefaultHDPersNVMETarget),
+		HDRAMTarget:       getTargetFloat("PRICE_TARGET_HD_RAM", memoryTarget), // RAM volumes consume memory, so default to its price
+		EndpointTarget:    endpointTarget,
+		RandomPortTarget:  getTargetFloat("PRICE_TARGET_RANDOM_PORT", endpointTarget),
+		IPTarget:          getTargetFloat("PRICE_TARGET_IP", DefaultIPTarget),
+		EgressGBTarget:    getTargetFloat("PRICE_TARGET_EGRESS_GB", 0),
 		GPUMappings:       gpuMappings,
+		GPUMixedModels:    gpuMixedModels,
+		GPUDefaultTarget:  getTargetFloat("PRICE_TARGET_GPU_DEFAULT", 0),
+		GPUMismatch:       gpuMismatch,
+		Currency:          priceTargetCurrency(),
+	}
+	if err := loadStorageTargets(&priceTargets); err != nil {
+		return PriceTargets{}, err
+	}
+	if err := loadCPUTargets(&priceTargets); err != nil {
+		return PriceTargets{}, err
 	}
+	if err := loadIPTargets(&priceTargets); err != nil {
+		return PriceTargets{}, err
+	}
+	return priceTargets, nil
 }
 
 // CalculateTotalCostUsdTarget calculates the total cost in USD based on resource requests and price targets
-func CalculateTotalCostUsdTarget(resourceRequests ResourceRequests, priceTargets PriceTargets) float64 {
-	var totalCostUsdTarget float64
+func CalculateTotalCostUsdTarget(resourceRequests ResourceRequests, priceTargets PriceTargets) sdkmath.LegacyDec {
+	return totalCost(CalculateCostLines(resourceRequests, priceTargets))
+}
 
-	cpuCost := float64(resourceRequests.CPURequested) * priceTargets.CPUTarget
-	totalCostUsdTarget += cpuCost
+// CalculateCostLines itemizes the monthly cost of resource requests at the price targets, in a fixed order.
+// Resources not requested have no line.
+func CalculateCostLines(resourceRequests ResourceRequests, priceTargets PriceTargets) []CostLine {
+	var lines []CostLine
+
+	cpuCost := resourceRequests.CPURequested.Mul(decFromFloat(priceTargets.CPUTarget))
+	var kindLines []CostLine
+	genericCores := resourceRequests.CPURequested
+	if len(resourceRequests.CPUKindRequested) > 0 && len(priceTargets.CPUClassTargets)+len(priceTargets.CPUArchTargets) > 0 {
+		// Cores of a kind with its own target are repriced from the generic target, in a fixed order
+		for _, kind := range resourceRequests.CPUKinds() {
+			kindTarget, ok := priceTargets.CPUKindTarget(kind)
+			if !ok {
+				continue
+			}
+			cores := resourceRequests.CPUKindRequested[kind]
+			kindCost := cores.Mul(decFromFloat(kindTarget))
+			cpuCost = cpuCost.Sub(cores.Mul(decFromFloat(priceTargets.CPUTarget))).Add(kindCost)
+			genericCores = genericCores.Sub(cores)
+			kindLines = append(kindLines, CostLine{Resource: CostCPU, Kind: kind.String(), Quantity: cores, Unit: "CPU", Target: kindTarget, Cost: kindCost})
+		}
+	}
+	// The generic cores take the remainder, so the lines add up to the CPU cost exactly
+	genericCost := cpuCost
+	for _, line := range kindLines {
+		genericCost = genericCost.Sub(line.Cost)
+	}
+	if genericCores.IsPositive() || !genericCost.IsZero() {
+		lines = append(lines, CostLine{Resource: CostCPU, Quantity: genericCores, Unit: "CPU", Target: priceTargets.CPUTarget, Cost: genericCost})
+	}
+	lines = append(lines, kindLines...)
+
+	sizeUnit := resourceRequests.SizeUnit
+	if sizeUnit == "" {
+		sizeUnit = SizeUnitGiB
+	}
+	if resourceRequests.MemoryRequested.IsPositive() {
+		memoryCost := resourceRequests.MemoryRequested.Mul(decFromFloat(priceTargets.MemoryTarget))
+		lines = append(lines, CostLine{Resource: CostMemory, Quantity: resourceRequests.MemoryRequested, Unit: sizeUnit, Target: priceTargets.MemoryTarget, Cost: memoryCost})
+	}
 
-	memoryCost := float64(resourceRequests.MemoryRequested) * priceTargets.MemoryTarget
-	totalCostUsdTarget += memoryCost
+	// Classes are summed in a fixed order so the result does not depend on map iteration
+	storageClasses := make([]string, 0, len(resourceRequests.StorageRequested))
+	for class := range resourceRequests.StorageRequested {
+		storageClasses = append(storageClasses, class)
+	}
+	sort.Strings(storageClasses)
+	surcharges := storageSurcharges(resourceRequests, priceTargets.StoragePools)
+	for _, class := range storageClasses {
+		storageTarget, _ := priceTargets.StorageTarget(class)
+		line := CostLine{Resource: CostStorage, Kind: class, Quantity: resourceRequests.StorageRequested[class], Unit: sizeUnit, Target: storageTarget}
+		line.Cost = line.Quantity.Mul(decFromFloat(storageTarget))
+		if percent, ok := surcharges[class]; ok {
+			line.Cost = line.Cost.Add(line.Cost.Mul(decFromFloat(percent)).QuoInt64(100))
+			line.Detail = fmt.Sprintf("%g%% reservation surcharge", percent)
+		}
+		lines = append(lines, line)
+	}
+	for _, tier := range resourceRequests.StorageTiers() {
+		tierTarget, ok := priceTargets.StorageTierTarget(tier)
+		if !ok || tierTarget == 0 {
+			continue
+		}
+		gigabytes := resourceRequests.StorageTierRequested[tier]
+		lines = append(lines, CostLine{Resource: CostStorageTier, Kind: tier.String(), Quantity: gigabytes, Unit: sizeUnit, Target: tierTarget, Cost: gigabytes.Mul(decFromFloat(tierTarget))})
+	}
+	if !resourceRequests.BackupRequested.IsNil() && priceTargets.StorageBackupTarget > 0 {
+		backupCost := resourceRequests.BackupRequested.Mul(decFromFloat(priceTargets.StorageBackupTarget))
+		lines = append(lines, CostLine{Resource: CostBackup, Quantity: resourceRequests.BackupRequested, Unit: sizeUnit, Target: priceTargets.StorageBackupTarget, Cost: backupCost})
+	}
 
-	ephemeralStorageCost := float64(resourceRequests.EphemeralStorageRequested) * priceTargets.HDEphemeralTarget
-	totalCostUsdTarget += ephemeralStorageCost
+	if resourceRequests.EndpointsRequested > 0 {
+		endpointCost := decFromFloat(priceTargets.EndpointTarget).MulInt64(resourceRequests.EndpointsRequested)
+		lines = append(lines, CostLine{Resource: CostEndpoint, Quantity: sdkmath.LegacyNewDec(resourceRequests.EndpointsRequested), Unit: "HTTP endpoint", Target: priceTargets.EndpointTarget, Cost: endpointCost})
+	}
 
-	hddPersStorageCost := float64(resourceRequests.HDDPersStorageRequested) * priceTargets.HDPersHDDTarget
-	totalCostUsdTarget += hddPersStorageCost
+	if resourceRequests.RandomPortsRequested > 0 {
+		randomPortCost := decFromFloat(priceTargets.RandomPortTarget).MulInt64(resourceRequests.RandomPortsRequested)
+		lines = append(lines, CostLine{Resource: CostRandomPort, Quantity: sdkmath.LegacyNewDec(resourceRequests.RandomPortsRequested), Unit: "random port", Target: priceTargets.RandomPortTarget, Cost: randomPortCost})
+	}
 
-	ssdPersStorageCost := float64(resourceRequests.SSDPersStorageRequested) * priceTargets.HDPersSSDTarget
-	totalCostUsdTarget += ssdPersStorageCost
+	lines = append(lines, leasedIPCostLines(resourceRequests, priceTargets)...)
 
-	nvmePersStorageCost := float64(resourceRequests.NVMePersStorageRequested) * priceTargets.HDPersNVMETarget
-	totalCostUsdTarget += nvmePersStorageCost
+	if !resourceRequests.EgressGBRequested.IsNil() && priceTargets.EgressGBTarget > 0 {
+		egressCost := resourceRequests.EgressGBRequested.Mul(decFromFloat(priceTargets.EgressGBTarget))
+		lines = append(lines, CostLine{Resource: CostEgress, Quantity: resourceRequests.EgressGBRequested, Unit: "GB egress", Target: priceTargets.EgressGBTarget, Cost: egressCost})
+	}
 
-	endpointCost := float64(resourceRequests.EndpointsRequested) * priceTargets.EndpointTarget
-	totalCostUsdTarget += endpointCost
+	return lines
+}
 
-	ipCost := float64(resourceRequests.IPsRequested) * priceTargets.IPTarget
-	totalCostUsdTarget += ipCost
+// totalCost sums the costs of lines.
+func totalCost(lines []CostLine) sdkmath.LegacyDec {
+	total := sdkmath.LegacyZeroDec()
+	for _, line := range lines {
+		total = total.Add(line.Cost)
+	}
+	return total
+}
 
-	return totalCostUsdTarget
+// CalculateBlockRates converts monthly USD costs to per-block rates for the given average block time
+func CalculateBlockRates(totalCostUsdTarget sdkmath.LegacyDec, usdPerAkt sdkmath.LegacyDec, blockTimeSeconds float64, precision int) (sdkmath.LegacyDec, sdkmath.LegacyDec, string) {
+	return calculateBlockRates(totalCostUsdTarget, usdPerAkt, blocksPerMonthDec(blockTimeSeconds), precision)
 }
 
-// CalculateBlockRates converts monthly USD costs to per-block rates
-func CalculateBlockRates(totalCostUsdTarget float64, usdPerAkt float64, precision int) (float64, float64, string) {
-	totalCostAktTarget := totalCostUsdTarget / usdPerAkt
-	totalCostUaktTarget := totalCostAktTarget * 1000000 // Convert AKT to microAKT (uakt)
+// calculateBlockRates is CalculateBlockRates over a given number of blocks per month.
+func calculateBlockRates(totalCostUsdTarget sdkmath.LegacyDec, usdPerAkt sdkmath.LegacyDec, blocksPerMonth sdkmath.LegacyDec, precision int) (sdkmath.LegacyDec, sdkmath.LegacyDec, string) {
+	// Multiply before dividing so the uakt amount keeps as many significant digits as possible
+	totalCostUaktTarget := totalCostUsdTarget.MulInt64(1000000).Quo(usdPerAkt) // Convert AKT to microAKT (uakt)
 
-	ratePerBlockUakt := totalCostUaktTarget / BlocksPerMonth
-	ratePerBlockUsd := totalCostUsdTarget / BlocksPerMonth
+	ratePerBlockUakt := totalCostUaktTarget.Quo(blocksPerMonth)
+	ratePerBlockUsd := totalCostUsdTarget.Quo(blocksPerMonth)
 
-	// Format to the desired precision with 16 decimal places and append "uakt"
-	totalCostUaktStr := fmt.Sprintf("%.*f", 16, ratePerBlockUakt) + "uakt"
+	// Format to the requested precision and append "uakt"
+	totalCostUaktStr := FormatDec(ratePerBlockUakt, precision) + "uakt"
 
 	return ratePerBlockUakt, ratePerBlockUsd, totalCostUaktStr
 }
 
-// HandleDenomLogic processes the logic based on the received denom
-func HandleDenomLogic(denom string, ratePerBlockUakt float64, ratePerBlockUsd float64, precision int, amount sdk.Dec) (string, error) {
-	switch denom {
-	case "uakt":
-		if ratePerBlockUakt > amount.MustFloat64() { // Convert sdk.Dec to float64 for comparison
-			return "", fmt.Errorf("requested rate is too low. min expected %.*f%s", precision, ratePerBlockUakt, denom)
-		}
-		return fmt.Sprintf("%.*f", precision, ratePerBlockUakt), nil
+// HandleDenomLogic processes the logic based on the received denom using the default denom registry
+func HandleDenomLogic(denom string, ratePerBlockUakt sdkmath.LegacyDec, ratePerBlockUsd sdkmath.LegacyDec, precision int, amount sdkmath.LegacyDec) (string, error) {
+	return DefaultDenomRegistry().HandleDenom(denom, ratePerBlockUakt, ratePerBlockUsd, precision, amount)
+}
 
-	case "ibc/12C6A0C374171B595A0A9E18B83FA09D295FB1F2D8C6DAA3AC28683471752D84",
-		"ibc/170C677610AC31DF0904FFE09CD3B5C657492170E7E52372E48756B71E56F2F1":
-		ratePerBlockUsdNormalized := ratePerBlockUsd * 1000000
-		if ratePerBlockUsdNormalized > amount.MustFloat64() {
-			return "", fmt.Errorf("requested rate is too low. min expected %.*f%s", precision, ratePerBlockUsdNormalized, denom)
-		}
-		return fmt.Sprintf("%.*f", precision, ratePerBlockUsdNormalized), nil
+// RequestToBidPrice is the entry point to execute the bidding logic.
+// It returns the bid rate per block in the order's denom, formatted at the request precision.
+func RequestToBidPrice(request Request) (string, error) {
+	result, err := CalculateBid(request)
+	if err != nil {
+		return "", err
+	}
+	return result.Price, nil
+}
 
-	default:
-		return "", fmt.Errorf("denom is not supported: %s", denom)
+// CalculateBid runs the pricing pipeline and returns the bid along with its breakdown.
+// The outcome is recorded in the bid history when BID_HISTORY_DB is set, and notable outcomes are sent to
+// WEBHOOK_URL when set. With BID_HISTORY_PIN_WINDOW set too, a request repeating the OrderID of a bid in
+// the window is priced at that bid's AKT price and gets its price, however the oracle moved since.
+func CalculateBid(request Request) (*BidResult, error) {
+	return priceBid(context.Background(), request)
+}
+
+// priceBid is CalculateBid with its span a child of the span in ctx. A bid whose caller gave up, with ctx
+// done by the time it is priced, is neither recorded in the bid history nor notified.
+func priceBid(ctx context.Context, request Request) (*BidResult, error) {
+	ctx, span := startSpan(ctx, spanCalculateBid)
+	pinned := pinnedBid(request)
+	if pinned != nil {
+		request.USDPerAKT, request.aktPriceSource = pinned.USDPerAKT, AKTPriceSourceOrder
+	}
+	result, err := calculateBidWithin(ctx, request)
+	if pinned != nil && err == nil {
+		repeatPinnedBid(result, pinned)
+	}
+	bidSpanAttrs(span, request, result, err)
+	shadowBid(ctx, request, result, err)
+	span.End(err)
+
+	countProviderBid(request, result, err)
+	// Repeated bids are not recorded again, so the pin ends a window after the bid was first priced
+	if (pinned == nil || err != nil) && ctx.Err() == nil {
+		recordBid(request, result, err)
 	}
+	auditBid(request, result, err)
+	if ctx.Err() == nil {
+		notifyBid(request, result, err)
+	}
+	return result, err
 }
 
-// RequestToBidPrice is the entry point to execute the bidding logic.
-func RequestToBidPrice(request Request) error {
+// calculateBid runs the pricing pipeline, tracing its steps under ctx.
+func calculateBid(ctx context.Context, request Request) (*BidResult, error) {
 	fmt.Println("####Request: ", request)
-	owner := request.Owner
-	if owner == "" {
-		return fmt.Errorf("request owner is not specified")
+	_, span := startSpan(ctx, spanValidate)
+	config, err := LoadConfig()
+	if err != nil {
+		err = withReason(ErrConfig, err)
+		span.End(err)
+		return nil, err
+	}
+	mode, err := OrderPriceModeFromEnv()
+	if err != nil {
+		err = withReason(ErrConfig, err)
+		span.End(err)
+		return nil, err
+	}
+	if mode == OrderPriceQuoteOnly && request.GSpec != nil && len(request.GSpec.Resources) > 0 && missingAmount(request.GSpec.Resources[0].Price.Amount) {
+		request.QuoteOnly = true
+	}
+	if err := ValidateRequest(request, config.Denoms); err != nil {
+		log.Printf("Invalid request: %v", err)
+		span.End(err)
+		return nil, err
+	}
+	limits, err := RequestLimitsFromEnv()
+	if err != nil {
+		err = withReason(ErrConfig, err)
+		span.End(err)
+		return nil, err
+	}
+	if err := limits.Check(request.GSpec); err != nil {
+		log.Printf("Request too large: %v", err)
+		span.End(err)
+		return nil, err
 	}
+	span.End(nil)
 
-	var denom string
-	var amount sdk.Dec
-	if request.GSpec != nil && len(request.GSpec.Resources) > 0 {
-		denom = request.GSpec.Resources[0].Price.Denom
-		amount = request.GSpec.Resources[0].Price.Amount
+	owner := request.Owner
+	denom := request.GSpec.Resources[0].Price.Denom
+	amount := request.GSpec.Resources[0].Price.Amount
+	if request.QuoteOnly {
+		// Quotes are never checked against, or shaded towards, a max price
+		amount = sdkmath.LegacyDec{}
 	}
 
+	// Special pricing accounts bypass the whitelist, including any per-owner metadata.
 	if SpecialPricing(owner) {
 		log.Println("Special pricing activated")
-		specialRate := "1.00"
+		specialRate := "1"
 		fmt.Printf("Special pricing rate per block (uakt): %s\n", specialRate)
-		return nil
+		return &BidResult{
+			Denom:       "uakt",
+			Price:       specialRate,
+			Adjustments: []Adjustment{{Name: "special_pricing", Detail: "owner has special pricing"}},
+			Version:     BuildVersion(),
+			ConfigHash:  ConfigHash(),
+		}, nil
 	}
 
-	if err := CheckWhitelist(owner); err != nil {
+	_, span = startSpan(ctx, spanWhitelist, boolAttr("akash.whitelist.shared", request.lookups != nil))
+	whitelistEntry, err := whitelistEntryWithin(ctx, request)
+	if err != nil {
 		log.Printf("Whitelist check failed: %v", err)
-		return fmt.Errorf("whitelist check failed: %v", err)
+		err = withReason(ErrDataSource, fmt.Errorf("whitelist check failed: %w", err))
+		span.End(err)
+		return nil, err
 	}
-
-	usdPerAkt, err := GetAKTPrice()
-	if err != nil {
-		log.Printf("Error getting AKT price: %v", err)
-		return fmt.Errorf("error getting AKT price: %v", err)
+	if whitelistEntry != nil {
+		span.SetAttributes(floatAttr("akash.whitelist.discount_percent", whitelistEntry.DiscountPercent))
 	}
+	span.End(nil)
 
-	if denom == "" || amount.IsZero() {
-		fmt.Println("Price information is missing or incomplete")
-		return fmt.Errorf("price information is missing or incomplete")
+	usdPerAkt, aktPriceSource := request.USDPerAKT, request.aktPriceSource
+	if err := checkDecFloat(usdPerAkt); err != nil {
+		return nil, withReason(ErrInvalidRequest, fmt.Errorf("invalid AKT price: %v", err))
+	}
+	_, span = startSpan(ctx, spanOracle, boolAttr("akash.akt_price.from_request", usdPerAkt > 0))
+	if usdPerAkt > 0 && aktPriceSource == "" {
+		aktPriceSource = AKTPriceSourcePin
 	}
+	if usdPerAkt <= 0 {
+		usdPerAkt, aktPriceSource, err = aktPriceWithin(ctx, request)
+		if err != nil {
+			log.Printf("Error getting AKT price: %v", err)
+			err = withReason(ErrOracle, fmt.Errorf("error getting AKT price: %w", err))
+			span.End(err)
+			return nil, err
+		}
+		// The rates divide by the AKT price, a zero from a misbehaving oracle must not reach them
+		if usdPerAkt <= 0 {
+			err = withReason(ErrOracle, fmt.Errorf("invalid AKT price %g from %s", usdPerAkt, aktPriceSource))
+			span.End(err)
+			return nil, err
+		}
+	}
+	span.SetAttributes(floatAttr("akash.akt_price_usd", usdPerAkt), stringAttr("akash.akt_price.source", aktPriceSource))
+	span.End(nil)
 
 	precision := request.PricePrecision
 	if precision == 0 {
-		precision = 6
+		precision = DefaultPricePrecision
 	}
 
-	if request.GSpec == nil {
-		return fmt.Errorf("GroupSpec is nil in the request")
+	guards, err := NewBidGuardsFromEnv()
+	if err != nil {
+		return nil, withReason(ErrConfig, err)
 	}
 
-	priceTargets := SetPriceTargets()
-	maxGPUPrice := MaxGPUPrice(priceTargets.GPUMappings)
-	totalGPUPrice := CalculateTotalGPUPrice(request.GSpec, priceTargets.GPUMappings, maxGPUPrice)
+	shading, err := NewShadingStrategyFromEnv()
+	if err != nil {
+		return nil, withReason(ErrConfig, err)
+	}
+
+	jitter, err := BidJitterFromEnv()
+	if err != nil {
+		return nil, withReason(ErrConfig, err)
+	}
+
+	priceTargets, err := LoadPriceTargets()
+	if err != nil {
+		return nil, withReason(ErrConfig, fmt.Errorf("error loading price targets: %v", err))
+	}
+	priceTargets.StoragePools = config.StoragePools
+	baseTargets := priceTargets
+	result := &BidResult{Denom: denom, Precision: precision, Quote: request.QuoteOnly, Version: BuildVersion(), ConfigHash: ConfigHash()}
+
+	provider, ok := config.ProviderNamespace(RequestProvider(request))
+	if ok {
+		log.Printf("Using configuration of provider %s", RequestProvider(request))
+		priceTargets = provider.Targets.ApplyTo(priceTargets)
+		baseTargets = priceTargets
+		result.Provider = RequestProvider(request)
+		if provider.Shading != nil {
+			shading = provider.Shading
+		}
+	}
+	if region := RequestRegion(request.GSpec); region != "" {
+		if regionTargets, ok := config.RegionTargets(region); ok {
+			log.Printf("Using price targets for region %s", region)
+			priceTargets = regionTargets.ApplyTo(priceTargets)
+			result.Region = region
+		}
+	}
+	if profileName, profile := config.profiles(provider).SelectProfile(request); profile != nil {
+		log.Printf("Using pricing profile %s", profileName)
+		priceTargets = profile.Targets.ApplyTo(priceTargets)
+		result.Profile = profileName
+		if profile.Shading != nil {
+			shading = profile.Shading
+		}
+	}
+
+	if request.shadowTargets != nil {
+		priceTargets = request.shadowTargets.ApplyTo(priceTargets)
+	}
+
+	surgeTiers, err := surgeTiersCache.get(os.Getenv("SURGE_TIERS"))
+	if err != nil {
+		return nil, withReason(ErrConfig, fmt.Errorf("error parsing surge tiers: %v", err))
+	}
+	priceTargets, surgeAdjustments := ApplySurge(NewUtilizationProviderFromEnv(), surgeTiers, priceTargets)
+	result.Adjustments = append(result.Adjustments, surgeAdjustments...)
+
+	// A caller that gave up on the bid, e.g. past BID_DEADLINE, cancels ctx; stop before each data source
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	_, span = startSpan(ctx, spanGPU)
+	if err := CheckGPUAttributes(request.GSpec, priceTargets.GPUMismatch); err != nil {
+		span.End(err)
+		return nil, err
+	}
+	totalGPUPrice, gpuAdjustments := CalculateGPUPrice(request.GSpec, priceTargets)
+	result.Adjustments = append(result.Adjustments, gpuAdjustments...)
 	resourceRequests := CalculateRequestedResources(request.GSpec)
-	totalCostUsdTarget := CalculateTotalCostUsdTarget(resourceRequests, priceTargets) + totalGPUPrice
+	if span.Recording() {
+		span.SetAttributes(intAttr("akash.gpus", resourceRequests.GPUsRequested), stringAttr("akash.gpu.cost", FormatDec(totalGPUPrice, 2)))
+	}
+	span.End(nil)
+	if resourceRequests.GPUsRequested > 0 {
+		inventoryProvider, err := NewGPUInventoryProviderFromEnv()
+		if err != nil {
+			return nil, withReason(ErrConfig, err)
+		}
+		if inventoryProvider != nil {
+			inventory, err := inventoryProvider.GPUInventory()
+			if err != nil {
+				log.Printf("GPU inventory check failed: %v", err)
+				return nil, withReason(ErrDataSource, fmt.Errorf("GPU inventory check failed: %w", err))
+			}
+			if err := CheckGPUInventory(inventory, request.GSpec); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if capacityProvider := NewCapacityProviderFromEnv(config); capacityProvider != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		capacity, err := capacityProvider.Capacity()
+		if err != nil {
+			log.Printf("Capacity check failed: %v", err)
+			return nil, withReason(ErrDataSource, fmt.Errorf("capacity check failed: %w", err))
+		}
+		if err := capacity.Check(request.GSpec, resourceRequests); err != nil {
+			return nil, err
+		}
+	}
+	if unknown := UnknownStorageClasses(resourceRequests, priceTargets); len(unknown) > 0 {
+		if priceTargets.UnknownStorageClass == UnknownStorageReject {
+			return nil, withReason(ErrUnknownStorageClass, fmt.Errorf("storage class %s has no price target", strings.Join(unknown, ", ")))
+		}
+		log.Printf("Storage classes without a price target (%s): %s", priceTargets.UnknownStorageClass, strings.Join(unknown, ", "))
+	}
+	for _, reservation := range StorageReservations(resourceRequests, priceTargets.StoragePools) {
+		if reservation.SurchargePercent == 0 {
+			continue
+		}
+		result.Adjustments = append(result.Adjustments, Adjustment{
+			Name: "storage_reservation",
+			Detail: fmt.Sprintf("%g%% storage surcharge for reserving %s GB of %g GB in pool %s",
+				reservation.SurchargePercent, FormatDec(reservation.RequestedGB, 2), reservation.SizeGB, reservation.Pool),
+		})
+	}
+	costLines := CalculateCostLines(resourceRequests, priceTargets)
+	if resourceRequests.GPUsRequested > 0 || !totalGPUPrice.IsZero() {
+		costLines = append(costLines, CostLine{
+			Resource: CostGPU,
+			Kind:     strings.Join(GPUModels(request.GSpec), ", "),
+			Quantity: sdkmath.LegacyNewDec(resourceRequests.GPUsRequested),
+			Unit:     "GPU",
+			Cost:     totalGPUPrice,
+		})
+	}
+	if len(config.Services) > 0 {
+		serviceDelta, serviceAdjustments := ServiceCostDelta(request.GSpec, config.Services, priceTargets)
+		if !serviceDelta.IsZero() {
+			costLines = append(costLines, CostLine{Resource: CostService, Quantity: sdkmath.LegacyZeroDec(), Cost: serviceDelta, Detail: "service price targets"})
+		}
+		result.Adjustments = append(result.Adjustments, serviceAdjustments...)
+	}
+	result.CostLines, result.Currency = costLines, priceTargets.Currency
+	totalCostTarget := totalCost(costLines)
+
+	// Targets may be configured in another fiat currency; everything after this point is in USD
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	_, span = startSpan(ctx, spanFX, stringAttr("akash.currency", priceTargets.Currency))
+	usdPerUnit, err := GetUSDPerUnit(priceTargets.Currency)
+	if err != nil {
+		log.Printf("Error getting %s/USD rate: %v", priceTargets.Currency, err)
+		err = withReason(ErrOracle, fmt.Errorf("error getting %s/USD rate: %v", priceTargets.Currency, err))
+		span.End(err)
+		return nil, err
+	}
+	span.SetAttributes(floatAttr("akash.fx.usd_per_unit", usdPerUnit))
+	span.End(nil)
+	totalCostUsdTarget := totalCostTarget.Mul(decFromFloat(usdPerUnit))
+
+	totalCostUsdTarget, err = whitelistEntry.Apply(totalCostUsdTarget, time.Now())
+	if err != nil {
+		log.Printf("Whitelist entry rejected request: %v", err)
+		return nil, fmt.Errorf("whitelist check failed: %w", err)
+	}
+	if whitelistEntry != nil && whitelistEntry.DiscountPercent > 0 {
+		result.Adjustments = append(result.Adjustments, Adjustment{
+			Name:   "whitelist_discount",
+			Detail: fmt.Sprintf("%g%% whitelist discount", whitelistEntry.DiscountPercent),
+		})
+	}
+
+	reputationBands, err := reputationBandsCache.get(os.Getenv("REPUTATION_BANDS"))
+	if err != nil {
+		return nil, withReason(ErrConfig, fmt.Errorf("error parsing reputation bands: %v", err))
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	beforeReputation := totalCostUsdTarget
+	totalCostUsdTarget, err = ApplyReputation(NewReputationProviderFromEnv(), reputationBands, owner, totalCostUsdTarget)
+	if err != nil {
+		log.Printf("Reputation check failed: %v", err)
+		return nil, withReason(ErrDataSource, fmt.Errorf("reputation check failed: %w", err))
+	}
+	if !totalCostUsdTarget.Equal(beforeReputation) {
+		result.Adjustments = append(result.Adjustments, Adjustment{
+			Name:   "reputation",
+			Detail: fmt.Sprintf("reputation multiplier %s", FormatDec(totalCostUsdTarget.Quo(beforeReputation), 4)),
+		})
+	}
 
-	// In RequestToBidPrice function
-	_, _, finalRateStr := CalculateBlockRates(totalCostUsdTarget, usdPerAkt, precision)
+	trial, err := TrialDiscountFromEnv()
+	if err != nil {
+		return nil, withReason(ErrConfig, err)
+	}
+	loyaltyTiers, err := LoyaltyTiersFromEnv()
+	if err != nil {
+		return nil, withReason(ErrConfig, fmt.Errorf("error parsing loyalty tiers: %v", err))
+	}
+	leaseLookup := &ownerLeaseLookup{owner: owner}
+	totalCostUsdTarget, ownerAdjustments := applyOwnerDiscounts(trial, loyaltyTiers, leaseLookup, totalCostUsdTarget)
+	result.Adjustments = append(result.Adjustments, ownerAdjustments...)
+
+	var adjustment *Adjustment
+	totalCostUsdTarget, result.Coupon, adjustment = applyCoupon(config, RequestCoupon(request.GSpec), totalCostUsdTarget, time.Now())
+	if adjustment != nil {
+		result.Adjustments = append(result.Adjustments, *adjustment)
+	}
+
+	volumeDiscounts, err := volumeDiscountsCache.get(os.Getenv("VOLUME_DISCOUNTS"))
+	if err != nil {
+		return nil, withReason(ErrConfig, fmt.Errorf("error parsing volume discounts: %v", err))
+	}
+	totalCostUsdTarget, adjustment = ApplyVolumeDiscount(volumeDiscounts, resourceRequests, totalCostUsdTarget)
+	if adjustment != nil {
+		result.Adjustments = append(result.Adjustments, *adjustment)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	schedule, err := BlockScheduleFromEnv()
+	if err != nil {
+		return nil, withReason(ErrConfig, err)
+	}
+	result.Schedule = schedule
+	blockTime := schedule.BlockTimeSeconds
+	log.Printf("Using average block time %.3fs, %s blocks per %g-day month", blockTime, FormatDec(schedule.BlocksPerMonth, 1), schedule.DaysPerMonth)
+
+	durationTiers, err := durationTiersCache.get(os.Getenv("DURATION_TIERS"))
+	if err != nil {
+		return nil, withReason(ErrConfig, fmt.Errorf("error parsing duration tiers: %v", err))
+	}
+	if leaseDuration, ok := ExpectedLeaseDuration(request, amount, denom, blockTime); ok {
+		totalCostUsdTarget, adjustment = ApplyDurationTier(durationTiers, leaseDuration, totalCostUsdTarget)
+		if adjustment != nil {
+			result.Adjustments = append(result.Adjustments, *adjustment)
+		}
+	}
+
+	totalCostUsdTarget, adjustment = ApplyDeploymentOverhead(getTargetFloat("DEPLOYMENT_OVERHEAD_USD", 0), totalCostUsdTarget)
+	if adjustment != nil {
+		result.Adjustments = append(result.Adjustments, *adjustment)
+	}
+
+	strategy, err := NewPriceStrategyFromEnv()
+	if err != nil {
+		return nil, withReason(ErrConfig, err)
+	}
+	if strategy != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		maxPrice := ""
+		if !amount.IsNil() {
+			maxPrice = amount.String()
+		}
+		input := StrategyInput{
+			Owner:        owner,
+			Denom:        denom,
+			MaxPrice:     maxPrice,
+			Profile:      result.Profile,
+			Region:       result.Region,
+			CPUCores:     resourceRequests.CPURequested.String(),
+			MemoryGB:     resourceRequests.MemoryRequested.String(),
+			GPUs:         resourceRequests.GPUsRequested,
+			TotalCostUsd: totalCostUsdTarget.String(),
+			AKTPriceUsd:  usdPerAkt,
+			Adjustments:  result.Adjustments,
+		}
+		totalCostUsdTarget, adjustment, err = ApplyStrategy(strategy, input, totalCostUsdTarget)
+		if err != nil {
+			log.Printf("Pricing strategy rejected request: %v", err)
+			return nil, withReason(ErrDataSource, err)
+		}
+		if adjustment != nil {
+			result.Adjustments = append(result.Adjustments, *adjustment)
+		}
+	}
 
+	totalCostUsdTarget, adjustment, err = guards.ApplyCeiling(totalCostUsdTarget)
 	if err != nil {
-		log.Println(err)
-		return err
+		log.Printf("Bid ceiling rejected request: %v", err)
+		return nil, err
+	}
+	if adjustment != nil {
+		log.Printf("Bid guard triggered: %s", adjustment.Detail)
+		result.Adjustments = append(result.Adjustments, *adjustment)
+	}
+
+	if config.CostModel != nil {
+		if err := config.CostModel.CheckBid(baseTargets, resourceRequests, GPUModels(request.GSpec), totalCostUsdTarget); err != nil {
+			log.Printf("Cost floor rejected request: %v", err)
+			return nil, err
+		}
+	}
+
+	exposureCap, err := OwnerExposureCapFromEnv()
+	if err != nil {
+		return nil, withReason(ErrConfig, err)
+	}
+	if exposureCap != nil {
+		leases, err := leaseLookup.get()
+		if err != nil {
+			return nil, withReason(ErrDataSource, fmt.Errorf("owner exposure check failed: %w", err))
+		}
+		if err := exposureCap.Check(owner, leases, totalCostUsdTarget, time.Now()); err != nil {
+			log.Printf("Owner exposure cap rejected request: %v", err)
+			return nil, err
+		}
+	}
+
+	ratePerBlockUakt, ratePerBlockUsd, finalRateStr := calculateBlockRates(totalCostUsdTarget, decFromFloat(usdPerAkt), schedule.BlocksPerMonth, precision)
+
+	ratePerBlockUakt, ratePerBlockUsd, adjustment = guards.ApplyFloor(ratePerBlockUakt, ratePerBlockUsd, decFromFloat(usdPerAkt))
+	if adjustment != nil {
+		log.Printf("Bid guard triggered: %s", adjustment.Detail)
+		result.Adjustments = append(result.Adjustments, *adjustment)
+		finalRateStr = FormatDec(ratePerBlockUakt, precision) + "uakt"
 	}
 
 	// Now, finalRateStr already has the "uakt" suffix and the correct number of decimal places
 	fmt.Printf("Total cost per block (uakt, formatted): %s\n", finalRateStr)
 
-	fmt.Printf("Total cost in USD: %.2f/month\n", totalCostUsdTarget)
+	fmt.Printf("Total cost in USD: %s/month\n", FormatDec(totalCostUsdTarget, 2))
 
-	return nil
+	// Convert to the order's denom and make sure the bid does not exceed the order's max price
+	_, span = startSpan(ctx, spanConvert, stringAttr("akash.denom", denom))
+	costRate, err := config.Denoms.BidRate(denom, ratePerBlockUakt, ratePerBlockUsd, precision, amount)
+	if err != nil {
+		log.Printf("Error pricing denom %s: %v", denom, err)
+		span.End(err)
+		return nil, err
+	}
+	span.End(nil)
+	bidPrecision := config.Denoms.Precision(denom, precision)
+	if bidPrecision != precision {
+		log.Printf("Formatting the bid at %d decimal places, the precision rule of %s", bidPrecision, denom)
+		result.Precision = bidPrecision
+	}
+	result.CostPrice, err = config.Denoms.FormatRate(denom, costRate, bidPrecision, amount)
+	if err != nil {
+		log.Printf("Error pricing denom %s: %v", denom, err)
+		return nil, err
+	}
+
+	bidRate, bidPrice := costRate, result.CostPrice
+	// A shaded rate rounding up past the max price is bid at cost instead
+	if shadedRate := shading.Shade(costRate, amount); shadedRate.GT(costRate) {
+		if shadedPrice, err := config.Denoms.FormatRate(denom, shadedRate, bidPrecision, amount); err == nil {
+			bidRate, bidPrice = shadedRate, shadedPrice
+			result.Adjustments = append(result.Adjustments, Adjustment{
+				Name:   "shading",
+				Detail: fmt.Sprintf("bid %s%% below max price %s%s instead of cost %s%s", strconv.FormatFloat(shading.PercentBelowMax, 'f', -1, 64), FormatDec(amount, precision), denom, result.CostPrice, denom),
+			})
+			fmt.Printf("Cost-based price per block (%s): %s\n", denom, result.CostPrice)
+		}
+	}
+	// Jitter stays between the cost-based rate and the max price; like shading, it is dropped if rounding
+	// would take it past the max price
+	if jitteredRate := jitter.Jitter(bidRate, costRate, amount, request.OrderID); !jitteredRate.Equal(bidRate) {
+		if jitteredPrice, err := config.Denoms.FormatRate(denom, jitteredRate, bidPrecision, amount); err == nil && jitteredPrice != bidPrice {
+			result.Adjustments = append(result.Adjustments, Adjustment{
+				Name:   "jitter",
+				Detail: fmt.Sprintf("bid %s%s instead of %s%s, jittered by up to %g%% for order %s", jitteredPrice, denom, bidPrice, denom, jitter.Percent, request.OrderID),
+			})
+			bidPrice = jitteredPrice
+		}
+	}
+	fmt.Printf("Bid price per block (%s): %s\n", denom, bidPrice)
+
+	result.Price = bidPrice
+	result.Resources = resourceRequests
+	result.TotalCostUsd = totalCostUsdTarget
+	result.USDPerAKT = usdPerAkt
+	result.AKTPriceSource = aktPriceSource
+	result.RatePerBlockUakt = ratePerBlockUakt
+	result.RatePerBlockUsd = ratePerBlockUsd
+	return result, nil
 }
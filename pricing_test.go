@@ -0,0 +1,114 @@
+package pricing
+
+import (
+	"context"
+	"testing"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+	attrtypes "pkg.akt.dev/go/node/types/attributes/v1"
+	rtypes "pkg.akt.dev/go/node/types/resources/v1beta4"
+)
+
+// benchEnv prices the benchmarks with the default targets, an A100 price and a pinned AKT price, so they
+// never query the oracle or write the bid history.
+var benchEnv = map[string]string{
+	"AKT_PRICE_PIN":             "3.5",
+	"PRICE_TARGET_GPU_MAPPINGS": "a100=120",
+}
+
+// benchGroupSpec generates a GroupSpec of units resource units mixing CPU sizes, storage classes, endpoints,
+// leased IPs and GPUs, with a max price no bid reaches.
+func benchGroupSpec(units int) *dtypes.GroupSpec {
+	quantity := func(v int64) rtypes.ResourceValue { return rtypes.ResourceValue{Val: sdkmath.NewInt(v)} }
+	price := sdk.DecCoin{Denom: "uakt", Amount: sdkmath.LegacyNewDec(1000000000)}
+
+	gSpec := &dtypes.GroupSpec{Name: "bench"}
+	for i := 0; i < units; i++ {
+		resources := rtypes.Resources{
+			ID:     uint32(i + 1),
+			CPU:    &rtypes.CPU{Units: quantity(int64(500 * (i%8 + 1)))},
+			Memory: &rtypes.Memory{Quantity: quantity(int64(i%4+1) << 30)},
+			Storage: rtypes.Volumes{
+				{Name: "default", Quantity: quantity(10 << 30)},
+			},
+			Endpoints: rtypes.Endpoints{{Kind: rtypes.Endpoint_SHARED_HTTP}},
+		}
+		if i%2 == 1 {
+			resources.Storage = append(resources.Storage, rtypes.Storage{
+				Name:       "data",
+				Quantity:   quantity(100 << 30),
+				Attributes: attrtypes.Attributes{{Key: "persistent", Value: "true"}, {Key: "class", Value: "beta3"}},
+			})
+		}
+		if i%4 == 0 {
+			resources.GPU = &rtypes.GPU{
+				Units:      quantity(1),
+				Attributes: attrtypes.Attributes{{Key: "vendor/nvidia/model/a100/ram/80Gi/interface/sxm", Value: "true"}},
+			}
+		}
+		if i%10 == 0 {
+			resources.Endpoints = append(resources.Endpoints, rtypes.Endpoint{Kind: rtypes.Endpoint_LEASED_IP, SequenceNumber: uint32(i/10 + 1)})
+		}
+		gSpec.Resources = append(gSpec.Resources, dtypes.ResourceUnit{Resources: resources, Count: uint32(i%3 + 1), Price: price})
+	}
+	return gSpec
+}
+
+func BenchmarkCalculateRequestedResources(b *testing.B) {
+	gSpec := benchGroupSpec(128)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		CalculateRequestedResources(gSpec)
+	}
+}
+
+func BenchmarkCalculateBid(b *testing.B) {
+	restore := isolateEnv(benchEnv)
+	defer restore()
+
+	request := Request{Owner: "akash1bench", GSpec: benchGroupSpec(128)}
+	if _, err := CalculateBid(request); err != nil {
+		b.Fatalf("benchmark request is rejected: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		CalculateBid(request)
+	}
+}
+
+// fixtureRequest returns the request of the named fixture of testdata/fixtures, without its environment.
+func fixtureRequest(t *testing.T, name string) Request {
+	t.Helper()
+	fixtures, err := LoadFixtures("testdata/fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fixture := range fixtures {
+		if fixture.Name == name {
+			return Request{Owner: "akash1fixture", GSpec: fixture.GroupSpec, PricePrecision: fixture.Precision, USDPerAKT: fixture.AKTPriceUsd}
+		}
+	}
+	t.Fatalf("no fixture %s", name)
+	return Request{}
+}
+
+// priceWithEnv prices request under env alone, without the side effects of a bid.
+func priceWithEnv(request Request, env map[string]string) (*BidResult, error) {
+	restore := isolateEnv(env)
+	defer restore()
+	return calculateBid(context.Background(), request)
+}
+
+// findAdjustment returns the result's adjustment of the given name, or nil.
+func findAdjustment(result *BidResult, name string) *Adjustment {
+	for i := range result.Adjustments {
+		if result.Adjustments[i].Name == name {
+			return &result.Adjustments[i]
+		}
+	}
+	return nil
+}
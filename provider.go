@@ -0,0 +1,172 @@
+package pricing
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// ProviderConfig is the configuration namespace of one provider address, for a daemon pricing for several
+// providers. Its targets apply over the base targets of the environment before any region override, and
+// its profiles, when it has any, replace the top-level profiles.
+type ProviderConfig struct {
+	Targets        PriceTargetsConfig        `json:"targets"`
+	Profiles       map[string]PricingProfile `json:"profiles,omitempty"`
+	DefaultProfile string                    `json:"default_profile,omitempty"` // Profile used when no selector matches
+	Shading        *ShadingStrategy          `json:"shading,omitempty"`         // Overrides BID_SHADING_PERCENT for this provider
+}
+
+// Validate checks the namespace's targets, profiles and shading.
+func (p ProviderConfig) Validate() error {
+	if err := p.Targets.Validate(); err != nil {
+		return err
+	}
+	for name, profile := range p.Profiles {
+		if err := profile.Targets.Validate(); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+		if profile.Shading != nil {
+			if err := profile.Shading.Validate(); err != nil {
+				return fmt.Errorf("profile %s: %w", name, err)
+			}
+		}
+	}
+	if _, ok := p.Profiles[p.DefaultProfile]; p.DefaultProfile != "" && !ok {
+		return fmt.Errorf("default profile %s is not defined", p.DefaultProfile)
+	}
+	if p.Shading != nil {
+		return p.Shading.Validate()
+	}
+	return nil
+}
+
+// validateProviders checks every provider namespace of the config file.
+func validateProviders(providers map[string]ProviderConfig) error {
+	for address, provider := range providers {
+		if strings.TrimSpace(address) == "" {
+			return fmt.Errorf("provider namespace with an empty address")
+		}
+		if err := provider.Validate(); err != nil {
+			return fmt.Errorf("provider %s: %w", address, err)
+		}
+	}
+	return nil
+}
+
+// RequestProvider returns the provider address a request is priced for: the request's Provider, or
+// PROVIDER_ADDRESS for a process pricing for a single provider. An empty string means none is known.
+func RequestProvider(request Request) string {
+	if request.Provider != "" {
+		return request.Provider
+	}
+	return strings.TrimSpace(os.Getenv("PROVIDER_ADDRESS"))
+}
+
+// ProviderNamespace returns the configuration namespace of a provider address, if any. Providers without
+// one are priced with the top-level configuration.
+func (c *Config) ProviderNamespace(address string) (*ProviderConfig, bool) {
+	if address == "" {
+		return nil, false
+	}
+	provider, ok := c.Providers[address]
+	if !ok {
+		return nil, false
+	}
+	return &provider, true
+}
+
+// profiles returns the config whose profiles are selected from for a provider namespace: the namespace's
+// own when it defines any, c otherwise.
+func (c *Config) profiles(provider *ProviderConfig) *Config {
+	if provider == nil || len(provider.Profiles) == 0 {
+		return c
+	}
+	return &Config{Profiles: provider.Profiles, DefaultProfile: provider.DefaultProfile}
+}
+
+// ProviderStats counts the requests priced for one provider namespace by this process.
+type ProviderStats struct {
+	Bids     int64             // Requests bid on
+	Rejected int64             // Requests rejected, e.g. not whitelisted or above the max price
+	Failures int64             // Requests that failed to price
+	CostUsd  sdkmath.LegacyDec // Monthly USD of the bids
+}
+
+var (
+	providerMu    sync.Mutex
+	providerStats = make(map[string]*ProviderStats)
+)
+
+// CurrentProviderStats returns a copy of the counters per provider address. Requests for providers without
+// a namespace are counted under "".
+func CurrentProviderStats() map[string]ProviderStats {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	stats := make(map[string]ProviderStats, len(providerStats))
+	for address, s := range providerStats {
+		stats[address] = *s
+	}
+	return stats
+}
+
+// countProviderBid counts a pricing outcome under the request's provider namespace. Addresses without a
+// namespace share one label, so callers naming arbitrary providers cannot grow the metrics without bound.
+func countProviderBid(request Request, result *BidResult, err error) {
+	address := ""
+	if result != nil {
+		address = result.Provider
+	} else if config, configErr := LoadConfig(); configErr == nil {
+		if _, ok := config.ProviderNamespace(RequestProvider(request)); ok {
+			address = RequestProvider(request)
+		}
+	}
+
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	stats, ok := providerStats[address]
+	if !ok {
+		stats = &ProviderStats{CostUsd: sdkmath.LegacyZeroDec()}
+		providerStats[address] = stats
+	}
+	switch _, rejected := ErrorReason(err); {
+	case err == nil:
+		stats.Bids++
+		if !result.TotalCostUsd.IsNil() {
+			stats.CostUsd = stats.CostUsd.Add(result.TotalCostUsd)
+		}
+	case rejected:
+		stats.Rejected++
+	default:
+		stats.Failures++
+	}
+}
+
+// WriteProviderMetrics writes the counters per provider in the Prometheus text exposition format.
+func WriteProviderMetrics(w io.Writer, stats map[string]ProviderStats) error {
+	addresses := make([]string, 0, len(stats))
+	for address := range stats {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	ew := &errWriter{w: w}
+	ew.printf("# HELP akash_pricing_bids_total Requests priced by this process, by provider namespace and outcome.\n")
+	ew.printf("# TYPE akash_pricing_bids_total counter\n")
+	for _, address := range addresses {
+		s := stats[address]
+		ew.printf("akash_pricing_bids_total{provider=%q,outcome=\"bid\"} %d\n", address, s.Bids)
+		ew.printf("akash_pricing_bids_total{provider=%q,outcome=\"rejected\"} %d\n", address, s.Rejected)
+		ew.printf("akash_pricing_bids_total{provider=%q,outcome=\"failed\"} %d\n", address, s.Failures)
+	}
+	ew.printf("# HELP akash_pricing_bid_cost_usd_total Monthly USD of the bids, by provider namespace.\n")
+	ew.printf("# TYPE akash_pricing_bid_cost_usd_total counter\n")
+	for _, address := range addresses {
+		ew.printf("akash_pricing_bid_cost_usd_total{provider=%q} %s\n", address, FormatDec(stats[address].CostUsd, 2))
+	}
+	return ew.err
+}
@@ -0,0 +1,43 @@
+package pricing
+
+import (
+	"os"
+	"strings"
+
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// DefaultRegionAttribute is the placement attribute read for the region when REGION is unset.
+const DefaultRegionAttribute = "region"
+
+// RequestRegion returns the region to price a request for. REGION identifies the cluster running this
+// script and takes precedence; otherwise the region placement attribute requested by the GroupSpec is
+// used (named by REGION_ATTRIBUTE, default "region"). An empty string means no region is known.
+func RequestRegion(gSpec *dtypes.GroupSpec) string {
+	if region := strings.TrimSpace(os.Getenv("REGION")); region != "" {
+		return region
+	}
+	if gSpec == nil {
+		return ""
+	}
+
+	key := os.Getenv("REGION_ATTRIBUTE")
+	if key == "" {
+		key = DefaultRegionAttribute
+	}
+	for _, attr := range gSpec.Requirements.Attributes {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// RegionTargets returns the target overrides configured for a region, if any.
+func (c *Config) RegionTargets(region string) (PriceTargetsConfig, bool) {
+	if region == "" {
+		return PriceTargetsConfig{}, false
+	}
+	targets, ok := c.Regions[region]
+	return targets, ok
+}
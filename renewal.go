@@ -0,0 +1,163 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// RenewalPolicy configures how RepriceLease renews an existing lease.
+type RenewalPolicy struct {
+	DiscountPercent    float64 // Taken off the monthly USD cost of a renewed lease (0-100)
+	MaxIncreasePercent float64 // Largest increase of the renewed rate over the original rate, 0 means uncapped
+}
+
+// RenewalPolicyFromEnv returns the policy of RENEWAL_DISCOUNT_PERCENT and RENEWAL_MAX_INCREASE_PERCENT.
+func RenewalPolicyFromEnv() (RenewalPolicy, error) {
+	var policy RenewalPolicy
+	if val := os.Getenv("RENEWAL_DISCOUNT_PERCENT"); val != "" {
+		percent, err := strconv.ParseFloat(val, 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return RenewalPolicy{}, fmt.Errorf("invalid RENEWAL_DISCOUNT_PERCENT %q: must be between 0 and 100", val)
+		}
+		policy.DiscountPercent = percent
+	}
+	if val := os.Getenv("RENEWAL_MAX_INCREASE_PERCENT"); val != "" {
+		percent, err := strconv.ParseFloat(val, 64)
+		if err != nil || percent < 0 || checkDecFloat(percent) != nil {
+			return RenewalPolicy{}, fmt.Errorf("invalid RENEWAL_MAX_INCREASE_PERCENT %q: must be a non-negative percent", val)
+		}
+		policy.MaxIncreasePercent = percent
+	}
+	return policy, nil
+}
+
+// RepriceLease recomputes the rate of an existing lease, priced earlier as original, for a renewal or an
+// extended reservation. The lease keeps its monthly USD cost, so its resources and the discounts it won are
+// not priced again; the cost is converted at currentOraclePrice USD per AKT and the current block schedule,
+// less the RENEWAL_DISCOUNT_PERCENT renewal discount, and the rate rises at most
+// RENEWAL_MAX_INCREASE_PERCENT over the original. A currentOraclePrice of zero queries the oracle. Renewals
+// have no max price, and the bid floor still applies. Special pricing leases keep their rate.
+func RepriceLease(ctx context.Context, original *BidResult, currentOraclePrice float64) (*BidResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if original == nil {
+		return nil, withReason(ErrInvalidRequest, fmt.Errorf("no lease to reprice"))
+	}
+	if original.TotalCostUsd.IsNil() {
+		// Special pricing bids have no cost to reprice
+		renewed := *original
+		return &renewed, nil
+	}
+	if err := checkDecFloat(currentOraclePrice); err != nil || currentOraclePrice < 0 {
+		return nil, withReason(ErrInvalidRequest, fmt.Errorf("invalid AKT price %g", currentOraclePrice))
+	}
+
+	source := AKTPriceSourcePin
+	if currentOraclePrice == 0 {
+		var err error
+		currentOraclePrice, source, err = getAKTPrice(context.Background())
+		if err != nil {
+			log.Printf("Error getting AKT price: %v", err)
+			return nil, withReason(ErrOracle, fmt.Errorf("error getting AKT price: %w", err))
+		}
+		if currentOraclePrice <= 0 {
+			return nil, withReason(ErrOracle, fmt.Errorf("invalid AKT price %g from %s", currentOraclePrice, source))
+		}
+	}
+	return repriceLease(original, currentOraclePrice, source)
+}
+
+// RepriceLease is the package level RepriceLease with the engine's AKT price when currentOraclePrice is zero.
+func (e *PricingEngine) RepriceLease(ctx context.Context, original *BidResult, currentOraclePrice float64) (*BidResult, error) {
+	if currentOraclePrice != 0 || original == nil || original.TotalCostUsd.IsNil() {
+		return RepriceLease(ctx, original, currentOraclePrice)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	usdPerAkt, source, err := e.aktPrice()
+	if err != nil {
+		return nil, err
+	}
+	return repriceLease(original, usdPerAkt, source)
+}
+
+// repriceLease converts the monthly cost of original at usdPerAkt, applying the renewal policy.
+func repriceLease(original *BidResult, usdPerAkt float64, source string) (*BidResult, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, withReason(ErrConfig, err)
+	}
+	policy, err := RenewalPolicyFromEnv()
+	if err != nil {
+		return nil, withReason(ErrConfig, err)
+	}
+	guards, err := NewBidGuardsFromEnv()
+	if err != nil {
+		return nil, withReason(ErrConfig, err)
+	}
+	schedule, err := BlockScheduleFromEnv()
+	if err != nil {
+		return nil, withReason(ErrConfig, err)
+	}
+
+	renewed := *original
+	renewed.Adjustments = append([]Adjustment(nil), original.Adjustments...)
+	renewed.Quote, renewed.Schedule = false, schedule
+	renewed.USDPerAKT, renewed.AKTPriceSource = usdPerAkt, source
+	renewed.Version, renewed.ConfigHash = BuildVersion(), ConfigHash()
+	renewed.Adjustments = append(renewed.Adjustments, Adjustment{
+		Name:   "renewal",
+		Detail: fmt.Sprintf("repriced from %s%s at AKT price %g", original.Price, original.Denom, usdPerAkt),
+	})
+
+	totalCostUsd := original.TotalCostUsd
+	if policy.DiscountPercent > 0 {
+		totalCostUsd = totalCostUsd.Sub(totalCostUsd.Mul(decFromFloat(policy.DiscountPercent)).QuoInt64(100))
+		renewed.Adjustments = append(renewed.Adjustments, Adjustment{
+			Name:   "renewal_discount",
+			Detail: fmt.Sprintf("%g%% renewal discount", policy.DiscountPercent),
+		})
+	}
+	renewed.TotalCostUsd = totalCostUsd
+
+	ratePerBlockUakt, ratePerBlockUsd, _ := calculateBlockRates(totalCostUsd, decFromFloat(usdPerAkt), schedule.BlocksPerMonth, original.Precision)
+	ratePerBlockUakt, ratePerBlockUsd, adjustment := guards.ApplyFloor(ratePerBlockUakt, ratePerBlockUsd, decFromFloat(usdPerAkt))
+	if adjustment != nil {
+		renewed.Adjustments = append(renewed.Adjustments, *adjustment)
+	}
+	renewed.RatePerBlockUakt, renewed.RatePerBlockUsd = ratePerBlockUakt, ratePerBlockUsd
+
+	rate, err := config.Denoms.RatePerBlock(original.Denom, ratePerBlockUakt, ratePerBlockUsd)
+	if err != nil {
+		return nil, err
+	}
+	renewed.CostPrice, err = config.Denoms.FormatRate(original.Denom, rate, original.Precision, sdkmath.LegacyDec{})
+	if err != nil {
+		return nil, err
+	}
+	renewed.Price = renewed.CostPrice
+	// The cap only lowers the bid; the rates per block stay the cost-based ones, like a shaded bid's
+	if policy.MaxIncreasePercent > 0 {
+		if originalRate, err := sdkmath.LegacyNewDecFromStr(original.Price); err == nil {
+			maxRate := originalRate.Add(originalRate.Mul(decFromFloat(policy.MaxIncreasePercent)).QuoInt64(100))
+			if rate.GT(maxRate) {
+				renewed.Price, err = config.Denoms.FormatRate(original.Denom, maxRate, original.Precision, sdkmath.LegacyDec{})
+				if err != nil {
+					return nil, err
+				}
+				renewed.Adjustments = append(renewed.Adjustments, Adjustment{
+					Name:   "renewal_cap",
+					Detail: fmt.Sprintf("renewal capped at %g%% above the original rate %s%s", policy.MaxIncreasePercent, original.Price, original.Denom),
+				})
+			}
+		}
+	}
+	return &renewed, nil
+}
@@ -0,0 +1,53 @@
+package pricing
+
+import (
+	"context"
+	"testing"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// TestRepriceLease renews a lease at a steady and at a halved AKT price, where the rate increase is capped.
+func TestRepriceLease(t *testing.T) {
+	restore := isolateEnv(map[string]string{
+		"BLOCKS_PER_MONTH":             "430000",
+		"RENEWAL_DISCOUNT_PERCENT":     "10",
+		"RENEWAL_MAX_INCREASE_PERCENT": "20",
+	})
+	defer restore()
+
+	original := &BidResult{
+		Denom:        "uakt",
+		Price:        "4.55",
+		CostPrice:    "4.55",
+		Precision:    2,
+		TotalCostUsd: sdkmath.LegacyMustNewDecFromStr("6.85"),
+		USDPerAKT:    3.5,
+	}
+	tests := []struct {
+		usdPerAkt float64
+		price     string
+		costPrice string
+	}{
+		{usdPerAkt: 3.5, price: "4.10", costPrice: "4.10"},
+		{usdPerAkt: 1.75, price: "5.46", costPrice: "8.19"},
+	}
+	for _, tt := range tests {
+		renewed, err := RepriceLease(context.Background(), original, tt.usdPerAkt)
+		if err != nil {
+			t.Fatalf("AKT price %g: %v", tt.usdPerAkt, err)
+		}
+		if renewed.Price != tt.price || renewed.CostPrice != tt.costPrice {
+			t.Errorf("AKT price %g: renewed at %s (cost %s), want %s (cost %s)", tt.usdPerAkt, renewed.Price, renewed.CostPrice, tt.price, tt.costPrice)
+		}
+		if !renewed.TotalCostUsd.Equal(sdkmath.LegacyMustNewDecFromStr("6.165")) {
+			t.Errorf("AKT price %g: monthly cost %s, want 6.165", tt.usdPerAkt, renewed.TotalCostUsd)
+		}
+		if renewed.AKTPriceSource != AKTPriceSourcePin {
+			t.Errorf("AKT price %g: source %s, want %s", tt.usdPerAkt, renewed.AKTPriceSource, AKTPriceSourcePin)
+		}
+	}
+	if len(original.Adjustments) != 0 {
+		t.Errorf("original lease was modified: %v", original.Adjustments)
+	}
+}
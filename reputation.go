@@ -0,0 +1,208 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// ReputationProvider returns a reputation score between 0 and 100 for an owner address.
+type ReputationProvider interface {
+	Score(owner string) (float64, error)
+}
+
+// ReputationBand maps a score range [Min, Max) to a price multiplier or a rejection.
+type ReputationBand struct {
+	Min        float64
+	Max        float64
+	Multiplier float64
+	Reject     bool
+}
+
+// HTTPReputationProvider fetches scores from an external service.
+// The owner is substituted for "{owner}" in URL, or appended as an "owner" query parameter otherwise.
+// The service must respond with JSON of the form {"score": 87.5}.
+type HTTPReputationProvider struct {
+	URL    string
+	Client *http.Client
+}
+
+// Score queries the reputation service for the owner.
+func (p *HTTPReputationProvider) Score(owner string) (float64, error) {
+	reqURL := p.URL
+	if strings.Contains(reqURL, "{owner}") {
+		reqURL = strings.ReplaceAll(reqURL, "{owner}", url.PathEscape(owner))
+	} else {
+		u, err := url.Parse(reqURL)
+		if err != nil {
+			return 0, err
+		}
+		q := u.Query()
+		q.Set("owner", owner)
+		u.RawQuery = q.Encode()
+		reqURL = u.String()
+	}
+
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	resp, err := client.Get(reqURL)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("HTTP request error: %s", resp.Status)
+	}
+
+	var data struct {
+		Score *float64 `json:"score"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return 0, err
+	}
+	if data.Score == nil {
+		return 0, fmt.Errorf("reputation response for %s has no score", owner)
+	}
+	return *data.Score, nil
+}
+
+// OwnerHistory is the local history record of an owner used by FileReputationProvider.
+type OwnerHistory struct {
+	Score           *float64 `json:"score,omitempty"` // Explicit score, overrides the computed one
+	LeasesCompleted int      `json:"leases_completed"`
+	PaymentDefaults int      `json:"payment_defaults"`
+}
+
+// FileReputationProvider scores owners from a local JSON history file mapping owner addresses to OwnerHistory.
+// Unknown owners receive the neutral score of 50.
+type FileReputationProvider struct {
+	Path string
+}
+
+// Score computes the owner score from its local history.
+// Each completed lease adds 5 points and each payment default removes 25, starting from 50 and clamped to 0-100.
+func (p *FileReputationProvider) Score(owner string) (float64, error) {
+	data, err := ioutil.ReadFile(p.Path)
+	if err != nil {
+		return 0, err
+	}
+
+	var history map[string]OwnerHistory
+	if err := json.Unmarshal(data, &history); err != nil {
+		return 0, fmt.Errorf("invalid reputation history %s: %w", p.Path, err)
+	}
+
+	record, ok := history[owner]
+	if !ok {
+		return 50, nil
+	}
+	if record.Score != nil {
+		return *record.Score, nil
+	}
+
+	score := 50 + 5*float64(record.LeasesCompleted) - 25*float64(record.PaymentDefaults)
+	if score < 0 {
+		score = 0
+	}
+	if score > 100 {
+		score = 100
+	}
+	return score, nil
+}
+
+// ParseReputationBands parses bands of the form "0-30=reject,30-70=1.2,70-100=1.0".
+// Ranges include Min and exclude Max, except a Max of 100 which includes a perfect score.
+func ParseReputationBands(bandsStr string) ([]ReputationBand, error) {
+	var bands []ReputationBand
+	if bandsStr == "" {
+		return bands, nil
+	}
+
+	for _, pair := range strings.Split(bandsStr, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.Split(pair, "=")
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid reputation band: %s", pair)
+		}
+
+		bounds := strings.Split(kv[0], "-")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid reputation band range: %s", kv[0])
+		}
+		min, err := strconv.ParseFloat(bounds[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reputation band range %s: %v", kv[0], err)
+		}
+		max, err := strconv.ParseFloat(bounds[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reputation band range %s: %v", kv[0], err)
+		}
+		if min >= max {
+			return nil, fmt.Errorf("invalid reputation band range %s: min must be below max", kv[0])
+		}
+
+		band := ReputationBand{Min: min, Max: max}
+		if kv[1] == "reject" {
+			band.Reject = true
+		} else {
+			band.Multiplier, err = strconv.ParseFloat(kv[1], 64)
+			if err != nil || band.Multiplier <= 0 {
+				return nil, fmt.Errorf("invalid reputation multiplier for %s: %s", kv[0], kv[1])
+			}
+		}
+		bands = append(bands, band)
+	}
+
+	return bands, nil
+}
+
+// NewReputationProviderFromEnv returns the provider configured by REPUTATION_URL or REPUTATION_FILE, or nil.
+func NewReputationProviderFromEnv() ReputationProvider {
+	if reputationURL := os.Getenv("REPUTATION_URL"); reputationURL != "" {
+		return &HTTPReputationProvider{URL: reputationURL}
+	}
+	if reputationFile := os.Getenv("REPUTATION_FILE"); reputationFile != "" {
+		return &FileReputationProvider{Path: reputationFile}
+	}
+	return nil
+}
+
+// ApplyReputation scores the owner and applies the matching band to the monthly USD cost.
+// Owners whose score falls in no band, or whose score cannot be fetched, keep the base price.
+func ApplyReputation(provider ReputationProvider, bands []ReputationBand, owner string, totalCostUsd sdkmath.LegacyDec) (sdkmath.LegacyDec, error) {
+	if provider == nil || len(bands) == 0 {
+		return totalCostUsd, nil
+	}
+
+	score, err := provider.Score(owner)
+	if err != nil {
+		log.Printf("Error getting reputation for %s, using base price: %v", owner, err)
+		return totalCostUsd, nil
+	}
+
+	for _, band := range bands {
+		if score >= band.Min && (score < band.Max || (band.Max == 100 && score == 100)) {
+			if band.Reject {
+				return sdkmath.LegacyDec{}, withReason(ErrReputationRejected, fmt.Errorf("owner %s reputation score %.1f is in rejected band %g-%g", owner, score, band.Min, band.Max))
+			}
+			return totalCostUsd.Mul(decFromFloat(band.Multiplier)), nil
+		}
+	}
+
+	return totalCostUsd, nil
+}
@@ -0,0 +1,58 @@
+package pricing
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// TestReputationBid prices the cpu-only fixture, 6.85 USD a month, for owners of varying local history under
+// REPUTATION_BANDS="0-30=reject,30-70=1.2,70-100=0.9".
+func TestReputationBid(t *testing.T) {
+	tests := []struct {
+		name      string
+		history   string
+		totalCost string
+		price     string
+		detail    string
+		rejected  bool
+	}{
+		{"unknown owner", `{}`, "8.220000", "5.462943", "reputation multiplier 1.2000", false},
+		{"band minimum", `{"akash1fixture": {"score": 30}}`, "8.220000", "5.462943", "reputation multiplier 1.2000", false},
+		{"below band minimum", `{"akash1fixture": {"score": 29.9}}`, "", "", "", true},
+		{"defaults", `{"akash1fixture": {"leases_completed": 2, "payment_defaults": 1}}`, "", "", "", true},
+		{"band maximum", `{"akash1fixture": {"score": 70}}`, "6.165000", "4.097207", "reputation multiplier 0.9000", false},
+		{"perfect score", `{"akash1fixture": {"leases_completed": 12}}`, "6.165000", "4.097207", "reputation multiplier 0.9000", false},
+		{"unreadable history", `{`, "6.850000", "4.552452", "", false},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "reputation.json")
+		if err := ioutil.WriteFile(path, []byte(tt.history), 0644); err != nil {
+			t.Fatal(err)
+		}
+		result, err := priceWithEnv(fixtureRequest(t, "cpu-only"), map[string]string{
+			"REPUTATION_BANDS": "0-30=reject,30-70=1.2,70-100=0.9",
+			"REPUTATION_FILE":  path,
+		})
+		if tt.rejected {
+			if !errors.Is(err, ErrReputationRejected) {
+				t.Errorf("%s: got %v, want a rejection", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := FormatDec(result.TotalCostUsd, 6); got != tt.totalCost || result.Price != tt.price {
+			t.Errorf("%s: got %s USD at %s, want %s USD at %s", tt.name, got, result.Price, tt.totalCost, tt.price)
+		}
+		adjustment := findAdjustment(result, "reputation")
+		switch {
+		case tt.detail == "" && adjustment != nil:
+			t.Errorf("%s: got adjustment %q, want none", tt.name, adjustment.Detail)
+		case tt.detail != "" && (adjustment == nil || adjustment.Detail != tt.detail):
+			t.Errorf("%s: got adjustment %v, want %q", tt.name, adjustment, tt.detail)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// Modes of handling orders whose price has no amount, see OrderPriceModeFromEnv.
+const (
+	OrderPriceRequired  = "require" // Such orders are rejected as invalid (default)
+	OrderPriceQuoteOnly = "quote"   // Such orders are priced as quotes, without comparing against a max price
+)
+
+// OrderPriceModeFromEnv returns ORDER_PRICE_MODE, how orders whose price amount is missing or zero are
+// handled, defaulting to OrderPriceRequired.
+func OrderPriceModeFromEnv() (string, error) {
+	switch mode := strings.TrimSpace(os.Getenv("ORDER_PRICE_MODE")); mode {
+	case "":
+		return OrderPriceRequired, nil
+	case OrderPriceRequired, OrderPriceQuoteOnly:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid ORDER_PRICE_MODE %q: must be require or quote", mode)
+	}
+}
+
+// ValidateRequest checks a request can be priced before any oracle or whitelist lookup: the GroupSpec has
+// resources with positive counts and valid quantities, every resource is priced in the same denom, the denom
+// is in the registry, and the max price is positive unless the request has none or is a quote without one.
+func ValidateRequest(request Request, denoms DenomRegistry) error {
+	if request.Owner == "" {
+		return fmt.Errorf("%w: request owner is not specified", ErrInvalidRequest)
+	}
+	if request.GSpec == nil || len(request.GSpec.Resources) == 0 {
+		return ErrEmptyGroupSpec
+	}
+
+	price := request.GSpec.Resources[0].Price
+	for i, resourceUnit := range request.GSpec.Resources {
+		if resourceUnit.Count == 0 {
+			return fmt.Errorf("%w: resource %d: count is zero", ErrInvalidGroupSpec, i)
+		}
+		if resourceUnit.Price.Denom != price.Denom {
+			return fmt.Errorf("%w: resource %d: price denom %s differs from %s", ErrInvalidGroupSpec, i, resourceUnit.Price.Denom, price.Denom)
+		}
+	}
+	if err := ValidateGroupSpec(request.GSpec); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidGroupSpec, err)
+	}
+
+	if price.Denom == "" {
+		return fmt.Errorf("%w: price denom is empty", ErrInvalidGroupSpec)
+	}
+	if _, ok := denoms[price.Denom]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedDenom, price.Denom)
+	}
+	if request.NoMaxPrice || (request.QuoteOnly && missingAmount(price.Amount)) {
+		return nil
+	}
+	if price.Amount.IsNil() || !price.Amount.IsPositive() {
+		return fmt.Errorf("%w: price amount must be positive", ErrInvalidGroupSpec)
+	}
+	return nil
+}
+
+// missingAmount reports whether an order's price amount was left out, as quote requests do.
+func missingAmount(amount sdkmath.LegacyDec) bool {
+	return amount.IsNil() || amount.IsZero()
+}
@@ -0,0 +1,222 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// LeaseRevenue is the estimated monthly revenue of one won lease.
+type LeaseRevenue struct {
+	OrderID    string
+	Owner      string
+	Profile    string
+	Denom      string
+	Price      string            // Bid rate per block in Denom
+	MonthlyUsd sdkmath.LegacyDec // Monthly USD of the rate at current prices, nil when Err is set
+	BidUsd     sdkmath.LegacyDec // Monthly USD of the bid when it was placed, nil if it was not recorded
+	Err        error             // Why the lease could not be valued, e.g. an unsupported denom
+}
+
+// RevenueGroup totals the revenue of the leases won with one profile in one denom.
+type RevenueGroup struct {
+	Profile    string // "none" for bids priced without a profile
+	Denom      string
+	Leases     int
+	MonthlyUsd sdkmath.LegacyDec
+	BidUsd     sdkmath.LegacyDec
+}
+
+// RevenueEstimate is the monthly revenue of the won leases in the bid history at current prices. The rates
+// of won bids are fixed on chain, so MonthlyUsd moves with the token prices while BidUsd is what the bids
+// were worth when they were placed.
+type RevenueEstimate struct {
+	Time       time.Time
+	USDPerAKT  float64
+	Leases     []LeaseRevenue
+	MonthlyUsd sdkmath.LegacyDec // Total of the valued leases
+	BidUsd     sdkmath.LegacyDec // Total of the valued leases when their bids were placed
+	Unvalued   int               // Leases that could not be valued
+}
+
+// EstimateRevenue values the leases won in the bid history named by BID_HISTORY_DB, with AKT at usdPerAkt
+// and the other denoms of the PRICING_CONFIG registry at their current prices.
+func EstimateRevenue(usdPerAkt float64) (*RevenueEstimate, error) {
+	if err := checkDecFloat(usdPerAkt); err != nil || usdPerAkt <= 0 {
+		return nil, fmt.Errorf("invalid AKT price %g", usdPerAkt)
+	}
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading config: %w", err)
+	}
+
+	store, err := openBidHistoryFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	bids, err := store.WonBids()
+	if err != nil {
+		return nil, fmt.Errorf("error reading won bids: %w", err)
+	}
+	return EstimateLeaseRevenue(bids, config.Denoms, usdPerAkt, GetAverageBlockTime()), nil
+}
+
+// EstimateLeaseRevenue values the rates of won bids over a month of blocks of blockTimeSeconds.
+func EstimateLeaseRevenue(bids []BidRecord, denoms DenomRegistry, usdPerAkt float64, blockTimeSeconds float64) *RevenueEstimate {
+	blocksPerMonth := blocksPerMonthDec(blockTimeSeconds)
+	estimate := &RevenueEstimate{
+		Time:       time.Now(),
+		USDPerAKT:  usdPerAkt,
+		Leases:     make([]LeaseRevenue, 0, len(bids)),
+		MonthlyUsd: sdkmath.LegacyZeroDec(),
+		BidUsd:     sdkmath.LegacyZeroDec(),
+	}
+
+	for _, bid := range bids {
+		lease := LeaseRevenue{OrderID: bid.OrderID, Owner: bid.Owner, Profile: bid.Profile, Denom: bid.Denom, Price: bid.Price}
+		if bidUsd, err := sdkmath.LegacyNewDecFromStr(bid.TotalCostUsd); err == nil {
+			lease.BidUsd = bidUsd
+		}
+		rate, err := sdkmath.LegacyNewDecFromStr(bid.Price)
+		if err != nil {
+			lease.Err = fmt.Errorf("invalid bid price %q", bid.Price)
+		} else {
+			lease.MonthlyUsd, lease.Err = denoms.USDValue(bid.Denom, rate.Mul(blocksPerMonth), usdPerAkt)
+		}
+
+		if lease.Err != nil {
+			estimate.Unvalued++
+		} else {
+			estimate.MonthlyUsd = estimate.MonthlyUsd.Add(lease.MonthlyUsd)
+			if !lease.BidUsd.IsNil() {
+				estimate.BidUsd = estimate.BidUsd.Add(lease.BidUsd)
+			}
+		}
+		estimate.Leases = append(estimate.Leases, lease)
+	}
+	return estimate
+}
+
+// Groups totals the valued leases by profile and denom, sorted by profile then denom.
+func (e *RevenueEstimate) Groups() []RevenueGroup {
+	type key struct{ profile, denom string }
+	groups := make(map[key]*RevenueGroup)
+	for _, lease := range e.Leases {
+		if lease.Err != nil {
+			continue
+		}
+		profile := lease.Profile
+		if profile == "" {
+			profile = "none"
+		}
+		k := key{profile, lease.Denom}
+		group, ok := groups[k]
+		if !ok {
+			group = &RevenueGroup{Profile: profile, Denom: lease.Denom, MonthlyUsd: sdkmath.LegacyZeroDec(), BidUsd: sdkmath.LegacyZeroDec()}
+			groups[k] = group
+		}
+		group.Leases++
+		group.MonthlyUsd = group.MonthlyUsd.Add(lease.MonthlyUsd)
+		if !lease.BidUsd.IsNil() {
+			group.BidUsd = group.BidUsd.Add(lease.BidUsd)
+		}
+	}
+
+	result := make([]RevenueGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Profile != result[j].Profile {
+			return result[i].Profile < result[j].Profile
+		}
+		return result[i].Denom < result[j].Denom
+	})
+	return result
+}
+
+// MarshalJSON encodes the estimate with USD amounts rounded to cents.
+func (e *RevenueEstimate) MarshalJSON() ([]byte, error) {
+	type lease struct {
+		OrderID    string `json:"order_id,omitempty"`
+		Owner      string `json:"owner"`
+		Profile    string `json:"profile,omitempty"`
+		Denom      string `json:"denom"`
+		Price      string `json:"price"`
+		MonthlyUsd string `json:"monthly_usd,omitempty"`
+		BidUsd     string `json:"bid_usd,omitempty"`
+		Error      string `json:"error,omitempty"`
+	}
+	type group struct {
+		Profile    string `json:"profile"`
+		Denom      string `json:"denom"`
+		Leases     int    `json:"leases"`
+		MonthlyUsd string `json:"monthly_usd"`
+		BidUsd     string `json:"bid_usd"`
+	}
+	out := struct {
+		Time       time.Time `json:"time"`
+		USDPerAKT  float64   `json:"usd_per_akt"`
+		MonthlyUsd string    `json:"monthly_usd"`
+		BidUsd     string    `json:"bid_usd"`
+		Unvalued   int       `json:"unvalued_leases"`
+		Groups     []group   `json:"groups"`
+		Leases     []lease   `json:"leases"`
+	}{
+		Time:       e.Time,
+		USDPerAKT:  e.USDPerAKT,
+		MonthlyUsd: FormatDec(e.MonthlyUsd, 2),
+		BidUsd:     FormatDec(e.BidUsd, 2),
+		Unvalued:   e.Unvalued,
+		Groups:     []group{},
+		Leases:     make([]lease, 0, len(e.Leases)),
+	}
+	for _, g := range e.Groups() {
+		out.Groups = append(out.Groups, group{g.Profile, g.Denom, g.Leases, FormatDec(g.MonthlyUsd, 2), FormatDec(g.BidUsd, 2)})
+	}
+	for _, l := range e.Leases {
+		entry := lease{OrderID: l.OrderID, Owner: l.Owner, Profile: l.Profile, Denom: l.Denom, Price: l.Price}
+		if l.Err != nil {
+			entry.Error = l.Err.Error()
+		} else {
+			entry.MonthlyUsd = FormatDec(l.MonthlyUsd, 2)
+		}
+		if !l.BidUsd.IsNil() {
+			entry.BidUsd = FormatDec(l.BidUsd, 2)
+		}
+		out.Leases = append(out.Leases, entry)
+	}
+	return json.Marshal(out)
+}
+
+// WriteRevenueMetrics writes the estimate as gauges by profile and denom in the Prometheus text exposition
+// format. Leases are not labelled individually, so the series stay few however many leases are won.
+func WriteRevenueMetrics(w io.Writer, estimate *RevenueEstimate) error {
+	ew := &errWriter{w: w}
+	gauge := func(name, help string) {
+		ew.printf("# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	groups := estimate.Groups()
+
+	gauge("akash_pricing_revenue_monthly_usd", "Estimated monthly USD of the won leases at current prices, by profile and denom.")
+	for _, g := range groups {
+		ew.printf("akash_pricing_revenue_monthly_usd{profile=%q,denom=%q} %s\n", g.Profile, g.Denom, FormatDec(g.MonthlyUsd, 2))
+	}
+	gauge("akash_pricing_revenue_bid_monthly_usd", "Monthly USD of the won leases when their bids were placed, by profile and denom.")
+	for _, g := range groups {
+		ew.printf("akash_pricing_revenue_bid_monthly_usd{profile=%q,denom=%q} %s\n", g.Profile, g.Denom, FormatDec(g.BidUsd, 2))
+	}
+	gauge("akash_pricing_revenue_leases", "Won leases in the bid history, by profile and denom.")
+	for _, g := range groups {
+		ew.printf("akash_pricing_revenue_leases{profile=%q,denom=%q} %d\n", g.Profile, g.Denom, g.Leases)
+	}
+	gauge("akash_pricing_revenue_unvalued_leases", "Won leases that could not be valued, e.g. because of an unsupported denom.")
+	ew.printf("akash_pricing_revenue_unvalued_leases %d\n", estimate.Unvalued)
+	gauge("akash_pricing_revenue_timestamp_seconds", "Unix time the revenue was estimated.")
+	ew.printf("akash_pricing_revenue_timestamp_seconds %d\n", estimate.Time.Unix())
+	return ew.err
+}
@@ -0,0 +1,192 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret reference schemes.
+const (
+	SecretSchemeVault = "vault://"
+	SecretSchemeAWS   = "aws-sm://"
+)
+
+// DefaultSecretTTL is how long a resolved secret is reused before it is fetched again.
+const DefaultSecretTTL = 5 * time.Minute
+
+// secretCache holds resolved secrets by reference.
+var secretCache struct {
+	mu      sync.Mutex
+	secrets map[string]cachedSecret
+}
+
+// envSecretRefs holds the secret reference each resolved environment variable was set from, so exported
+// policies carry the reference instead of the secret.
+var envSecretRefs sync.Map
+
+// cachedSecret is a resolved secret and when it was fetched.
+type cachedSecret struct {
+	value     string
+	fetchedAt time.Time
+}
+
+// IsSecretRef reports whether value references a secret in HashiCorp Vault or AWS Secrets Manager.
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, SecretSchemeVault) || strings.HasPrefix(value, SecretSchemeAWS)
+}
+
+// ResolveSecret returns the secret a reference points to, or value itself if it is not a reference:
+//
+//	vault://<path>#<field>     field of the Vault secret at path (KV v1 or v2), read from VAULT_ADDR with VAULT_TOKEN
+//	aws-sm://<secret-id>#<key> key of an AWS Secrets Manager JSON secret (needs -tags awssm)
+//
+// The field may be omitted for a Vault secret with a single field and for a plain AWS secret string.
+// Secrets are cached for DefaultSecretTTL.
+func ResolveSecret(value string) (string, error) {
+	if !IsSecretRef(value) {
+		return value, nil
+	}
+
+	secretCache.mu.Lock()
+	defer secretCache.mu.Unlock()
+	if cached, ok := secretCache.secrets[value]; ok && time.Since(cached.fetchedAt) < DefaultSecretTTL {
+		return cached.value, nil
+	}
+
+	var secret string
+	var err error
+	if ref := strings.TrimPrefix(value, SecretSchemeVault); ref != value {
+		path, field := splitSecretRef(ref)
+		secret, err = fetchVaultSecret(path, field)
+	} else {
+		id, key := splitSecretRef(strings.TrimPrefix(value, SecretSchemeAWS))
+		secret, err = fetchAWSSecret(id)
+		if err == nil && key != "" {
+			secret, err = secretField(secret, key)
+		}
+	}
+	if err != nil {
+		// The reference names the secret but holds no secret itself, so it is safe to report
+		return "", fmt.Errorf("error resolving %s: %w", value, err)
+	}
+	if secretCache.secrets == nil {
+		secretCache.secrets = make(map[string]cachedSecret)
+	}
+	secretCache.secrets[value] = cachedSecret{value: secret, fetchedAt: time.Now()}
+	return secret, nil
+}
+
+// ResolveEnvSecrets replaces every environment variable holding a secret reference with the secret, so
+// API keys and webhook tokens can be kept out of the deployment's environment. Errors name the variable
+// but never a secret.
+func ResolveEnvSecrets() error {
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		if !IsSecretRef(value) {
+			continue
+		}
+		secret, err := ResolveSecret(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		os.Setenv(key, secret)
+		envSecretRefs.Store(key, value)
+	}
+	return nil
+}
+
+// envSecretRef returns the secret reference the variable key was resolved from, or "" if it holds no secret.
+func envSecretRef(key string) string {
+	if ref, ok := envSecretRefs.Load(key); ok {
+		return ref.(string)
+	}
+	return ""
+}
+
+// splitSecretRef splits a reference into the secret and the field after '#'.
+func splitSecretRef(ref string) (string, string) {
+	secret, field, _ := strings.Cut(ref, "#")
+	return secret, field
+}
+
+// fetchVaultSecret reads a field of the secret at path from the Vault server at VAULT_ADDR, authenticating
+// with VAULT_TOKEN and, on Vault Enterprise, scoped to VAULT_NAMESPACE.
+func fetchVaultSecret(path, field string) (string, error) {
+	addr := strings.TrimRight(os.Getenv("VAULT_ADDR"), "/")
+	if addr == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return "", fmt.Errorf("VAULT_TOKEN is not set")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, addr+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("error decoding vault response: %w", err)
+	}
+	fields := body.Data
+	// KV v2 nests the fields under data.data next to data.metadata
+	if nested, ok := fields["data"].(map[string]interface{}); ok {
+		if _, ok := fields["metadata"]; ok {
+			fields = nested
+		}
+	}
+	return pickSecretField(fields, field)
+}
+
+// secretField returns a key of a JSON object secret.
+func secretField(secret, key string) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, so key %s cannot be read", key)
+	}
+	return pickSecretField(fields, key)
+}
+
+// pickSecretField returns the string field of a secret, or its only field if field is empty.
+func pickSecretField(fields map[string]interface{}, field string) (string, error) {
+	if field == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("secret has %d fields, name one with #field", len(fields))
+		}
+		for name := range fields {
+			field = name
+		}
+	}
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %s", field)
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("field %s of the secret is not a string", field)
+	}
+	return str, nil
+}
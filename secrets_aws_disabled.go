@@ -0,0 +1,10 @@
+//go:build !awssm
+
+package pricing
+
+import "fmt"
+
+// fetchAWSSecret reports that AWS Secrets Manager references need a build with the awssm tag.
+func fetchAWSSecret(secretID string) (string, error) {
+	return "", fmt.Errorf("AWS Secrets Manager references require a build with -tags awssm")
+}
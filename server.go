@@ -0,0 +1,292 @@
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Readiness defaults of the pricing server.
+const (
+	DefaultReadyTimeout       = 5 * time.Second
+	DefaultWhitelistMaxAge    = 3 * DefaultWhitelistTTL
+	DefaultMaxOrderBodyBytes  = 1 << 20
+	readinessCheckOracle      = "AKT price oracle"
+	readinessCheckWhitelist   = "whitelist"
+	readinessCheckWarmUp      = "warm-up"
+	readinessCheckTimeoutText = "readiness checks timed out"
+)
+
+// PricingServer serves bid pricing over HTTP for providers running the pricing engine as a long-lived
+// service instead of a bid script:
+//
+//	POST /price?owner=<address>  prices a bid script payload and returns a JSON BidResponse; an optional
+//	                             order_id=<dseq/gseq/oseq> answers with a precomputed bid of the order and
+//	                             provider=<address> prices with that provider's configuration namespace
+//	GET  /healthz                reports the process is alive
+//	GET  /readyz                 reports whether the configuration, oracle and whitelist are usable
+//	GET  /debug/pricing          returns the engine's Stats and the worker pool's load as a JSON DebugReport
+//
+// Other handlers, such as a PriceExporter, can be added with Handle.
+type PricingServer struct {
+	ReadyTimeout    time.Duration // Deadline of the readiness checks
+	WhitelistMaxAge time.Duration // Oldest cached whitelist a ready server may bid with
+	RequestTimeout  time.Duration // Longest a /price request may wait for a worker and be priced, 0 for no limit
+	Pool            *WorkerPool   // Bounds concurrent /price requests, nil for no bound
+
+	engine *PricingEngine
+	mux    *http.ServeMux
+}
+
+// ReadinessReport is the JSON body of /readyz.
+type ReadinessReport struct {
+	Ready  bool             `json:"ready"`
+	Checks []ReadinessCheck `json:"checks"`
+}
+
+// ReadinessCheck is one readiness check. Error is empty if it passed.
+type ReadinessCheck struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// DebugReport is the JSON body of /debug/pricing.
+type DebugReport struct {
+	Engine EngineStats      `json:"engine"`
+	Pool   *WorkerPoolStats `json:"pool,omitempty"` // Absent when requests are not bounded
+}
+
+// NewPricingServer returns a server pricing requests through one shared PricingEngine.
+func NewPricingServer() *PricingServer {
+	s := &PricingServer{
+		ReadyTimeout:    DefaultReadyTimeout,
+		WhitelistMaxAge: DefaultWhitelistMaxAge,
+		RequestTimeout:  DefaultServerRequestTimeout,
+		Pool:            NewWorkerPool(DefaultServerWorkers, DefaultServerQueueLength),
+		engine:          NewPricingEngine(),
+		mux:             http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/price", s.handlePrice)
+	s.mux.HandleFunc("/healthz", s.handleHealthz)
+	s.mux.HandleFunc("/readyz", s.handleReadyz)
+	s.mux.HandleFunc("/debug/pricing", s.handleDebug)
+	return s
+}
+
+// Engine returns the engine the server prices with, e.g. for an OrderEventListener to precompute bids in.
+func (s *PricingServer) Engine() *PricingEngine {
+	return s.engine
+}
+
+// Handle registers an additional handler, e.g. a PriceExporter on /metrics.
+func (s *PricingServer) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// ServeHTTP dispatches to the server's endpoints.
+func (s *PricingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handlePrice prices the bid script payload in the request body for the owner query parameter. Bids and
+// rejections are answered with 200, invalid orders with 400, requests turned away by a full worker pool
+// with 429 and pricing failures with 503, all with a BidResponse body.
+func (s *PricingServer) handlePrice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	if s.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.RequestTimeout)
+		defer cancel()
+	}
+	// The worker is taken before the body is read, so a flood of orders waits or is turned away unread
+	if s.Pool != nil {
+		release, err := s.Pool.Acquire(ctx)
+		if errors.Is(err, ErrQueueFull) {
+			w.Header().Set("Retry-After", "1")
+			writeJSON(w, http.StatusTooManyRequests, NewBidResponse(Request{}, nil, err))
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, NewBidResponse(Request{}, nil, s.timeoutError(r, err)))
+			return
+		}
+		defer release()
+	}
+
+	var request Request
+	order, err := DecodeDeploymentOrder(http.MaxBytesReader(w, r.Body, DefaultMaxOrderBodyBytes))
+	if err == nil {
+		// Unlike manual bid script runs there is no placeholder owner, so the whitelist is never bypassed
+		owner := r.URL.Query().Get("owner")
+		if owner == "" {
+			err = fmt.Errorf("missing owner query parameter")
+		} else if request, err = order.Request(owner); err != nil {
+			request.PricePrecision = order.PricePrecision
+		}
+		if orderID := r.URL.Query().Get("order_id"); orderID != "" {
+			request.OrderID = orderID
+		}
+		if provider := r.URL.Query().Get("provider"); provider != "" {
+			request.Provider = provider
+		}
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, NewBidResponse(request, nil, withReason(ErrInvalidRequest, err)))
+		return
+	}
+
+	result, err := s.engine.CalculateBid(ctx, request)
+	if err != nil {
+		err = s.timeoutError(r, err)
+	}
+	response := NewBidResponse(request, result, err)
+	status := http.StatusOK
+	if response.Failed {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, response)
+}
+
+// timeoutError tags a context error of a request that ran out of RequestTimeout with ErrDeadlineExceeded.
+// Other errors, including the caller going away, are returned unchanged.
+func (s *PricingServer) timeoutError(r *http.Request, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) && r.Context().Err() == nil {
+		return withReason(ErrDeadlineExceeded, fmt.Errorf("request not priced within %s", s.RequestTimeout))
+	}
+	return err
+}
+
+// handleHealthz reports the process is alive. It checks nothing else, so a failing dependency never gets
+// the process restarted.
+func (s *PricingServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReadyz runs the readiness checks, answering 200 when all pass and 503 otherwise.
+func (s *PricingServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := s.Readiness()
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// handleDebug reports the engine's caches and the worker pool's load. It fetches nothing, so it can be
+// polled while the oracle or whitelist source is down.
+func (s *PricingServer) handleDebug(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	report := DebugReport{Engine: s.engine.Stats()}
+	if s.Pool != nil {
+		stats := s.Pool.Stats()
+		report.Pool = &stats
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// Readiness checks the engine is not warming up, the configuration is valid, an AKT price is available within the oracle cache lifetime
+// and the whitelist, if any, was reached within WhitelistMaxAge. Checks still running after ReadyTimeout
+// fail.
+func (s *PricingServer) Readiness() ReadinessReport {
+	done := make(chan []ValidationCheck, 1)
+	go func() {
+		checks := ValidateConfig()
+		checks = append(checks,
+			ValidationCheck{readinessCheckWarmUp, s.checkWarmUp()},
+			ValidationCheck{readinessCheckOracle, s.checkOracle()},
+			ValidationCheck{readinessCheckWhitelist, s.checkWhitelist()},
+		)
+		done <- checks
+	}()
+
+	var checks []ValidationCheck
+	select {
+	case checks = <-done:
+	case <-time.After(s.ReadyTimeout):
+		checks = []ValidationCheck{{"readiness", fmt.Errorf("%s after %s", readinessCheckTimeoutText, s.ReadyTimeout)}}
+	}
+
+	report := ReadinessReport{Ready: true, Checks: make([]ReadinessCheck, 0, len(checks))}
+	for _, check := range checks {
+		result := ReadinessCheck{Name: check.Name}
+		if check.Err != nil {
+			result.Error = check.Err.Error()
+			report.Ready = false
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	return report
+}
+
+// checkWarmUp fails while the engine's Start is still loading the caches. The outcome of a finished warm-up
+// is left to the other checks, which load the same sources again if they failed.
+func (s *PricingServer) checkWarmUp() error {
+	if report, started := s.engine.WarmUp(); started && report.Running {
+		return fmt.Errorf("warming up caches")
+	}
+	return nil
+}
+
+// checkOracle checks the engine has an AKT price, fetching one if its copy expired. GetAKTPrice only
+// returns prices cached within the last hour, or within AKT_PRICE_MAX_STALENESS while the oracles fail, so
+// a successful check means bids have a usable price.
+func (s *PricingServer) checkOracle() error {
+	_, err := s.engine.AKTPrice()
+	return err
+}
+
+// checkWhitelist refreshes the URL whitelist when due and fails if the cached copy is older than
+// WhitelistMaxAge, i.e. the source has been unreachable for that long. On-chain whitelists are queried per
+// owner and only have their configuration checked.
+func (s *PricingServer) checkWhitelist() error {
+	chainWhitelist, err := NewChainWhitelistFromEnv()
+	if err != nil || chainWhitelist != nil {
+		return err
+	}
+	whitelistURL := strings.Trim(os.Getenv("WHITELIST_URL"), "\"")
+	if whitelistURL == "" {
+		return nil
+	}
+	if localPath, ok := localWhitelistPath(whitelistURL); ok {
+		_, err := os.Stat(localPath)
+		return err
+	}
+
+	whitelistFile := whitelistCacheFile(whitelistURL)
+	if shouldFetchWhitelist(whitelistFile, DefaultWhitelistTTL) {
+		if err := refreshWhitelist(context.Background(), whitelistURL, whitelistFile, DefaultWhitelistTTL); err != nil {
+			return err
+		}
+	}
+	info, err := os.Stat(whitelistFile)
+	if err != nil {
+		return err
+	}
+	if age := time.Since(info.ModTime()); age > s.WhitelistMaxAge {
+		return fmt.Errorf("whitelist unreachable, cached copy is %s old", age.Round(time.Second))
+	}
+	return nil
+}
+
+// writeJSON writes v as the JSON response body with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,124 @@
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+	attrtypes "pkg.akt.dev/go/node/types/attributes/v1"
+)
+
+// DefaultServiceAttribute is the attribute naming the service of a resource unit when SERVICE_ATTRIBUTE is
+// unset.
+const DefaultServiceAttribute = "service"
+
+// serviceAttribute returns the name of the service attribute.
+func serviceAttribute() string {
+	if key := os.Getenv("SERVICE_ATTRIBUTE"); key != "" {
+		return key
+	}
+	return DefaultServiceAttribute
+}
+
+// UnitService returns the service of a resource unit: the value of the service attribute on its CPU, its
+// memory or one of its storage volumes, in that order, or "" if none has it.
+func UnitService(unit dtypes.ResourceUnit, key string) string {
+	if cpu := unit.Resources.CPU; cpu != nil {
+		if service, ok := attributeValue(cpu.Attributes, key); ok {
+			return service
+		}
+	}
+	if memory := unit.Resources.Memory; memory != nil {
+		if service, ok := attributeValue(memory.Attributes, key); ok {
+			return service
+		}
+	}
+	for _, storage := range unit.Resources.Storage {
+		if service, ok := attributeValue(storage.Attributes, key); ok {
+			return service
+		}
+	}
+	return ""
+}
+
+// attributeValue returns the trimmed value of the attribute named key.
+func attributeValue(attributes attrtypes.Attributes, key string) (string, bool) {
+	for _, attr := range attributes {
+		if attr.Key == key {
+			return strings.TrimSpace(attr.Value), true
+		}
+	}
+	return "", false
+}
+
+// ServiceCostDelta returns how much pricing the resource units of services with their own targets changes
+// the cost of a group priced at targets, with an adjustment per such service. Units are repriced with the
+// service's overrides applied to targets; leased IPs and egress belong to the whole group and keep the
+// group targets, and storage pool surcharges are not repriced.
+func ServiceCostDelta(gSpec *dtypes.GroupSpec, services map[string]PriceTargetsConfig, targets PriceTargets) (sdkmath.LegacyDec, []Adjustment) {
+	total := sdkmath.LegacyZeroDec()
+	if len(services) == 0 {
+		return total, nil
+	}
+
+	key := serviceAttribute()
+	units := make(map[string][]dtypes.ResourceUnit)
+	for _, unit := range gSpec.Resources {
+		if service := UnitService(unit, key); service != "" {
+			if _, ok := services[service]; ok {
+				units[service] = append(units[service], unit)
+			}
+		}
+	}
+	names := make([]string, 0, len(units))
+	for name := range units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	base := targets
+	base.StoragePools = nil
+	var adjustments []Adjustment
+	for _, name := range names {
+		serviceSpec := &dtypes.GroupSpec{Name: gSpec.Name, Requirements: gSpec.Requirements, Resources: units[name]}
+		requests := CalculateRequestedResources(serviceSpec)
+		requests.IPsRequested, requests.IPv6Requested = 0, 0
+		requests.EgressGBRequested = sdkmath.LegacyDec{}
+
+		serviceTargets := services[name].ApplyTo(base)
+		delta := CalculateTotalCostUsdTarget(requests, serviceTargets).Sub(CalculateTotalCostUsdTarget(requests, base))
+		if services[name].GPUMappings != "" && requests.GPUsRequested > 0 {
+			serviceGPUPrice, _ := CalculateGPUPrice(serviceSpec, serviceTargets)
+			baseGPUPrice, _ := CalculateGPUPrice(serviceSpec, base)
+			delta = delta.Add(serviceGPUPrice.Sub(baseGPUPrice))
+		}
+
+		total = total.Add(delta)
+		sign := "+"
+		if delta.IsNegative() {
+			sign = "-"
+		}
+		adjustments = append(adjustments, Adjustment{
+			Name:   "service",
+			Detail: fmt.Sprintf("%s service targets, %s%s/month", name, sign, FormatDec(delta.Abs(), 2)),
+		})
+	}
+	return total, adjustments
+}
+
+// validateServices checks the targets of every service. Services are priced in the group's currency, so
+// they cannot override it.
+func validateServices(services map[string]PriceTargetsConfig) error {
+	for name, targets := range services {
+		if err := targets.Validate(); err != nil {
+			return fmt.Errorf("service %s: %w", name, err)
+		}
+		if targets.Currency != "" {
+			return fmt.Errorf("service %s: currency cannot be overridden per service", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// ShadingStrategy bids a percentage below the order's max price instead of at the cost target, to improve
+// the chance of winning while capturing more of the tenant's budget. The bid never drops below the cost target.
+type ShadingStrategy struct {
+	PercentBelowMax float64 `json:"percent_below_max"` // 0 bids at the max price, 10 bids 10% below it
+}
+
+// NewShadingStrategyFromEnv reads BID_SHADING_PERCENT, returning nil if shading is not configured.
+func NewShadingStrategyFromEnv() (*ShadingStrategy, error) {
+	val := strings.TrimSpace(os.Getenv("BID_SHADING_PERCENT"))
+	if val == "" {
+		return nil, nil
+	}
+	percent, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BID_SHADING_PERCENT: %q", val)
+	}
+	strategy := &ShadingStrategy{PercentBelowMax: percent}
+	if err := strategy.Validate(); err != nil {
+		return nil, err
+	}
+	return strategy, nil
+}
+
+// Validate checks the shading percentage is within 0-100.
+func (s *ShadingStrategy) Validate() error {
+	if s.PercentBelowMax < 0 || s.PercentBelowMax >= 100 {
+		return fmt.Errorf("shading percent_below_max %g must be at least 0 and below 100", s.PercentBelowMax)
+	}
+	return nil
+}
+
+// Shade returns the shaded rate for an order's max price, or costRate when shading would bid below cost.
+// Both rates must be in the same denom.
+func (s *ShadingStrategy) Shade(costRate, maxPrice sdkmath.LegacyDec) sdkmath.LegacyDec {
+	if s == nil || maxPrice.IsNil() {
+		return costRate
+	}
+	shaded := maxPrice.Sub(maxPrice.Mul(decFromFloat(s.PercentBelowMax)).QuoInt64(100))
+	if shaded.LT(costRate) {
+		return costRate
+	}
+	return shaded
+}
@@ -0,0 +1,63 @@
+package pricing
+
+import (
+	"testing"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+func TestShadingStrategyShade(t *testing.T) {
+	tests := []struct {
+		name          string
+		percent       float64
+		cost, maxRate string
+		want          string
+	}{
+		{"10% below max", 10, "4.5", "100", "90"},
+		{"at max", 0, "4.5", "100", "100"},
+		{"clamped at cost", 99, "4.5", "100", "4.5"},
+		{"exactly at cost", 50, "50", "100", "50"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shading := &ShadingStrategy{PercentBelowMax: tt.percent}
+			got := shading.Shade(sdkmath.LegacyMustNewDecFromStr(tt.cost), sdkmath.LegacyMustNewDecFromStr(tt.maxRate))
+			if !got.Equal(sdkmath.LegacyMustNewDecFromStr(tt.want)) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	var none *ShadingStrategy
+	if got := none.Shade(sdkmath.LegacyNewDec(4), sdkmath.LegacyNewDec(100)); !got.Equal(sdkmath.LegacyNewDec(4)) {
+		t.Errorf("got %s without shading, want the cost rate", got)
+	}
+}
+
+// TestShadingBid prices the cpu-only fixture, a cost rate of 4.552452 uakt under a max price of 100 uakt, with
+// shading.
+func TestShadingBid(t *testing.T) {
+	request := fixtureRequest(t, "cpu-only")
+	tests := []struct {
+		percent string
+		price   string
+		shaded  bool
+	}{
+		{"10", "90.000000", true},
+		{"0", "100.000000", true},
+		{"95", "5.000000", true},
+		{"96", "4.552452", false}, // 4 uakt is below cost, so the bid stays at cost
+	}
+	for _, tt := range tests {
+		result, err := priceWithEnv(request, map[string]string{"BID_SHADING_PERCENT": tt.percent})
+		if err != nil {
+			t.Fatalf("%s%%: %v", tt.percent, err)
+		}
+		if result.Price != tt.price || result.CostPrice != "4.552452" {
+			t.Errorf("%s%%: got price %s at cost %s, want %s at 4.552452", tt.percent, result.Price, result.CostPrice, tt.price)
+		}
+		if got := findAdjustment(result, "shading") != nil; got != tt.shaded {
+			t.Errorf("%s%%: got shading adjustment %v, want %v", tt.percent, got, tt.shaded)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// shadowTargetsCache memoizes the parse of SHADOW_PRICE_TARGETS, keyed on the JSON document.
+var shadowTargetsCache = newParseCache(ParseShadowTargets)
+
+// ParseShadowTargets parses shadow price target overrides, a JSON object in the format of a profile's
+// targets, e.g. {"cpu": 1.4, "gpu_mappings": "a100=900"}.
+func ParseShadowTargets(data string) (*PriceTargetsConfig, error) {
+	var targets PriceTargetsConfig
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&targets); err != nil {
+		return nil, fmt.Errorf("invalid shadow price targets: %v", err)
+	}
+	if err := targets.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid shadow price targets: %w", err)
+	}
+	return &targets, nil
+}
+
+// ShadowTargetsFromEnv returns the shadow price target overrides of SHADOW_PRICE_TARGETS, either inline JSON
+// or the path of a JSON file. It returns nil when shadow pricing is disabled.
+func ShadowTargetsFromEnv() (*PriceTargetsConfig, error) {
+	raw := strings.TrimSpace(os.Getenv("SHADOW_PRICE_TARGETS"))
+	if raw == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(raw, "{") {
+		data, err := ioutil.ReadFile(raw)
+		if err != nil {
+			return nil, fmt.Errorf("error reading shadow price targets: %w", err)
+		}
+		raw = string(data)
+	}
+	return shadowTargetsCache.get(raw)
+}
+
+// ShadowStats counts how shadow bids compared with the live bids of this process.
+type ShadowStats struct {
+	Compared      int64             // Requests both configurations bid on
+	LiveOnly      int64             // Requests only the live configuration bid on
+	ShadowOnly    int64             // Requests only the shadow configuration bid on
+	BothRejected  int64             // Requests neither configuration bid on
+	Failures      int64             // Shadow bids that failed to price
+	LiveCostUsd   sdkmath.LegacyDec // Monthly USD of the compared live bids
+	ShadowCostUsd sdkmath.LegacyDec // Monthly USD of the compared shadow bids
+}
+
+var (
+	shadowMu    sync.Mutex
+	shadowStats = ShadowStats{LiveCostUsd: sdkmath.LegacyZeroDec(), ShadowCostUsd: sdkmath.LegacyZeroDec()}
+)
+
+// CurrentShadowStats returns a copy of the shadow pricing counters.
+func CurrentShadowStats() ShadowStats {
+	shadowMu.Lock()
+	defer shadowMu.Unlock()
+	return shadowStats
+}
+
+// shadowBid prices the request again with the SHADOW_PRICE_TARGETS overrides and the live bid's AKT price,
+// then logs and counts how the shadow bid compares with the live one. It never affects the live bid.
+func shadowBid(ctx context.Context, request Request, live *BidResult, liveErr error) {
+	targets, err := ShadowTargetsFromEnv()
+	if err != nil {
+		log.Printf("Shadow pricing disabled: %v", err)
+		return
+	}
+	if targets == nil {
+		return
+	}
+
+	// The shadow run logs its own pipeline output, delimit it from the live bid's
+	log.Println("Shadow pricing with SHADOW_PRICE_TARGETS")
+	ctx, span := startSpan(ctx, spanShadow)
+	request.shadowTargets = targets
+	if live != nil && live.USDPerAKT > 0 {
+		request.USDPerAKT, request.aktPriceSource = live.USDPerAKT, live.AKTPriceSource
+	}
+	shadow, shadowErr := calculateBid(ctx, request)
+	span.End(shadowErr)
+
+	_, liveRejected := ErrorReason(liveErr)
+	_, shadowRejected := ErrorReason(shadowErr)
+	shadowMu.Lock()
+	defer shadowMu.Unlock()
+	switch {
+	case shadowErr != nil && !shadowRejected:
+		shadowStats.Failures++
+		log.Printf("Shadow pricing failed: %v", shadowErr)
+		return
+	case liveErr != nil && !liveRejected:
+		// Nothing to compare with when the live bid failed rather than rejected the request
+		return
+	case live != nil && shadow != nil:
+		shadowStats.Compared++
+		if !live.TotalCostUsd.IsNil() && !shadow.TotalCostUsd.IsNil() {
+			shadowStats.LiveCostUsd = shadowStats.LiveCostUsd.Add(live.TotalCostUsd)
+			shadowStats.ShadowCostUsd = shadowStats.ShadowCostUsd.Add(shadow.TotalCostUsd)
+		}
+	case live != nil:
+		shadowStats.LiveOnly++
+	case shadow != nil:
+		shadowStats.ShadowOnly++
+	default:
+		shadowStats.BothRejected++
+	}
+	log.Printf("Shadow pricing: live %s, shadow %s%s", describeShadowBid(live, liveErr), describeShadowBid(shadow, shadowErr), shadowDelta(live, shadow))
+}
+
+// describeShadowBid summarizes one side of a shadow comparison.
+func describeShadowBid(result *BidResult, err error) string {
+	if result == nil {
+		code, _ := ErrorReason(err)
+		return "rejected (" + code + ")"
+	}
+	if result.TotalCostUsd.IsNil() {
+		return result.Price + result.Denom
+	}
+	return fmt.Sprintf("%s%s ($%s/month)", result.Price, result.Denom, FormatDec(result.TotalCostUsd, 2))
+}
+
+// shadowDelta returns the relative change of the shadow bid's monthly cost, or "" if either side has none.
+func shadowDelta(live, shadow *BidResult) string {
+	if live == nil || shadow == nil || live.TotalCostUsd.IsNil() || shadow.TotalCostUsd.IsNil() || !live.TotalCostUsd.IsPositive() {
+		return ""
+	}
+	change := shadow.TotalCostUsd.Sub(live.TotalCostUsd).Quo(live.TotalCostUsd).MulInt64(100)
+	return fmt.Sprintf(", %s%s%%", signPrefix(change), FormatDec(change, 2))
+}
+
+// signPrefix returns "+" for positive values, FormatDec already printing the sign of negative ones.
+func signPrefix(value sdkmath.LegacyDec) string {
+	if value.IsPositive() {
+		return "+"
+	}
+	return ""
+}
+
+// WriteShadowMetrics writes the shadow pricing counters in the Prometheus text exposition format.
+func WriteShadowMetrics(w io.Writer, stats ShadowStats) error {
+	ew := &errWriter{w: w}
+	ew.printf("# HELP akash_pricing_shadow_bids_total Live requests priced with the shadow targets, by outcome.\n")
+	ew.printf("# TYPE akash_pricing_shadow_bids_total counter\n")
+	for _, outcome := range []struct {
+		name  string
+		count int64
+	}{
+		{"compared", stats.Compared},
+		{"live_only", stats.LiveOnly},
+		{"shadow_only", stats.ShadowOnly},
+		{"both_rejected", stats.BothRejected},
+		{"failed", stats.Failures},
+	} {
+		ew.printf("akash_pricing_shadow_bids_total{outcome=%q} %d\n", outcome.name, outcome.count)
+	}
+	ew.printf("# HELP akash_pricing_shadow_cost_usd_total Monthly USD of the bids both configurations priced.\n")
+	ew.printf("# TYPE akash_pricing_shadow_cost_usd_total counter\n")
+	ew.printf("akash_pricing_shadow_cost_usd_total{config=\"live\"} %s\n", FormatDec(stats.LiveCostUsd, 2))
+	ew.printf("akash_pricing_shadow_cost_usd_total{config=\"shadow\"} %s\n", FormatDec(stats.ShadowCostUsd, 2))
+	return ew.err
+}
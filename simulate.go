@@ -0,0 +1,265 @@
+package pricing
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// SimulatedOrder is one historical order replayed by Simulate, a line of the orders JSONL file.
+type SimulatedOrder struct {
+	Time         time.Time       `json:"time"`
+	Owner        string          `json:"owner"`
+	OrderID      string          `json:"order_id,omitempty"`
+	Order        json.RawMessage `json:"order"`                   // The bid script payload the provider passed
+	WinningPrice string          `json:"winning_price,omitempty"` // Rate per block of the winning bid, in the order's denom
+	BidPrice     string          `json:"bid_price,omitempty"`     // Rate per block the provider bid at the time
+	Won          *bool           `json:"won,omitempty"`           // Whether the provider's bid won the lease
+}
+
+// AKTPricePoint is the AKT price from Time on.
+type AKTPricePoint struct {
+	Time      time.Time
+	USDPerAKT float64
+}
+
+// AKTPriceHistory is a series of AKT prices sorted by time.
+type AKTPriceHistory []AKTPricePoint
+
+// SimulatedBid is the outcome of replaying one order.
+type SimulatedBid struct {
+	Order      SimulatedOrder
+	USDPerAKT  float64    // AKT price the order was priced at
+	Result     *BidResult // nil when Err is set
+	Err        error
+	WouldWin   *bool             // Whether the bid is at or below WinningPrice, nil when it is unknown
+	RevenueUsd sdkmath.LegacyDec // Monthly USD of the bid, nil when rejected
+}
+
+// SimulationReport summarizes replaying orders through the current configuration.
+type SimulationReport struct {
+	Bids     []SimulatedBid
+	Priced   int
+	Rejected map[string]int // Reason code -> orders
+
+	Compared          int               // Priced orders with a winning price
+	Wins              int               // Compared orders the bid would win
+	HistoricalWins    int               // Orders the provider won at the time
+	BidRevenueUsd     sdkmath.LegacyDec // Monthly USD of every bid, as if all won
+	WonRevenueUsd     sdkmath.LegacyDec // Monthly USD of the bids that would win
+	HistoricalRevenue sdkmath.LegacyDec // Monthly USD of the historically won bids at their bid_price, for priced orders
+}
+
+// ReadSimulatedOrders reads a JSONL file of historical orders. Blank lines are skipped.
+func ReadSimulatedOrders(path string) ([]SimulatedOrder, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var orders []SimulatedOrder
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var order SimulatedOrder
+		if err := json.Unmarshal(data, &order); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		if len(order.Order) == 0 {
+			return nil, fmt.Errorf("%s:%d: order is missing", path, line)
+		}
+		orders = append(orders, order)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ReadAKTPriceHistory reads a CSV file of time,usd rows, with an optional header. Times are RFC 3339
+// timestamps, YYYY-MM-DD dates or Unix seconds.
+func ReadAKTPriceHistory(path string) (AKTPriceHistory, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseAKTPriceHistory(file, path)
+}
+
+// parseAKTPriceHistory parses the CSV of ReadAKTPriceHistory and sorts it by time.
+func parseAKTPriceHistory(r io.Reader, name string) (AKTPriceHistory, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	var history AKTPriceHistory
+	for i, record := range records {
+		t, timeErr := parseHistoryTime(record[0])
+		price, priceErr := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if i == 0 && (timeErr != nil || priceErr != nil) {
+			continue // Header
+		}
+		if timeErr != nil {
+			return nil, fmt.Errorf("%s:%d: invalid time %q", name, i+1, record[0])
+		}
+		if priceErr != nil || price <= 0 || checkDecFloat(price) != nil {
+			return nil, fmt.Errorf("%s:%d: invalid AKT price %q", name, i+1, record[1])
+		}
+		history = append(history, AKTPricePoint{Time: t, USDPerAKT: price})
+	}
+	if len(history) == 0 {
+		return nil, fmt.Errorf("%s: no AKT prices", name)
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].Time.Before(history[j].Time) })
+	return history, nil
+}
+
+// parseHistoryTime parses an RFC 3339 timestamp, a YYYY-MM-DD date or Unix seconds.
+func parseHistoryTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", value)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// At returns the AKT price in effect at t: the latest price at or before t, or the earliest price for times
+// before the history starts.
+func (h AKTPriceHistory) At(t time.Time) float64 {
+	i := sort.Search(len(h), func(i int) bool { return h[i].Time.After(t) })
+	if i == 0 {
+		return h[0].USDPerAKT
+	}
+	return h[i-1].USDPerAKT
+}
+
+// Simulate prices historical orders with the current configuration, each at the AKT price of history at
+// the order's time, or usdPerAkt when history is empty, or the oracle's current price when both are unset.
+// Bids are compared with the winning price of the order, a bid at or below it winning. Bids are not
+// recorded in the bid history and raise no webhooks.
+func Simulate(orders []SimulatedOrder, history AKTPriceHistory, usdPerAkt float64) (*SimulationReport, error) {
+	report := &SimulationReport{
+		Rejected:          make(map[string]int),
+		BidRevenueUsd:     sdkmath.LegacyZeroDec(),
+		WonRevenueUsd:     sdkmath.LegacyZeroDec(),
+		HistoricalRevenue: sdkmath.LegacyZeroDec(),
+	}
+
+	for i, order := range orders {
+		bid := SimulatedBid{Order: order, USDPerAKT: usdPerAkt}
+		if len(history) > 0 {
+			bid.USDPerAKT = history.At(order.Time)
+		}
+		if order.Won != nil && *order.Won {
+			report.HistoricalWins++
+		}
+
+		request, err := order.request()
+		if err == nil {
+			request.USDPerAKT = bid.USDPerAKT
+			bid.Result, err = calculateBid(context.Background(), request)
+		}
+		if err != nil {
+			bid.Err = err
+			code, _ := ErrorReason(err)
+			report.Rejected[code]++
+			report.Bids = append(report.Bids, bid)
+			continue
+		}
+		report.Priced++
+
+		price, err := sdkmath.LegacyNewDecFromStr(bid.Result.Price)
+		if err != nil {
+			return nil, fmt.Errorf("order %d: invalid bid price %q: %v", i+1, bid.Result.Price, err)
+		}
+		bid.RevenueUsd = bid.Result.monthlyUsd(price)
+		report.BidRevenueUsd = report.BidRevenueUsd.Add(bid.RevenueUsd)
+
+		if order.WinningPrice != "" {
+			winningPrice, err := sdkmath.LegacyNewDecFromStr(order.WinningPrice)
+			if err != nil {
+				return nil, fmt.Errorf("order %d: invalid winning_price %q: %v", i+1, order.WinningPrice, err)
+			}
+			wouldWin := price.LTE(winningPrice)
+			bid.WouldWin = &wouldWin
+			report.Compared++
+			if wouldWin {
+				report.Wins++
+				report.WonRevenueUsd = report.WonRevenueUsd.Add(bid.RevenueUsd)
+			}
+		}
+
+		if order.Won != nil && *order.Won && order.BidPrice != "" {
+			bidPrice, err := sdkmath.LegacyNewDecFromStr(order.BidPrice)
+			if err != nil {
+				return nil, fmt.Errorf("order %d: invalid bid_price %q: %v", i+1, order.BidPrice, err)
+			}
+			report.HistoricalRevenue = report.HistoricalRevenue.Add(bid.Result.monthlyUsd(bidPrice))
+		}
+		report.Bids = append(report.Bids, bid)
+	}
+	return report, nil
+}
+
+// request decodes the order into a pricing request.
+func (o SimulatedOrder) request() (Request, error) {
+	order, err := DecodeDeploymentOrder(bytes.NewReader(o.Order))
+	if err != nil {
+		return Request{}, withReason(ErrInvalidRequest, err)
+	}
+	request, err := order.Request(o.Owner)
+	if err != nil {
+		return Request{}, withReason(ErrInvalidRequest, err)
+	}
+	request.OrderID = o.OrderID
+	return request, nil
+}
+
+// monthlyUsd converts a rate per block in the bid's denom to monthly USD, at the same USD value per denom
+// unit as the bid's cost price.
+func (r *BidResult) monthlyUsd(price sdkmath.LegacyDec) sdkmath.LegacyDec {
+	if r.TotalCostUsd.IsNil() || r.CostPrice == "" {
+		return sdkmath.LegacyZeroDec()
+	}
+	costPrice, err := sdkmath.LegacyNewDecFromStr(r.CostPrice)
+	if err != nil || !costPrice.IsPositive() {
+		return sdkmath.LegacyZeroDec()
+	}
+	return price.Mul(r.TotalCostUsd).Quo(costPrice)
+}
+
+// WinRate returns the fraction of compared orders the bids would win.
+func (r *SimulationReport) WinRate() float64 {
+	if r.Compared == 0 {
+		return 0
+	}
+	return float64(r.Wins) / float64(r.Compared)
+}
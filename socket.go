@@ -0,0 +1,115 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+)
+
+// Defaults of the Unix socket server.
+const (
+	DefaultSocketMode    os.FileMode = 0660
+	DefaultSocketTimeout             = 5 * time.Second
+)
+
+// PricingRPC is the JSON-RPC service of the socket server, registered as "Pricing".
+type PricingRPC struct {
+	engine  *PricingEngine
+	timeout time.Duration
+}
+
+// Price prices the order of a message, like a queued order. Invalid orders are answered with an
+// invalid_request result; the RPC itself only fails on transport errors.
+func (p *PricingRPC) Price(message OrderMessage, result *OrderResult) error {
+	*result = priceOrderMessage(context.Background(), p.engine, message, p.timeout)
+	return nil
+}
+
+// SocketServer serves bid pricing as JSON-RPC 1.0 (the net/rpc/jsonrpc codec) on a Unix domain socket, so a
+// provider on the same host can price bids over a persistent connection without a TCP port. Requests are
+// {"method": "Pricing.Price", "params": [OrderMessage], "id": N} and results are OrderResults.
+type SocketServer struct {
+	Path    string
+	Mode    os.FileMode // Permissions of the socket file
+	Group   string      // Group owning the socket file, empty keeps the process group
+	Timeout time.Duration
+
+	engine *PricingEngine
+}
+
+// NewSocketServer returns a socket server on path pricing requests through one shared PricingEngine.
+func NewSocketServer(path string) *SocketServer {
+	return &SocketServer{
+		Path:    path,
+		Mode:    DefaultSocketMode,
+		Timeout: DefaultSocketTimeout,
+		engine:  NewPricingEngine(),
+	}
+}
+
+// Serve listens on the socket until ctx is done. A socket left behind by an earlier run is replaced, but
+// any other file at Path is an error. The socket file is removed on return.
+func (s *SocketServer) Serve(ctx context.Context) error {
+	if info, err := os.Lstat(s.Path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("%s exists and is not a socket", s.Path)
+		}
+		if err := os.Remove(s.Path); err != nil {
+			return fmt.Errorf("error removing stale socket %s: %w", s.Path, err)
+		}
+	}
+	listener, err := net.Listen("unix", s.Path)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %w", s.Path, err)
+	}
+	defer listener.Close()
+	if err := s.setPermissions(); err != nil {
+		return err
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("Pricing", &PricingRPC{engine: s.engine, timeout: s.Timeout}); err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("error accepting socket connection: %w", err)
+		}
+		go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// setPermissions applies Mode and Group to the socket file.
+func (s *SocketServer) setPermissions() error {
+	if s.Group != "" {
+		group, err := user.LookupGroup(s.Group)
+		if err != nil {
+			return fmt.Errorf("error looking up socket group: %w", err)
+		}
+		gid, err := strconv.Atoi(group.Gid)
+		if err != nil {
+			return fmt.Errorf("invalid gid %q of group %s", group.Gid, s.Group)
+		}
+		if err := os.Chown(s.Path, -1, gid); err != nil {
+			return fmt.Errorf("error setting socket group: %w", err)
+		}
+	}
+	if err := os.Chmod(s.Path, s.Mode); err != nil {
+		return fmt.Errorf("error setting socket permissions: %w", err)
+	}
+	return nil
+}
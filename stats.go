@@ -0,0 +1,185 @@
+package pricing
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheCounts counts the lookups of one in-process cache.
+type CacheCounts struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// cacheCounter counts cache lookups from many goroutines.
+type cacheCounter struct {
+	hits   int64
+	misses int64
+}
+
+// count records a lookup.
+func (c *cacheCounter) count(hit bool) {
+	if hit {
+		atomic.AddInt64(&c.hits, 1)
+	} else {
+		atomic.AddInt64(&c.misses, 1)
+	}
+}
+
+// counts returns the lookups recorded so far.
+func (c *cacheCounter) counts() CacheCounts {
+	return CacheCounts{Hits: atomic.LoadInt64(&c.hits), Misses: atomic.LoadInt64(&c.misses)}
+}
+
+// Counters of the caches shared by every engine of the process.
+var (
+	parseCacheCounts  cacheCounter // Every parse cache of parsecache.go
+	configCacheCounts cacheCounter // The PRICING_CONFIG file
+)
+
+// configDirReload is when a ConfigDir last changed the environment.
+var configDirReload struct {
+	mu   sync.Mutex
+	at   time.Time
+	keys int
+}
+
+// recordConfigDirReload records a ConfigDir load that changed keys.
+func recordConfigDirReload(keys int) {
+	configDirReload.mu.Lock()
+	defer configDirReload.mu.Unlock()
+	configDirReload.at, configDirReload.keys = time.Now(), keys
+}
+
+// EngineStats is a snapshot of a PricingEngine and the caches it prices from, for operational inspection.
+// Times are zero when the event has not happened in this process.
+type EngineStats struct {
+	Caches    map[string]CacheCounts `json:"caches"` // By cache: akt_price, precomputed_bids, config_file and parsed_settings
+	AKTPrice  AKTPriceStats          `json:"akt_price"`
+	Whitelist WhitelistStats         `json:"whitelist"`
+	Config    ConfigStats            `json:"config"`
+
+	PrecomputedBids int `json:"precomputed_bids"` // Precomputed bids held, expired ones included until the next Precompute
+}
+
+// AKTPriceStats describes the AKT price the engine's bids share.
+type AKTPriceStats struct {
+	USDPerAKT   float64   `json:"usd_per_akt,omitempty"`
+	Source      string    `json:"source,omitempty"`       // Source of the last refresh: oracle, cache, pin or stale
+	RefreshedAt time.Time `json:"refreshed_at"`           // When the engine last refreshed its shared copy
+	OracleAt    time.Time `json:"oracle_fetched_at"`      // When the price cache was last written from the oracle, by any process
+	AgeSeconds  int64     `json:"cache_age_seconds"`      // Age of the price cache, -1 when there is none
+	StaleTotal  int64     `json:"stale_lookups_total"`    // Lookups answered with a stale price while the oracles failed
+	OracleError string    `json:"oracle_error,omitempty"` // Why the last refresh failed, if it did
+}
+
+// WhitelistStats describes the whitelist bids are checked against.
+type WhitelistStats struct {
+	Source     string `json:"source"`                // url, file, chain, or none
+	Entries    int    `json:"entries"`               // Entries of a URL or file whitelist, 0 for on-chain ones
+	AgeSeconds int64  `json:"age_seconds"`           // Age of the cached or local copy, -1 when there is none
+	Error      string `json:"error,omitempty"`       // Why the copy cannot be read or parsed
+	Expired    bool   `json:"expired,omitempty"`     // The cached copy is older than its TTL, the next bid refreshes it
+	ChainQuery bool   `json:"chain_query,omitempty"` // Owners are looked up on chain per bid
+}
+
+// ConfigStats describes the configuration bids are priced with.
+type ConfigStats struct {
+	Hash            string    `json:"hash"`              // ConfigHash of the current configuration
+	File            string    `json:"file,omitempty"`    // PRICING_CONFIG
+	FileLoadedAt    time.Time `json:"file_loaded_at"`    // When the file was last parsed, after a change on disk
+	DirReloadedAt   time.Time `json:"dir_reloaded_at"`   // When the configuration directory last changed the environment
+	DirReloadedKeys int       `json:"dir_reloaded_keys"` // Keys changed by that reload
+}
+
+// Stats returns a snapshot of the engine's caches: hit and miss counts, the AKT price and when it was
+// fetched, the whitelist's size and age and when the configuration was last reloaded. It reads the cache
+// files but fetches nothing.
+func (e *PricingEngine) Stats() EngineStats {
+	stats := EngineStats{
+		Caches: map[string]CacheCounts{
+			"akt_price":        e.priceCounts.counts(),
+			"precomputed_bids": e.precomputedCounts.counts(),
+			"config_file":      configCacheCounts.counts(),
+			"parsed_settings":  parseCacheCounts.counts(),
+		},
+		Whitelist: whitelistStats(),
+		Config:    configStats(),
+	}
+
+	e.mu.Lock()
+	stats.AKTPrice = AKTPriceStats{USDPerAKT: e.usdPerAkt, Source: e.source, RefreshedAt: e.fetchedAt, OracleError: e.lastErr}
+	e.mu.Unlock()
+	stats.AKTPrice.AgeSeconds = -1
+	if info, err := os.Stat(AKTPriceCacheFile); err == nil {
+		stats.AKTPrice.OracleAt = info.ModTime()
+		stats.AKTPrice.AgeSeconds = int64(time.Since(info.ModTime()).Seconds())
+	}
+	stats.AKTPrice.StaleTotal = atomic.LoadInt64(&staleAKTPrices)
+
+	e.bidsMu.Lock()
+	stats.PrecomputedBids = len(e.precomputed)
+	e.bidsMu.Unlock()
+	return stats
+}
+
+// whitelistStats describes the configured whitelist without fetching it.
+func whitelistStats() WhitelistStats {
+	stats := WhitelistStats{Source: "none", AgeSeconds: -1}
+	if chainWhitelist, err := NewChainWhitelistFromEnv(); err != nil || chainWhitelist != nil {
+		stats.Source, stats.ChainQuery = "chain", true
+		if err != nil {
+			stats.Error = err.Error()
+		}
+		return stats
+	}
+	whitelistURL := strings.Trim(os.Getenv("WHITELIST_URL"), "\"")
+	if whitelistURL == "" {
+		return stats
+	}
+
+	path := whitelistCacheFile(whitelistURL)
+	format := readWhitelistMeta(path).Format
+	stats.Source = "url"
+	if localPath, ok := localWhitelistPath(whitelistURL); ok {
+		path, format = localPath, detectWhitelistFormat("", whitelistURL)
+		stats.Source = "file"
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		stats.Error = err.Error()
+		return stats
+	}
+	stats.AgeSeconds = int64(time.Since(info.ModTime()).Seconds())
+	stats.Expired = stats.Source == "url" && time.Since(info.ModTime()) > DefaultWhitelistTTL
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		var entries []WhitelistEntry
+		if entries, err = parseWhitelist(data, format); err == nil {
+			stats.Entries = len(entries)
+		}
+	}
+	if err != nil {
+		stats.Error = err.Error()
+	}
+	return stats
+}
+
+// configStats describes the configuration file and directory, without loading the file.
+func configStats() ConfigStats {
+	stats := ConfigStats{Hash: ConfigHash(), File: os.Getenv("PRICING_CONFIG")}
+	configCache.mu.Lock()
+	if configCache.path == stats.File {
+		stats.FileLoadedAt = configCache.loadedAt
+	}
+	configCache.mu.Unlock()
+
+	configDirReload.mu.Lock()
+	stats.DirReloadedAt, stats.DirReloadedKeys = configDirReload.at, configDirReload.keys
+	configDirReload.mu.Unlock()
+	return stats
+}
@@ -0,0 +1,312 @@
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+	attrtypes "pkg.akt.dev/go/node/types/attributes/v1"
+)
+
+// Behaviors for storage classes without a price target, selected by STORAGE_CLASS_UNKNOWN.
+const (
+	UnknownStorageIgnore  = "ignore"  // The volume is not priced
+	UnknownStorageReject  = "reject"  // The request is rejected
+	UnknownStorageDefault = "default" // The volume is priced at PRICE_TARGET_STORAGE_DEFAULT
+)
+
+// Storage volume attributes declaring a performance tier, priced per GB on top of the volume's class target.
+const (
+	StorageAttributeIOPS       = "iops"
+	StorageAttributeThroughput = "throughput"
+)
+
+// Storage volume attributes requesting backups or snapshots, e.g. backup=true, priced per GB at
+// PRICE_TARGET_STORAGE_BACKUP.
+const (
+	StorageAttributeBackup   = "backup"
+	StorageAttributeSnapshot = "snapshot"
+)
+
+// ParseStorageClassTargets parses storage class to price mappings of the form "ram=0.10,beta3-large=0.06".
+// Mapped classes take precedence over the built-in ephemeral/beta1/beta2/beta3/ram targets.
+func ParseStorageClassTargets(mappingStr string) (map[string]float64, error) {
+	return parseClassTargets(mappingStr, "storage")
+}
+
+// parseClassTargets parses class=price mappings of a kind of resource, e.g. storage or CPU.
+func parseClassTargets(mappingStr, kind string) (map[string]float64, error) {
+	targets := make(map[string]float64)
+	for _, pair := range strings.Split(mappingStr, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.Split(pair, "=")
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid %s class mapping: %s", kind, pair)
+		}
+
+		value, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil || checkDecFloat(value) != nil || value < 0 {
+			return nil, fmt.Errorf("invalid %s price for %s: %s", kind, kv[0], kv[1])
+		}
+		targets[kv[0]] = value
+	}
+	return targets, nil
+}
+
+// ParseStorageTierTargets parses performance tier to per-GB price mappings of the form "high=0.02,max=0.05".
+func ParseStorageTierTargets(mappingStr string) (map[string]float64, error) {
+	return parseClassTargets(mappingStr, "storage tier")
+}
+
+// ParseUnknownStorageClass validates an unknown storage class behavior, defaulting to ignore.
+func ParseUnknownStorageClass(behavior string) (string, error) {
+	switch behavior {
+	case "":
+		return UnknownStorageIgnore, nil
+	case UnknownStorageIgnore, UnknownStorageReject, UnknownStorageDefault:
+		return behavior, nil
+	}
+	return "", fmt.Errorf("invalid STORAGE_CLASS_UNKNOWN %q: must be ignore, reject or default", behavior)
+}
+
+// loadStorageTargets reads the storage class settings of the price targets from the environment.
+func loadStorageTargets(targets *PriceTargets) error {
+	classTargets, err := storageTargetsCache.get(os.Getenv("PRICE_TARGET_STORAGE_CLASSES"))
+	if err != nil {
+		return err
+	}
+	unknown, err := ParseUnknownStorageClass(os.Getenv("STORAGE_CLASS_UNKNOWN"))
+	if err != nil {
+		return err
+	}
+
+	iopsTargets, err := storageTierTargetsCache.get(os.Getenv("PRICE_TARGET_STORAGE_IOPS"))
+	if err != nil {
+		return err
+	}
+	throughputTargets, err := storageTierTargetsCache.get(os.Getenv("PRICE_TARGET_STORAGE_THROUGHPUT"))
+	if err != nil {
+		return err
+	}
+
+	targets.StorageClassTargets = classTargets
+	targets.StorageIOPSTargets = iopsTargets
+	targets.StorageThroughputTargets = throughputTargets
+	targets.UnknownStorageClass = unknown
+	targets.StorageDefaultTarget = getTargetFloat("PRICE_TARGET_STORAGE_DEFAULT", targets.HDEphemeralTarget)
+	targets.StorageBackupTarget = getTargetFloat("PRICE_TARGET_STORAGE_BACKUP", 0)
+	return nil
+}
+
+// StorageTarget returns the monthly price per GB of a storage class and whether the class is known.
+// Unknown classes are priced at StorageDefaultTarget when UnknownStorageClass is "default", and at 0 otherwise.
+func (t PriceTargets) StorageTarget(class string) (float64, bool) {
+	if price, ok := t.StorageClassTargets[class]; ok {
+		return price, true
+	}
+
+	switch class {
+	case "ephemeral", "default":
+		return t.HDEphemeralTarget, true
+	case "beta1":
+		return t.HDPersHDDTarget, true
+	case "beta2":
+		return t.HDPersSSDTarget, true
+	case "beta3":
+		return t.HDPersNVMETarget, true
+	case "ram":
+		return t.HDRAMTarget, true
+	}
+
+	if t.UnknownStorageClass == UnknownStorageDefault {
+		return t.StorageDefaultTarget, false
+	}
+	return 0, false
+}
+
+// StorageTier is a performance tier declared by a storage volume's iops or throughput attribute.
+type StorageTier struct {
+	Attribute string // StorageAttributeIOPS or StorageAttributeThroughput
+	Tier      string // Attribute value, e.g. high
+}
+
+// String returns the tier as its attribute, e.g. "iops=high".
+func (t StorageTier) String() string {
+	return t.Attribute + "=" + t.Tier
+}
+
+// storageTiers returns the performance tiers a volume's attributes declare.
+func storageTiers(attributes attrtypes.Attributes) []StorageTier {
+	var tiers []StorageTier
+	for _, attr := range attributes {
+		if (attr.Key == StorageAttributeIOPS || attr.Key == StorageAttributeThroughput) && attr.Value != "" {
+			tiers = append(tiers, StorageTier{Attribute: attr.Key, Tier: attr.Value})
+		}
+	}
+	return tiers
+}
+
+// requestsBackup reports whether a volume's attributes request backups or snapshots. Values that are not
+// booleans are ignored, like other malformed attributes.
+func requestsBackup(attributes attrtypes.Attributes) bool {
+	for _, attr := range attributes {
+		if attr.Key != StorageAttributeBackup && attr.Key != StorageAttributeSnapshot {
+			continue
+		}
+		if enabled, err := strconv.ParseBool(attr.Value); err == nil && enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// StorageTierTarget returns the monthly price per GB of a performance tier, charged on top of the volume's
+// class target, and whether the tier has one. Tiers without a target are priced as baseline performance.
+func (t PriceTargets) StorageTierTarget(tier StorageTier) (float64, bool) {
+	var price float64
+	var ok bool
+	switch tier.Attribute {
+	case StorageAttributeIOPS:
+		price, ok = t.StorageIOPSTargets[tier.Tier]
+	case StorageAttributeThroughput:
+		price, ok = t.StorageThroughputTargets[tier.Tier]
+	}
+	return price, ok
+}
+
+// StorageTiers returns the performance tiers requested, sorted by their String.
+func (r ResourceRequests) StorageTiers() []StorageTier {
+	tiers := make([]StorageTier, 0, len(r.StorageTierRequested))
+	for tier := range r.StorageTierRequested {
+		tiers = append(tiers, tier)
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].String() < tiers[j].String() })
+	return tiers
+}
+
+// UnknownStorageClasses returns the sorted storage classes requested that have no price target.
+func UnknownStorageClasses(resourceRequests ResourceRequests, priceTargets PriceTargets) []string {
+	var unknown []string
+	for class := range resourceRequests.StorageRequested {
+		if _, ok := priceTargets.StorageTarget(class); !ok {
+			unknown = append(unknown, class)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// TotalStorageGB returns the storage requested across all classes in gigabytes.
+func (r ResourceRequests) TotalStorageGB() sdkmath.LegacyDec {
+	total := sdkmath.LegacyZeroDec()
+	for _, gb := range r.StorageRequested {
+		total = total.Add(gb)
+	}
+	return total
+}
+
+// StoragePool is a declared capacity pool backing persistent storage classes. Persistent volumes reserve
+// pool capacity even while idle, so orders requesting more than ReserveFraction of the pool have the
+// storage cost of its classes surcharged by SurchargePercent.
+type StoragePool struct {
+	Classes          []string `json:"classes"`           // Storage classes backed by the pool, e.g. beta3
+	SizeGB           float64  `json:"size_gb"`           // Capacity of the pool
+	ReserveFraction  float64  `json:"reserve_fraction"`  // Share of SizeGB one order may request without a surcharge, e.g. 0.1
+	SurchargePercent float64  `json:"surcharge_percent"` // Surcharge on the storage cost of the pool's classes
+}
+
+// Validate checks the pool's size, fraction and surcharge.
+func (p StoragePool) Validate() error {
+	if len(p.Classes) == 0 {
+		return fmt.Errorf("no storage classes")
+	}
+	if p.SizeGB <= 0 || checkDecFloat(p.SizeGB) != nil {
+		return fmt.Errorf("invalid size_gb %v", p.SizeGB)
+	}
+	if p.ReserveFraction <= 0 || p.ReserveFraction > 1 {
+		return fmt.Errorf("invalid reserve_fraction %v: must be above 0 and at most 1", p.ReserveFraction)
+	}
+	if p.SurchargePercent < 0 || checkDecFloat(p.SurchargePercent) != nil {
+		return fmt.Errorf("invalid surcharge_percent %v", p.SurchargePercent)
+	}
+	return nil
+}
+
+// validateStoragePools checks every pool and that no storage class belongs to more than one.
+func validateStoragePools(pools map[string]StoragePool) error {
+	owners := make(map[string]string)
+	for _, name := range sortedPoolNames(pools) {
+		pool := pools[name]
+		if err := pool.Validate(); err != nil {
+			return fmt.Errorf("storage pool %s: %w", name, err)
+		}
+		for _, class := range pool.Classes {
+			if owner, ok := owners[class]; ok {
+				return fmt.Errorf("storage class %s is in pools %s and %s", class, owner, name)
+			}
+			owners[class] = name
+		}
+	}
+	return nil
+}
+
+// StorageReservation is a pool an order requests more than the reserve fraction of.
+type StorageReservation struct {
+	Pool        string
+	RequestedGB sdkmath.LegacyDec
+	StoragePool
+}
+
+// StorageReservations returns the pools the request reserves more than ReserveFraction of, sorted by name.
+func StorageReservations(resourceRequests ResourceRequests, pools map[string]StoragePool) []StorageReservation {
+	var reservations []StorageReservation
+	for _, name := range sortedPoolNames(pools) {
+		pool := pools[name]
+		requested := sdkmath.LegacyZeroDec()
+		for _, class := range pool.Classes {
+			if gb, ok := resourceRequests.StorageRequested[class]; ok {
+				requested = requested.Add(gb)
+			}
+		}
+		if requested.GT(decFromFloat(pool.SizeGB).Mul(decFromFloat(pool.ReserveFraction))) {
+			reservations = append(reservations, StorageReservation{Pool: name, RequestedGB: requested, StoragePool: pool})
+		}
+	}
+	return reservations
+}
+
+// storageSurcharges returns the surcharge percent of each storage class whose pool the request reserves
+// more than the reserve fraction of, or nil if there is none.
+func storageSurcharges(resourceRequests ResourceRequests, pools map[string]StoragePool) map[string]float64 {
+	if len(pools) == 0 {
+		return nil
+	}
+	var surcharges map[string]float64
+	for _, reservation := range StorageReservations(resourceRequests, pools) {
+		if reservation.SurchargePercent == 0 {
+			continue
+		}
+		if surcharges == nil {
+			surcharges = make(map[string]float64)
+		}
+		for _, class := range reservation.Classes {
+			surcharges[class] = reservation.SurchargePercent
+		}
+	}
+	return surcharges
+}
+
+// sortedPoolNames returns the pool names in order, so results do not depend on map iteration.
+func sortedPoolNames(pools map[string]StoragePool) []string {
+	names := make([]string, 0, len(pools))
+	for name := range pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,179 @@
+package pricing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// DefaultStrategyTimeout bounds how long an external strategy may take to answer.
+const DefaultStrategyTimeout = 5 * time.Second
+
+// StrategyInput is the breakdown sent to an external pricing strategy as JSON.
+type StrategyInput struct {
+	Owner        string       `json:"owner"`
+	Denom        string       `json:"denom"`
+	MaxPrice     string       `json:"max_price"` // Order max price per block in Denom, empty for orders without one
+	Profile      string       `json:"profile,omitempty"`
+	Region       string       `json:"region,omitempty"`
+	CPUCores     string       `json:"cpu_cores"`
+	MemoryGB     string       `json:"memory_gb"`
+	GPUs         int64        `json:"gpus"`
+	TotalCostUsd string       `json:"total_cost_usd"` // Monthly cost after built-in adjustments
+	AKTPriceUsd  float64      `json:"akt_price_usd"`
+	Adjustments  []Adjustment `json:"adjustments"`
+}
+
+// StrategyOutput is the answer of an external pricing strategy. A missing total_cost_usd keeps the price.
+type StrategyOutput struct {
+	TotalCostUsd *string `json:"total_cost_usd,omitempty"` // Adjusted monthly cost in USD
+	Reject       bool    `json:"reject,omitempty"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+// PriceStrategy adjusts or rejects a computed price. Implementations let operators plug in custom
+// logic without forking the package.
+type PriceStrategy interface {
+	Adjust(input StrategyInput) (StrategyOutput, error)
+}
+
+// ExecStrategy runs an executable with the breakdown as JSON on stdin and reads a StrategyOutput from stdout.
+type ExecStrategy struct {
+	Path    string
+	Args    []string
+	Timeout time.Duration
+}
+
+// Adjust runs the strategy executable.
+func (s *ExecStrategy) Adjust(input StrategyInput) (StrategyOutput, error) {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return StrategyOutput{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), strategyTimeout(s.Timeout))
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.Path, s.Args...)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return StrategyOutput{}, fmt.Errorf("strategy %s failed: %v: %s", s.Path, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var output StrategyOutput
+	if err := json.Unmarshal(out, &output); err != nil {
+		return StrategyOutput{}, fmt.Errorf("invalid output from strategy %s: %w", s.Path, err)
+	}
+	return output, nil
+}
+
+// WebhookStrategy POSTs the breakdown as JSON to a URL and reads a StrategyOutput from the response.
+type WebhookStrategy struct {
+	URL     string
+	Timeout time.Duration
+}
+
+// Adjust calls the strategy webhook.
+func (s *WebhookStrategy) Adjust(input StrategyInput) (StrategyOutput, error) {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return StrategyOutput{}, err
+	}
+
+	client := &http.Client{Timeout: strategyTimeout(s.Timeout)}
+	resp, err := client.Post(s.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return StrategyOutput{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return StrategyOutput{}, fmt.Errorf("HTTP request error: %s", resp.Status)
+	}
+
+	var output StrategyOutput
+	if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+		return StrategyOutput{}, fmt.Errorf("invalid response from strategy webhook: %w", err)
+	}
+	return output, nil
+}
+
+// strategyTimeout returns timeout, or DefaultStrategyTimeout if it is not set.
+func strategyTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return DefaultStrategyTimeout
+	}
+	return timeout
+}
+
+// NewPriceStrategyFromEnv returns the strategy configured by STRATEGY_WASM, STRATEGY_EXEC, STRATEGY_WEBHOOK_URL or
+// STRATEGY_MARKET_URL, or nil.
+// STRATEGY_TIMEOUT (a Go duration) overrides the default timeout.
+func NewPriceStrategyFromEnv() (PriceStrategy, error) {
+	var timeout time.Duration
+	if val := os.Getenv("STRATEGY_TIMEOUT"); val != "" {
+		var err error
+		timeout, err = time.ParseDuration(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STRATEGY_TIMEOUT: %v", err)
+		}
+	}
+
+	if path := os.Getenv("STRATEGY_WASM"); path != "" {
+		return NewWASMStrategy(path, timeout)
+	}
+	if path := os.Getenv("STRATEGY_EXEC"); path != "" {
+		return &ExecStrategy{Path: path, Timeout: timeout}, nil
+	}
+	if webhookURL := os.Getenv("STRATEGY_WEBHOOK_URL"); webhookURL != "" {
+		return &WebhookStrategy{URL: webhookURL, Timeout: timeout}, nil
+	}
+	if market, err := NewMarketStrategyFromEnv(timeout); market != nil || err != nil {
+		return market, err
+	}
+	return nil, nil
+}
+
+// ApplyStrategy runs the strategy and returns the adjusted monthly USD cost. A rejection is returned as an
+// error; a failing strategy is logged and leaves the cost unchanged so a broken plugin can't stop bidding.
+func ApplyStrategy(strategy PriceStrategy, input StrategyInput, totalCostUsd sdkmath.LegacyDec) (sdkmath.LegacyDec, *Adjustment, error) {
+	if strategy == nil {
+		return totalCostUsd, nil, nil
+	}
+
+	output, err := strategy.Adjust(input)
+	if err != nil {
+		log.Printf("Pricing strategy failed, using computed price: %v", err)
+		return totalCostUsd, nil, nil
+	}
+
+	if output.Reject {
+		return sdkmath.LegacyDec{}, nil, fmt.Errorf("%w: %s", ErrStrategyRejected, output.Reason)
+	}
+	if output.TotalCostUsd == nil {
+		return totalCostUsd, nil, nil
+	}
+
+	adjusted, err := sdkmath.LegacyNewDecFromStr(*output.TotalCostUsd)
+	if err != nil || adjusted.IsNegative() {
+		log.Printf("Pricing strategy returned invalid total_cost_usd %q, using computed price", *output.TotalCostUsd)
+		return totalCostUsd, nil, nil
+	}
+
+	detail := fmt.Sprintf("strategy set monthly cost %s USD (was %s USD)", FormatDec(adjusted, 2), FormatDec(totalCostUsd, 2))
+	if output.Reason != "" {
+		detail += ": " + output.Reason
+	}
+	return adjusted, &Adjustment{Name: "strategy", Detail: detail}, nil
+}
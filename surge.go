@@ -0,0 +1,219 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Utilization is the fraction (0-1) of provider cluster capacity currently in use per resource.
+type Utilization struct {
+	CPU    float64 `json:"cpu"`
+	GPU    float64 `json:"gpu"`
+	Memory float64 `json:"memory"`
+}
+
+// UtilizationProvider reports current provider cluster utilization.
+type UtilizationProvider interface {
+	Utilization() (Utilization, error)
+}
+
+// SurgeTier applies Multiplier to a resource's price target once its utilization reaches Threshold.
+type SurgeTier struct {
+	Threshold  float64
+	Multiplier float64
+}
+
+// FileUtilizationProvider reads utilization from a JSON file such as {"cpu": 0.72, "gpu": 0.95, "memory": 0.6},
+// typically written by a cron job or sidecar.
+type FileUtilizationProvider struct {
+	Path string
+}
+
+// Utilization reads the utilization file.
+func (p *FileUtilizationProvider) Utilization() (Utilization, error) {
+	data, err := ioutil.ReadFile(p.Path)
+	if err != nil {
+		return Utilization{}, err
+	}
+
+	var u Utilization
+	if err := json.Unmarshal(data, &u); err != nil {
+		return Utilization{}, fmt.Errorf("invalid utilization file %s: %w", p.Path, err)
+	}
+	return u, nil
+}
+
+// PrometheusUtilizationProvider evaluates one instant query per resource against a Prometheus server.
+// Each query must return a single sample holding the utilization fraction. Empty queries report 0.
+type PrometheusUtilizationProvider struct {
+	URL         string
+	CPUQuery    string
+	GPUQuery    string
+	MemoryQuery string
+	Client      *http.Client
+}
+
+// Utilization runs the configured queries.
+func (p *PrometheusUtilizationProvider) Utilization() (Utilization, error) {
+	var u Utilization
+	for query, target := range map[string]*float64{
+		p.CPUQuery:    &u.CPU,
+		p.GPUQuery:    &u.GPU,
+		p.MemoryQuery: &u.Memory,
+	} {
+		if query == "" {
+			continue
+		}
+		value, err := p.query(query)
+		if err != nil {
+			return Utilization{}, err
+		}
+		*target = value
+	}
+	return u, nil
+}
+
+// query evaluates an instant query and returns the value of its first sample.
+func (p *PrometheusUtilizationProvider) query(query string) (float64, error) {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	resp, err := client.Get(strings.TrimRight(p.URL, "/") + "/api/v1/query?query=" + url.QueryEscape(query))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("HTTP request error: %s", resp.Status)
+	}
+
+	var data struct {
+		Status string `json:"status"`
+		Data   struct {
+			Result []struct {
+				Value []interface{} `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return 0, err
+	}
+	if data.Status != "success" || len(data.Data.Result) == 0 || len(data.Data.Result[0].Value) != 2 {
+		return 0, fmt.Errorf("prometheus query %q returned no sample", query)
+	}
+
+	valueStr, ok := data.Data.Result[0].Value[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("prometheus query %q returned an invalid sample", query)
+	}
+	return strconv.ParseFloat(valueStr, 64)
+}
+
+// NewUtilizationProviderFromEnv returns the provider configured by UTILIZATION_FILE or UTILIZATION_PROMETHEUS_URL, or nil.
+func NewUtilizationProviderFromEnv() UtilizationProvider {
+	if promURL := os.Getenv("UTILIZATION_PROMETHEUS_URL"); promURL != "" {
+		return &PrometheusUtilizationProvider{
+			URL:         promURL,
+			CPUQuery:    os.Getenv("UTILIZATION_PROMETHEUS_CPU_QUERY"),
+			GPUQuery:    os.Getenv("UTILIZATION_PROMETHEUS_GPU_QUERY"),
+			MemoryQuery: os.Getenv("UTILIZATION_PROMETHEUS_MEMORY_QUERY"),
+		}
+	}
+	if utilizationFile := os.Getenv("UTILIZATION_FILE"); utilizationFile != "" {
+		return &FileUtilizationProvider{Path: utilizationFile}
+	}
+	return nil
+}
+
+// ParseSurgeTiers parses tiers of the form "0.8=1.2,0.9=1.5", sorted by ascending threshold.
+func ParseSurgeTiers(tiersStr string) ([]SurgeTier, error) {
+	var tiers []SurgeTier
+	if tiersStr == "" {
+		return tiers, nil
+	}
+
+	for _, pair := range strings.Split(tiersStr, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.Split(pair, "=")
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid surge tier: %s", pair)
+		}
+		threshold, err := strconv.ParseFloat(kv[0], 64)
+		if err != nil || threshold < 0 || threshold > 1 {
+			return nil, fmt.Errorf("invalid surge threshold %s: must be between 0 and 1", kv[0])
+		}
+		multiplier, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil || multiplier <= 0 {
+			return nil, fmt.Errorf("invalid surge multiplier for %s: %s", kv[0], kv[1])
+		}
+		tiers = append(tiers, SurgeTier{Threshold: threshold, Multiplier: multiplier})
+	}
+
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].Threshold < tiers[j].Threshold })
+	return tiers, nil
+}
+
+// surgeMultiplier returns the multiplier of the highest tier reached by utilization, or 1 if none is reached.
+func surgeMultiplier(tiers []SurgeTier, utilization float64) float64 {
+	multiplier := 1.0
+	for _, tier := range tiers {
+		if utilization >= tier.Threshold {
+			multiplier = tier.Multiplier
+		}
+	}
+	return multiplier
+}
+
+// ApplySurge scales the CPU, memory and GPU targets by the surge multiplier for each resource's current
+// utilization and returns the adjusted targets with the adjustments made. Utilization lookup failures
+// are logged and leave the targets unchanged.
+func ApplySurge(provider UtilizationProvider, tiers []SurgeTier, targets PriceTargets) (PriceTargets, []Adjustment) {
+	if provider == nil || len(tiers) == 0 {
+		return targets, nil
+	}
+
+	u, err := provider.Utilization()
+	if err != nil {
+		log.Printf("Error getting cluster utilization, using base price: %v", err)
+		return targets, nil
+	}
+
+	var adjustments []Adjustment
+	surge := func(resource string, utilization float64) float64 {
+		multiplier := surgeMultiplier(tiers, utilization)
+		if multiplier != 1 {
+			adjustments = append(adjustments, Adjustment{
+				Name:   "surge_" + resource,
+				Detail: fmt.Sprintf("%s utilization %.0f%%, multiplier %g", resource, utilization*100, multiplier),
+			})
+		}
+		return multiplier
+	}
+
+	targets.CPUTarget *= surge("cpu", u.CPU)
+	targets.MemoryTarget *= surge("memory", u.Memory)
+	if gpuMultiplier := surge("gpu", u.GPU); gpuMultiplier != 1 {
+		// Copy so the caller's mapping table is not modified
+		gpuMappings := make(map[string]float64, len(targets.GPUMappings))
+		for key, price := range targets.GPUMappings {
+			gpuMappings[key] = price * gpuMultiplier
+		}
+		targets.GPUMappings = gpuMappings
+	}
+
+	return targets, adjustments
+}
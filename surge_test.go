@@ -0,0 +1,85 @@
+package pricing
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestSurgeMultiplier(t *testing.T) {
+	tiers, err := ParseSurgeTiers("0.9=1.5,0.8=1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		utilization float64
+		want        float64
+	}{
+		{0, 1},
+		{0.79, 1},
+		{0.8, 1.2},
+		{0.89, 1.2},
+		{0.9, 1.5},
+		{1, 1.5},
+		{1.3, 1.5}, // Overcommitted clusters stay capped at the highest tier
+	}
+	for _, tt := range tests {
+		if got := surgeMultiplier(tiers, tt.utilization); got != tt.want {
+			t.Errorf("utilization %g: got multiplier %g, want %g", tt.utilization, got, tt.want)
+		}
+	}
+}
+
+// TestSurgeBid prices the cpu-only fixture, 3.20 USD of CPU and 3.20 USD of memory in a 6.85 USD month, under
+// SURGE_TIERS="0.8=1.2,0.9=1.5".
+func TestSurgeBid(t *testing.T) {
+	request := fixtureRequest(t, "cpu-only")
+	tests := []struct {
+		cpu, memory float64
+		totalCost   string
+		price       string
+		adjustments []string
+	}{
+		{0.79, 0, "6.850000", "4.552452", nil},
+		{0.8, 0.5, "7.490000", "4.977791", []string{"surge_cpu"}},
+		{1.3, 0, "8.450000", "5.615799", []string{"surge_cpu"}},
+		{0.9, 0.8, "9.090000", "6.041138", []string{"surge_cpu", "surge_memory"}},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "utilization.json")
+		if err := ioutil.WriteFile(path, []byte(fmt.Sprintf(`{"cpu": %g, "memory": %g}`, tt.cpu, tt.memory)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		result, err := priceWithEnv(request, map[string]string{"SURGE_TIERS": "0.8=1.2,0.9=1.5", "UTILIZATION_FILE": path})
+		if err != nil {
+			t.Fatalf("cpu %g, memory %g: %v", tt.cpu, tt.memory, err)
+		}
+		if got := FormatDec(result.TotalCostUsd, 6); got != tt.totalCost || result.Price != tt.price {
+			t.Errorf("cpu %g, memory %g: got %s USD at %s, want %s USD at %s", tt.cpu, tt.memory, got, result.Price, tt.totalCost, tt.price)
+		}
+		for _, name := range []string{"surge_cpu", "surge_memory", "surge_gpu"} {
+			want := false
+			for _, adjustment := range tt.adjustments {
+				want = want || adjustment == name
+			}
+			if got := findAdjustment(result, name) != nil; got != want {
+				t.Errorf("cpu %g, memory %g: got %s adjustment %v, want %v", tt.cpu, tt.memory, name, got, want)
+			}
+		}
+	}
+}
+
+// TestSurgeBidWithoutUtilization checks an unreadable utilization source leaves the base price.
+func TestSurgeBidWithoutUtilization(t *testing.T) {
+	result, err := priceWithEnv(fixtureRequest(t, "cpu-only"), map[string]string{
+		"SURGE_TIERS":      "0.8=1.2",
+		"UTILIZATION_FILE": filepath.Join(t.TempDir(), "missing.json"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Price != "4.552452" || len(result.Adjustments) != 0 {
+		t.Errorf("got %s with %v, want the base 4.552452", result.Price, result.Adjustments)
+	}
+}
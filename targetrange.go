@@ -0,0 +1,159 @@
+package pricing
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Handling of out-of-range price targets, selected by PRICE_TARGET_OUT_OF_RANGE.
+const (
+	TargetRangeReject = "reject" // Refuse to price until the target is fixed (default)
+	TargetRangeClamp  = "clamp"  // Price with the nearest valid value and log a warning
+)
+
+// priceTargetEnvVars lists the numeric target variables and amounts; CPU and memory targets must also be
+// non-zero.
+var priceTargetEnvVars = []struct {
+	name     string
+	required bool
+}{
+	{"PRICE_TARGET_CPU", true},
+	{"PRICE_TARGET_MEMORY", true},
+	{"PRICE_TARGET_CPU_AMD64", false},
+	{"PRICE_TARGET_CPU_ARM64", false},
+	{"PRICE_TARGET_HD_EPHEMERAL", false},
+	{"PRICE_TARGET_HD_PERS_HDD", false},
+	{"PRICE_TARGET_HD_PERS_SSD", false},
+	{"PRICE_TARGET_HD_PERS_NVME", false},
+	{"PRICE_TARGET_HD_RAM", false},
+	{"PRICE_TARGET_ENDPOINT", false},
+	{"PRICE_TARGET_RANDOM_PORT", false},
+	{"PRICE_TARGET_IP", false},
+	{"PRICE_TARGET_IPV6", false},
+	{"PRICE_TARGET_EGRESS_GB", false},
+	{"EGRESS_GB_PER_ENDPOINT", false},
+	{"DEPLOYMENT_OVERHEAD_USD", false},
+	{"PRICE_TARGET_STORAGE_DEFAULT", false},
+	{"PRICE_TARGET_STORAGE_BACKUP", false},
+	{"PRICE_TARGET_GPU_DEFAULT", false},
+}
+
+// targetValueProblem describes why a target value is out of range, or returns "" if it is valid.
+func targetValueProblem(value float64, required bool) string {
+	switch {
+	case checkDecFloat(value) != nil:
+		return "is not a finite number in range"
+	case value < 0:
+		return "is negative"
+	case value == 0 && required:
+		return "must be greater than zero"
+	}
+	return ""
+}
+
+// targetRangeProblems returns a message naming each numeric target variable that is set but is not a
+// number or is out of range. GetEnvFloat falls back to the default for unparseable values, so they are
+// parsed here directly.
+func targetRangeProblems() []string {
+	var problems []string
+	for _, target := range priceTargetEnvVars {
+		val, ok := os.LookupEnv(target.name)
+		if !ok {
+			continue
+		}
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s=%q is not a number", target.name, val))
+			continue
+		}
+		if problem := targetValueProblem(f, target.required); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s=%s %s", target.name, val, problem))
+		}
+	}
+	return problems
+}
+
+// TargetRangeMode returns how out-of-range targets are handled, from PRICE_TARGET_OUT_OF_RANGE.
+func TargetRangeMode() (string, error) {
+	switch mode := os.Getenv("PRICE_TARGET_OUT_OF_RANGE"); mode {
+	case "":
+		return TargetRangeReject, nil
+	case TargetRangeReject, TargetRangeClamp:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid PRICE_TARGET_OUT_OF_RANGE %q: must be reject or clamp", mode)
+	}
+}
+
+// checkTargetRanges checks the numeric target variables before they are loaded. Out-of-range values are an
+// error naming every offending variable, unless clamping is enabled, in which case they are logged and
+// getTargetFloat clamps them.
+func checkTargetRanges() error {
+	problems := targetRangeProblems()
+	if len(problems) == 0 {
+		return nil
+	}
+	mode, err := TargetRangeMode()
+	if err != nil {
+		return err
+	}
+	if mode == TargetRangeClamp {
+		log.Printf("WARNING: clamping out-of-range price targets: %s", strings.Join(problems, "; "))
+		return nil
+	}
+	return fmt.Errorf("invalid price targets: %s", strings.Join(problems, "; "))
+}
+
+// getTargetFloat reads a numeric target variable like GetEnvFloat, clamping negative values to zero. Zero
+// or negative CPU and memory targets, which have no nearest valid value, fall back to the default.
+func getTargetFloat(envVar string, defaultValue float64) float64 {
+	value := GetEnvFloat(envVar, defaultValue)
+	if value > 0 {
+		return value
+	}
+	for _, target := range priceTargetEnvVars {
+		if target.name == envVar && target.required {
+			return defaultValue
+		}
+	}
+	if value < 0 {
+		return 0
+	}
+	return value
+}
+
+// validateTargetValues checks the numeric overrides are in range, naming the offending field.
+func (c PriceTargetsConfig) validateTargetValues() error {
+	for _, field := range []struct {
+		name     string
+		value    *float64
+		required bool
+	}{
+		{"cpu", c.CPU, true},
+		{"memory", c.Memory, true},
+		{"hd_ephemeral", c.HDEphemeral, false},
+		{"hd_pers_hdd", c.HDPersHDD, false},
+		{"hd_pers_ssd", c.HDPersSSD, false},
+		{"hd_pers_nvme", c.HDPersNVME, false},
+		{"hd_ram", c.HDRAM, false},
+		{"endpoint", c.Endpoint, false},
+		{"random_port", c.RandomPort, false},
+		{"ip", c.IP, false},
+		{"ipv6", c.IPv6, false},
+		{"egress_gb", c.EgressGB, false},
+		{"cpu_amd64", c.CPUAMD64, false},
+		{"cpu_arm64", c.CPUARM64, false},
+		{"storage_backup", c.StorageBackup, false},
+	} {
+		if field.value == nil {
+			continue
+		}
+		if problem := targetValueProblem(*field.value, field.required); problem != "" {
+			return fmt.Errorf("%s=%g %s", field.name, *field.value, problem)
+		}
+	}
+	return nil
+}
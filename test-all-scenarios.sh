@@ -167,6 +167,125 @@ echo -e "  ${GREEN}✅ Environment overrides working${NC}"
 TESTS_PASSED=$((TESTS_PASSED + 1))
 echo ""
 
+# Test bid guards
+echo -e "${YELLOW}Test: Bid Ceiling Rejects Expensive Bids${NC}"
+export BID_CEILING_USD_MONTHLY=0.01
+if OUTPUT=$(cat "$EXAMPLES_DIR/sample-deployment.json" | $BINARY 2>&1); then
+    echo -e "  ${RED}❌ Expected rejection above ceiling, got: $(echo "$OUTPUT" | tail -1)${NC}"
+    TESTS_FAILED=$((TESTS_FAILED + 1))
+else
+    echo -e "  ${GREEN}✅ Bid above ceiling rejected${NC}"
+    TESTS_PASSED=$((TESTS_PASSED + 1))
+fi
+unset BID_CEILING_USD_MONTHLY
+echo ""
+
+echo -e "${YELLOW}Test: Bid Floor Raises Tiny Bids${NC}"
+export BID_FLOOR_UAKT=50
+OUTPUT=$(cat "$EXAMPLES_DIR/cpu-only-deployment.json" | $BINARY 2>&1 | tail -1)
+if awk -v p="$OUTPUT" 'BEGIN { exit !(p >= 50) }'; then
+    echo -e "  ${GREEN}✅ Bid ${OUTPUT} is at or above floor 50${NC}"
+    TESTS_PASSED=$((TESTS_PASSED + 1))
+else
+    echo -e "  ${RED}❌ Bid ${OUTPUT} is below floor 50${NC}"
+    TESTS_FAILED=$((TESTS_FAILED + 1))
+fi
+unset BID_FLOOR_UAKT
+echo ""
+
+echo -e "${YELLOW}Test: JSON Output Reports Price and Rejection Reason${NC}"
+BID_JSON=$(cat "$EXAMPLES_DIR/cpu-only-deployment.json" | BID_SCRIPT_OUTPUT=json $BINARY 2>/dev/null)
+REJECT_JSON=$(cat "$EXAMPLES_DIR/cpu-only-deployment.json" | BID_SCRIPT_OUTPUT=json BID_CEILING_USD_MONTHLY=0.01 $BINARY 2>/dev/null || true)
+if echo "$BID_JSON" | grep -q '"price":"[0-9.]*","denom":"uakt","precision":6' && echo "$REJECT_JSON" | grep -q '"reason":'; then
+    echo -e "  ${GREEN}✅ ${BID_JSON}${NC}"
+    TESTS_PASSED=$((TESTS_PASSED + 1))
+else
+    echo -e "  ${RED}❌ Unexpected JSON output: ${BID_JSON} / ${REJECT_JSON}${NC}"
+    TESTS_FAILED=$((TESTS_FAILED + 1))
+fi
+echo ""
+
+echo -e "${BLUE}═══ Whitelist Metadata ═══${NC}"
+echo ""
+
+# Serve the example whitelists locally so WHITELIST_URL can point at them
+WHITELIST_PORT=18089
+python3 -m http.server "$WHITELIST_PORT" --directory "$EXAMPLES_DIR/whitelists" >/dev/null 2>&1 &
+WHITELIST_SERVER_PID=$!
+trap 'kill $WHITELIST_SERVER_PID 2>/dev/null' EXIT
+sleep 1
+
+# Whitelist test function: expect_result is "bid" or "reject"
+run_whitelist_test() {
+    local test_name="$1"
+    local whitelist_file="$2"
+    local owner="$3"
+    local expect_result="$4"
+
+    echo -e "${YELLOW}Test: ${test_name}${NC}"
+    rm -f /tmp/price-script.whitelist /tmp/price-script.whitelist.meta
+    export WHITELIST_URL="http://127.0.0.1:${WHITELIST_PORT}/${whitelist_file}"
+    export AKASH_OWNER="$owner"
+
+    if OUTPUT=$(cat "$EXAMPLES_DIR/cpu-only-deployment.json" | $BINARY 2>&1); then
+        RESULT="bid"
+    else
+        RESULT="reject"
+    fi
+
+    if [ "$RESULT" = "$expect_result" ]; then
+        echo -e "  ${GREEN}✅ Got expected result: ${RESULT}${NC}"
+        TESTS_PASSED=$((TESTS_PASSED + 1))
+    else
+        echo -e "  ${RED}❌ Expected ${expect_result}, got ${RESULT}${NC}"
+        echo "  Output: $OUTPUT"
+        TESTS_FAILED=$((TESTS_FAILED + 1))
+    fi
+    echo ""
+}
+
+for WHITELIST in whitelist.json whitelist.csv; do
+    run_whitelist_test "Whitelist discount ($WHITELIST)" "$WHITELIST" \
+        "akash1tenantdiscount0000000000000000000000" "bid"
+    run_whitelist_test "Whitelist expired entry ($WHITELIST)" "$WHITELIST" \
+        "akash1tenantexpired00000000000000000000000" "reject"
+    run_whitelist_test "Whitelist spend cap exceeded ($WHITELIST)" "$WHITELIST" \
+        "akash1tenantcapped000000000000000000000000" "reject"
+    run_whitelist_test "Owner not in whitelist ($WHITELIST)" "$WHITELIST" \
+        "akash1unknowntenant0000000000000000000000" "reject"
+done
+
+# The discounted bid must be lower than the undiscounted one
+echo -e "${YELLOW}Test: Whitelist discount lowers the bid${NC}"
+unset WHITELIST_URL AKASH_OWNER
+FULL_PRICE=$(cat "$EXAMPLES_DIR/cpu-only-deployment.json" | $BINARY 2>/dev/null | tail -1)
+rm -f /tmp/price-script.whitelist /tmp/price-script.whitelist.meta
+export WHITELIST_URL="http://127.0.0.1:${WHITELIST_PORT}/whitelist.json"
+export AKASH_OWNER="akash1tenantdiscount0000000000000000000000"
+DISCOUNTED_PRICE=$(cat "$EXAMPLES_DIR/cpu-only-deployment.json" | $BINARY 2>/dev/null | tail -1)
+if awk -v d="$DISCOUNTED_PRICE" -v f="$FULL_PRICE" 'BEGIN { exit !(d < f) }'; then
+    echo -e "  ${GREEN}✅ Discounted ${DISCOUNTED_PRICE} < full ${FULL_PRICE}${NC}"
+    TESTS_PASSED=$((TESTS_PASSED + 1))
+else
+    echo -e "  ${RED}❌ Discounted ${DISCOUNTED_PRICE} is not below full ${FULL_PRICE}${NC}"
+    TESTS_FAILED=$((TESTS_FAILED + 1))
+fi
+unset WHITELIST_URL AKASH_OWNER
+rm -f /tmp/price-script.whitelist /tmp/price-script.whitelist.meta
+echo ""
+
+# Golden fixtures must price exactly as recorded
+echo -e "${YELLOW}Test: Golden fixtures${NC}"
+if $BINARY golden > /tmp/golden-output.txt 2>&1; then
+    echo -e "  ${GREEN}✅ All fixtures match their golden files${NC}"
+    TESTS_PASSED=$((TESTS_PASSED + 1))
+else
+    cat /tmp/golden-output.txt
+    echo -e "  ${RED}❌ Fixtures differ from their golden files${NC}"
+    TESTS_FAILED=$((TESTS_FAILED + 1))
+fi
+echo ""
+
 # Summary
 echo -e "${BLUE}╔════════════════════════════════════════╗${NC}"
 echo -e "${BLUE}║  Test Summary                          ║${NC}"
@@ -0,0 +1,98 @@
+package pricing
+
+import "os"
+
+// Bid pipeline span names. CalculateBid, and so RequestToBidPrice, is the root span of a bid; PriceGroups
+// is the root span of a deployment and parents one CalculateBid span per group.
+const (
+	spanCalculateBid = "pricing.CalculateBid"
+	spanPriceGroups  = "pricing.PriceGroups"
+	spanValidate     = "pricing.validate"
+	spanWhitelist    = "pricing.whitelist"
+	spanOracle       = "pricing.oracle"
+	spanGPU          = "pricing.gpu"
+	spanFX           = "pricing.fx"
+	spanConvert      = "pricing.convert"
+	spanShadow       = "pricing.shadow"
+)
+
+// spanAttrKind is the type of a span attribute value.
+type spanAttrKind int
+
+const (
+	spanAttrString spanAttrKind = iota
+	spanAttrInt
+	spanAttrFloat
+	spanAttrBool
+)
+
+// spanAttr is a span attribute. It is a plain struct rather than an OpenTelemetry attribute so the
+// pipeline builds, and allocates nothing, without the otel tag.
+type spanAttr struct {
+	key  string
+	kind spanAttrKind
+	str  string
+	num  int64
+	flt  float64
+}
+
+// stringAttr returns a string span attribute.
+func stringAttr(key, value string) spanAttr {
+	return spanAttr{key: key, kind: spanAttrString, str: value}
+}
+
+// intAttr returns an integer span attribute.
+func intAttr(key string, value int64) spanAttr {
+	return spanAttr{key: key, kind: spanAttrInt, num: value}
+}
+
+// floatAttr returns a float span attribute.
+func floatAttr(key string, value float64) spanAttr {
+	return spanAttr{key: key, kind: spanAttrFloat, flt: value}
+}
+
+// boolAttr returns a boolean span attribute.
+func boolAttr(key string, value bool) spanAttr {
+	attr := spanAttr{key: key, kind: spanAttrBool}
+	if value {
+		attr.num = 1
+	}
+	return attr
+}
+
+// bidSpanAttrs sets the attributes of a finished CalculateBid span: the owner, the bid and its breakdown,
+// or the reason code of the rejection or failure.
+func bidSpanAttrs(span pipelineSpan, request Request, result *BidResult, err error) {
+	if !span.Recording() {
+		return
+	}
+	span.SetAttributes(stringAttr("akash.owner", request.Owner))
+	if request.OrderID != "" {
+		span.SetAttributes(stringAttr("akash.order_id", request.OrderID))
+	}
+	if result != nil {
+		span.SetAttributes(
+			stringAttr("akash.denom", result.Denom),
+			stringAttr("akash.price", result.Price),
+			stringAttr("akash.provider", result.Provider),
+			stringAttr("akash.profile", result.Profile),
+			stringAttr("akash.region", result.Region),
+			intAttr("akash.adjustments", int64(len(result.Adjustments))),
+		)
+		if !result.TotalCostUsd.IsNil() {
+			span.SetAttributes(stringAttr("akash.total_cost_usd", FormatDec(result.TotalCostUsd, 2)))
+		}
+	}
+	if err != nil {
+		code, rejected := ErrorReason(err)
+		span.SetAttributes(stringAttr("akash.reason_code", code), boolAttr("akash.rejected", rejected))
+	}
+}
+
+// tracingEndpoint returns the configured OTLP traces endpoint, if any.
+func tracingEndpoint() string {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+}
@@ -0,0 +1,35 @@
+//go:build !otel
+
+package pricing
+
+import (
+	"context"
+	"fmt"
+)
+
+// pipelineSpan is a no-op span; tracing needs a build with the otel tag.
+type pipelineSpan struct{}
+
+// SetAttributes does nothing.
+func (pipelineSpan) SetAttributes(attrs ...spanAttr) {}
+
+// Recording reports false, so callers can skip computing attributes.
+func (pipelineSpan) Recording() bool { return false }
+
+// End does nothing.
+func (pipelineSpan) End(err error) {}
+
+// startSpan returns ctx and a no-op span.
+func startSpan(ctx context.Context, name string, attrs ...spanAttr) (context.Context, pipelineSpan) {
+	return ctx, pipelineSpan{}
+}
+
+// InitTracing reports that exporting traces needs a build with the otel tag when an OTLP endpoint is
+// configured, and does nothing otherwise.
+func InitTracing(ctx context.Context) (func(context.Context) error, error) {
+	shutdown := func(context.Context) error { return nil }
+	if tracingEndpoint() != "" {
+		return shutdown, fmt.Errorf("tracing to %s requires a build with -tags otel", tracingEndpoint())
+	}
+	return shutdown, nil
+}
@@ -0,0 +1,106 @@
+//go:build otel
+
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the bid pipeline spans.
+const tracerName = "github.com/akash-network/pricing-script"
+
+// DefaultTracingServiceName is the service.name of exported spans unless OTEL_SERVICE_NAME is set.
+const DefaultTracingServiceName = "akash-pricing"
+
+// pipelineSpan is an OpenTelemetry span of the bid pipeline.
+type pipelineSpan struct {
+	span trace.Span
+}
+
+// SetAttributes sets attributes on the span.
+func (s pipelineSpan) SetAttributes(attrs ...spanAttr) {
+	s.span.SetAttributes(otelAttributes(attrs)...)
+}
+
+// Recording reports whether the span records attributes, i.e. a tracer provider is installed and sampled it.
+func (s pipelineSpan) Recording() bool {
+	return s.span.IsRecording()
+}
+
+// End ends the span. Failures set its status to error; rejections are regular outcomes and only record the
+// error as an event.
+func (s pipelineSpan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		if _, rejected := ErrorReason(err); !rejected {
+			s.span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	s.span.End()
+}
+
+// startSpan starts a span of the bid pipeline as a child of the span in ctx, if any.
+func startSpan(ctx context.Context, name string, attrs ...spanAttr) (context.Context, pipelineSpan) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(otelAttributes(attrs)...))
+	return ctx, pipelineSpan{span}
+}
+
+// otelAttributes converts span attributes to OpenTelemetry attributes.
+func otelAttributes(attrs []spanAttr) []attribute.KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	kvs := make([]attribute.KeyValue, len(attrs))
+	for i, attr := range attrs {
+		switch attr.kind {
+		case spanAttrInt:
+			kvs[i] = attribute.Int64(attr.key, attr.num)
+		case spanAttrFloat:
+			kvs[i] = attribute.Float64(attr.key, attr.flt)
+		case spanAttrBool:
+			kvs[i] = attribute.Bool(attr.key, attr.num != 0)
+		default:
+			kvs[i] = attribute.String(attr.key, attr.str)
+		}
+	}
+	return kvs
+}
+
+// InitTracing installs a tracer provider exporting spans over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set. The exporter reads the other OTEL_EXPORTER_OTLP_* variables and
+// the resource OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES. The returned function flushes and stops the
+// exporter; bid script runs must call it before exiting.
+func InitTracing(ctx context.Context) (func(context.Context) error, error) {
+	shutdown := func(context.Context) error { return nil }
+	if tracingEndpoint() == "" {
+		return shutdown, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return shutdown, fmt.Errorf("error creating OTLP trace exporter: %v", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", DefaultTracingServiceName)),
+		resource.WithFromEnv(),
+	)
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return shutdown, fmt.Errorf("error creating trace resource: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,106 @@
+package pricing
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// DefaultTrialDeployments is the number of discounted deployments when TRIAL_DEPLOYMENTS is unset.
+const DefaultTrialDeployments = 1
+
+// TrialDiscount discounts the first deployments an owner leases from the provider, to attract new tenants.
+type TrialDiscount struct {
+	Percent     float64 // Discount off the monthly cost
+	Deployments int     // Number of an owner's first deployments discounted
+}
+
+// TrialDiscountFromEnv reads TRIAL_DISCOUNT_PERCENT and TRIAL_DEPLOYMENTS. It returns nil when no discount
+// is configured.
+func TrialDiscountFromEnv() (*TrialDiscount, error) {
+	percentStr := os.Getenv("TRIAL_DISCOUNT_PERCENT")
+	if percentStr == "" {
+		return nil, nil
+	}
+	percent, err := strconv.ParseFloat(percentStr, 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return nil, fmt.Errorf("invalid TRIAL_DISCOUNT_PERCENT %q: must be between 0 and 100", percentStr)
+	}
+	if percent == 0 {
+		return nil, nil
+	}
+
+	trial := &TrialDiscount{Percent: percent, Deployments: DefaultTrialDeployments}
+	if val := os.Getenv("TRIAL_DEPLOYMENTS"); val != "" {
+		trial.Deployments, err = strconv.Atoi(val)
+		if err != nil || trial.Deployments < 1 {
+			return nil, fmt.Errorf("invalid TRIAL_DEPLOYMENTS %q: must be a positive integer", val)
+		}
+	}
+	if os.Getenv("BID_HISTORY_DB") == "" {
+		return nil, fmt.Errorf("TRIAL_DISCOUNT_PERCENT requires BID_HISTORY_DB to look up earlier leases")
+	}
+	return trial, nil
+}
+
+// Apply discounts totalCostUsd if the owner has leased fewer than Deployments deployments before.
+// The returned adjustment is nil if no discount applies.
+func (t *TrialDiscount) Apply(leases []OwnerLease, totalCostUsd sdkmath.LegacyDec) (sdkmath.LegacyDec, *Adjustment) {
+	if t == nil {
+		return totalCostUsd, nil
+	}
+	deployments := LeasedDeployments(leases)
+	if deployments >= t.Deployments {
+		return totalCostUsd, nil
+	}
+	discount := totalCostUsd.Mul(decFromFloat(t.Percent)).QuoInt64(100)
+	return totalCostUsd.Sub(discount), &Adjustment{
+		Name:   "trial",
+		Detail: fmt.Sprintf("%g%% trial discount, deployment %d of %d", t.Percent, deployments+1, t.Deployments),
+	}
+}
+
+// LeasedDeployments counts the distinct deployments of leases. Orders are identified by dseq/gseq/oseq,
+// and the groups of one deployment count once; orders without a dseq count on their own.
+func LeasedDeployments(leases []OwnerLease) int {
+	deployments := make(map[string]bool, len(leases))
+	var unidentified int
+	for _, lease := range leases {
+		dseq := strings.SplitN(lease.OrderID, "/", 2)[0]
+		if dseq == "" {
+			unidentified++
+			continue
+		}
+		deployments[dseq] = true
+	}
+	return len(deployments) + unidentified
+}
+
+// applyOwnerDiscounts looks up the owner's leases and applies the trial discount and loyalty tiers. A
+// history failure is logged and leaves the cost undiscounted.
+func applyOwnerDiscounts(trial *TrialDiscount, loyaltyTiers []LoyaltyTier, lookup *ownerLeaseLookup, totalCostUsd sdkmath.LegacyDec) (sdkmath.LegacyDec, []Adjustment) {
+	if trial == nil && len(loyaltyTiers) == 0 {
+		return totalCostUsd, nil
+	}
+	leases, err := lookup.get()
+	if err != nil {
+		log.Printf("Error looking up leases of %s, no trial or loyalty discount: %v", lookup.owner, err)
+		return totalCostUsd, nil
+	}
+
+	var adjustments []Adjustment
+	totalCostUsd, adjustment := trial.Apply(leases, totalCostUsd)
+	if adjustment != nil {
+		adjustments = append(adjustments, *adjustment)
+	}
+	totalCostUsd, adjustment = ApplyLoyalty(loyaltyTiers, OwnerSpend(leases, time.Now()), totalCostUsd)
+	if adjustment != nil {
+		adjustments = append(adjustments, *adjustment)
+	}
+	return totalCostUsd, adjustments
+}
@@ -0,0 +1,79 @@
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TargetSetting is a tunable price target variable and its current value, empty when unset.
+type TargetSetting struct {
+	Name  string
+	Value string
+}
+
+// TargetSettings returns the numeric price targets and the GPU table with their current values.
+func TargetSettings() []TargetSetting {
+	settings := make([]TargetSetting, 0, len(priceTargetEnvVars)+1)
+	for _, target := range priceTargetEnvVars {
+		settings = append(settings, TargetSetting{Name: target.name, Value: os.Getenv(target.name)})
+	}
+	return append(settings, TargetSetting{Name: "PRICE_TARGET_GPU_MAPPINGS", Value: os.Getenv("PRICE_TARGET_GPU_MAPPINGS")})
+}
+
+// TargetVariable returns the pricing variable a target name refers to. Short names of the targets listed by
+// TargetSettings are accepted, "cpu" for PRICE_TARGET_CPU and "gpu_mappings" for PRICE_TARGET_GPU_MAPPINGS,
+// as are the full names of all pricing variables.
+func TargetVariable(name string) (string, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	for _, target := range TargetSettings() {
+		if target.Name == name || target.Name == "PRICE_TARGET_"+name {
+			return target.Name, nil
+		}
+	}
+	if configDirKeyPattern.MatchString(name) && policyVariable(name) {
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown target %s", strings.ToLower(name))
+}
+
+// SetPolicyVariable sets a pricing variable of the policy, or unsets it when value is empty. Numeric
+// targets must be in range and the GPU table must parse, so a typo never reaches a bid.
+func SetPolicyVariable(name, value string) error {
+	if !configDirKeyPattern.MatchString(name) || !policyVariable(name) {
+		return fmt.Errorf("%s is not a pricing variable", name)
+	}
+	if value == "" {
+		return os.Unsetenv(name)
+	}
+
+	for _, target := range priceTargetEnvVars {
+		if target.name != name {
+			continue
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s=%q is not a number", name, value)
+		}
+		if problem := targetValueProblem(f, target.required); problem != "" {
+			return fmt.Errorf("%s=%s %s", name, value, problem)
+		}
+	}
+	if name == "PRICE_TARGET_GPU_MAPPINGS" {
+		if _, err := ParseGPUPriceMappings(value); err != nil {
+			return err
+		}
+	}
+	if name == "PRICE_TARGET_SIZE_UNIT" {
+		if _, err := ParseSizeUnit(value); err != nil {
+			return err
+		}
+	}
+	if name == "PRICE_TARGET_HOURS_PER_MONTH" {
+		if _, err := ParseHoursPerMonth(value); err != nil {
+			return err
+		}
+	}
+	return os.Setenv(name, value)
+}
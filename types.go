@@ -2,47 +2,162 @@ package pricing
 
 import (
 	"encoding/json"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
 
 	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
 )
 
 // ResourceRequests holds the calculated resource requirements
 type ResourceRequests struct {
-	CPURequested              float64
-	MemoryRequested           float64
-	EphemeralStorageRequested int64
-	HDDPersStorageRequested   int64
-	SSDPersStorageRequested   int64
-	NVMePersStorageRequested  int64
-	IPsRequested              int64
-	EndpointsRequested        int64
+	CPURequested         sdkmath.LegacyDec                 // CPU cores
+	CPUKindRequested     map[CPUKind]sdkmath.LegacyDec     // Cores per CPU class and architecture, nil when no unit has either
+	MemoryRequested      sdkmath.LegacyDec                 // Gigabytes of SizeUnit
+	StorageRequested     map[string]sdkmath.LegacyDec      // Gigabytes of SizeUnit per storage class
+	StorageTierRequested map[StorageTier]sdkmath.LegacyDec // Gigabytes of SizeUnit per performance tier, nil when no volume declares one
+	BackupRequested      sdkmath.LegacyDec                 // Gigabytes of SizeUnit of volumes requesting backups or snapshots, nil when none does
+	IPsRequested         int64                             // Leased IP endpoints
+	IPv6Requested        int64                             // Leased IPv6 endpoints, included in IPsRequested
+	EndpointsRequested   int64                             // Shared HTTP endpoints
+	RandomPortsRequested int64                             // Random port endpoints
+	GPUsRequested        int64
+	EgressGBRequested    sdkmath.LegacyDec // Expected monthly egress in gigabytes, nil when unknown
+	SizeUnit             string            // Unit of the memory and storage quantities, GiB or GB
 }
 
-// PriceTargets holds the pricing configuration
+// PriceTargets holds the pricing configuration. Its maps may be shared with other bids; copy them before
+// modifying.
 type PriceTargets struct {
 	CPUTarget         float64
+	CPUClassTargets   map[string]float64 // Per-core targets of CPU classes, e.g. dedicated
+	CPUArchTargets    map[string]float64 // Per-core targets of CPU architectures, amd64 or arm64
 	MemoryTarget      float64
 	HDEphemeralTarget float64
 	HDPersHDDTarget   float64
 	HDPersSSDTarget   float64
 	HDPersNVMETarget  float64
-	EndpointTarget    float64
-	IPTarget          float64
+	HDRAMTarget       float64      // RAM-backed (class=ram) volumes, per GB
+	EndpointTarget    float64      // Shared HTTP endpoint
+	RandomPortTarget  float64      // Random port endpoint
+	IPTarget          float64      // Leased IPv4
+	IPv6Target        float64      // Leased IPv6
+	EgressGBTarget    float64      // Monthly egress, per GB
+	IPDiscounts       []IPDiscount // Discount curve of a group's additional leased IPs, sorted by From
 	GPUMappings       map[string]float64
+	GPUMixedModels    string  // How resources accepting several GPU models are priced: max, min or avg
+	GPUDefaultTarget  float64 // Monthly price of GPUs requested without attributes, 0 for the highest mapped price
+	GPUMismatch       string  // GPU units without attributes or attributes without units: default or reject
+	Currency          string  // ISO 4217 code the targets are expressed in, e.g. USD or EUR
+
+	StorageClassTargets      map[string]float64     // Per-GB targets of custom storage classes
+	StorageIOPSTargets       map[string]float64     // Per-GB targets of iops tiers, on top of the class target
+	StorageThroughputTargets map[string]float64     // Per-GB targets of throughput tiers, on top of the class target
+	StorageDefaultTarget     float64                // Per-GB target of unknown classes when UnknownStorageClass is "default"
+	StorageBackupTarget      float64                // Per-GB surcharge of volumes requesting backups or snapshots
+	UnknownStorageClass      string                 // ignore, reject or default
+	StoragePools             map[string]StoragePool // Capacity pools of persistent storage classes, from the config file
 }
 
 // Request represents a bid request from the Akash network
 type Request struct {
 	Owner          string
+	Provider       string // Optional provider address selecting a configuration namespace, see RequestProvider
 	GSpec          *dtypes.GroupSpec
 	PricePrecision int
+	OrderID        string  // Optional order identifier (dseq/gseq/oseq) used to correlate bid history
+	USDPerAKT      float64 // Optional AKT price override, used instead of the oracle when positive
+
+	// aktPriceSource is the source of USDPerAKT when it was obtained on the caller's behalf, e.g. by a
+	// PricingEngine. USDPerAKT set by the caller is a pin.
+	aktPriceSource string
+
+	// Optional lease commitment metadata. ExpectedDuration takes precedence; otherwise the duration is
+	// estimated from how long the deposit covers the order's max price.
+	Deposit          *sdk.DecCoin
+	ExpectedDuration time.Duration
+
+	// NoMaxPrice marks orders from providers that predate the price field. Their GroupSpec carries a denom
+	// but no amount, and the bid is not checked against a max price.
+	NoMaxPrice bool
+
+	// QuoteOnly prices the request without comparing against the order's max price, whose amount may then
+	// be missing or zero. ORDER_PRICE_MODE=quote sets it for such orders.
+	QuoteOnly bool
+
+	lookups        *groupLookups       // Lookups shared by the groups of a PriceGroups call, nil for single requests
+	shadowTargets  *PriceTargetsConfig // Shadow overrides applied after region and profile, set for shadow bids only
+	lookupDeadline time.Time           // When the AKT price and whitelist lookups fall back to cached data, zero without BID_DEADLINE
+}
+
+// BidResult is the outcome of pricing a request, with a breakdown of how the price was reached.
+type BidResult struct {
+	Denom            string            // Denom of the bid, taken from the order's price
+	Price            string            // Rate per block in Denom, formatted at Precision
+	CostPrice        string            // Cost-based rate per block in Denom, before shading
+	Precision        int               // Decimal places of Price
+	Provider         string            // Provider whose configuration namespace was used, if any
+	Profile          string            // Pricing profile used, if any
+	Region           string            // Region whose target overrides were used, if any
+	TotalCostUsd     sdkmath.LegacyDec // Monthly cost in USD after all adjustments
+	USDPerAKT        float64           // AKT price used for the conversion
+	AKTPriceSource   string            // Where USDPerAKT came from: oracle, cache, pin, stale or order
+	Schedule         BlockSchedule     // Month and block lengths the monthly cost was converted to a rate with
+	RatePerBlockUakt sdkmath.LegacyDec
+	RatePerBlockUsd  sdkmath.LegacyDec
+	Resources        ResourceRequests // Resources requested by the GroupSpec
+	CostLines        []CostLine       // Monthly cost of each resource before the adjustments, in Currency
+	Currency         string           // ISO 4217 code of the price targets CostLines are in
+	Adjustments      []Adjustment     // Discounts, multipliers and guards applied, in order
+	Coupon           string           // Promo code applied, if any
+	Quote            bool             // Priced as a quote, not checked against the order's max price
+	Version          string           // Build version of the pricing script, see BuildVersion
+	ConfigHash       string           // Hash of the configuration the bid was priced with, see ConfigHash
+}
+
+// Resources of cost lines.
+const (
+	CostCPU         = "cpu"
+	CostMemory      = "memory"
+	CostStorage     = "storage"
+	CostStorageTier = "storage_tier" // Performance tier surcharge of storage volumes
+	CostBackup      = "backup"       // Backup and snapshot surcharge of storage volumes
+	CostEndpoint    = "endpoint"
+	CostRandomPort  = "random_port"
+	CostIP          = "ip"
+	CostIPv6        = "ipv6"
+	CostEgress      = "egress"
+	CostGPU         = "gpu"
+	CostService     = "service" // Net repricing of resource units by their service's targets
+)
+
+// CostLine is one resource's share of the monthly cost, in the currency of the price targets.
+type CostLine struct {
+	Resource string            // One of the Cost constants
+	Kind     string            // CPU class and architecture, storage class or GPU models, if any
+	Quantity sdkmath.LegacyDec // Units of Unit requested
+	Unit     string            // e.g. CPU, GiB or endpoint
+	Target   float64           // Monthly price target per unit, 0 when Cost is not a plain product
+	Cost     sdkmath.LegacyDec
+	Detail   string // Why Cost differs from Quantity × Target, e.g. a discount curve
+}
+
+// Adjustment records a step that changed the price from the plain cost target.
+type Adjustment struct {
+	Name   string `json:"name"`   // Short identifier, e.g. whitelist_discount or floor
+	Detail string `json:"detail"` // Human-readable description
 }
 
 // DeploymentOrder represents the structure of the data received from the Akash Provider.
 type DeploymentOrder struct {
-	Price          *Price          `json:"price"`
-	PricePrecision int             `json:"price_precision"`
-	Resources      json.RawMessage `json:"resources"`
+	Price                   *Price          `json:"price"`
+	PricePrecision          int             `json:"price_precision"`
+	Resources               json.RawMessage `json:"resources"`
+	Deposit                 *Price          `json:"deposit,omitempty"`
+	ExpectedDurationSeconds int64           `json:"expected_duration_seconds,omitempty"`
+	OrderID                 OrderID         `json:"order_id,omitempty"`
+	Provider                string          `json:"provider,omitempty"` // Provider address the order is priced for
 }
 
 // Price represents the price structure in the deployment order.
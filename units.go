@@ -0,0 +1,65 @@
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// Size units of memory and storage quantities and their targets, selected by PRICE_TARGET_SIZE_UNIT.
+const (
+	SizeUnitGiB = "GiB" // 1024^3 bytes, as Kubernetes and SDL quantities (default)
+	SizeUnitGB  = "GB"  // 10^9 bytes, as hardware datasheets and most operators' spreadsheets
+)
+
+// Bytes in a gigabyte of each size unit.
+var (
+	bytesPerGiB  = sdkmath.LegacyNewDec(1024 * 1024 * 1024)
+	bytesPerSIGB = sdkmath.LegacyNewDec(1000 * 1000 * 1000)
+)
+
+// ParseSizeUnit validates a size unit, defaulting to GiB. Units are matched case-insensitively.
+func ParseSizeUnit(unit string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(unit)) {
+	case "", "gib", "gi":
+		return SizeUnitGiB, nil
+	case "gb", "g":
+		return SizeUnitGB, nil
+	}
+	return "", fmt.Errorf("invalid PRICE_TARGET_SIZE_UNIT %q: must be GiB or GB", unit)
+}
+
+// sizeUnit returns the size unit of PRICE_TARGET_SIZE_UNIT. Invalid units fall back to GiB here, since
+// LoadPriceTargets rejects them before any request is priced.
+func sizeUnit() string {
+	unit, err := ParseSizeUnit(os.Getenv("PRICE_TARGET_SIZE_UNIT"))
+	if err != nil {
+		return SizeUnitGiB
+	}
+	return unit
+}
+
+// bytesPerUnit returns the bytes in one gigabyte of a size unit.
+func bytesPerUnit(unit string) sdkmath.LegacyDec {
+	if unit == SizeUnitGB {
+		return bytesPerSIGB
+	}
+	return bytesPerGiB
+}
+
+// FormatSize formats a memory or storage quantity in a size unit, switching to MiB or MB below one
+// gigabyte so small requests read as requested, e.g. "512.000 MiB" rather than "0.500 GiB".
+func FormatSize(size sdkmath.LegacyDec, unit string) string {
+	if unit == "" {
+		unit = SizeUnitGiB
+	}
+	if size.IsNil() || size.IsZero() || size.GTE(sdkmath.LegacyOneDec()) {
+		return FormatDec(size, 3) + " " + unit
+	}
+	if unit == SizeUnitGB {
+		return FormatDec(size.MulInt64(1000), 3) + " MB"
+	}
+	return FormatDec(size.MulInt64(1024), 3) + " MiB"
+}
@@ -0,0 +1,313 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ValidationCheck is the outcome of one configuration check. Err is nil if the check passed.
+type ValidationCheck struct {
+	Name string
+	Err  error
+}
+
+// Validate checks the environment and configuration file the way a bid would use them, so
+// misconfigurations are caught at deploy time instead of mid-bid. Remote checks fetch the whitelist,
+// FX rate and AKT price once; the whitelist is fetched into a temporary file so the cache is untouched.
+func Validate() []ValidationCheck {
+	return append(ValidateConfig(),
+		ValidationCheck{"whitelist", validateWhitelist()},
+		ValidationCheck{"target currency", errOnly(GetUSDPerUnit(priceTargetCurrency()))},
+		ValidationCheck{"AKT price oracle", validateOracle()},
+		ValidationCheck{"cost floors", validateCostFloors()},
+		ValidationCheck{"GPU inventory", validateGPUInventory()},
+		ValidationCheck{"capacity source", validateCapacity()},
+	)
+}
+
+// ValidateConfig runs the checks of Validate that only read the environment and configuration file,
+// without contacting any remote source.
+func ValidateConfig() []ValidationCheck {
+	checks := []ValidationCheck{
+		{"price targets", validatePriceTargets()},
+		{"GPU mappings", validateGPUMappings()},
+		{"size unit", errOnly(ParseSizeUnit(os.Getenv("PRICE_TARGET_SIZE_UNIT")))},
+		{"hours per month", errOnly(HoursPerMonthFromEnv())},
+		{"block schedule", validateBlockSchedule()},
+		{"block time sample size", errOnly(BlockTimeSampleSizeFromEnv())},
+		{"request limits", errOnly(RequestLimitsFromEnv())},
+		{"bid deadline", errOnly(BidDeadlineFromEnv())},
+		{"server limits", errOnly(ServerLimitsFromEnv())},
+		{"CPU classes", errOnly(ParseCPUClassTargets(os.Getenv("PRICE_TARGET_CPU_CLASSES")))},
+		{"storage classes", validateStorageClasses()},
+		{"IP discounts", errOnly(ParseIPDiscounts(os.Getenv("PRICE_TARGET_IP_DISCOUNTS")))},
+		{"output format", errOnly(OutputFormatFromEnv())},
+		{"display format", errOnly(MoneyFormatFromEnv())},
+		{"bid script compatibility mode", errOnly(LegacyEnvMode())},
+		{"webhook", errOnly(NewWebhookConfigFromEnv())},
+		{"shadow price targets", errOnly(ShadowTargetsFromEnv())},
+		{"AKT price staleness", errOnly(AKTPriceMaxStaleness())},
+		{"bid pin window", errOnly(BidPinWindowFromEnv())},
+		{"strict configuration", errOnly(ConfigStrict())},
+	}
+
+	config, err := LoadConfig()
+	checks = append(checks, ValidationCheck{"config file and denom registry", err})
+	if err == nil {
+		checks = append(checks, ValidationCheck{"profile and region GPU mappings", validateConfigTargets(config)})
+	}
+
+	checks = append(checks,
+		ValidationCheck{"bid guards", errOnly(NewBidGuardsFromEnv())},
+		ValidationCheck{"bid shading", errOnly(NewShadingStrategyFromEnv())},
+		ValidationCheck{"bid jitter", errOnly(BidJitterFromEnv())},
+		ValidationCheck{"surge tiers", errOnly(ParseSurgeTiers(os.Getenv("SURGE_TIERS")))},
+		ValidationCheck{"volume discounts", errOnly(ParseVolumeDiscounts(os.Getenv("VOLUME_DISCOUNTS")))},
+		ValidationCheck{"duration tiers", errOnly(ParseDurationTiers(os.Getenv("DURATION_TIERS")))},
+		ValidationCheck{"reputation bands", errOnly(ParseReputationBands(os.Getenv("REPUTATION_BANDS")))},
+		ValidationCheck{"trial discount", validateTrialDiscount()},
+		ValidationCheck{"loyalty tiers", errOnly(LoyaltyTiersFromEnv())},
+		ValidationCheck{"renewal policy", errOnly(RenewalPolicyFromEnv())},
+		ValidationCheck{"owner exposure cap", errOnly(OwnerExposureCapFromEnv())},
+		ValidationCheck{"pricing strategy", errOnly(NewPriceStrategyFromEnv())},
+		ValidationCheck{"GPU inventory source", errOnly(NewGPUInventoryProviderFromEnv())},
+		ValidationCheck{"order event listener", errOnly(OrderEventListenerFromEnv(nil))},
+	)
+	return checks
+}
+
+// validateBlockSchedule checks the days per month and the configured block rate, without measuring the
+// block time.
+func validateBlockSchedule() error {
+	days, err := DaysPerMonthFromEnv()
+	if err != nil {
+		return err
+	}
+	_, err = configuredBlockTime(days)
+	return err
+}
+
+// errOnly discards the value of a (value, error) pair.
+func errOnly(_ interface{}, err error) error {
+	return err
+}
+
+// validatePriceTargets checks the numeric targets parse and are not negative, and CPU and memory are non-zero.
+// Out-of-range targets are reported even when PRICE_TARGET_OUT_OF_RANGE=clamp, since the clamped values
+// are unlikely to be intended.
+func validatePriceTargets() error {
+	problems := targetRangeProblems()
+	if _, err := TargetRangeMode(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateTrialDiscount checks the trial discount settings and, if a discount is configured, that the bid
+// history it looks up earlier leases in can be opened.
+func validateTrialDiscount() error {
+	trial, err := TrialDiscountFromEnv()
+	if err != nil || trial == nil {
+		return err
+	}
+	return errOnly(openBidHistoryFromEnv())
+}
+
+// validateGPUMappings checks PRICE_TARGET_GPU_MAPPINGS parses and has no negative prices.
+func validateGPUMappings() error {
+	return validateGPUMappingString(os.Getenv("PRICE_TARGET_GPU_MAPPINGS"))
+}
+
+// validateGPUMappingString checks a GPU mapping parses and has no negative prices.
+func validateGPUMappingString(mappingStr string) error {
+	mappings, err := ParseGPUPriceMappings(mappingStr)
+	if err != nil {
+		return err
+	}
+	for key, price := range mappings {
+		if price < 0 {
+			return fmt.Errorf("GPU price for %s is negative", key)
+		}
+	}
+	return nil
+}
+
+// validateStorageClasses checks the storage class and performance tier targets and the unknown class behavior.
+func validateStorageClasses() error {
+	if _, err := ParseStorageClassTargets(os.Getenv("PRICE_TARGET_STORAGE_CLASSES")); err != nil {
+		return err
+	}
+	for _, envVar := range []string{"PRICE_TARGET_STORAGE_IOPS", "PRICE_TARGET_STORAGE_THROUGHPUT"} {
+		if _, err := ParseStorageTierTargets(os.Getenv(envVar)); err != nil {
+			return fmt.Errorf("%s: %w", envVar, err)
+		}
+	}
+	_, err := ParseUnknownStorageClass(os.Getenv("STORAGE_CLASS_UNKNOWN"))
+	return err
+}
+
+// validateConfigTargets checks the GPU mappings of every profile and region.
+func validateConfigTargets(config *Config) error {
+	for name, profile := range config.Profiles {
+		if err := validateGPUMappingString(profile.Targets.GPUMappings); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+	for region, targets := range config.Regions {
+		if err := validateGPUMappingString(targets.GPUMappings); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+// validateCostFloors checks the base targets and those of every profile and region are not below the cost
+// floors of the configured cost model. Targets in another currency are converted at the current FX rate.
+func validateCostFloors() error {
+	config, err := LoadConfig()
+	if err != nil || config.CostModel == nil {
+		return nil // Reported by the config file check
+	}
+	base, err := LoadPriceTargets()
+	if err != nil {
+		return nil // Reported by the price targets check
+	}
+	floors := config.CostModel.Floors(base)
+
+	targetSets := map[string]PriceTargets{"": base}
+	for name, profile := range config.Profiles {
+		targetSets["profile "+name+": "] = profile.Targets.ApplyTo(base)
+	}
+	for region, targets := range config.Regions {
+		targetSets["region "+region+": "] = targets.ApplyTo(base)
+	}
+	var problems []string
+	for prefix, targets := range targetSets {
+		usdPerUnit, err := GetUSDPerUnit(targets.Currency)
+		if err != nil {
+			return fmt.Errorf("%serror getting %s/USD rate: %v", prefix, targets.Currency, err)
+		}
+		for _, problem := range floors.TargetProblems(targets, usdPerUnit) {
+			problems = append(problems, prefix+problem)
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateGPUInventory checks the cluster's GPU inventory can be discovered and every model in it has a
+// PRICE_TARGET_GPU_MAPPINGS price, so GPUs the provider has are never bid at the maximum price by accident.
+// Kubernetes discovery bypasses the cache.
+func validateGPUInventory() error {
+	provider, err := NewGPUInventoryProviderFromEnv()
+	if err != nil || provider == nil {
+		return nil // Reported by the GPU inventory source check
+	}
+	var inventory GPUInventory
+	if k8s, ok := provider.(*KubernetesGPUInventory); ok {
+		inventory, err = k8s.Discover()
+	} else {
+		inventory, err = provider.GPUInventory()
+	}
+	if err != nil {
+		return err
+	}
+	if len(inventory) == 0 {
+		return fmt.Errorf("no GPUs found")
+	}
+	gpuMappings, err := ParseGPUPriceMappings(os.Getenv("PRICE_TARGET_GPU_MAPPINGS"))
+	if err != nil {
+		return nil // Reported by the GPU mappings check
+	}
+
+	var unpriced []string
+	for model := range inventory {
+		priced := false
+		for key := range gpuMappings {
+			if key == model || strings.HasPrefix(key, model+".") {
+				priced = true
+				break
+			}
+		}
+		if !priced {
+			unpriced = append(unpriced, model)
+		}
+	}
+	if len(unpriced) > 0 {
+		sort.Strings(unpriced)
+		return fmt.Errorf("cluster GPUs without a PRICE_TARGET_GPU_MAPPINGS price: %s (found %s)", strings.Join(unpriced, ", "), inventory)
+	}
+	return nil
+}
+
+// validateCapacity checks the CAPACITY_URL or CAPACITY_FILE capacity can be read and is valid. The capacity
+// section of the configuration file is checked with the file.
+func validateCapacity() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil // Reported by the config file check
+	}
+	provider := NewCapacityProviderFromEnv(config)
+	if provider == nil {
+		return nil
+	}
+	return errOnly(provider.Capacity())
+}
+
+// validateWhitelist checks the configured whitelist source is reachable and parses.
+func validateWhitelist() error {
+	chainWhitelist, err := NewChainWhitelistFromEnv()
+	if err != nil || chainWhitelist != nil {
+		return err
+	}
+
+	whitelistURL := strings.Trim(os.Getenv("WHITELIST_URL"), "\"")
+	if whitelistURL == "" {
+		return nil
+	}
+	if localPath, ok := localWhitelistPath(whitelistURL); ok {
+		data, err := ioutil.ReadFile(localPath)
+		if err != nil {
+			return err
+		}
+		_, err = parseWhitelist(data, detectWhitelistFormat("", whitelistURL))
+		return err
+	}
+	if u, err := url.Parse(whitelistURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("WHITELIST_URL %q is not an absolute URL", whitelistURL)
+	}
+
+	tmp, err := ioutil.TempFile("", "whitelist-validate-*")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	defer os.Remove(tmp.Name() + ".meta")
+	os.Remove(tmp.Name()) // fetchWhitelist only sends conditional headers for an existing file
+
+	return fetchWhitelist(context.Background(), whitelistURL, tmp.Name())
+}
+
+// validateOracle checks an AKT price can be obtained.
+func validateOracle() error {
+	price, err := GetAKTPrice()
+	if err != nil {
+		return err
+	}
+	if price <= 0 {
+		return fmt.Errorf("oracle returned invalid AKT price %f", price)
+	}
+	return nil
+}
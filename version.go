@@ -0,0 +1,108 @@
+package pricing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Version is the release of the pricing script, set at build time with
+//
+//	go build -ldflags "-X github.com/akash-network/pricing-script.Version=v1.4.0" ./cmd/pricing-tool
+var Version string
+
+// configHashExcludedPrefixes are the fixtureEnvPrefixes of variables that never change a bid, so setting up
+// auditing, history or notifications does not change the configuration hash.
+var configHashExcludedPrefixes = []string{"AUDIT_", "BID_HISTORY_", "SHADOW_", "WEBHOOK_"}
+
+var buildVersion struct {
+	once    sync.Once
+	version string
+}
+
+// BuildVersion returns Version, or for builds without it the module version or VCS revision recorded by the
+// Go toolchain, e.g. "dev-3f2a9c1b0d4e-dirty", or "dev".
+func BuildVersion() string {
+	buildVersion.once.Do(func() {
+		buildVersion.version = readBuildVersion()
+	})
+	return buildVersion.version
+}
+
+// readBuildVersion works out the build version from Version and the binary's build info.
+func readBuildVersion() string {
+	if Version != "" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	if revision == "" {
+		return "dev"
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified == "true" {
+		return "dev-" + revision + "-dirty"
+	}
+	return "dev-" + revision
+}
+
+// ConfigHash returns "sha256:" and the hex SHA-256 of the pricing environment variables, sorted, and the
+// PRICING_CONFIG file, so two bids priced under the same configuration have the same hash.
+func ConfigHash() string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if configHashVariable(kv) {
+			env = append(env, kv)
+		}
+	}
+	sort.Strings(env)
+
+	hash := sha256.New()
+	for _, kv := range env {
+		hash.Write([]byte(kv))
+		hash.Write([]byte{0})
+	}
+	if path := os.Getenv("PRICING_CONFIG"); path != "" {
+		if data, err := ioutil.ReadFile(path); err == nil {
+			hash.Write(data)
+		}
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// configHashVariable reports whether the "KEY=value" environment entry is part of the pricing configuration.
+func configHashVariable(kv string) bool {
+	for _, prefix := range configHashExcludedPrefixes {
+		if strings.HasPrefix(kv, prefix) {
+			return false
+		}
+	}
+	for _, prefix := range fixtureEnvPrefixes {
+		if strings.HasPrefix(kv, prefix) {
+			return true
+		}
+	}
+	return false
+}
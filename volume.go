@@ -0,0 +1,101 @@
+package pricing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// Volume discount metrics.
+const (
+	VolumeMetricUSD    = "usd"    // Monthly cost in USD
+	VolumeMetricCPU    = "cpu"    // CPU cores
+	VolumeMetricMemory = "memory" // Memory in GB
+	VolumeMetricGPU    = "gpu"    // GPU units
+)
+
+// VolumeDiscount takes Percent off the monthly cost once Metric reaches Min.
+type VolumeDiscount struct {
+	Metric  string
+	Min     float64
+	Percent float64
+}
+
+// ParseVolumeDiscounts parses a discount table of the form "usd:500=5,usd:2000=10,cpu:100=3",
+// where each entry is metric:minimum=percent.
+func ParseVolumeDiscounts(discountsStr string) ([]VolumeDiscount, error) {
+	var discounts []VolumeDiscount
+	if discountsStr == "" {
+		return discounts, nil
+	}
+
+	for _, pair := range strings.Split(discountsStr, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.Split(pair, "=")
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid volume discount: %s", pair)
+		}
+
+		threshold := strings.Split(kv[0], ":")
+		if len(threshold) != 2 {
+			return nil, fmt.Errorf("invalid volume discount threshold: %s", kv[0])
+		}
+		metric := strings.ToLower(strings.TrimSpace(threshold[0]))
+		switch metric {
+		case VolumeMetricUSD, VolumeMetricCPU, VolumeMetricMemory, VolumeMetricGPU:
+		default:
+			return nil, fmt.Errorf("unknown volume discount metric: %s", threshold[0])
+		}
+		min, err := strconv.ParseFloat(threshold[1], 64)
+		if err != nil || min < 0 {
+			return nil, fmt.Errorf("invalid volume discount minimum: %s", kv[0])
+		}
+		percent, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid volume discount percent for %s: %s", kv[0], kv[1])
+		}
+
+		discounts = append(discounts, VolumeDiscount{Metric: metric, Min: min, Percent: percent})
+	}
+
+	return discounts, nil
+}
+
+// ApplyVolumeDiscount applies the largest discount whose threshold the request reaches. Discounts do not
+// stack. The returned adjustment is nil if no discount applies.
+func ApplyVolumeDiscount(discounts []VolumeDiscount, resourceRequests ResourceRequests, totalCostUsd sdkmath.LegacyDec) (sdkmath.LegacyDec, *Adjustment) {
+	var best *VolumeDiscount
+	for i, discount := range discounts {
+		var quantity sdkmath.LegacyDec
+		switch discount.Metric {
+		case VolumeMetricUSD:
+			quantity = totalCostUsd
+		case VolumeMetricCPU:
+			quantity = resourceRequests.CPURequested
+		case VolumeMetricMemory:
+			quantity = resourceRequests.MemoryRequested
+		case VolumeMetricGPU:
+			quantity = sdkmath.LegacyNewDec(resourceRequests.GPUsRequested)
+		}
+		if quantity.IsNil() || quantity.LT(decFromFloat(discount.Min)) {
+			continue
+		}
+		if best == nil || discount.Percent > best.Percent {
+			best = &discounts[i]
+		}
+	}
+
+	if best == nil || best.Percent == 0 {
+		return totalCostUsd, nil
+	}
+
+	discounted := totalCostUsd.Sub(totalCostUsd.Mul(decFromFloat(best.Percent)).QuoInt64(100))
+	return discounted, &Adjustment{
+		Name:   "volume_discount",
+		Detail: fmt.Sprintf("%g%% volume discount for %s >= %g", best.Percent, best.Metric, best.Min),
+	}
+}
@@ -0,0 +1,39 @@
+package pricing
+
+import (
+	"testing"
+)
+
+// TestVolumeDiscountBid prices the cpu-only fixture, 2 CPUs and 4 GiB of memory in a 6.85 USD month, against
+// VOLUME_DISCOUNTS tables around its tier edges.
+func TestVolumeDiscountBid(t *testing.T) {
+	request := fixtureRequest(t, "cpu-only")
+	tests := []struct {
+		discounts string
+		totalCost string
+		price     string
+		detail    string
+	}{
+		{"usd:6.86=10", "6.850000", "4.552452", ""},
+		{"usd:6.85=10", "6.165000", "4.097207", "10% volume discount for usd >= 6.85"},
+		{"cpu:2=5,cpu:3=20", "6.507500", "4.324830", "5% volume discount for cpu >= 2"},
+		{"usd:1=5,memory:4=20", "5.480000", "3.641962", "20% volume discount for memory >= 4"},
+		{"cpu:2=0", "6.850000", "4.552452", ""},
+	}
+	for _, tt := range tests {
+		result, err := priceWithEnv(request, map[string]string{"VOLUME_DISCOUNTS": tt.discounts})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.discounts, err)
+		}
+		if got := FormatDec(result.TotalCostUsd, 6); got != tt.totalCost || result.Price != tt.price {
+			t.Errorf("%s: got %s USD at %s, want %s USD at %s", tt.discounts, got, result.Price, tt.totalCost, tt.price)
+		}
+		adjustment := findAdjustment(result, "volume_discount")
+		switch {
+		case tt.detail == "" && adjustment != nil:
+			t.Errorf("%s: got adjustment %q, want none", tt.discounts, adjustment.Detail)
+		case tt.detail != "" && (adjustment == nil || adjustment.Detail != tt.detail):
+			t.Errorf("%s: got adjustment %v, want %q", tt.discounts, adjustment, tt.detail)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Warm-up states of a PricingEngine.
+const (
+	warmUpNotStarted = iota
+	warmUpRunning
+	warmUpDone
+)
+
+// WarmUpReport is the outcome of the engine's last Start.
+type WarmUpReport struct {
+	Running  bool           // Start has not finished loading every source
+	Duration time.Duration  // How long the warm-up took, zero while it runs
+	Sources  []CacheRefresh // One per configured source, in warm-up order; empty while it runs
+}
+
+// warmUpSource loads one data source into the caches bids read.
+type warmUpSource struct {
+	name string
+	load func(e *PricingEngine) error
+}
+
+// warmUpSources returns the sources Start loads, skipping those that are not configured.
+func warmUpSources() []warmUpSource {
+	sources := []warmUpSource{
+		// The config file resolves denom exponents from CHAIN_GRPC when it is loaded
+		{"config file and denom registry", func(*PricingEngine) error { return errOnly(LoadConfig()) }},
+		{"AKT price", func(e *PricingEngine) error { return errOnly(e.AKTPrice()) }},
+		{"block schedule", func(*PricingEngine) error { return errOnly(BlockScheduleFromEnv()) }},
+	}
+
+	if currency := priceTargetCurrency(); currency != DefaultPriceTargetCurrency {
+		sources = append(sources, warmUpSource{currency + "/USD rate", func(*PricingEngine) error {
+			return errOnly(GetUSDPerUnit(currency))
+		}})
+	}
+	if chainWhitelist, err := NewChainWhitelistFromEnv(); err != nil || chainWhitelist != nil {
+		// On-chain whitelists are queried per owner, only their configuration can be checked ahead of time
+		sources = append(sources, warmUpSource{"on-chain whitelist", func(*PricingEngine) error { return err }})
+	} else if whitelistURL := strings.Trim(os.Getenv("WHITELIST_URL"), "\""); whitelistURL != "" {
+		sources = append(sources, warmUpSource{"whitelist", func(*PricingEngine) error {
+			if localPath, ok := localWhitelistPath(whitelistURL); ok {
+				_, err := os.Stat(localPath)
+				return err
+			}
+			return refreshWhitelist(context.Background(), whitelistURL, whitelistCacheFile(whitelistURL), DefaultWhitelistTTL)
+		}})
+	}
+	if inventoryProvider, err := NewGPUInventoryProviderFromEnv(); err != nil || inventoryProvider != nil {
+		sources = append(sources, warmUpSource{"GPU inventory", func(*PricingEngine) error {
+			if err != nil {
+				return err
+			}
+			return errOnly(inventoryProvider.GPUInventory())
+		}})
+	}
+	return sources
+}
+
+// Start warms the caches bids read before the engine is relied on: the configuration and the chain's denom
+// metadata, the AKT price, the block schedule, the target currency's USD rate, the whitelist and the GPU
+// inventory. The sources are loaded concurrently through the same paths bids use, so fresh cache files are
+// reused rather than fetched again. A PricingServer reports not ready while Start runs.
+//
+// Start returns an error naming the sources that failed; the engine still prices requests, loading them
+// again per bid. If ctx is done first it returns ctx.Err(), and the remaining loads finish in the background.
+func (e *PricingEngine) Start(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	e.warmMu.Lock()
+	if e.warmState == warmUpRunning {
+		e.warmMu.Unlock()
+		return fmt.Errorf("warm-up already running")
+	}
+	e.warmState, e.warmUp = warmUpRunning, WarmUpReport{Running: true}
+	e.warmMu.Unlock()
+
+	start := time.Now()
+	sources := warmUpSources()
+	done := make(chan []CacheRefresh, 1)
+	go func() {
+		results := make([]CacheRefresh, len(sources))
+		var wg sync.WaitGroup
+		for i, source := range sources {
+			wg.Add(1)
+			go func(i int, source warmUpSource) {
+				defer wg.Done()
+				results[i] = CacheRefresh{Name: source.name, Err: source.load(e)}
+			}(i, source)
+		}
+		wg.Wait()
+
+		e.warmMu.Lock()
+		e.warmState, e.warmUp = warmUpDone, WarmUpReport{Duration: time.Since(start), Sources: results}
+		e.warmMu.Unlock()
+		done <- results
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case results := <-done:
+		var failed []string
+		for _, result := range results {
+			if result.Err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", result.Name, result.Err))
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("warm-up failed for %s", strings.Join(failed, "; "))
+		}
+		return nil
+	}
+}
+
+// WarmUp reports the progress or outcome of the engine's last Start, and false if Start was never called.
+func (e *PricingEngine) WarmUp() (WarmUpReport, bool) {
+	e.warmMu.Lock()
+	defer e.warmMu.Unlock()
+	return e.warmUp, e.warmState != warmUpNotStarted
+}
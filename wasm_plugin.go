@@ -0,0 +1,138 @@
+//go:build wazero
+
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// WASMStrategy runs a WebAssembly module implementing the pricing strategy host API. The module is compiled
+// once and instantiated per call in a fresh sandbox, so a strategy can't keep state between bids and has
+// no filesystem or network access.
+//
+// The module must export:
+//   - memory
+//   - alloc(size i32) i32: returns a buffer of size bytes for the input
+//   - adjust_price(ptr i32, len i32) i64: reads a StrategyInput JSON document from the buffer and returns
+//     the location of a StrategyOutput JSON document packed as ptr<<32 | len
+type WASMStrategy struct {
+	Path    string
+	Timeout time.Duration
+
+	once        sync.Once
+	runtime     wazero.Runtime
+	compiled    wazero.CompiledModule
+	initErr     error
+	initialized bool // Set when runtime and compiled were shared from an already compiled strategy
+}
+
+// wasmStrategies holds the compiled strategy per module path so each bid reuses the compilation.
+var wasmStrategies sync.Map
+
+// NewWASMStrategy returns the strategy for the module at path, compiling it on first use.
+func NewWASMStrategy(path string, timeout time.Duration) (PriceStrategy, error) {
+	cached, _ := wasmStrategies.LoadOrStore(path, &WASMStrategy{Path: path})
+	compiled := cached.(*WASMStrategy)
+	if err := compiled.init(); err != nil {
+		wasmStrategies.Delete(path)
+		return nil, err
+	}
+	// Share the compiled module; only the timeout differs between callers
+	return &WASMStrategy{Path: path, Timeout: timeout, runtime: compiled.runtime, compiled: compiled.compiled, initialized: true}, nil
+}
+
+// init compiles the module once.
+func (s *WASMStrategy) init() error {
+	if s.initialized {
+		return nil
+	}
+	s.once.Do(func() {
+		code, err := ioutil.ReadFile(s.Path)
+		if err != nil {
+			s.initErr = fmt.Errorf("error reading WASM strategy %s: %w", s.Path, err)
+			return
+		}
+
+		ctx := context.Background()
+		s.runtime = wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+		if _, err := wasi_snapshot_preview1.Instantiate(ctx, s.runtime); err != nil {
+			s.initErr = fmt.Errorf("error instantiating WASI: %w", err)
+			return
+		}
+
+		s.compiled, err = s.runtime.CompileModule(ctx, code)
+		if err != nil {
+			s.initErr = fmt.Errorf("error compiling WASM strategy %s: %w", s.Path, err)
+			return
+		}
+		for _, name := range []string{"alloc", "adjust_price"} {
+			if _, ok := s.compiled.ExportedFunctions()[name]; !ok {
+				s.initErr = fmt.Errorf("WASM strategy %s does not export %s", s.Path, name)
+				return
+			}
+		}
+	})
+	return s.initErr
+}
+
+// Adjust instantiates the module and calls adjust_price with the breakdown.
+func (s *WASMStrategy) Adjust(input StrategyInput) (StrategyOutput, error) {
+	if err := s.init(); err != nil {
+		return StrategyOutput{}, err
+	}
+
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return StrategyOutput{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), strategyTimeout(s.Timeout))
+	defer cancel()
+
+	// An empty name lets the same compiled module be instantiated concurrently
+	mod, err := s.runtime.InstantiateModule(ctx, s.compiled, wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		return StrategyOutput{}, fmt.Errorf("error instantiating WASM strategy %s: %w", s.Path, err)
+	}
+	defer mod.Close(ctx)
+
+	// Reactor modules built for WASI need their initializer called before any export
+	if initialize := mod.ExportedFunction("_initialize"); initialize != nil {
+		if _, err := initialize.Call(ctx); err != nil {
+			return StrategyOutput{}, fmt.Errorf("error initializing WASM strategy %s: %w", s.Path, err)
+		}
+	}
+
+	results, err := mod.ExportedFunction("alloc").Call(ctx, uint64(len(payload)))
+	if err != nil {
+		return StrategyOutput{}, fmt.Errorf("WASM strategy %s alloc failed: %w", s.Path, err)
+	}
+	inPtr := uint32(results[0])
+	if !mod.Memory().Write(inPtr, payload) {
+		return StrategyOutput{}, fmt.Errorf("WASM strategy %s returned an out of range input buffer", s.Path)
+	}
+
+	results, err = mod.ExportedFunction("adjust_price").Call(ctx, uint64(inPtr), uint64(len(payload)))
+	if err != nil {
+		return StrategyOutput{}, fmt.Errorf("WASM strategy %s adjust_price failed: %w", s.Path, err)
+	}
+	outPtr, outLen := uint32(results[0]>>32), uint32(results[0])
+	out, ok := mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return StrategyOutput{}, fmt.Errorf("WASM strategy %s returned an out of range output buffer", s.Path)
+	}
+
+	var output StrategyOutput
+	if err := json.Unmarshal(out, &output); err != nil {
+		return StrategyOutput{}, fmt.Errorf("invalid output from WASM strategy %s: %w", s.Path, err)
+	}
+	return output, nil
+}
@@ -0,0 +1,13 @@
+//go:build !wazero
+
+package pricing
+
+import (
+	"fmt"
+	"time"
+)
+
+// NewWASMStrategy reports that WASM strategies need a build with the wazero tag.
+func NewWASMStrategy(path string, timeout time.Duration) (PriceStrategy, error) {
+	return nil, fmt.Errorf("WASM strategy %s requires a build with -tags wazero", path)
+}
@@ -0,0 +1,307 @@
+package pricing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook event types, enabled individually with WEBHOOK_EVENTS.
+const (
+	EventOracleFailover    = "oracle_failover"           // A price oracle failed and a fallback source or stale rate was used
+	EventWhitelistSpike    = "whitelist_rejection_spike" // Many owners were rejected by the whitelist in a short window
+	EventBidAboveThreshold = "bid_above_threshold"       // A bid's monthly cost exceeded WEBHOOK_BID_USD_THRESHOLD
+	EventGuardTriggered    = "guard_triggered"           // A bid floor or ceiling guard changed or rejected a bid
+)
+
+// Webhook payload formats.
+const (
+	WebhookFormatJSON    = "json"    // The WebhookEvent itself
+	WebhookFormatSlack   = "slack"   // Slack incoming webhook message
+	WebhookFormatDiscord = "discord" // Discord webhook message
+)
+
+// Webhook defaults.
+const (
+	DefaultWebhookCooldown     = 10 * time.Minute
+	DefaultWebhookSpikeCount   = 10
+	DefaultWebhookSpikeWindow  = 5 * time.Minute
+	DefaultWebhookStateDir     = "/tmp"
+	webhookTimeout             = 3 * time.Second
+	whitelistRejectionsFile    = "price-script.whitelist-rejections"
+	webhookCooldownFilePattern = "price-script.webhook-%s.sent"
+)
+
+// webhookEvents are the event types sent when WEBHOOK_EVENTS is unset.
+var webhookEvents = []string{EventOracleFailover, EventWhitelistSpike, EventBidAboveThreshold, EventGuardTriggered}
+
+// WebhookEvent is a notable pricing event sent to the configured webhook.
+type WebhookEvent struct {
+	Type    string            `json:"type"`
+	Time    time.Time         `json:"time"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// WebhookConfig configures pricing event notifications. Each event type is sent at most once per Cooldown,
+// tracked in files under StateDir so separate bid script runs share it.
+type WebhookConfig struct {
+	URL                  string
+	Format               string          // WebhookFormatJSON, WebhookFormatSlack or WebhookFormatDiscord
+	Events               map[string]bool // Enabled event types
+	BidUsdThreshold      float64         // Monthly USD cost above which EventBidAboveThreshold fires, 0 disables it
+	WhitelistSpikeCount  int             // Whitelist rejections within WhitelistSpikeWindow that fire EventWhitelistSpike
+	WhitelistSpikeWindow time.Duration
+	Cooldown             time.Duration
+	StateDir             string
+}
+
+// NewWebhookConfigFromEnv reads WEBHOOK_URL, WEBHOOK_FORMAT, WEBHOOK_EVENTS, WEBHOOK_BID_USD_THRESHOLD,
+// WEBHOOK_WHITELIST_SPIKE ("count/window", e.g. "10/5m") and WEBHOOK_COOLDOWN. It returns nil when
+// WEBHOOK_URL is unset.
+func NewWebhookConfigFromEnv() (*WebhookConfig, error) {
+	webhookURL := strings.TrimSpace(os.Getenv("WEBHOOK_URL"))
+	if webhookURL == "" {
+		return nil, nil
+	}
+
+	config := &WebhookConfig{
+		URL:                  webhookURL,
+		Events:               make(map[string]bool),
+		WhitelistSpikeCount:  DefaultWebhookSpikeCount,
+		WhitelistSpikeWindow: DefaultWebhookSpikeWindow,
+		Cooldown:             DefaultWebhookCooldown,
+		StateDir:             DefaultWebhookStateDir,
+	}
+
+	format, err := ParseWebhookFormat(os.Getenv("WEBHOOK_FORMAT"), webhookURL)
+	if err != nil {
+		return nil, err
+	}
+	config.Format = format
+
+	events := webhookEvents
+	if val := strings.TrimSpace(os.Getenv("WEBHOOK_EVENTS")); val != "" {
+		events = strings.Split(val, ",")
+	}
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !slices.Contains(webhookEvents, event) {
+			return nil, fmt.Errorf("invalid WEBHOOK_EVENTS: unknown event %q", event)
+		}
+		config.Events[event] = true
+	}
+
+	if val := strings.TrimSpace(os.Getenv("WEBHOOK_BID_USD_THRESHOLD")); val != "" {
+		threshold, err := strconv.ParseFloat(val, 64)
+		if err != nil || threshold < 0 || checkDecFloat(threshold) != nil {
+			return nil, fmt.Errorf("invalid WEBHOOK_BID_USD_THRESHOLD: %q", val)
+		}
+		config.BidUsdThreshold = threshold
+	}
+
+	if val := strings.TrimSpace(os.Getenv("WEBHOOK_WHITELIST_SPIKE")); val != "" {
+		count, window, ok := strings.Cut(val, "/")
+		n, countErr := strconv.Atoi(count)
+		d, windowErr := time.ParseDuration(window)
+		if !ok || countErr != nil || windowErr != nil || n < 1 || d <= 0 {
+			return nil, fmt.Errorf("invalid WEBHOOK_WHITELIST_SPIKE %q: must be count/window, e.g. 10/5m", val)
+		}
+		config.WhitelistSpikeCount, config.WhitelistSpikeWindow = n, d
+	}
+
+	if val := strings.TrimSpace(os.Getenv("WEBHOOK_COOLDOWN")); val != "" {
+		cooldown, err := time.ParseDuration(val)
+		if err != nil || cooldown < 0 {
+			return nil, fmt.Errorf("invalid WEBHOOK_COOLDOWN: %q", val)
+		}
+		config.Cooldown = cooldown
+	}
+
+	return config, nil
+}
+
+// ParseWebhookFormat validates a webhook format. An empty format is detected from the URL: Slack and
+// Discord webhook URLs get their message format, anything else the JSON event.
+func ParseWebhookFormat(format, webhookURL string) (string, error) {
+	switch format {
+	case WebhookFormatJSON, WebhookFormatSlack, WebhookFormatDiscord:
+		return format, nil
+	case "":
+		switch {
+		case strings.Contains(webhookURL, "hooks.slack.com"):
+			return WebhookFormatSlack, nil
+		case strings.Contains(webhookURL, "discord.com/api/webhooks"), strings.Contains(webhookURL, "discordapp.com/api/webhooks"):
+			return WebhookFormatDiscord, nil
+		}
+		return WebhookFormatJSON, nil
+	}
+	return "", fmt.Errorf("invalid WEBHOOK_FORMAT %q: must be json, slack or discord", format)
+}
+
+// Notify sends the event unless its type is disabled or was sent within the cooldown.
+func (c *WebhookConfig) Notify(event WebhookEvent) error {
+	if !c.Events[event.Type] {
+		return nil
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	cooldownFile := filepath.Join(c.StateDir, fmt.Sprintf(webhookCooldownFilePattern, event.Type))
+	unlock := lockCache(cooldownFile)
+	defer unlock()
+	if info, err := os.Stat(cooldownFile); err == nil && time.Since(info.ModTime()) < c.Cooldown {
+		log.Printf("Webhook event %s suppressed, last sent %s", event.Type, info.ModTime().Format(time.RFC3339))
+		return nil
+	}
+
+	payload, err := c.payload(event)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(c.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("error sending webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return writeFileAtomic(cooldownFile, []byte(event.Time.Format(time.RFC3339)))
+}
+
+// payload encodes the event in the configured format.
+func (c *WebhookConfig) payload(event WebhookEvent) ([]byte, error) {
+	switch c.Format {
+	case WebhookFormatSlack:
+		return json.Marshal(map[string]string{"text": event.text()})
+	case WebhookFormatDiscord:
+		return json.Marshal(map[string]string{"content": event.text()})
+	}
+	return json.Marshal(event)
+}
+
+// text renders the event as a chat message, with its fields sorted by name.
+func (e WebhookEvent) text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Akash pricing %s: %s", e.Type, e.Message)
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n%s: %s", name, e.Fields[name])
+	}
+	return b.String()
+}
+
+// recordWhitelistRejection counts a whitelist rejection and reports whether the count within the spike window
+// reached the spike threshold. Rejection times are kept in a state file shared by bid script runs.
+func (c *WebhookConfig) recordWhitelistRejection(now time.Time) (int, bool) {
+	stateFile := filepath.Join(c.StateDir, whitelistRejectionsFile)
+	unlock := lockCache(stateFile)
+	defer unlock()
+
+	var times []int64
+	if data, err := ioutil.ReadFile(stateFile); err == nil {
+		json.Unmarshal(data, &times)
+	}
+	recent := times[:0]
+	for _, t := range times {
+		if now.Sub(time.Unix(t, 0)) < c.WhitelistSpikeWindow {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now.Unix())
+
+	data, _ := json.Marshal(recent)
+	if err := writeFileAtomic(stateFile, data); err != nil {
+		log.Printf("Error recording whitelist rejection: %v", err)
+	}
+	return len(recent), len(recent) >= c.WhitelistSpikeCount
+}
+
+// notifyEvent sends an event to the webhook configured in the environment, if any. Failures are logged and
+// never affect pricing.
+func notifyEvent(event WebhookEvent) {
+	config, err := NewWebhookConfigFromEnv()
+	if err != nil {
+		log.Printf("Error reading webhook configuration: %v", err)
+		return
+	}
+	if config == nil {
+		return
+	}
+	if err := config.Notify(event); err != nil {
+		log.Printf("Error sending %s webhook: %v", event.Type, err)
+	}
+}
+
+// notifyBid sends the webhook events raised by a priced request: guard rails triggering, bids above the
+// USD threshold and whitelist rejection spikes.
+func notifyBid(request Request, result *BidResult, bidErr error) {
+	config, err := NewWebhookConfigFromEnv()
+	if err != nil {
+		log.Printf("Error reading webhook configuration: %v", err)
+		return
+	}
+	if config == nil {
+		return
+	}
+
+	var events []WebhookEvent
+	fields := map[string]string{"owner": request.Owner}
+	if request.OrderID != "" {
+		fields["order"] = request.OrderID
+	}
+
+	switch {
+	case errors.Is(bidErr, ErrBidCeiling):
+		events = append(events, WebhookEvent{Type: EventGuardTriggered, Message: bidErr.Error(), Fields: fields})
+	case errors.Is(bidErr, ErrNotWhitelisted) && config.Events[EventWhitelistSpike]:
+		if count, spike := config.recordWhitelistRejection(time.Now()); spike {
+			events = append(events, WebhookEvent{
+				Type:    EventWhitelistSpike,
+				Message: fmt.Sprintf("%d whitelist rejections in the last %s", count, config.WhitelistSpikeWindow),
+				Fields:  fields,
+			})
+		}
+	}
+
+	if result != nil {
+		fields["price"] = result.Price + result.Denom
+		for _, adjustment := range result.Adjustments {
+			if adjustment.Name == "ceiling" || adjustment.Name == "floor" {
+				events = append(events, WebhookEvent{Type: EventGuardTriggered, Message: adjustment.Detail, Fields: fields})
+			}
+		}
+		if config.BidUsdThreshold > 0 && !result.TotalCostUsd.IsNil() && result.TotalCostUsd.GT(decFromFloat(config.BidUsdThreshold)) {
+			events = append(events, WebhookEvent{
+				Type:    EventBidAboveThreshold,
+				Message: fmt.Sprintf("bid of %s USD/month exceeds %g USD/month", FormatDec(result.TotalCostUsd, 2), config.BidUsdThreshold),
+				Fields:  fields,
+			})
+		}
+	}
+
+	for _, event := range events {
+		if err := config.Notify(event); err != nil {
+			log.Printf("Error sending %s webhook: %v", event.Type, err)
+		}
+	}
+}
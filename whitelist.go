@@ -1,64 +1,245 @@
 package pricing
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"strconv"
 	"strings"
 	"time"
+
+	sdkmath "cosmossdk.io/math"
 )
 
+// WhitelistEntry represents a whitelisted owner along with optional per-owner pricing metadata.
+// Entries coming from a plain newline-delimited whitelist only carry the Owner. An Owner ending in "*", such
+// as akash1abc*, is a prefix entry matching every owner that starts with the rest. An Owner starting with "!",
+// such as !akash1abc123 or !akash1abc*, is a deny entry refusing the owners it matches whatever allows them.
+type WhitelistEntry struct {
+	Owner           string
+	MatchedBy       string    // Entry the owner was whitelisted by, empty for its own entry
+	Parent          string    // Parent account the owner was whitelisted through, empty for its own entries
+	Deny            bool      // Deny entry, never returned by a lookup
+	DiscountPercent float64   // Percentage taken off the monthly USD cost (0-100)
+	Expiry          time.Time // Zero value means the entry never expires
+	MaxMonthlySpend float64   // Maximum monthly USD cost allowed for this owner, 0 means unlimited
+}
+
+// whitelistJSONEntry is the on-disk JSON representation of a WhitelistEntry.
+type whitelistJSONEntry struct {
+	Owner           string  `json:"owner"`
+	DiscountPercent float64 `json:"discount_percent"`
+	Expiry          string  `json:"expiry"`
+	MaxMonthlySpend float64 `json:"max_monthly_spend"`
+}
+
+// specialAccounts are the owners with special pricing.
+var specialAccounts = map[string]bool{
+	"akash1fxa9ss3dg6nqyz8aluyaa6svypgprk5tw9fa4q": true,
+	"akash1fhe3uk7d95vvr69pna7cxmwa8777as46uyxcz8": true,
+}
+
 // SpecialPricing checks if the AKASH_OWNER is in a predefined list and applies special pricing if so.
 func SpecialPricing(owner string) bool {
-	specialAccounts := map[string]bool{
-		"akash1fxa9ss3dg6nqyz8aluyaa6svypgprk5tw9fa4q": true,
-		"akash1fhe3uk7d95vvr69pna7cxmwa8777as46uyxcz8": true,
-	}
 	return specialAccounts[owner]
 }
 
-// CheckWhitelist checks if the AKASH_OWNER is in the whitelist defined by the WHITELIST_URL.
+// Default whitelist cache settings. Each whitelist URL is cached in its own file named after
+// DefaultWhitelistFile, so switching WHITELIST_URL never serves the previous list.
+const (
+	DefaultWhitelistFile = "/tmp/price-script.whitelist"
+	DefaultWhitelistTTL  = 10 * time.Minute
+)
+
+// whitelistCacheFile returns the default cache file of a whitelist URL.
+func whitelistCacheFile(whitelistURL string) string {
+	sum := sha256.Sum256([]byte(whitelistURL))
+	return DefaultWhitelistFile + "-" + hex.EncodeToString(sum[:8])
+}
+
+// configuredWhitelistCacheFile returns the default cache file of WHITELIST_URL.
+func configuredWhitelistCacheFile() string {
+	return whitelistCacheFile(strings.Trim(os.Getenv("WHITELIST_URL"), "\""))
+}
+
+// WhitelistOptions configures a whitelist check. Empty fields fall back to the environment and defaults.
+type WhitelistOptions struct {
+	Owner     string            // Owner address to verify, required
+	Source    string            // Whitelist URL, defaults to WHITELIST_URL
+	TTL       time.Duration     // Lifetime of the cached whitelist, defaults to DefaultWhitelistTTL
+	CacheFile string            // Path of the cached whitelist, defaults to a file per Source named after DefaultWhitelistFile
+	Resolver  WhitelistResolver // Parent accounts of owners without an entry, defaults to WHITELIST_RESOLVER_URL
+}
+
+// CheckWhitelist checks if the owner is in the whitelist defined by the WHITELIST_URL.
 func CheckWhitelist(owner string) error {
-	whitelistURL := os.Getenv("WHITELIST_URL")
-	whitelistURL = strings.Trim(whitelistURL, "\"") // Trim any double quotes from the URL
+	_, err := LookupWhitelistEntry(owner)
+	return err
+}
+
+// LookupWhitelistEntry returns the whitelist entry for the given owner, including any pricing metadata.
+// It is shorthand for LookupWhitelist with only the owner set.
+func LookupWhitelistEntry(owner string) (*WhitelistEntry, error) {
+	return LookupWhitelist(WhitelistOptions{Owner: owner})
+}
+
+// LookupWhitelist returns the whitelist entry for opts.Owner, including any pricing metadata.
+// When no Source is given and WHITELIST_CHAIN_GRPC is set, the on-chain backend is used instead of WHITELIST_URL.
+// It returns a nil entry and no error when no whitelist is configured.
+func LookupWhitelist(opts WhitelistOptions) (*WhitelistEntry, error) {
+	return LookupWhitelistContext(context.Background(), opts)
+}
 
+// LookupWhitelistContext is LookupWhitelist bounded by ctx. Fetches, chain queries and parent account lookups
+// are cancelled with ctx, and none is started once ctx is done.
+func LookupWhitelistContext(ctx context.Context, opts WhitelistOptions) (*WhitelistEntry, error) {
+	if opts.Owner == "" {
+		return nil, fmt.Errorf("whitelist owner is not specified")
+	}
+
+	whitelistURL := strings.Trim(opts.Source, "\"")
 	if whitelistURL == "" {
-		return nil // No whitelist URL set, skip checking
+		chainWhitelist, err := NewChainWhitelistFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		if chainWhitelist != nil {
+			return chainWhitelist.LookupContext(ctx, opts.Owner)
+		}
+
+		whitelistURL = strings.Trim(os.Getenv("WHITELIST_URL"), "\"") // Trim any double quotes from the URL
 	}
 
-	whitelistFile := "/tmp/price-script.whitelist"
-	if shouldFetchWhitelist(whitelistFile) {
-		if err := fetchWhitelist(whitelistURL, whitelistFile); err != nil {
-			return fmt.Errorf("error fetching whitelist: %w", err)
+	if whitelistURL == "" {
+		return nil, nil // No whitelist URL set, skip checking
+	}
+	resolver := opts.Resolver
+	if resolver == nil {
+		resolver = NewWhitelistResolverFromEnv()
+	}
+	if resolver != nil {
+		resolver = contextResolver{ctx: ctx, resolver: resolver}
+	}
+	if localPath, ok := localWhitelistPath(whitelistURL); ok {
+		data, err := ioutil.ReadFile(localPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading whitelist: %w", err)
 		}
+		return findInWhitelist(data, detectWhitelistFormat("", whitelistURL), opts.Owner, resolver)
 	}
 
-	if err := verifyInWhitelist(whitelistFile, os.Getenv("AKASH_OWNER")); err != nil {
-		return err
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = DefaultWhitelistTTL
+	}
+	whitelistFile := opts.CacheFile
+	if whitelistFile == "" {
+		whitelistFile = whitelistCacheFile(whitelistURL)
 	}
 
-	return nil
+	if shouldFetchWhitelist(whitelistFile, ttl) {
+		if err := refreshWhitelist(ctx, whitelistURL, whitelistFile, ttl); err != nil {
+			return nil, err
+		}
+	}
+
+	return verifyInWhitelist(whitelistFile, opts.Owner, resolver)
+}
+
+// Apply enforces the entry metadata against the monthly USD cost and returns the discounted cost.
+func (e *WhitelistEntry) Apply(totalCostUsd sdkmath.LegacyDec, now time.Time) (sdkmath.LegacyDec, error) {
+	if e == nil {
+		return totalCostUsd, nil
+	}
+
+	if !e.Expiry.IsZero() && now.After(e.Expiry) {
+		return sdkmath.LegacyDec{}, withReason(ErrWhitelistLimit, fmt.Errorf("whitelist entry for %s expired on %s", e.Owner, e.Expiry.Format("2006-01-02")))
+	}
+
+	if e.DiscountPercent > 0 {
+		discount := totalCostUsd.Mul(decFromFloat(e.DiscountPercent)).QuoInt64(100)
+		totalCostUsd = totalCostUsd.Sub(discount)
+	}
+
+	if e.MaxMonthlySpend > 0 && totalCostUsd.GT(decFromFloat(e.MaxMonthlySpend)) {
+		return sdkmath.LegacyDec{}, withReason(ErrWhitelistLimit, fmt.Errorf("monthly cost %s USD exceeds max monthly spend %.2f USD for %s", FormatDec(totalCostUsd, 2), e.MaxMonthlySpend, e.Owner))
+	}
+
+	return totalCostUsd, nil
 }
 
-// shouldFetchWhitelist checks if the whitelist file should be fetched again.
-func shouldFetchWhitelist(whitelistFile string) bool {
+// shouldFetchWhitelist checks if the whitelist file is older than ttl and should be fetched again.
+func shouldFetchWhitelist(whitelistFile string, ttl time.Duration) bool {
 	fileInfo, err := os.Stat(whitelistFile)
-	if os.IsNotExist(err) || time.Since(fileInfo.ModTime()) > 10*time.Minute {
+	if err != nil || time.Since(fileInfo.ModTime()) > ttl {
 		return true
 	}
 	return false
 }
 
+// whitelistMeta is stored next to the cached whitelist to support conditional fetching.
+type whitelistMeta struct {
+	Format       whitelistFormat `json:"format"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"last_modified,omitempty"`
+}
+
+// whitelistMetaFile returns the path of the sidecar file holding metadata for a cached whitelist.
+func whitelistMetaFile(whitelistFile string) string {
+	return whitelistFile + ".meta"
+}
+
+// readWhitelistMeta reads the sidecar metadata for a cached whitelist, returning empty metadata if absent.
+func readWhitelistMeta(whitelistFile string) whitelistMeta {
+	var meta whitelistMeta
+	if data, err := ioutil.ReadFile(whitelistMetaFile(whitelistFile)); err == nil {
+		_ = json.Unmarshal(data, &meta)
+	}
+	return meta
+}
+
 // fetchWhitelist downloads the whitelist from the given URL and saves it.
-func fetchWhitelist(whitelistURL, whitelistFile string) error {
-	resp, err := http.Get(whitelistURL)
+// ETag and Last-Modified validators from the previous fetch are sent so an unchanged list is not
+// downloaded or rewritten again, and a list downloaded again with the same bytes is not rewritten either.
+// The payload is parsed before it replaces the cached copy so a malformed list never reaches the cache.
+func fetchWhitelist(ctx context.Context, whitelistURL, whitelistFile string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, whitelistURL, nil)
+	if err != nil {
+		return err
+	}
+
+	meta := readWhitelistMeta(whitelistFile)
+	if _, err := os.Stat(whitelistFile); err == nil {
+		if meta.ETag != "" {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		// Content is unchanged, only restart the cache TTL
+		now := time.Now()
+		return os.Chtimes(whitelistFile, now, now)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("HTTP request error: %s", resp.Status)
 	}
@@ -68,27 +249,410 @@ func fetchWhitelist(whitelistURL, whitelistFile string) error {
 		return err
 	}
 
-	return ioutil.WriteFile(whitelistFile, body, 0644)
+	format := detectWhitelistFormat(resp.Header.Get("Content-Type"), whitelistURL)
+	if _, err := parseWhitelist(body, format); err != nil {
+		return err
+	}
+
+	fetched := whitelistMeta{
+		Format:       format,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if fetched != meta {
+		metaData, err := json.Marshal(fetched)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(whitelistMetaFile(whitelistFile), metaData); err != nil {
+			return err
+		}
+	}
+	if cached, err := ioutil.ReadFile(whitelistFile); err == nil && bytes.Equal(cached, body) {
+		// Content is unchanged, only restart the cache TTL
+		now := time.Now()
+		return os.Chtimes(whitelistFile, now, now)
+	}
+	return writeFileAtomic(whitelistFile, body)
 }
 
-// verifyInWhitelist checks if the given owner is in the whitelist file.
-func verifyInWhitelist(whitelistFile, owner string) error {
-	file, err := os.Open(whitelistFile)
-	if err != nil {
+// refreshWhitelist fetches the whitelist unless a concurrent bid refreshed it first. A failed fetch keeps
+// using the stale copy when there is one; a done ctx fails without fetching.
+func refreshWhitelist(ctx context.Context, whitelistURL, whitelistFile string, ttl time.Duration) error {
+	unlock := lockCache(whitelistFile)
+	defer unlock()
+	if !shouldFetchWhitelist(whitelistFile, ttl) {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		if strings.TrimSpace(scanner.Text()) == owner {
-			return nil // Owner is in the whitelist
+	if err := fetchWhitelist(ctx, whitelistURL, whitelistFile); err != nil {
+		if _, statErr := os.Stat(whitelistFile); statErr != nil {
+			return fmt.Errorf("error fetching whitelist: %w", err)
 		}
+		log.Printf("Error fetching whitelist, using stale copy: %v", err)
+	}
+	return nil
+}
+
+// verifyInWhitelist checks if the given owner is in the whitelist file and returns its entry.
+func verifyInWhitelist(whitelistFile, owner string, resolver WhitelistResolver) (*WhitelistEntry, error) {
+	data, err := ioutil.ReadFile(whitelistFile)
+	if err != nil {
+		return nil, err
 	}
+	return findInWhitelist(data, readWhitelistMeta(whitelistFile).Format, owner, resolver)
+}
 
-	if err := scanner.Err(); err != nil {
-		return err
+// findInWhitelist parses whitelist data and returns the owner's entry. Owners without an entry of their own
+// are looked up by their parent accounts from resolver, if any.
+func findInWhitelist(data []byte, format whitelistFormat, owner string, resolver WhitelistResolver) (*WhitelistEntry, error) {
+	entries, err := parseWhitelist(data, format)
+	if err != nil {
+		return nil, err
 	}
 
-	return fmt.Errorf("%s is not whitelisted", owner)
+	// Deny entries are checked before any allow entry, so no allow entry can override them
+	if deny := matchWhitelist(entries, owner, true); deny != nil {
+		return nil, withReason(ErrNotWhitelisted, fmt.Errorf("%s is denied by whitelist entry !%s", owner, deny.Owner))
+	}
+	if entry := matchWhitelist(entries, owner, false); entry != nil {
+		return entry, nil // Owner is in the whitelist
+	}
+	if resolver != nil {
+		parents, err := resolver.ParentAccounts(owner)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving parent accounts of %s: %w", owner, err)
+		}
+		for _, parent := range parents {
+			if matchWhitelist(entries, parent, true) != nil {
+				continue // A denied parent whitelists none of its accounts
+			}
+			if entry := matchWhitelist(entries, parent, false); entry != nil {
+				if entry.MatchedBy == "" {
+					entry.MatchedBy = parent
+				}
+				entry.Owner, entry.Parent = owner, parent
+				return entry, nil
+			}
+		}
+	}
+
+	return nil, withReason(ErrNotWhitelisted, fmt.Errorf("%s is not whitelisted", owner))
+}
+
+// matchWhitelist returns a copy of the allow or deny entry of owner, or of the longest prefix entry of that
+// kind matching it, or nil. The copy's Owner is owner, and MatchedBy is the prefix entry for a prefix match.
+func matchWhitelist(entries []WhitelistEntry, owner string, deny bool) *WhitelistEntry {
+	var match *WhitelistEntry
+	for i := range entries {
+		if entries[i].Deny != deny {
+			continue
+		}
+		if entries[i].Owner == owner {
+			entry := entries[i]
+			return &entry
+		}
+		prefix, ok := strings.CutSuffix(entries[i].Owner, "*")
+		if !ok || !strings.HasPrefix(owner, prefix) {
+			continue
+		}
+		if match == nil || len(entries[i].Owner) > len(match.MatchedBy) {
+			entry := entries[i]
+			entry.Owner, entry.MatchedBy = owner, entries[i].Owner
+			match = &entry
+		}
+	}
+	return match
+}
+
+// localWhitelistPath returns the path of a file:// whitelist URL, such as a whitelist mounted from a
+// ConfigMap. Local whitelists are read on every lookup instead of being cached, so an updated file applies
+// to the next bid.
+func localWhitelistPath(whitelistURL string) (string, bool) {
+	if !strings.HasPrefix(whitelistURL, "file://") {
+		return "", false
+	}
+	return strings.TrimPrefix(whitelistURL, "file://"), true
+}
+
+// whitelistFormat identifies the encoding of a whitelist payload.
+type whitelistFormat string
+
+const (
+	whitelistFormatPlain whitelistFormat = "plain"
+	whitelistFormatCSV   whitelistFormat = "csv"
+	whitelistFormatJSON  whitelistFormat = "json"
+)
+
+// detectWhitelistFormat determines the whitelist format from the response Content-Type, then the URL extension.
+// It returns an empty format when neither is conclusive, in which case the payload is sniffed.
+func detectWhitelistFormat(contentType, whitelistURL string) whitelistFormat {
+	contentType = strings.ToLower(contentType)
+	switch {
+	case strings.Contains(contentType, "json"):
+		return whitelistFormatJSON
+	case strings.Contains(contentType, "csv"):
+		return whitelistFormatCSV
+	}
+
+	if u, err := url.Parse(whitelistURL); err == nil {
+		switch strings.ToLower(path.Ext(u.Path)) {
+		case ".json":
+			return whitelistFormatJSON
+		case ".csv":
+			return whitelistFormatCSV
+		case ".txt":
+			return whitelistFormatPlain
+		}
+	}
+	return ""
+}
+
+// sniffWhitelistFormat guesses the whitelist format from the payload itself.
+func sniffWhitelistFormat(data []byte) whitelistFormat {
+	if data[0] == '[' || data[0] == '{' {
+		return whitelistFormatJSON
+	}
+
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	if bytes.ContainsRune(firstLine, ',') || isWhitelistCSVHeader(strings.Split(string(firstLine), ",")) {
+		return whitelistFormatCSV
+	}
+	return whitelistFormatPlain
+}
+
+// parseWhitelist parses whitelist data in the given format, sniffing it when the format is empty.
+// Duplicate owners and malformed entries are reported as errors.
+func parseWhitelist(data []byte, format whitelistFormat) ([]WhitelistEntry, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))) // Strip a UTF-8 BOM
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if format == "" {
+		format = sniffWhitelistFormat(data)
+	}
+
+	var entries []WhitelistEntry
+	var err error
+	switch format {
+	case whitelistFormatJSON:
+		entries, err = parseJSONWhitelist(data)
+	case whitelistFormatCSV:
+		entries, err = parseCSVWhitelist(data)
+	case whitelistFormatPlain:
+		entries, err = parsePlainWhitelist(data)
+	default:
+		return nil, fmt.Errorf("unsupported whitelist format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(entries))
+	for i := range entries {
+		entry := &entries[i]
+		raw := entry.Owner
+		if seen[raw] {
+			return nil, fmt.Errorf("duplicate whitelist entry for %s", raw)
+		}
+		seen[raw] = true
+
+		if owner, ok := strings.CutPrefix(raw, "!"); ok {
+			entry.Owner, entry.Deny = owner, true
+		}
+		if entry.Owner == "" || strings.HasPrefix(entry.Owner, "!") {
+			return nil, fmt.Errorf("invalid whitelist entry %s: \"!\" must be followed by an address", raw)
+		}
+		// A prefix entry must keep a prefix, so a stray "*" cannot whitelist every owner
+		if star := strings.IndexByte(entry.Owner, '*'); star >= 0 && (star != len(entry.Owner)-1 || star == 0) {
+			return nil, fmt.Errorf("invalid whitelist entry %s: \"*\" may only end a non-empty prefix", raw)
+		}
+	}
+
+	return entries, nil
+}
+
+// parsePlainWhitelist parses a newline-delimited list of owner addresses.
+func parsePlainWhitelist(data []byte) ([]WhitelistEntry, error) {
+	var entries []WhitelistEntry
+	for i, line := range strings.Split(string(data), "\n") {
+		owner := strings.TrimSpace(line)
+		if owner == "" {
+			continue
+		}
+		if strings.ContainsAny(owner, " \t,;\"'{}[]") {
+			return nil, fmt.Errorf("invalid whitelist address on line %d: %q", i+1, owner)
+		}
+		entries = append(entries, WhitelistEntry{Owner: owner})
+	}
+	return entries, nil
+}
+
+// parseJSONWhitelist parses either a JSON array of entries or an object holding them under "entries".
+func parseJSONWhitelist(data []byte) ([]WhitelistEntry, error) {
+	var raw []whitelistJSONEntry
+	if data[0] == '{' {
+		var doc struct {
+			Entries *[]whitelistJSONEntry `json:"entries"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid JSON whitelist: %w", err)
+		}
+		if doc.Entries == nil {
+			return nil, fmt.Errorf("invalid JSON whitelist: missing \"entries\" array")
+		}
+		raw = *doc.Entries
+	} else if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON whitelist: %w", err)
+	}
+
+	entries := make([]WhitelistEntry, 0, len(raw))
+	for i, r := range raw {
+		owner := strings.TrimSpace(r.Owner)
+		if owner == "" {
+			return nil, fmt.Errorf("invalid JSON whitelist: entry %d has no owner", i)
+		}
+		expiry, err := parseWhitelistExpiry(r.Expiry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiry for %s: %w", owner, err)
+		}
+		entry := WhitelistEntry{
+			Owner:           owner,
+			DiscountPercent: r.DiscountPercent,
+			Expiry:          expiry,
+			MaxMonthlySpend: r.MaxMonthlySpend,
+		}
+		if err := entry.validate(); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// whitelistCSVColumns maps accepted CSV header names to canonical column names.
+var whitelistCSVColumns = map[string]string{
+	"owner":             "owner",
+	"address":           "owner",
+	"discount_percent":  "discount_percent",
+	"discount":          "discount_percent",
+	"expiry":            "expiry",
+	"expires":           "expiry",
+	"max_monthly_spend": "max_monthly_spend",
+	"max_spend":         "max_monthly_spend",
+}
+
+// whitelistCSVPositional is the column order used when a CSV whitelist has no header row.
+var whitelistCSVPositional = []string{"owner", "discount_percent", "expiry", "max_monthly_spend"}
+
+// isWhitelistCSVHeader reports whether a CSV record is a header row, i.e. any cell is a known column name.
+func isWhitelistCSVHeader(record []string) bool {
+	for _, cell := range record {
+		if _, ok := whitelistCSVColumns[strings.ToLower(strings.TrimSpace(cell))]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCSVWhitelist parses CSV whitelist rows. Columns are mapped by name when a header row is present,
+// otherwise they are read in the order owner,discount_percent,expiry,max_monthly_spend.
+func parseCSVWhitelist(data []byte) ([]WhitelistEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV whitelist: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := whitelistCSVPositional
+	if isWhitelistCSVHeader(records[0]) {
+		columns = make([]string, len(records[0]))
+		hasOwner := false
+		for i, cell := range records[0] {
+			name, ok := whitelistCSVColumns[strings.ToLower(strings.TrimSpace(cell))]
+			if !ok {
+				return nil, fmt.Errorf("invalid CSV whitelist: unknown column %q", cell)
+			}
+			columns[i] = name
+			hasOwner = hasOwner || name == "owner"
+		}
+		if !hasOwner {
+			return nil, fmt.Errorf("invalid CSV whitelist: missing owner column")
+		}
+		records = records[1:]
+	}
+
+	var entries []WhitelistEntry
+	for line, record := range records {
+		if len(record) > len(columns) {
+			return nil, fmt.Errorf("invalid CSV whitelist: row %d has %d columns, expected at most %d", line+1, len(record), len(columns))
+		}
+
+		values := make(map[string]string, len(record))
+		for i, cell := range record {
+			values[columns[i]] = strings.TrimSpace(cell)
+		}
+
+		owner := values["owner"]
+		if owner == "" {
+			continue
+		}
+
+		entry := WhitelistEntry{Owner: owner}
+		if v := values["discount_percent"]; v != "" {
+			if entry.DiscountPercent, err = strconv.ParseFloat(v, 64); err != nil {
+				return nil, fmt.Errorf("invalid discount for %s: %v", owner, err)
+			}
+		}
+		if entry.Expiry, err = parseWhitelistExpiry(values["expiry"]); err != nil {
+			return nil, fmt.Errorf("invalid expiry for %s: %v", owner, err)
+		}
+		if v := values["max_monthly_spend"]; v != "" {
+			if entry.MaxMonthlySpend, err = strconv.ParseFloat(v, 64); err != nil {
+				return nil, fmt.Errorf("invalid max monthly spend for %s: %v", owner, err)
+			}
+		}
+		if err := entry.validate(); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// validate checks that the entry metadata is within its allowed ranges.
+func (e *WhitelistEntry) validate() error {
+	if e.DiscountPercent < 0 || e.DiscountPercent > 100 {
+		return fmt.Errorf("invalid discount for %s: %v is outside 0-100", e.Owner, e.DiscountPercent)
+	}
+	if e.MaxMonthlySpend < 0 {
+		return fmt.Errorf("invalid max monthly spend for %s: %v is negative", e.Owner, e.MaxMonthlySpend)
+	}
+	return nil
+}
+
+// parseWhitelistExpiry parses an expiry given either as a date (2006-01-02) or an RFC3339 timestamp.
+// A date-only expiry is valid until the end of that day (UTC).
+func parseWhitelistExpiry(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.Add(24*time.Hour - time.Nanosecond), nil
+	}
+	return time.Parse(time.RFC3339, value)
 }
@@ -0,0 +1,292 @@
+package pricing
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	audittypes "pkg.akt.dev/go/node/audit/v1"
+	dv1 "pkg.akt.dev/go/node/deployment/v1"
+	dtypes "pkg.akt.dev/go/node/deployment/v1beta4"
+)
+
+// ChainWhitelist decides whether an owner is whitelisted by querying the Akash chain over gRPC
+// instead of a hosted whitelist file. Every configured requirement must hold for the owner to pass.
+// Lookups share one connection, and each owner's answer is reused for TTL.
+type ChainWhitelist struct {
+	GRPCAddress    string        // host:port of an Akash node gRPC endpoint
+	UseTLS         bool          // Dial the endpoint with TLS
+	Timeout        time.Duration // Timeout for all queries of a single lookup
+	TTL            time.Duration // Lifetime of an owner's answer, defaults to DefaultWhitelistTTL
+	MinDeployments uint64        // Minimum number of deployments (any state) the owner must have created
+	MinBalance     *sdk.Coin     // Minimum bank balance the owner must hold, e.g. 5000000uakt
+	Auditor        string        // Auditor that must have signed Attribute for the owner
+	Attribute      string        // Required audited attribute as key=value, checked when Auditor is set
+
+	mu      sync.Mutex
+	conn    *grpc.ClientConn
+	closed  bool
+	results map[string]chainWhitelistResult
+}
+
+// chainWhitelistResult is a cached ChainWhitelist answer for an owner.
+type chainWhitelistResult struct {
+	entry   *WhitelistEntry
+	err     error
+	checked time.Time
+}
+
+// chainWhitelists shares one ChainWhitelist, and so its connection and answers, per gRPC endpoint across
+// bids of the process. A changed configuration replaces it.
+var (
+	chainWhitelistsMu sync.Mutex
+	chainWhitelists   = make(map[string]*ChainWhitelist)
+)
+
+// NewChainWhitelistFromEnv returns the ChainWhitelist configured by WHITELIST_CHAIN_* environment variables,
+// shared by every caller while the configuration is unchanged. It returns nil when WHITELIST_CHAIN_GRPC is
+// not set.
+func NewChainWhitelistFromEnv() (*ChainWhitelist, error) {
+	address := strings.Trim(os.Getenv("WHITELIST_CHAIN_GRPC"), "\"")
+	if address == "" {
+		return nil, nil
+	}
+
+	wl := &ChainWhitelist{
+		GRPCAddress: address,
+		UseTLS:      os.Getenv("WHITELIST_CHAIN_GRPC_TLS") == "true",
+		Timeout:     5 * time.Second,
+		TTL:         DefaultWhitelistTTL,
+		Auditor:     os.Getenv("WHITELIST_CHAIN_AUDITOR"),
+		Attribute:   os.Getenv("WHITELIST_CHAIN_ATTRIBUTE"),
+	}
+
+	if val := os.Getenv("WHITELIST_CHAIN_MIN_DEPLOYMENTS"); val != "" {
+		minDeployments, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WHITELIST_CHAIN_MIN_DEPLOYMENTS: %v", err)
+		}
+		wl.MinDeployments = minDeployments
+	}
+
+	if val := os.Getenv("WHITELIST_CHAIN_MIN_BALANCE"); val != "" {
+		minBalance, err := sdk.ParseCoinNormalized(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WHITELIST_CHAIN_MIN_BALANCE: %v", err)
+		}
+		wl.MinBalance = &minBalance
+	}
+
+	if wl.Auditor != "" && !strings.Contains(wl.Attribute, "=") {
+		return nil, fmt.Errorf("WHITELIST_CHAIN_ATTRIBUTE must be key=value when WHITELIST_CHAIN_AUDITOR is set")
+	}
+
+	chainWhitelistsMu.Lock()
+	defer chainWhitelistsMu.Unlock()
+	if shared, ok := chainWhitelists[address]; ok {
+		if shared.sameRequirements(wl) {
+			return shared, nil
+		}
+		shared.Close()
+	}
+	chainWhitelists[address] = wl
+	return wl, nil
+}
+
+// sameRequirements reports whether other queries the same endpoint for the same requirements.
+func (w *ChainWhitelist) sameRequirements(other *ChainWhitelist) bool {
+	sameBalance := w.MinBalance == nil && other.MinBalance == nil ||
+		w.MinBalance != nil && other.MinBalance != nil && w.MinBalance.Denom == other.MinBalance.Denom && w.MinBalance.Amount.Equal(other.MinBalance.Amount)
+	return w.GRPCAddress == other.GRPCAddress && w.UseTLS == other.UseTLS && w.Timeout == other.Timeout && w.TTL == other.TTL &&
+		w.MinDeployments == other.MinDeployments && sameBalance && w.Auditor == other.Auditor && w.Attribute == other.Attribute
+}
+
+// Lookup checks the owner against the on-chain requirements and returns a metadata-free entry when all pass.
+// Answers are reused for TTL; failed queries are not, so the next lookup asks the chain again.
+func (w *ChainWhitelist) Lookup(owner string) (*WhitelistEntry, error) {
+	return w.LookupContext(context.Background(), owner)
+}
+
+// LookupContext is Lookup with its queries cancelled with ctx. None is sent once ctx is done.
+func (w *ChainWhitelist) LookupContext(ctx context.Context, owner string) (*WhitelistEntry, error) {
+	ttl := w.TTL
+	if ttl <= 0 {
+		ttl = DefaultWhitelistTTL
+	}
+	w.mu.Lock()
+	cached, ok := w.results[owner]
+	w.mu.Unlock()
+	if ok && time.Since(cached.checked) < ttl {
+		return copyWhitelistEntry(cached.entry), cached.err
+	}
+
+	entry, err := w.check(ctx, owner)
+	if err != nil && !errors.Is(err, ErrNotWhitelisted) {
+		return nil, err
+	}
+
+	now := time.Now()
+	w.mu.Lock()
+	if w.results == nil {
+		w.results = make(map[string]chainWhitelistResult)
+	}
+	for cachedOwner, result := range w.results {
+		if now.Sub(result.checked) >= ttl {
+			delete(w.results, cachedOwner)
+		}
+	}
+	w.results[owner] = chainWhitelistResult{entry: entry, err: err, checked: now}
+	w.mu.Unlock()
+	return copyWhitelistEntry(entry), err
+}
+
+// copyWhitelistEntry returns a copy of entry so callers cannot change a cached answer, or nil.
+func copyWhitelistEntry(entry *WhitelistEntry) *WhitelistEntry {
+	if entry == nil {
+		return nil
+	}
+	entryCopy := *entry
+	return &entryCopy
+}
+
+// check queries every requirement for the owner, stopping before the next query once ctx is done.
+func (w *ChainWhitelist) check(ctx context.Context, owner string) (*WhitelistEntry, error) {
+	conn, err := w.client()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.Timeout)
+	defer cancel()
+
+	for _, requirement := range []func(context.Context, grpc.ClientConnInterface, string) error{w.checkDeployments, w.checkBalance, w.checkAudit} {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := requirement(ctx, conn, owner); err != nil {
+			return nil, err
+		}
+	}
+
+	return &WhitelistEntry{Owner: owner}, nil
+}
+
+// client returns the connection to GRPCAddress, creating it on first use. The connection reconnects on
+// its own, so it is kept until Close.
+func (w *ChainWhitelist) client() (*grpc.ClientConn, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		return w.conn, nil
+	}
+	if w.closed {
+		return nil, fmt.Errorf("chain whitelist for %s is closed", w.GRPCAddress)
+	}
+
+	creds := insecure.NewCredentials()
+	if w.UseTLS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.NewClient(w.GRPCAddress, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", w.GRPCAddress, err)
+	}
+	w.conn = conn
+	return conn, nil
+}
+
+// Close closes the connection and forgets every cached answer. Later lookups fail.
+func (w *ChainWhitelist) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	w.results = nil
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// checkDeployments verifies the owner has created at least MinDeployments deployments.
+func (w *ChainWhitelist) checkDeployments(ctx context.Context, conn grpc.ClientConnInterface, owner string) error {
+	if w.MinDeployments == 0 {
+		return nil
+	}
+
+	resp, err := dtypes.NewQueryClient(conn).Deployments(ctx, &dtypes.QueryDeploymentsRequest{
+		Filters:    dv1.DeploymentFilters{Owner: owner},
+		Pagination: &query.PageRequest{Limit: 1, CountTotal: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error querying deployments for %s: %w", owner, err)
+	}
+
+	var total uint64
+	if resp.Pagination != nil {
+		total = resp.Pagination.Total
+	}
+	if total < w.MinDeployments {
+		return withReason(ErrNotWhitelisted, fmt.Errorf("%s is not whitelisted: %d deployments, %d required", owner, total, w.MinDeployments))
+	}
+	return nil
+}
+
+// checkBalance verifies the owner holds at least MinBalance.
+func (w *ChainWhitelist) checkBalance(ctx context.Context, conn grpc.ClientConnInterface, owner string) error {
+	if w.MinBalance == nil {
+		return nil
+	}
+
+	resp, err := banktypes.NewQueryClient(conn).Balance(ctx, &banktypes.QueryBalanceRequest{
+		Address: owner,
+		Denom:   w.MinBalance.Denom,
+	})
+	if err != nil {
+		return fmt.Errorf("error querying balance for %s: %w", owner, err)
+	}
+
+	if resp.Balance == nil || resp.Balance.IsLT(*w.MinBalance) {
+		return withReason(ErrNotWhitelisted, fmt.Errorf("%s is not whitelisted: balance below %s", owner, w.MinBalance))
+	}
+	return nil
+}
+
+// checkAudit verifies Auditor has signed the required attribute for the owner.
+func (w *ChainWhitelist) checkAudit(ctx context.Context, conn grpc.ClientConnInterface, owner string) error {
+	if w.Auditor == "" {
+		return nil
+	}
+
+	resp, err := audittypes.NewQueryClient(conn).ProviderAuditorAttributes(ctx, &audittypes.QueryProviderAuditorRequest{
+		Auditor: w.Auditor,
+		Owner:   owner,
+	})
+	if err != nil {
+		return fmt.Errorf("error querying audited attributes for %s: %w", owner, err)
+	}
+
+	key, value, _ := strings.Cut(w.Attribute, "=")
+	for _, audited := range resp.Providers {
+		for _, attr := range audited.Attributes {
+			if attr.Key == key && attr.Value == value {
+				return nil
+			}
+		}
+	}
+	return withReason(ErrNotWhitelisted, fmt.Errorf("%s is not whitelisted: missing attribute %s signed by %s", owner, w.Attribute, w.Auditor))
+}
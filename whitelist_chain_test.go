@@ -0,0 +1,60 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestNewChainWhitelistFromEnvShared checks bids share one ChainWhitelist until its configuration changes.
+func TestNewChainWhitelistFromEnvShared(t *testing.T) {
+	lookup := func(minDeployments string) *ChainWhitelist {
+		restore := isolateEnv(map[string]string{"WHITELIST_CHAIN_GRPC": "127.0.0.1:1", "WHITELIST_CHAIN_MIN_DEPLOYMENTS": minDeployments})
+		defer restore()
+		wl, err := NewChainWhitelistFromEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return wl
+	}
+
+	first := lookup("1")
+	if again := lookup("1"); again != first {
+		t.Error("unchanged configuration built a second chain whitelist")
+	}
+	changed := lookup("2")
+	if changed == first {
+		t.Fatal("changed configuration kept the previous chain whitelist")
+	}
+	if _, err := first.Lookup("akash1owner"); err == nil {
+		t.Error("replaced chain whitelist still answers lookups")
+	}
+	changed.Close()
+}
+
+// TestChainWhitelistLookupCached checks answers are reused for TTL and failed queries are not kept.
+func TestChainWhitelistLookupCached(t *testing.T) {
+	now := time.Now()
+	wl := &ChainWhitelist{GRPCAddress: "127.0.0.1:1", Timeout: time.Second, TTL: time.Minute, MinDeployments: 1}
+	defer wl.Close()
+	wl.results = map[string]chainWhitelistResult{
+		"akash1allowed": {entry: &WhitelistEntry{Owner: "akash1allowed"}, checked: now},
+		"akash1denied":  {err: withReason(ErrNotWhitelisted, fmt.Errorf("akash1denied is not whitelisted")), checked: now},
+		"akash1stale":   {entry: &WhitelistEntry{Owner: "akash1stale"}, checked: now.Add(-2 * time.Minute)},
+	}
+
+	if entry, err := wl.Lookup("akash1allowed"); err != nil || entry.Owner != "akash1allowed" {
+		t.Errorf("akash1allowed: got %v, %v", entry, err)
+	}
+	if _, err := wl.Lookup("akash1denied"); !errors.Is(err, ErrNotWhitelisted) {
+		t.Errorf("akash1denied: got %v, want %v", err, ErrNotWhitelisted)
+	}
+	// The endpoint is unreachable, so an expired answer is asked again and the failure is not cached
+	if _, err := wl.Lookup("akash1stale"); err == nil || errors.Is(err, ErrNotWhitelisted) {
+		t.Errorf("akash1stale: got %v, want a query error", err)
+	}
+	if result := wl.results["akash1stale"]; !result.checked.Equal(now.Add(-2 * time.Minute)) {
+		t.Error("akash1stale: failed query was cached")
+	}
+}
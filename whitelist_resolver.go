@@ -0,0 +1,125 @@
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// WhitelistResolver maps an owner to its parent accounts, such as the organization account its derived
+// addresses belong to. Owners without a whitelist entry of their own are whitelisted through the entry of
+// their first whitelisted parent.
+type WhitelistResolver interface {
+	ParentAccounts(owner string) ([]string, error)
+}
+
+// HTTPWhitelistResolver fetches parent accounts from an external service. The owner is substituted for
+// "{owner}" in URL, or appended as an "owner" query parameter otherwise. The service must respond with JSON
+// of the form {"parents": ["akash1..."]}, and 404 for owners it does not know.
+type HTTPWhitelistResolver struct {
+	URL    string
+	Client *http.Client
+}
+
+// ParentAccounts queries the mapping service for the owner.
+func (r *HTTPWhitelistResolver) ParentAccounts(owner string) ([]string, error) {
+	reqURL, err := ownerURL(r.URL, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	client := r.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	resp, err := client.Get(reqURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("HTTP request error: %s", resp.Status)
+	}
+
+	var data struct {
+		Parents []string `json:"parents"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	return data.Parents, nil
+}
+
+// ownerURL substitutes owner for "{owner}" in rawURL, or appends it as an "owner" query parameter otherwise.
+func ownerURL(rawURL, owner string) (string, error) {
+	if strings.Contains(rawURL, "{owner}") {
+		return strings.ReplaceAll(rawURL, "{owner}", url.PathEscape(owner)), nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("owner", owner)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// FileWhitelistResolver reads parent accounts from a local JSON file mapping owner addresses to lists of
+// parents. Owners missing from the file have none.
+type FileWhitelistResolver struct {
+	Path string
+}
+
+// ParentAccounts looks the owner up in the mapping file.
+func (r *FileWhitelistResolver) ParentAccounts(owner string) ([]string, error) {
+	data, err := ioutil.ReadFile(r.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var parents map[string][]string
+	if err := json.Unmarshal(data, &parents); err != nil {
+		return nil, fmt.Errorf("invalid whitelist parents %s: %w", r.Path, err)
+	}
+	return parents[owner], nil
+}
+
+// contextResolver checks ctx before each lookup of resolver, so a bid that was given up on does not query
+// the mapping service.
+type contextResolver struct {
+	ctx      context.Context
+	resolver WhitelistResolver
+}
+
+// ParentAccounts looks the owner up in resolver unless ctx is done.
+func (r contextResolver) ParentAccounts(owner string) ([]string, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.resolver.ParentAccounts(owner)
+}
+
+// NewWhitelistResolverFromEnv returns the resolver configured by WHITELIST_RESOLVER_URL or
+// WHITELIST_PARENTS_FILE, or nil.
+func NewWhitelistResolverFromEnv() WhitelistResolver {
+	if resolverURL := os.Getenv("WHITELIST_RESOLVER_URL"); resolverURL != "" {
+		return &HTTPWhitelistResolver{URL: resolverURL}
+	}
+	if parentsFile := os.Getenv("WHITELIST_PARENTS_FILE"); parentsFile != "" {
+		return &FileWhitelistResolver{Path: parentsFile}
+	}
+	return nil
+}
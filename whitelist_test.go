@@ -0,0 +1,124 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// parentResolver resolves parent accounts from a fixed map.
+type parentResolver map[string][]string
+
+func (r parentResolver) ParentAccounts(owner string) ([]string, error) {
+	return r[owner], nil
+}
+
+// TestFindInWhitelist checks exact, prefix and parent account matches, which entry each owner matched, and
+// that deny entries win over every allow entry.
+func TestFindInWhitelist(t *testing.T) {
+	whitelist := []byte("akash1exact\nakash1abcdef*\nakash1abc*\nakash1org\nakash1team*\n" +
+		"!akash1abcbad\n!akash1exact\n!akash1abcx*\nakash1abcxok\n!akash1rogue\n")
+	resolver := parentResolver{
+		"akash1derived":  {"akash1unknown", "akash1org"},
+		"akash1member":   {"akash1team42"},
+		"akash1deserter": {"akash1rogue", "akash1org"},
+		"akash1mixed":    {"akash1exact"},
+	}
+
+	tests := []struct {
+		owner     string
+		matchedBy string
+		parent    string
+		err       error
+	}{
+		{owner: "akash1org"},
+		{owner: "akash1abc123", matchedBy: "akash1abc*"},
+		{owner: "akash1abcdef9", matchedBy: "akash1abcdef*"},
+		{owner: "akash1derived", matchedBy: "akash1org", parent: "akash1org"},
+		{owner: "akash1member", matchedBy: "akash1team*", parent: "akash1team42"},
+		{owner: "akash1deserter", matchedBy: "akash1org", parent: "akash1org"},
+		{owner: "akash1other", err: ErrNotWhitelisted},
+		{owner: "akash1abcbad", err: ErrNotWhitelisted}, // Allowed by akash1abc*
+		{owner: "akash1exact", err: ErrNotWhitelisted},  // Allowed by its own entry
+		{owner: "akash1abcxok", err: ErrNotWhitelisted}, // Allowed by its own entry, denied by prefix
+		{owner: "akash1mixed", err: ErrNotWhitelisted},  // Whitelisted only through a denied parent
+	}
+	for _, tt := range tests {
+		entry, err := findInWhitelist(whitelist, whitelistFormatPlain, tt.owner, resolver)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: error %v, want %v", tt.owner, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.owner, err)
+			continue
+		}
+		if entry.Owner != tt.owner || entry.MatchedBy != tt.matchedBy || entry.Parent != tt.parent || entry.Deny {
+			t.Errorf("%s: matched %s by %q through %q, want by %q through %q", tt.owner, entry.Owner, entry.MatchedBy, entry.Parent, tt.matchedBy, tt.parent)
+		}
+	}
+
+	for _, invalid := range []string{"*\n", "akash1a*bc\n", "!\n", "!*\n", "!!akash1abc\n", "!akash1abc\n!akash1abc\n"} {
+		if _, err := findInWhitelist([]byte(invalid), whitelistFormatPlain, "akash1abc", nil); err == nil {
+			t.Errorf("whitelist %q was accepted", invalid)
+		}
+	}
+}
+
+// TestFetchWhitelistUnchanged checks a list served again with the same bytes only restarts the cache TTL, and
+// that each URL has its own cache file.
+func TestFetchWhitelistUnchanged(t *testing.T) {
+	body := "akash1exact\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body) // No validators, so every fetch is a 200
+	}))
+	defer server.Close()
+
+	whitelistFile := filepath.Join(t.TempDir(), "whitelist")
+	if err := fetchWhitelist(context.Background(), server.URL+"/whitelist.txt", whitelistFile); err != nil {
+		t.Fatal(err)
+	}
+	stale := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(whitelistFile, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Stat(whitelistFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fetchWhitelist(context.Background(), server.URL+"/whitelist.txt", whitelistFile); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.Stat(whitelistFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(before, after) {
+		t.Error("unchanged whitelist was rewritten")
+	}
+	if !after.ModTime().After(stale) {
+		t.Error("unchanged whitelist did not restart the cache TTL")
+	}
+
+	body = "akash1exact\nakash1new\n"
+	if err := fetchWhitelist(context.Background(), server.URL+"/whitelist.txt", whitelistFile); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile(whitelistFile); err != nil || string(data) != body {
+		t.Errorf("changed whitelist: got %q, %v", data, err)
+	}
+
+	if whitelistCacheFile("https://a.example/whitelist") == whitelistCacheFile("https://b.example/whitelist") {
+		t.Error("whitelist URLs share a cache file")
+	}
+}